@@ -2,9 +2,12 @@ package system
 
 import (
 	"fmt"
+	"os"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -50,6 +53,37 @@ func (a *AutoCodePluginApi) Install(c *gin.Context) {
 		}}, c)
 }
 
+// InstallAsync
+// @Tags      AutoCodePlugin
+// @Summary   创建后台安装插件任务
+// @Security  ApiKeyAuth
+// @accept    multipart/form-data
+// @Produce   application/json
+// @Param     plug  formData  file                                                   true  "插件压缩包"
+// @Success   200   {object}  response.Response{data=system.SysAsyncTask,msg=string}  "返回后台任务"
+// @Router    /autoCode/installPluginAsync [post]
+func (a *AutoCodePluginApi) InstallAsync(c *gin.Context) {
+	header, err := c.FormFile("plug")
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	path, err := asyncTaskService.SaveAsyncTaskUpload(header)
+	if err != nil {
+		global.GVA_LOG.Error("文件保存失败!", zap.Error(err))
+		response.FailWithMessage("文件保存失败", c)
+		return
+	}
+	task, err := asyncTaskService.Enqueue(utils.GetUserID(c), "InstallPlugin", request.InstallPluginTask{File: path})
+	if err != nil {
+		os.Remove(path)
+		global.GVA_LOG.Error("创建安装插件任务失败!", zap.Error(err))
+		response.FailWithMessage("创建安装插件任务失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(task, "已开始安装", c)
+}
+
 // Packaged
 // @Tags      AutoCodePlugin
 // @Summary   打包插件
@@ -82,6 +82,41 @@ func (a *AutoCodeTemplateApi) Create(c *gin.Context) {
 	}
 }
 
+// CreateAsync
+// @Tags      AutoCodeTemplate
+// @Summary   创建后台代码生成任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.AutoCode                                        true  "创建自动代码"
+// @Success   200   {object}  response.Response{data=system.SysAsyncTask,msg=string}  "返回后台任务"
+// @Router    /autoCode/createTempAsync [post]
+func (a *AutoCodeTemplateApi) CreateAsync(c *gin.Context) {
+	var info request.AutoCode
+	err := c.ShouldBindJSON(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(info, utils.AutoCodeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = info.Pretreatment()
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	task, err := asyncTaskService.Enqueue(utils.GetUserID(c), "CreateAutoCode", info)
+	if err != nil {
+		global.GVA_LOG.Error("创建代码生成任务失败!", zap.Error(err))
+		response.FailWithMessage("创建代码生成任务失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(task, "已开始生成", c)
+}
+
 // AddFunc
 // @Tags      AddFunc
 // @Summary   增加方法
@@ -6,6 +6,7 @@ type ApiGroup struct {
 	DBApi
 	JwtApi
 	BaseApi
+	UserGroupApi
 	SystemApi
 	CasbinApi
 	AutoCodeApi
@@ -23,6 +24,11 @@ type ApiGroup struct {
 	AutoCodeTemplateApi
 	SysParamsApi
 	SysVersionApi
+	ChangeHistoryApi
+	SysJobApi
+	SysAsyncTaskApi
+	SysExportSubscriptionApi
+	SysExportWorkbookApi
 }
 
 var (
@@ -30,6 +36,7 @@ var (
 	jwtService              = service.ServiceGroupApp.SystemServiceGroup.JwtService
 	menuService             = service.ServiceGroupApp.SystemServiceGroup.MenuService
 	userService             = service.ServiceGroupApp.SystemServiceGroup.UserService
+	userGroupService        = service.ServiceGroupApp.SystemServiceGroup.UserGroupService
 	initDBService           = service.ServiceGroupApp.SystemServiceGroup.InitDBService
 	casbinService           = service.ServiceGroupApp.SystemServiceGroup.CasbinService
 	baseMenuService         = service.ServiceGroupApp.SystemServiceGroup.BaseMenuService
@@ -46,4 +53,9 @@ var (
 	autoCodeHistoryService  = service.ServiceGroupApp.SystemServiceGroup.AutoCodeHistory
 	autoCodeTemplateService = service.ServiceGroupApp.SystemServiceGroup.AutoCodeTemplate
 	sysVersionService       = service.ServiceGroupApp.SystemServiceGroup.SysVersionService
+	changeHistoryService    = service.ServiceGroupApp.SystemServiceGroup.ChangeHistoryService
+	jobService              = service.ServiceGroupApp.SystemServiceGroup.JobService
+	asyncTaskService        = service.ServiceGroupApp.SystemServiceGroup.AsyncTaskService
+	subscriptionService     = service.ServiceGroupApp.SystemServiceGroup.ExportSubscriptionService
+	exportWorkbookService   = service.ServiceGroupApp.SystemServiceGroup.ExportWorkbookService
 )
@@ -0,0 +1,196 @@
+package system
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SysAsyncTaskApi struct{}
+
+// GetSysAsyncTaskList 分页获取当前用户的后台任务
+// @Tags      SysAsyncTask
+// @Summary   分页获取当前用户的后台任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysAsyncTaskSearch                           true  "页码, 每页大小, 任务类型, 状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取当前用户的后台任务,返回包括列表,总数,页码,每页数量"
+// @Router    /sysAsyncTask/getSysAsyncTaskList [get]
+func (s *SysAsyncTaskApi) GetSysAsyncTaskList(c *gin.Context) {
+	var pageInfo systemReq.SysAsyncTaskSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(pageInfo.PageInfo, utils.PageInfoVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := asyncTaskService.GetAsyncTaskList(pageInfo, utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// FindSysAsyncTask 获取后台任务的状态与进度 用于轮询
+// @Tags      SysAsyncTask
+// @Summary   获取后台任务的状态与进度
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     request.GetById                                        true  "任务ID"
+// @Success   200   {object}  response.Response{data=system.SysAsyncTask,msg=string}  "获取后台任务的状态与进度"
+// @Router    /sysAsyncTask/findSysAsyncTask [get]
+func (s *SysAsyncTaskApi) FindSysAsyncTask(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindQuery(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	task, err := asyncTaskService.GetAsyncTask(idInfo.Uint(), utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(task, "查询成功", c)
+}
+
+// StreamSysAsyncTask 以 SSE 推送后台任务的进度
+// @Tags      SysAsyncTask
+// @Summary   以 SSE 推送后台任务的进度 状态或进度变化时发送 progress 事件 任务结束时发送 done 事件后关闭
+// @Security  ApiKeyAuth
+// @Produce   text/event-stream
+// @Param     data  query     request.GetById      true  "任务ID"
+// @Success   200   {object}  system.SysAsyncTask  "任务的状态与进度"
+// @Router    /sysAsyncTask/streamSysAsyncTask [get]
+func (s *SysAsyncTaskApi) StreamSysAsyncTask(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindQuery(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	id, userID := idInfo.Uint(), utils.GetUserID(c)
+	task, err := asyncTaskService.GetAsyncTask(id, userID)
+	if err != nil {
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	var last system.SysAsyncTask
+	first := true
+	c.Stream(func(w io.Writer) bool {
+		if !first {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-time.After(time.Second):
+			}
+			if task, err = asyncTaskService.GetAsyncTask(id, userID); err != nil {
+				c.SSEvent("error", err.Error())
+				return false
+			}
+		}
+		if task.Finished() {
+			c.SSEvent("done", task)
+			return false
+		}
+		if first || task.Status != last.Status || task.Progress != last.Progress || task.Message != last.Message {
+			c.SSEvent("progress", task)
+		}
+		first, last = false, task
+		return true
+	})
+}
+
+// CancelSysAsyncTask 取消后台任务
+// @Tags      SysAsyncTask
+// @Summary   取消后台任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "任务ID"
+// @Success   200   {object}  response.Response{msg=string}  "取消后台任务"
+// @Router    /sysAsyncTask/cancelSysAsyncTask [post]
+func (s *SysAsyncTaskApi) CancelSysAsyncTask(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindJSON(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = asyncTaskService.CancelAsyncTask(idInfo.Uint(), utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("取消失败!", zap.Error(err))
+		response.FailWithMessage("取消失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("已取消", c)
+}
+
+// DownloadSysAsyncTaskArtifact 下载后台任务生成的文件
+// @Tags      SysAsyncTask
+// @Summary   下载后台任务生成的文件
+// @Security  ApiKeyAuth
+// @Produce   application/octet-stream
+// @Param     data  query  request.GetById  true  "任务ID"
+// @Success   200   {file}  file  "任务生成的文件"
+// @Router    /sysAsyncTask/downloadSysAsyncTaskArtifact [get]
+func (s *SysAsyncTaskApi) DownloadSysAsyncTaskArtifact(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindQuery(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	task, err := asyncTaskService.GetAsyncTask(idInfo.Uint(), utils.GetUserID(c))
+	if err != nil {
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	if task.Status != system.AsyncTaskSuccess || task.ArtifactURL == "" {
+		response.FailWithMessage("任务没有可下载的文件", c)
+		return
+	}
+	// 文件保存在对象存储中 本地存储时从 local.store-path 读取
+	if strings.HasPrefix(task.ArtifactURL, "http://") || strings.HasPrefix(task.ArtifactURL, "https://") {
+		c.Redirect(http.StatusFound, task.ArtifactURL)
+		return
+	}
+	c.FileAttachment(filepath.Join(global.GVA_CONFIG.Local.StorePath, task.ArtifactKey), task.ArtifactName)
+}
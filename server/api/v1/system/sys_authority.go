@@ -40,7 +40,7 @@ func (a *AuthorityApi) CreateAuthority(c *gin.Context) {
 		authority.ParentId = utils.Pointer(utils.GetUserAuthorityId(c))
 	}
 
-	if authBack, err = authorityService.CreateAuthority(authority); err != nil {
+	if authBack, err = authorityService.CreateAuthority(c.Request.Context(), authority); err != nil {
 		global.GVA_LOG.Error("创建失败!", zap.Error(err))
 		response.FailWithMessage("创建失败"+err.Error(), c)
 		return
@@ -111,7 +111,7 @@ func (a *AuthorityApi) DeleteAuthority(c *gin.Context) {
 		return
 	}
 	// 删除角色之前需要判断是否有用户正在使用此角色
-	if err = authorityService.DeleteAuthority(&authority); err != nil {
+	if err = authorityService.DeleteAuthority(c.Request.Context(), &authority); err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败"+err.Error(), c)
 		return
@@ -141,7 +141,7 @@ func (a *AuthorityApi) UpdateAuthority(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	authority, err := authorityService.UpdateAuthority(auth)
+	authority, err := authorityService.UpdateAuthority(c.Request.Context(), auth)
 	if err != nil {
 		global.GVA_LOG.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败"+err.Error(), c)
@@ -0,0 +1,77 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ChangeHistoryApi struct{}
+
+// GetChangeHistory 获取一条记录的变更历史
+// @Tags ChangeHistory
+// @Summary 获取一条记录的变更历史
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query systemReq.ChangeHistorySearch true "表名、记录主键及分页"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /changeHistory/getChangeHistory [get]
+func (changeHistoryApi *ChangeHistoryApi) GetChangeHistory(c *gin.Context) {
+	var info systemReq.ChangeHistorySearch
+	err := c.ShouldBindQuery(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if info.Table == "" || info.RecordID == "" {
+		response.FailWithMessage("请指定表名和记录主键", c)
+		return
+	}
+	list, total, err := changeHistoryService.GetChangeHistory(info)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     info.Page,
+		PageSize: info.PageSize,
+	}, "获取成功", c)
+}
+
+// RevertChange 将记录恢复到某次变更后的版本
+// @Tags ChangeHistory
+// @Summary 将记录恢复到某次变更后的版本
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.GetById true "变更历史ID"
+// @Success 200 {object} response.Response{msg=string} "恢复成功"
+// @Router /changeHistory/revertChange [post]
+func (changeHistoryApi *ChangeHistoryApi) RevertChange(c *gin.Context) {
+	var req request.GetById
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = changeHistoryService.RevertChange(c.Request.Context(), utils.GetUserAuthorityId(c), req.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("恢复失败!", zap.Error(err))
+		response.FailWithMessage("恢复失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("恢复成功", c)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -27,7 +28,7 @@ func (s *DictionaryApi) CreateSysDictionary(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = dictionaryService.CreateSysDictionary(dictionary)
+	err = dictionaryService.CreateSysDictionary(c.Request.Context(), dictionary)
 	if err != nil {
 		global.GVA_LOG.Error("创建失败!", zap.Error(err))
 		response.FailWithMessage("创建失败", c)
@@ -52,7 +53,7 @@ func (s *DictionaryApi) DeleteSysDictionary(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = dictionaryService.DeleteSysDictionary(dictionary)
+	err = dictionaryService.DeleteSysDictionary(c.Request.Context(), dictionary)
 	if err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败", c)
@@ -77,7 +78,7 @@ func (s *DictionaryApi) UpdateSysDictionary(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = dictionaryService.UpdateSysDictionary(&dictionary)
+	err = dictionaryService.UpdateSysDictionary(c.Request.Context(), &dictionary)
 	if err != nil {
 		global.GVA_LOG.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败", c)
@@ -92,8 +93,9 @@ func (s *DictionaryApi) UpdateSysDictionary(c *gin.Context) {
 // @Security  ApiKeyAuth
 // @accept    application/json
 // @Produce   application/json
-// @Param     data  query     system.SysDictionary                                       true  "ID或字典英名"
-// @Success   200   {object}  response.Response{data=map[string]interface{},msg=string}  "用id查询SysDictionary"
+// @Param     data           query     system.SysDictionary                                       true   "ID或字典英名"
+// @Param     If-None-Match  header    string                                                     false  "按type查询时上次响应的ETag 未变化时返回304"
+// @Success   200            {object}  response.Response{data=map[string]interface{},msg=string}  "用id查询SysDictionary"
 // @Router    /sysDictionary/findSysDictionary [get]
 func (s *DictionaryApi) FindSysDictionary(c *gin.Context) {
 	var dictionary system.SysDictionary
@@ -102,7 +104,17 @@ func (s *DictionaryApi) FindSysDictionary(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	sysDictionary, err := dictionaryService.GetSysDictionary(dictionary.Type, dictionary.ID, dictionary.Status)
+	var sysDictionary system.SysDictionary
+	if dictionary.Type != "" && dictionary.ID == 0 && (dictionary.Status == nil || *dictionary.Status) {
+		// 按type查询启用的字典时走缓存 并支持 If-None-Match
+		var etag string
+		sysDictionary, etag, err = dictionaryService.GetSysDictionaryByType(c, dictionary.Type)
+		if err == nil && utils.CheckETag(c, etag) {
+			return
+		}
+	} else {
+		sysDictionary, err = dictionaryService.GetSysDictionary(dictionary.Type, dictionary.ID, dictionary.Status)
+	}
 	if err != nil {
 		global.GVA_LOG.Error("字典未创建或未开启!", zap.Error(err))
 		response.FailWithMessage("字典未创建或未开启", c)
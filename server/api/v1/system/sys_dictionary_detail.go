@@ -2,6 +2,7 @@ package system
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
@@ -188,8 +189,9 @@ func (s *DictionaryDetailApi) GetDictionaryTreeList(c *gin.Context) {
 // @Security  ApiKeyAuth
 // @accept    application/json
 // @Produce   application/json
-// @Param     type  query     string                                                true  "字典类型"
-// @Success   200   {object}  response.Response{data=[]system.SysDictionaryDetail,msg=string}  "获取字典详情树形结构"
+// @Param     type           query     string                                                             true   "字典类型"
+// @Param     If-None-Match  header    string                                                             false  "上次响应的ETag 未变化时返回304"
+// @Success   200            {object}  response.Response{data=[]system.SysDictionaryDetail,msg=string}  "获取字典详情树形结构"
 // @Router    /sysDictionaryDetail/getDictionaryTreeListByType [get]
 func (s *DictionaryDetailApi) GetDictionaryTreeListByType(c *gin.Context) {
 	dictType := c.Query("type")
@@ -197,16 +199,57 @@ func (s *DictionaryDetailApi) GetDictionaryTreeListByType(c *gin.Context) {
 		response.FailWithMessage("字典类型不能为空", c)
 		return
 	}
-	
-	list, err := dictionaryDetailService.GetDictionaryTreeListByType(dictType)
+
+	list, etag, err := dictionaryDetailService.GetDictionaryTreeByType(c, dictType)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败", c)
 		return
 	}
+	if utils.CheckETag(c, etag) {
+		return
+	}
 	response.OkWithDetailed(gin.H{"list": list}, "获取成功", c)
 }
 
+// GetDictionaryTreeListByTypes
+// @Tags      SysDictionaryDetail
+// @Summary   批量根据字典类型获取字典详情树形结构
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     types          query     string                                                     true   "字典类型 逗号分隔 最多100个"
+// @Param     If-None-Match  header    string                                                     false  "上次响应的ETag 未变化时返回304"
+// @Success   200            {object}  response.Response{data=map[string]interface{},msg=string}  "返回lists 键为字典类型 值为字典详情树形结构 不存在的类型为空数组"
+// @Router    /sysDictionaryDetail/getDictionaryTreeListByTypes [get]
+func (s *DictionaryDetailApi) GetDictionaryTreeListByTypes(c *gin.Context) {
+	var types []string
+	for _, t := range strings.Split(c.Query("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		response.FailWithMessage("字典类型不能为空", c)
+		return
+	}
+	if len(types) > 100 {
+		response.FailWithMessage("一次最多获取100个字典类型", c)
+		return
+	}
+
+	lists, etag, err := dictionaryDetailService.GetDictionaryTreesByTypes(c, types)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	if utils.CheckETag(c, etag) {
+		return
+	}
+	response.OkWithDetailed(gin.H{"lists": lists}, "获取成功", c)
+}
+
 // GetDictionaryDetailsByParent
 // @Tags      SysDictionaryDetail
 // @Summary   根据父级ID获取字典详情
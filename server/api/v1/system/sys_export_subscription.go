@@ -0,0 +1,251 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SysExportSubscriptionApi struct{}
+
+// CreateSysExportSubscription 创建导出订阅
+// @Tags      SysExportSubscription
+// @Summary   创建导出订阅 按创建者的角色检查可导出的表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysExportSubscription                                  true  "订阅名称, 导出模板, 参数, 格式, cron表达式, 收件人或OSS目录"
+// @Success   200   {object}  response.Response{data=system.SysExportSubscription,msg=string}  "创建导出订阅"
+// @Router    /sysExportSubscription/createSysExportSubscription [post]
+func (s *SysExportSubscriptionApi) CreateSysExportSubscription(c *gin.Context) {
+	var subscription system.SysExportSubscription
+	err := c.ShouldBindJSON(&subscription)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	subscription.CreatedBy = utils.GetUserID(c)
+	subscription.AuthorityID = utils.GetUserAuthorityId(c)
+	err = subscriptionService.CreateExportSubscription(&subscription)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(subscription, "创建成功", c)
+}
+
+// UpdateSysExportSubscription 更新导出订阅
+// @Tags      SysExportSubscription
+// @Summary   更新导出订阅 之后按更新者的角色检查可导出的表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysExportSubscription   true  "订阅ID, 订阅名称, 导出模板, 参数, 格式, cron表达式, 收件人或OSS目录"
+// @Success   200   {object}  response.Response{msg=string}  "更新导出订阅"
+// @Router    /sysExportSubscription/updateSysExportSubscription [put]
+func (s *SysExportSubscriptionApi) UpdateSysExportSubscription(c *gin.Context) {
+	var subscription system.SysExportSubscription
+	err := c.ShouldBindJSON(&subscription)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if subscription.ID == 0 {
+		response.FailWithMessage("订阅ID不能为空", c)
+		return
+	}
+	subscription.AuthorityID = utils.GetUserAuthorityId(c)
+	err = subscriptionService.UpdateExportSubscription(subscription)
+	if err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteSysExportSubscription 删除导出订阅
+// @Tags      SysExportSubscription
+// @Summary   删除导出订阅
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "订阅ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除导出订阅"
+// @Router    /sysExportSubscription/deleteSysExportSubscription [delete]
+func (s *SysExportSubscriptionApi) DeleteSysExportSubscription(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindJSON(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = subscriptionService.DeleteExportSubscription(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// SetSysExportSubscriptionEnabled 启用或停用导出订阅
+// @Tags      SysExportSubscription
+// @Summary   启用或停用导出订阅
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.SetJobEnabled        true  "订阅ID, 是否启用"
+// @Success   200   {object}  response.Response{msg=string}  "启用或停用导出订阅"
+// @Router    /sysExportSubscription/setSysExportSubscriptionEnabled [put]
+func (s *SysExportSubscriptionApi) SetSysExportSubscriptionEnabled(c *gin.Context) {
+	var req systemReq.SetJobEnabled
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = subscriptionService.SetExportSubscriptionEnabled(req.ID, req.Enabled)
+	if err != nil {
+		global.GVA_LOG.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage("设置失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("设置成功", c)
+}
+
+// TriggerSysExportSubscription 立即导出发送一次
+// @Tags      SysExportSubscription
+// @Summary   立即导出发送一次
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                                      true  "订阅ID"
+// @Success   200   {object}  response.Response{data=system.SysJobRun,msg=string}  "返回执行记录 导出在后台执行"
+// @Router    /sysExportSubscription/triggerSysExportSubscription [post]
+func (s *SysExportSubscriptionApi) TriggerSysExportSubscription(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindJSON(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	run, err := subscriptionService.TriggerExportSubscription(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("执行失败!", zap.Error(err))
+		response.FailWithMessage("执行失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(run, "已开始执行", c)
+}
+
+// FindSysExportSubscription 根据ID获取导出订阅
+// @Tags      SysExportSubscription
+// @Summary   根据ID获取导出订阅
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     request.GetById                                                 true  "订阅ID"
+// @Success   200   {object}  response.Response{data=system.SysExportSubscription,msg=string}  "根据ID获取导出订阅"
+// @Router    /sysExportSubscription/findSysExportSubscription [get]
+func (s *SysExportSubscriptionApi) FindSysExportSubscription(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindQuery(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	subscription, err := subscriptionService.GetExportSubscription(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(subscription, "查询成功", c)
+}
+
+// GetSysExportSubscriptionList 分页获取导出订阅列表
+// @Tags      SysExportSubscription
+// @Summary   分页获取导出订阅列表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysExportSubscriptionSearch                   true  "页码, 每页大小, 搜索条件"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取导出订阅列表,返回包括列表,总数,页码,每页数量"
+// @Router    /sysExportSubscription/getSysExportSubscriptionList [get]
+func (s *SysExportSubscriptionApi) GetSysExportSubscriptionList(c *gin.Context) {
+	var pageInfo systemReq.SysExportSubscriptionSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := subscriptionService.GetExportSubscriptionList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// GetSysExportSubscriptionRunList 分页获取导出订阅执行记录
+// @Tags      SysExportSubscription
+// @Summary   分页获取导出订阅执行记录
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysExportSubscriptionRunSearch                true  "页码, 每页大小, 订阅ID, 状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取导出订阅执行记录,返回包括列表,总数,页码,每页数量"
+// @Router    /sysExportSubscription/getSysExportSubscriptionRunList [get]
+func (s *SysExportSubscriptionApi) GetSysExportSubscriptionRunList(c *gin.Context) {
+	var pageInfo systemReq.SysExportSubscriptionRunSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(pageInfo.PageInfo, utils.PageInfoVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := subscriptionService.GetExportSubscriptionRunList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
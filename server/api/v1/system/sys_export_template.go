@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"sync"
 	"time"
 
@@ -226,6 +227,10 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportExcel(c *gin.Context) {
 		response.FailWithMessage("模板ID不能为空", c)
 		return
 	}
+	if err := sysExportTemplateService.CheckTemplateAuthority(templateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 
 	queryParams := c.Request.URL.Query()
 
@@ -236,6 +241,7 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportExcel(c *gin.Context) {
 	exportParams := map[string]interface{}{
 		"templateID":  templateID,
 		"queryParams": queryParams,
+		"userID":      utils.GetUserID(c),
 	}
 
 	// 参数保留记录完成鉴权
@@ -249,6 +255,45 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportExcel(c *gin.Context) {
 	response.OkWithData(exportUrl, c)
 }
 
+// ExportExcelAsync 创建后台导出任务 查询参数与 exportExcel 相同 完成后通过后台任务接口下载
+// @Tags SysExportTemplate
+// @Summary 创建后台导出任务
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {object} response.Response{data=system.SysAsyncTask,msg=string} "返回后台任务"
+// @Router /sysExportTemplate/exportExcelAsync [post]
+func (sysExportTemplateApi *SysExportTemplateApi) ExportExcelAsync(c *gin.Context) {
+	templateID := c.Query("templateID")
+	if templateID == "" {
+		response.FailWithMessage("模板ID不能为空", c)
+		return
+	}
+	if err := sysExportTemplateService.CheckTemplateAuthority(templateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	task, err := asyncTaskService.Enqueue(utils.GetUserID(c), "ExportExcel", systemReq.ExportExcelTask{
+		TemplateID: templateID,
+		Query:      c.Request.URL.Query(),
+	})
+	if err != nil {
+		global.GVA_LOG.Error("创建导出任务失败!", zap.Error(err))
+		response.FailWithMessage("创建导出任务失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(task, "已开始导出", c)
+}
+
+// ExportTokenUserID 导出token对应的用户 通过token导出的路由不携带登录信息 按生成token的用户限制并发
+func (sysExportTemplateApi *SysExportTemplateApi) ExportTokenUserID(c *gin.Context) uint {
+	tokenMutex.RLock()
+	defer tokenMutex.RUnlock()
+	exportParams, _ := exportTokenCache[c.Query("token")].(map[string]interface{})
+	userID, _ := exportParams["userID"].(uint)
+	return userID
+}
+
 // ExportExcelByToken 导出表格
 // @Tags ExportExcelByToken
 // @Summary 导出表格
@@ -293,14 +338,20 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportExcelByToken(c *gin.Cont
 	delete(exportTokenExpiration, token)
 	tokenMutex.Unlock()
 
-	// 导出
-	if file, name, err := sysExportTemplateService.ExportExcel(templateID, queryParams); err != nil {
+	// 导出 查询参数中的 format 指定格式 xlsx|csv|ndjson 需为模板允许的格式
+	export, err := sysExportTemplateService.PrepareExport(templateID, queryParams)
+	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败", c)
-	} else {
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name+utils.RandomString(6)+".xlsx"))
-		c.Header("success", "true")
-		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", file.Bytes())
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", export.Name+utils.RandomString(6)+"."+export.Format))
+	c.Header("Content-Type", export.ContentType())
+	c.Header("success", "true")
+	c.Status(http.StatusOK)
+	// 数据边查询边写出 开始写出后无法再返回错误信息
+	if err = export.Stream(c.Request.Context(), c.Writer); err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
 	}
 }
 
@@ -325,6 +376,7 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportTemplate(c *gin.Context)
 	exportParams := map[string]interface{}{
 		"templateID": templateID,
 		"isTemplate": true,
+		"userID":     utils.GetUserID(c),
 	}
 
 	// 参数保留记录完成鉴权
@@ -406,6 +458,10 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportTemplateByToken(c *gin.C
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
+// @Param templateID query string true "模板ID"
+// @Param dryRun query bool false "只校验不导入"
+// @Param file formData file true "导入文件"
+// @Success 200 {object} response.Response{data=object,msg=string} "导入结果 数据有误时返回每行的错误与错误工作簿"
 // @Router /sysExportTemplate/importExcel [post]
 func (sysExportTemplateApi *SysExportTemplateApi) ImportExcel(c *gin.Context) {
 	templateID := c.Query("templateID")
@@ -413,16 +469,97 @@ func (sysExportTemplateApi *SysExportTemplateApi) ImportExcel(c *gin.Context) {
 		response.FailWithMessage("模板ID不能为空", c)
 		return
 	}
+	if err := sysExportTemplateService.CheckTemplateAuthority(templateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 	file, err := c.FormFile("file")
 	if err != nil {
 		global.GVA_LOG.Error("文件获取失败!", zap.Error(err))
 		response.FailWithMessage("文件获取失败", c)
 		return
 	}
-	if err := sysExportTemplateService.ImportExcel(templateID, file); err != nil {
+	dryRun := c.Query("dryRun") == "true"
+	result, err := sysExportTemplateService.ImportExcel(templateID, file, dryRun)
+	if err != nil {
 		global.GVA_LOG.Error(err.Error(), zap.Error(err))
 		response.FailWithMessage(err.Error(), c)
-	} else {
-		response.OkWithMessage("导入成功", c)
+		return
+	}
+	if result.Failed > 0 {
+		if err = sysExportTemplateService.SaveImportErrors(&result); err != nil {
+			global.GVA_LOG.Error("保存错误工作簿失败!", zap.Error(err))
+		}
+		response.FailWithDetailed(result, fmt.Sprintf("%d 行数据有误 未导入", result.Failed), c)
+		return
+	}
+	if dryRun {
+		response.OkWithDetailed(result, "校验通过", c)
+		return
+	}
+	response.OkWithDetailed(result, "导入成功", c)
+}
+
+// DownloadImportErrors 下载导入的错误工作簿
+// @Tags SysImportTemplate
+// @Summary 下载导入的错误工作簿 有误的单元格标红 最后一列为错误信息
+// @Security ApiKeyAuth
+// @Produce application/octet-stream
+// @Param file query string true "导入结果中的 errorFile"
+// @Success 200 {file} file "错误工作簿"
+// @Router /sysExportTemplate/downloadImportErrors [get]
+func (sysExportTemplateApi *SysExportTemplateApi) DownloadImportErrors(c *gin.Context) {
+	path, err := sysExportTemplateService.GetImportErrorFile(c.Query("file"))
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	c.FileAttachment(path, "导入错误.xlsx")
+}
+
+// ImportExcelAsync 创建后台导入任务
+// @Tags SysImportTemplate
+// @Summary 创建后台导入任务
+// @Security ApiKeyAuth
+// @accept multipart/form-data
+// @Produce application/json
+// @Param templateID query string true "模板ID"
+// @Param dryRun query bool false "只校验不导入"
+// @Param file formData file true "导入文件"
+// @Success 200 {object} response.Response{data=system.SysAsyncTask,msg=string} "返回后台任务"
+// @Router /sysExportTemplate/importExcelAsync [post]
+func (sysExportTemplateApi *SysExportTemplateApi) ImportExcelAsync(c *gin.Context) {
+	templateID := c.Query("templateID")
+	if templateID == "" {
+		response.FailWithMessage("模板ID不能为空", c)
+		return
+	}
+	if err := sysExportTemplateService.CheckTemplateAuthority(templateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		global.GVA_LOG.Error("文件获取失败!", zap.Error(err))
+		response.FailWithMessage("文件获取失败", c)
+		return
+	}
+	path, err := asyncTaskService.SaveAsyncTaskUpload(file)
+	if err != nil {
+		global.GVA_LOG.Error("文件保存失败!", zap.Error(err))
+		response.FailWithMessage("文件保存失败", c)
+		return
+	}
+	task, err := asyncTaskService.Enqueue(utils.GetUserID(c), "ImportExcel", systemReq.ImportExcelTask{
+		TemplateID: templateID,
+		File:       path,
+		DryRun:     c.Query("dryRun") == "true",
+	})
+	if err != nil {
+		os.Remove(path)
+		global.GVA_LOG.Error("创建导入任务失败!", zap.Error(err))
+		response.FailWithMessage("创建导入任务失败:"+err.Error(), c)
+		return
 	}
+	response.OkWithDetailed(task, "已开始导入", c)
 }
@@ -0,0 +1,196 @@
+package system
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SysExportWorkbookApi struct{}
+
+// CreateSysExportWorkbook 创建组合导出
+// @Tags      SysExportWorkbook
+// @Summary   创建组合导出
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysExportWorkbook                                  true  "工作簿名称, 标识, 工作表定义"
+// @Success   200   {object}  response.Response{data=system.SysExportWorkbook,msg=string}  "创建组合导出"
+// @Router    /sysExportWorkbook/createSysExportWorkbook [post]
+func (s *SysExportWorkbookApi) CreateSysExportWorkbook(c *gin.Context) {
+	var workbook system.SysExportWorkbook
+	err := c.ShouldBindJSON(&workbook)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = exportWorkbookService.CreateExportWorkbook(&workbook)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(workbook, "创建成功", c)
+}
+
+// UpdateSysExportWorkbook 更新组合导出
+// @Tags      SysExportWorkbook
+// @Summary   更新组合导出
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysExportWorkbook       true  "ID, 工作簿名称, 标识, 工作表定义"
+// @Success   200   {object}  response.Response{msg=string}  "更新组合导出"
+// @Router    /sysExportWorkbook/updateSysExportWorkbook [put]
+func (s *SysExportWorkbookApi) UpdateSysExportWorkbook(c *gin.Context) {
+	var workbook system.SysExportWorkbook
+	err := c.ShouldBindJSON(&workbook)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if workbook.ID == 0 {
+		response.FailWithMessage("ID不能为空", c)
+		return
+	}
+	err = exportWorkbookService.UpdateExportWorkbook(workbook)
+	if err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteSysExportWorkbook 删除组合导出
+// @Tags      SysExportWorkbook
+// @Summary   删除组合导出
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除组合导出"
+// @Router    /sysExportWorkbook/deleteSysExportWorkbook [delete]
+func (s *SysExportWorkbookApi) DeleteSysExportWorkbook(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindJSON(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = exportWorkbookService.DeleteExportWorkbook(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// FindSysExportWorkbook 根据ID获取组合导出
+// @Tags      SysExportWorkbook
+// @Summary   根据ID获取组合导出
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     request.GetById                                             true  "ID"
+// @Success   200   {object}  response.Response{data=system.SysExportWorkbook,msg=string}  "根据ID获取组合导出"
+// @Router    /sysExportWorkbook/findSysExportWorkbook [get]
+func (s *SysExportWorkbookApi) FindSysExportWorkbook(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindQuery(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	workbook, err := exportWorkbookService.GetExportWorkbook(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(workbook, "查询成功", c)
+}
+
+// GetSysExportWorkbookList 分页获取组合导出列表
+// @Tags      SysExportWorkbook
+// @Summary   分页获取组合导出列表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysExportWorkbookSearch                       true  "页码, 每页大小, 搜索条件"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取组合导出列表,返回包括列表,总数,页码,每页数量"
+// @Router    /sysExportWorkbook/getSysExportWorkbookList [get]
+func (s *SysExportWorkbookApi) GetSysExportWorkbookList(c *gin.Context) {
+	var pageInfo systemReq.SysExportWorkbookSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := exportWorkbookService.GetExportWorkbookList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// ExportSysExportWorkbook 导出组合导出的工作簿
+// @Tags      SysExportWorkbook
+// @Summary   导出组合导出的工作簿 params 与导出表格相同 传给每个工作表的模板
+// @Security  ApiKeyAuth
+// @Produce   application/octet-stream
+// @Param     workbookID  query     string  true   "组合导出标识"
+// @Param     params      query     string  false  "导出参数"
+// @Success   200         {file}    file    "工作簿"
+// @Router    /sysExportWorkbook/exportSysExportWorkbook [get]
+func (s *SysExportWorkbookApi) ExportSysExportWorkbook(c *gin.Context) {
+	workbookID := c.Query("workbookID")
+	if workbookID == "" {
+		response.FailWithMessage("组合导出标识不能为空", c)
+		return
+	}
+	if err := exportWorkbookService.CheckWorkbookAuthority(workbookID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	export, err := exportWorkbookService.PrepareWorkbook(workbookID, c.Request.URL.Query())
+	if err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
+		response.FailWithMessage("导出失败:"+err.Error(), c)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", export.Name+utils.RandomString(6)+".xlsx"))
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("success", "true")
+	c.Status(http.StatusOK)
+	// 工作表依次查询写入 开始写出后无法再返回错误信息
+	if err = export.Stream(c.Request.Context(), c.Writer); err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
+	}
+}
@@ -0,0 +1,259 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SysJobApi struct{}
+
+// CreateSysJob 创建定时任务
+// @Tags      SysJob
+// @Summary   创建定时任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysJob                                  true  "任务名称, 执行方法, cron表达式, 参数, 是否启用"
+// @Success   200   {object}  response.Response{data=system.SysJob,msg=string}  "创建定时任务"
+// @Router    /sysJob/createSysJob [post]
+func (s *SysJobApi) CreateSysJob(c *gin.Context) {
+	var job system.SysJob
+	err := c.ShouldBindJSON(&job)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = jobService.CreateJob(&job)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(job, "创建成功", c)
+}
+
+// UpdateSysJob 更新定时任务
+// @Tags      SysJob
+// @Summary   更新定时任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysJob                   true  "任务ID, 任务名称, 执行方法, cron表达式, 参数, 是否启用"
+// @Success   200   {object}  response.Response{msg=string}  "更新定时任务"
+// @Router    /sysJob/updateSysJob [put]
+func (s *SysJobApi) UpdateSysJob(c *gin.Context) {
+	var job system.SysJob
+	err := c.ShouldBindJSON(&job)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if job.ID == 0 {
+		response.FailWithMessage("任务ID不能为空", c)
+		return
+	}
+	err = jobService.UpdateJob(job)
+	if err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteSysJob 删除定时任务
+// @Tags      SysJob
+// @Summary   删除定时任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "任务ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除定时任务"
+// @Router    /sysJob/deleteSysJob [delete]
+func (s *SysJobApi) DeleteSysJob(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindJSON(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = jobService.DeleteJob(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// SetSysJobEnabled 启用或停用定时任务
+// @Tags      SysJob
+// @Summary   启用或停用定时任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.SetJobEnabled        true  "任务ID, 是否启用"
+// @Success   200   {object}  response.Response{msg=string}  "启用或停用定时任务"
+// @Router    /sysJob/setSysJobEnabled [put]
+func (s *SysJobApi) SetSysJobEnabled(c *gin.Context) {
+	var req systemReq.SetJobEnabled
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = jobService.SetJobEnabled(req.ID, req.Enabled)
+	if err != nil {
+		global.GVA_LOG.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage("设置失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("设置成功", c)
+}
+
+// TriggerSysJob 立即执行一次定时任务
+// @Tags      SysJob
+// @Summary   立即执行一次定时任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                                      true  "任务ID"
+// @Success   200   {object}  response.Response{data=system.SysJobRun,msg=string}  "返回执行记录 任务在后台执行"
+// @Router    /sysJob/triggerSysJob [post]
+func (s *SysJobApi) TriggerSysJob(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindJSON(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	run, err := jobService.TriggerJob(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("执行失败!", zap.Error(err))
+		response.FailWithMessage("执行失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(run, "已开始执行", c)
+}
+
+// FindSysJob 根据ID获取定时任务
+// @Tags      SysJob
+// @Summary   根据ID获取定时任务
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     request.GetById                                  true  "任务ID"
+// @Success   200   {object}  response.Response{data=system.SysJob,msg=string}  "根据ID获取定时任务"
+// @Router    /sysJob/findSysJob [get]
+func (s *SysJobApi) FindSysJob(c *gin.Context) {
+	var idInfo request.GetById
+	err := c.ShouldBindQuery(&idInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(idInfo, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	job, err := jobService.GetJob(idInfo.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(job, "查询成功", c)
+}
+
+// GetSysJobList 分页获取定时任务列表
+// @Tags      SysJob
+// @Summary   分页获取定时任务列表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysJobSearch                                 true  "页码, 每页大小, 搜索条件"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取定时任务列表,返回包括列表,总数,页码,每页数量"
+// @Router    /sysJob/getSysJobList [get]
+func (s *SysJobApi) GetSysJobList(c *gin.Context) {
+	var pageInfo systemReq.SysJobSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := jobService.GetJobList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// GetSysJobRunList 分页获取定时任务执行记录
+// @Tags      SysJob
+// @Summary   分页获取定时任务执行记录
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysJobRunSearch                              true  "页码, 每页大小, 任务ID, 状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取定时任务执行记录,返回包括列表,总数,页码,每页数量"
+// @Router    /sysJob/getSysJobRunList [get]
+func (s *SysJobApi) GetSysJobRunList(c *gin.Context) {
+	var pageInfo systemReq.SysJobRunSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(pageInfo.PageInfo, utils.PageInfoVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := jobService.GetJobRunList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// GetSysJobHandlers 获取已注册的执行方法
+// @Tags      SysJob
+// @Summary   获取已注册的执行方法
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]task.Definition,msg=string}  "获取已注册的执行方法"
+// @Router    /sysJob/getSysJobHandlers [get]
+func (s *SysJobApi) GetSysJobHandlers(c *gin.Context) {
+	response.OkWithDetailed(jobService.GetJobHandlers(), "获取成功", c)
+}
@@ -140,7 +140,7 @@ func (a *AuthorityMenuApi) AddBaseMenu(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = menuService.AddBaseMenu(menu)
+	err = menuService.AddBaseMenu(c.Request.Context(), menu)
 	if err != nil {
 		global.GVA_LOG.Error("添加失败!", zap.Error(err))
 		response.FailWithMessage("添加失败："+err.Error(), c)
@@ -170,7 +170,7 @@ func (a *AuthorityMenuApi) DeleteBaseMenu(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = baseMenuService.DeleteBaseMenu(menu.ID)
+	err = baseMenuService.DeleteBaseMenu(c.Request.Context(), menu.ID)
 	if err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败:"+err.Error(), c)
@@ -205,7 +205,7 @@ func (a *AuthorityMenuApi) UpdateBaseMenu(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = baseMenuService.UpdateBaseMenu(menu)
+	err = baseMenuService.UpdateBaseMenu(c.Request.Context(), menu)
 	if err != nil {
 		global.GVA_LOG.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败", c)
@@ -32,7 +32,7 @@ func (s *OperationRecordApi) DeleteSysOperationRecord(c *gin.Context) {
 	err = operationRecordService.DeleteSysOperationRecord(sysOperationRecord)
 	if err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
-		response.FailWithMessage("删除失败", c)
+		response.FailWithMessage("删除失败:"+err.Error(), c)
 		return
 	}
 	response.OkWithMessage("删除成功", c)
@@ -57,7 +57,7 @@ func (s *OperationRecordApi) DeleteSysOperationRecordByIds(c *gin.Context) {
 	err = operationRecordService.DeleteSysOperationRecordByIds(IDS)
 	if err != nil {
 		global.GVA_LOG.Error("批量删除失败!", zap.Error(err))
-		response.FailWithMessage("批量删除失败", c)
+		response.FailWithMessage("批量删除失败:"+err.Error(), c)
 		return
 	}
 	response.OkWithMessage("批量删除成功", c)
@@ -122,3 +122,66 @@ func (s *OperationRecordApi) GetSysOperationRecordList(c *gin.Context) {
 		PageSize: pageInfo.PageSize,
 	}, "获取成功", c)
 }
+
+// VerifyOperationRecordChain
+// @Tags      SysOperationRecord
+// @Summary   校验操作记录哈希链
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=systemRes.OperationRecordChainResult,msg=string}  "校验结果,包括是否完整及首个异常记录"
+// @Router    /sysOperationRecord/verifyOperationRecordChain [get]
+func (s *OperationRecordApi) VerifyOperationRecordChain(c *gin.Context) {
+	result, err := operationRecordService.VerifyOperationRecordChain()
+	if err != nil {
+		global.GVA_LOG.Error("校验失败!", zap.Error(err))
+		response.FailWithMessage("校验失败", c)
+		return
+	}
+	response.OkWithDetailed(result, "校验完成", c)
+}
+
+// CreateOperationRecordCheckpoint
+// @Tags      SysOperationRecord
+// @Summary   生成操作记录检查点
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=system.SysOperationRecordCheckpoint,msg=string}  "签名后的检查点"
+// @Router    /sysOperationRecord/createOperationRecordCheckpoint [post]
+func (s *OperationRecordApi) CreateOperationRecordCheckpoint(c *gin.Context) {
+	checkpoint, err := operationRecordService.CreateOperationRecordCheckpoint()
+	if err != nil {
+		global.GVA_LOG.Error("生成失败!", zap.Error(err))
+		response.FailWithMessage("生成失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(checkpoint, "生成成功", c)
+}
+
+// SealOperationRecordArchive
+// @Tags      SysOperationRecord
+// @Summary   封存归档操作记录
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.SealOperationRecordArchive                             true  "归档早于该时间的记录"
+// @Success   200   {object}  response.Response{data=system.SysOperationRecordArchive,msg=string}  "签名后的归档信息"
+// @Router    /sysOperationRecord/sealOperationRecordArchive [post]
+func (s *OperationRecordApi) SealOperationRecordArchive(c *gin.Context) {
+	var req systemReq.SealOperationRecordArchive
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.Before.IsZero() {
+		response.FailWithMessage("请指定归档截止时间", c)
+		return
+	}
+	archive, err := operationRecordService.SealOperationRecordArchive(req.Before)
+	if err != nil {
+		global.GVA_LOG.Error("归档失败!", zap.Error(err))
+		response.FailWithMessage("归档失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(archive, "归档成功", c)
+}
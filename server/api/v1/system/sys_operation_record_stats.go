@@ -0,0 +1,109 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetOperationRecordTimeline
+// @Tags      SysOperationRecord
+// @Summary   按时间统计各请求方法或路径的请求数
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.OperationRecordStatsSearch                                          true  "时间范围、角色、粒度及分组方式"
+// @Success   200   {object}  response.Response{data=[]systemRes.OperationRecordTimelinePoint,msg=string}  "请求趋势"
+// @Router    /sysOperationRecord/getOperationRecordTimeline [get]
+func (s *OperationRecordApi) GetOperationRecordTimeline(c *gin.Context) {
+	var info systemReq.OperationRecordStatsSearch
+	err := c.ShouldBindQuery(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, err := operationRecordService.GetOperationRecordTimeline(info)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// GetOperationRecordEndpointStats
+// @Tags      SysOperationRecord
+// @Summary   按接口统计请求数、错误率及延迟分位数
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.OperationRecordStatsSearch                                         true  "时间范围、角色、方法及路径"
+// @Success   200   {object}  response.Response{data=[]systemRes.OperationRecordEndpointStat,msg=string}  "接口统计"
+// @Router    /sysOperationRecord/getOperationRecordEndpointStats [get]
+func (s *OperationRecordApi) GetOperationRecordEndpointStats(c *gin.Context) {
+	var info systemReq.OperationRecordStatsSearch
+	err := c.ShouldBindQuery(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, err := operationRecordService.GetOperationRecordEndpointStats(info)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// GetOperationRecordTopUsers
+// @Tags      SysOperationRecord
+// @Summary   请求数最多的用户
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.OperationRecordStatsSearch                                     true  "时间范围、角色、方法及路径"
+// @Success   200   {object}  response.Response{data=[]systemRes.OperationRecordUserStat,msg=string}  "活跃用户"
+// @Router    /sysOperationRecord/getOperationRecordTopUsers [get]
+func (s *OperationRecordApi) GetOperationRecordTopUsers(c *gin.Context) {
+	var info systemReq.OperationRecordStatsSearch
+	err := c.ShouldBindQuery(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, err := operationRecordService.GetOperationRecordTopUsers(info)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// GetOperationRecordStatusStats
+// @Tags      SysOperationRecord
+// @Summary   状态码分布
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.OperationRecordStatsSearch                                       true  "时间范围、角色、方法及路径"
+// @Success   200   {object}  response.Response{data=[]systemRes.OperationRecordStatusStat,msg=string}  "状态码分布"
+// @Router    /sysOperationRecord/getOperationRecordStatusStats [get]
+func (s *OperationRecordApi) GetOperationRecordStatusStats(c *gin.Context) {
+	var info systemReq.OperationRecordStatsSearch
+	err := c.ShouldBindQuery(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, err := operationRecordService.GetOperationRecordStatusStats(info)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
@@ -1,6 +1,7 @@
 package system
 
 import (
+	"net/http"
 	"strconv"
 	"time"
 
@@ -63,7 +64,15 @@ func (b *BaseApi) Login(c *gin.Context) {
 		response.FailWithMessage("用户名不存在或者密码错误", c)
 		return
 	}
-	if user.Enable != 1 {
+	switch user.Enable {
+	case system.UserEnablePending:
+		response.FailWithMessage("账号正在等待管理员审核", c)
+		return
+	case system.UserEnableRejected:
+		response.FailWithMessage("注册申请已被拒绝", c)
+		return
+	}
+	if user.Enable != system.UserEnableNormal {
 		global.GVA_LOG.Error("登陆失败! 用户被禁止登录!")
 		// 验证码次数+1
 		global.BlackCache.Increment(key, 1)
@@ -320,7 +329,7 @@ func (b *BaseApi) DeleteUser(c *gin.Context) {
 		response.FailWithMessage("删除失败, 无法删除自己。", c)
 		return
 	}
-	err = userService.DeleteUser(reqId.ID)
+	err = userService.DeleteUser(c.Request.Context(), reqId.ID)
 	if err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败", c)
@@ -359,7 +368,7 @@ func (b *BaseApi) SetUserInfo(c *gin.Context) {
 			return
 		}
 	}
-	err = userService.SetUserInfo(system.SysUser{
+	err = userService.SetUserInfo(c.Request.Context(), system.SysUser{
 		GVA_MODEL: global.GVA_MODEL{
 			ID: user.ID,
 		},
@@ -394,7 +403,7 @@ func (b *BaseApi) SetSelfInfo(c *gin.Context) {
 		return
 	}
 	user.ID = utils.GetUserID(c)
-	err = userService.SetSelfInfo(system.SysUser{
+	err = userService.SetSelfInfo(c.Request.Context(), system.SysUser{
 		GVA_MODEL: global.GVA_MODEL{
 			ID: user.ID,
 		},
@@ -454,7 +463,13 @@ func (b *BaseApi) GetUserInfo(c *gin.Context) {
 		response.FailWithMessage("获取失败", c)
 		return
 	}
-	response.OkWithDetailed(gin.H{"userInfo": ReqUser}, "获取成功", c)
+	sources, err := userGroupService.GetUserAuthoritySources(ReqUser.ID)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(gin.H{"userInfo": ReqUser, "authoritySources": sources}, "获取成功", c)
 }
 
 // ResetPassword
@@ -480,3 +495,88 @@ func (b *BaseApi) ResetPassword(c *gin.Context) {
 	}
 	response.OkWithMessage("重置成功", c)
 }
+
+// ImportUsers
+// @Tags      SysUser
+// @Summary   批量导入用户
+// @Security  ApiKeyAuth
+// @accept    multipart/form-data
+// @Produce   application/json
+// @Param     file    formData  file    true   "xlsx 或 csv 文件"
+// @Param     upsert  query     bool    false  "用户名已存在时更新用户"
+// @Success   200     {object}  response.Response{data=systemRes.UserImportResult,msg=string}  "导入结果,存在错误时返回逐行错误和错误报告token"
+// @Router    /user/importUsers [post]
+func (b *BaseApi) ImportUsers(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		global.GVA_LOG.Error("文件获取失败!", zap.Error(err))
+		response.FailWithMessage("文件获取失败", c)
+		return
+	}
+	upsert := c.Query("upsert") == "true"
+	result, err := userService.ImportUsers(utils.GetUserAuthorityId(c), file, upsert)
+	if err != nil {
+		global.GVA_LOG.Error("导入失败!", zap.Error(err))
+		response.FailWithMessage("导入失败:"+err.Error(), c)
+		return
+	}
+	if len(result.Errors) > 0 {
+		report, err := userService.UserImportReport(result)
+		if err != nil {
+			global.GVA_LOG.Error("生成错误报告失败!", zap.Error(err))
+		} else {
+			result.ReportToken = utils.RandomString(32)
+			global.BlackCache.Set(userImportReportKey(result.ReportToken), report.Bytes(), 30*time.Minute)
+		}
+		response.FailWithDetailed(result, "导入数据校验未通过", c)
+		return
+	}
+	response.OkWithDetailed(result, "导入成功", c)
+}
+
+// DownloadUserImportReport
+// @Tags      SysUser
+// @Summary   下载用户导入错误报告
+// @Security  ApiKeyAuth
+// @Produce   application/octet-stream
+// @Param     token  query  string  true  "导入结果中的reportToken"
+// @Router    /user/downloadImportReport [get]
+func (b *BaseApi) DownloadUserImportReport(c *gin.Context) {
+	v, ok := global.BlackCache.Get(userImportReportKey(c.Query("token")))
+	report, isBytes := v.([]byte)
+	if !ok || !isBytes {
+		response.FailWithMessage("错误报告不存在或已过期", c)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=user_import_report.xlsx")
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", report)
+}
+
+// ExportUsers
+// @Tags      SysUser
+// @Summary   导出用户
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/octet-stream
+// @Param     data  body  systemReq.GetUserList  true  "筛选条件,与用户列表一致"
+// @Router    /user/exportUsers [post]
+func (b *BaseApi) ExportUsers(c *gin.Context) {
+	var info systemReq.GetUserList
+	err := c.ShouldBindJSON(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	file, err := userService.ExportUsers(info)
+	if err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
+		response.FailWithMessage("导出失败", c)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=users.xlsx")
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", file.Bytes())
+}
+
+func userImportReportKey(token string) string {
+	return "GVA_UserImportReport_" + token
+}
@@ -0,0 +1,246 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type UserGroupApi struct{}
+
+// CreateUserGroup
+// @Tags      UserGroup
+// @Summary   创建用户组
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysUserGroup            true  "用户组名称, 描述"
+// @Success   200   {object}  response.Response{msg=string}  "创建用户组"
+// @Router    /userGroup/createUserGroup [post]
+func (u *UserGroupApi) CreateUserGroup(c *gin.Context) {
+	var group system.SysUserGroup
+	err := c.ShouldBindJSON(&group)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(group, utils.UserGroupVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userGroupService.CreateUserGroup(group)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("创建成功", c)
+}
+
+// DeleteUserGroup
+// @Tags      UserGroup
+// @Summary   删除用户组
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "用户组ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除用户组"
+// @Router    /userGroup/deleteUserGroup [delete]
+func (u *UserGroupApi) DeleteUserGroup(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userGroupService.DeleteUserGroup(reqId.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// UpdateUserGroup
+// @Tags      UserGroup
+// @Summary   更新用户组
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysUserGroup            true  "用户组ID, 名称, 描述"
+// @Success   200   {object}  response.Response{msg=string}  "更新用户组"
+// @Router    /userGroup/updateUserGroup [put]
+func (u *UserGroupApi) UpdateUserGroup(c *gin.Context) {
+	var group system.SysUserGroup
+	err := c.ShouldBindJSON(&group)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(group, utils.UserGroupVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userGroupService.UpdateUserGroup(group)
+	if err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// FindUserGroup
+// @Tags      UserGroup
+// @Summary   根据ID获取用户组
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     request.GetById                                    true  "用户组ID"
+// @Success   200   {object}  response.Response{data=system.SysUserGroup,msg=string}  "用户组详情,包含成员与角色"
+// @Router    /userGroup/findUserGroup [get]
+func (u *UserGroupApi) FindUserGroup(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindQuery(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	group, err := userGroupService.GetUserGroup(reqId.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+		return
+	}
+	response.OkWithData(group, c)
+}
+
+// GetUserGroupList
+// @Tags      UserGroup
+// @Summary   分页获取用户组列表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysUserGroupSearch                            true  "页码, 每页大小, 名称"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取用户组列表,返回包括列表,总数,页码,每页数量"
+// @Router    /userGroup/getUserGroupList [get]
+func (u *UserGroupApi) GetUserGroupList(c *gin.Context) {
+	var pageInfo systemReq.SysUserGroupSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := userGroupService.GetUserGroupList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// SetUserGroupUsers
+// @Tags      UserGroup
+// @Summary   设置用户组成员
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.SetUserGroupUsers    true  "用户组ID, 用户ID列表"
+// @Success   200   {object}  response.Response{msg=string}  "设置用户组成员"
+// @Router    /userGroup/setUserGroupUsers [post]
+func (u *UserGroupApi) SetUserGroupUsers(c *gin.Context) {
+	var req systemReq.SetUserGroupUsers
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	authorityID := utils.GetUserAuthorityId(c)
+	err = userGroupService.SetUserGroupUsers(authorityID, req.ID, req.UserIds)
+	if err != nil {
+		global.GVA_LOG.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage("设置失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("设置成功", c)
+}
+
+// SetUserGroupAuthorities
+// @Tags      UserGroup
+// @Summary   设置用户组角色
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.SetUserGroupAuthorities  true  "用户组ID, 角色ID列表"
+// @Success   200   {object}  response.Response{msg=string}      "设置用户组角色"
+// @Router    /userGroup/setUserGroupAuthorities [post]
+func (u *UserGroupApi) SetUserGroupAuthorities(c *gin.Context) {
+	var req systemReq.SetUserGroupAuthorities
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	authorityID := utils.GetUserAuthorityId(c)
+	err = userGroupService.SetUserGroupAuthorities(authorityID, req.ID, req.AuthorityIds)
+	if err != nil {
+		global.GVA_LOG.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage("设置失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("设置成功", c)
+}
+
+// GetUserAuthoritySources
+// @Tags      UserGroup
+// @Summary   获取用户角色来源
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     request.GetById                                                          true  "用户ID"
+// @Success   200   {object}  response.Response{data=[]systemRes.SysUserAuthoritySource,msg=string}  "角色及其来源(直接分配/用户组)"
+// @Router    /userGroup/getUserAuthoritySources [get]
+func (u *UserGroupApi) GetUserAuthoritySources(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindQuery(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	sources, err := userGroupService.GetUserAuthoritySources(reqId.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithData(sources, c)
+}
@@ -0,0 +1,189 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetDeletedUserList
+// @Tags      SysUser
+// @Summary   分页获取已删除的用户
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.GetUserList                                   true  "页码, 每页大小"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取已删除的用户,返回包括列表,总数,页码,每页数量"
+// @Router    /user/getDeletedUserList [post]
+func (b *BaseApi) GetDeletedUserList(c *gin.Context) {
+	var pageInfo systemReq.GetUserList
+	err := c.ShouldBindJSON(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(pageInfo, utils.PageInfoVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := userService.GetDeletedUserList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// RestoreUser
+// @Tags      SysUser
+// @Summary   恢复已删除的用户
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}  "恢复已删除的用户"
+// @Router    /user/restoreUser [post]
+func (b *BaseApi) RestoreUser(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userService.RestoreUser(reqId.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("恢复失败!", zap.Error(err))
+		response.FailWithMessage("恢复失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("恢复成功", c)
+}
+
+// PurgeUser
+// @Tags      SysUser
+// @Summary   彻底清除已删除的用户
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}  "彻底清除已删除的用户"
+// @Router    /user/purgeUser [delete]
+func (b *BaseApi) PurgeUser(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userService.PurgeUser(reqId.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("清除失败!", zap.Error(err))
+		response.FailWithMessage("清除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("清除成功", c)
+}
+
+// AnonymizeUser
+// @Tags      SysUser
+// @Summary   匿名化用户个人数据
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "用户ID"
+// @Success   200   {object}  response.Response{data=systemRes.UserAnonymizeResult,msg=string}  "匿名化用户个人数据 仍有个人数据未能清除时返回失败及清除结果"
+// @Router    /user/anonymizeUser [post]
+func (b *BaseApi) AnonymizeUser(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if utils.GetUserID(c) == reqId.Uint() {
+		response.FailWithMessage("匿名化失败, 无法匿名化自己。", c)
+		return
+	}
+	var result systemRes.UserAnonymizeResult
+	result, err = userService.AnonymizeUser(reqId.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("匿名化失败!", zap.Error(err))
+		response.FailWithMessage("匿名化失败:"+err.Error(), c)
+		return
+	}
+	if !result.Complete() {
+		msg := fmt.Sprintf("用户已匿名化, 但仍有个人数据未能清除: 哈希链上的操作记录%d条", result.RetainedRecords)
+		if len(result.UnscrubbedSinks) > 0 {
+			msg += ", 操作记录存储 " + strings.Join(result.UnscrubbedSinks, "、")
+		}
+		global.GVA_LOG.Warn(msg, zap.Uint("id", reqId.Uint()))
+		response.FailWithDetailed(result, msg, c)
+		return
+	}
+	response.OkWithDetailed(result, "匿名化成功", c)
+}
+
+// ExportUserData
+// @Tags      SysUser
+// @Summary   导出用户个人数据
+// @Security  ApiKeyAuth
+// @Produce   application/octet-stream
+// @Param     data  query     request.GetById  true  "用户ID"
+// @Success   200   {file}    file             "用户个人数据JSON文件"
+// @Router    /user/exportUserData [get]
+func (b *BaseApi) ExportUserData(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindQuery(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	data, err := userService.ExportUserData(reqId.Uint())
+	if err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
+		response.FailWithMessage("导出失败:"+err.Error(), c)
+		return
+	}
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
+		response.FailWithMessage("导出失败", c)
+		return
+	}
+	fileName := fmt.Sprintf("user_data_%d.json", reqId.ID)
+	c.Header("Content-Disposition", "attachment; filename="+url.QueryEscape(fileName))
+	c.Data(http.StatusOK, "application/json", content)
+}
@@ -0,0 +1,140 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SelfRegister
+// @Tags      Base
+// @Summary   自助注册 提交后需管理员审核
+// @Produce   application/json
+// @Param     data  body      systemReq.SelfRegister         true  "用户名, 密码, 昵称, 手机号, 邮箱, 申请说明, 验证码"
+// @Success   200   {object}  response.Response{msg=string}  "提交注册申请"
+// @Router    /base/register [post]
+func (b *BaseApi) SelfRegister(c *gin.Context) {
+	if !global.GVA_CONFIG.Account.OpenRegister {
+		response.FailWithMessage("未开放注册", c)
+		return
+	}
+	var req systemReq.SelfRegister
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.SelfRegisterVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = userService.CheckRegisterLimit(c.ClientIP()); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if !store.Verify(req.CaptchaId, req.Captcha, true) {
+		response.FailWithMessage("验证码错误", c)
+		return
+	}
+	_, err = userService.SelfRegister(req)
+	if err != nil {
+		global.GVA_LOG.Error("注册失败!", zap.Error(err))
+		response.FailWithMessage("注册失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("注册申请已提交,请等待管理员审核", c)
+}
+
+// GetRegistrationList
+// @Tags      SysUser
+// @Summary   分页获取注册申请
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.RegistrationSearch                            true  "页码, 每页大小, 状态, 用户名"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取注册申请,返回包括列表,总数,页码,每页数量"
+// @Router    /user/getRegistrationList [get]
+func (b *BaseApi) GetRegistrationList(c *gin.Context) {
+	var pageInfo systemReq.RegistrationSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := userService.GetRegistrationList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// ApproveRegistration
+// @Tags      SysUser
+// @Summary   通过注册申请
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.ReviewRegistration   true  "申请ID, 审核意见"
+// @Success   200   {object}  response.Response{msg=string}  "通过注册申请"
+// @Router    /user/approveRegistration [post]
+func (b *BaseApi) ApproveRegistration(c *gin.Context) {
+	var req systemReq.ReviewRegistration
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userService.ApproveRegistration(utils.GetUserID(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("审核失败!", zap.Error(err))
+		response.FailWithMessage("审核失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("审核成功", c)
+}
+
+// RejectRegistration
+// @Tags      SysUser
+// @Summary   拒绝注册申请
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.ReviewRegistration   true  "申请ID, 拒绝原因"
+// @Success   200   {object}  response.Response{msg=string}  "拒绝注册申请"
+// @Router    /user/rejectRegistration [post]
+func (b *BaseApi) RejectRegistration(c *gin.Context) {
+	var req systemReq.ReviewRegistration
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userService.RejectRegistration(utils.GetUserID(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("审核失败!", zap.Error(err))
+		response.FailWithMessage("审核失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("审核成功", c)
+}
@@ -0,0 +1,169 @@
+package system
+
+import (
+	"strconv"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ForgotPassword
+// @Tags      Base
+// @Summary   找回密码
+// @Produce   application/json
+// @Param     data  body      systemReq.ForgotPassword       true  "用户名"
+// @Success   200   {object}  response.Response{msg=string}  "无论用户是否存在均返回相同结果"
+// @Router    /base/forgotPassword [post]
+func (b *BaseApi) ForgotPassword(c *gin.Context) {
+	var req systemReq.ForgotPassword
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.ForgotPasswordVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = userService.CheckSendLimit("ip:" + c.ClientIP()); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// 超出频率的用户名直接忽略 返回与正常情况一致的结果
+	if userService.CheckSendLimit("user:"+req.Username) == nil {
+		// 异步发送 避免通过响应时间判断用户是否存在
+		go userService.ForgotPassword(req.Username)
+	}
+	response.OkWithMessage("如果该账号存在且已验证邮箱,重置密码邮件将很快送达", c)
+}
+
+// ResetPasswordByToken
+// @Tags      Base
+// @Summary   通过找回密码链接重置密码
+// @Produce   application/json
+// @Param     data  body      systemReq.ResetPasswordByToken  true  "令牌, 新密码"
+// @Success   200   {object}  response.Response{msg=string}   "重置密码"
+// @Router    /base/resetPasswordByToken [post]
+func (b *BaseApi) ResetPasswordByToken(c *gin.Context) {
+	var req systemReq.ResetPasswordByToken
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.ResetByTokenVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = userService.CheckSendLimit("reset:" + c.ClientIP()); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = userService.ResetPasswordByToken(req.Token, req.NewPassword)
+	if err != nil {
+		global.GVA_LOG.Error("重置失败!", zap.Error(err))
+		response.FailWithMessage("重置失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("重置成功", c)
+}
+
+// SendEmailVerification
+// @Tags      SysUser
+// @Summary   发送邮箱验证码
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{msg=string}  "发送邮箱验证码"
+// @Router    /user/sendEmailVerification [post]
+func (b *BaseApi) SendEmailVerification(c *gin.Context) {
+	uid := utils.GetUserID(c)
+	if err := userService.CheckSendLimit("email:" + strconv.Itoa(int(uid))); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := userService.SendEmailVerification(uid); err != nil {
+		global.GVA_LOG.Error("发送失败!", zap.Error(err))
+		response.FailWithMessage("发送失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("发送成功", c)
+}
+
+// VerifyEmail
+// @Tags      SysUser
+// @Summary   验证邮箱
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.VerifyCode           true  "验证码"
+// @Success   200   {object}  response.Response{msg=string}  "验证邮箱"
+// @Router    /user/verifyEmail [post]
+func (b *BaseApi) VerifyEmail(c *gin.Context) {
+	b.verifyContact(c, userService.VerifyEmail)
+}
+
+// SendPhoneVerification
+// @Tags      SysUser
+// @Summary   发送手机验证码
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{msg=string}  "发送手机验证码"
+// @Router    /user/sendPhoneVerification [post]
+func (b *BaseApi) SendPhoneVerification(c *gin.Context) {
+	uid := utils.GetUserID(c)
+	if err := userService.CheckSendLimit("phone:" + strconv.Itoa(int(uid))); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := userService.SendPhoneVerification(uid); err != nil {
+		global.GVA_LOG.Error("发送失败!", zap.Error(err))
+		response.FailWithMessage("发送失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("发送成功", c)
+}
+
+// VerifyPhone
+// @Tags      SysUser
+// @Summary   验证手机号
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.VerifyCode           true  "验证码"
+// @Success   200   {object}  response.Response{msg=string}  "验证手机号"
+// @Router    /user/verifyPhone [post]
+func (b *BaseApi) VerifyPhone(c *gin.Context) {
+	b.verifyContact(c, userService.VerifyPhone)
+}
+
+func (b *BaseApi) verifyContact(c *gin.Context, verify func(userID uint, code string) error) {
+	var req systemReq.VerifyCode
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.VerifyCodeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	uid := utils.GetUserID(c)
+	// 限制尝试次数 防止暴力破解验证码
+	if err = userService.CheckSendLimit("verify:" + strconv.Itoa(int(uid))); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = verify(uid, req.Code); err != nil {
+		global.GVA_LOG.Error("验证失败!", zap.Error(err))
+		response.FailWithMessage("验证失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("验证成功", c)
+}
@@ -440,47 +440,40 @@ func (sysVersionApi *SysVersionApi) ImportVersion(c *gin.Context) {
 		return
 	}
 
-	// 导入菜单数据
-	if len(importData.ExportMenu) > 0 {
-		if err := sysVersionService.ImportMenus(ctx, importData.ExportMenu); err != nil {
-			global.GVA_LOG.Error("导入菜单失败!", zap.Error(err))
-			response.FailWithMessage("导入菜单失败: "+err.Error(), c)
-			return
-		}
+	if err = sysVersionService.ImportVersion(ctx, importData, nil); err != nil {
+		global.GVA_LOG.Error("导入失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
 	}
 
-	// 导入API数据
-	if len(importData.ExportApi) > 0 {
-		if err := sysVersionService.ImportApis(importData.ExportApi); err != nil {
-			global.GVA_LOG.Error("导入API失败!", zap.Error(err))
-			response.FailWithMessage("导入API失败: "+err.Error(), c)
-			return
-		}
-	}
+	response.OkWithMessage("导入成功", c)
+}
 
-	// 导入字典数据
-	if len(importData.ExportDictionary) > 0 {
-		if err := sysVersionService.ImportDictionaries(importData.ExportDictionary); err != nil {
-			global.GVA_LOG.Error("导入字典失败!", zap.Error(err))
-			response.FailWithMessage("导入字典失败: "+err.Error(), c)
-			return
-		}
+// ImportVersionAsync 创建后台导入版本任务
+// @Tags SysVersion
+// @Summary 创建后台导入版本任务
+// @Security ApiKeyAuth
+// @Accept application/json
+// @Produce application/json
+// @Param data body systemReq.ImportVersionRequest true "版本JSON数据"
+// @Success 200 {object} response.Response{data=system.SysAsyncTask,msg=string} "返回后台任务"
+// @Router /sysVersion/importVersionAsync [post]
+func (sysVersionApi *SysVersionApi) ImportVersionAsync(c *gin.Context) {
+	var importData systemReq.ImportVersionRequest
+	err := c.ShouldBindJSON(&importData)
+	if err != nil {
+		response.FailWithMessage("解析JSON数据失败:"+err.Error(), c)
+		return
 	}
-
-	// 创建导入记录
-	jsonData, _ := json.Marshal(importData)
-	version := system.SysVersion{
-		VersionName: utils.Pointer(importData.VersionInfo.Name),
-		VersionCode: utils.Pointer(fmt.Sprintf("%s_imported_%s", importData.VersionInfo.Code, time.Now().Format("20060102150405"))),
-		Description: utils.Pointer(fmt.Sprintf("导入版本: %s", importData.VersionInfo.Description)),
-		VersionData: utils.Pointer(string(jsonData)),
+	if importData.VersionInfo.Name == "" || importData.VersionInfo.Code == "" {
+		response.FailWithMessage("版本信息格式错误", c)
+		return
 	}
-
-	err = sysVersionService.CreateSysVersion(ctx, &version)
+	task, err := asyncTaskService.Enqueue(utils.GetUserID(c), "ImportVersion", importData)
 	if err != nil {
-		global.GVA_LOG.Error("保存导入记录失败!", zap.Error(err))
-		// 这里不返回错误，因为数据已经导入成功
+		global.GVA_LOG.Error("创建导入任务失败!", zap.Error(err))
+		response.FailWithMessage("创建导入任务失败:"+err.Error(), c)
+		return
 	}
-
-	response.OkWithMessage("导入成功", c)
+	response.OkWithDetailed(task, "已开始导入", c)
 }
@@ -0,0 +1,18 @@
+package config
+
+type Account struct {
+	ResetTokenExpire int    `mapstructure:"reset-token-expire" json:"reset-token-expire" yaml:"reset-token-expire"` // 找回密码链接有效期，单位：分钟
+	VerifyCodeExpire int    `mapstructure:"verify-code-expire" json:"verify-code-expire" yaml:"verify-code-expire"` // 邮箱/手机验证码有效期，单位：分钟
+	SendLimitCount   int    `mapstructure:"send-limit-count" json:"send-limit-count" yaml:"send-limit-count"`       // 同一账号或IP在限制窗口内最多发送次数
+	SendLimitTime    int    `mapstructure:"send-limit-time" json:"send-limit-time" yaml:"send-limit-time"`          // 发送次数限制窗口，单位：s(秒)
+	ResetURL         string `mapstructure:"reset-url" json:"reset-url" yaml:"reset-url"`                            // 前端重置密码页面地址，令牌以 token 参数拼接在后面
+	SmsProvider      string `mapstructure:"sms-provider" json:"sms-provider" yaml:"sms-provider"`                   // 短信服务商 local 为本地模拟发送
+
+	OpenRegister        bool   `mapstructure:"open-register" json:"open-register" yaml:"open-register"`                         // 是否开放自助注册
+	RegisterAuthorityId uint   `mapstructure:"register-authority-id" json:"register-authority-id" yaml:"register-authority-id"` // 自助注册用户审核通过后的默认角色
+	RegisterApprovers   string `mapstructure:"register-approvers" json:"register-approvers" yaml:"register-approvers"`          // 注册审核通知邮箱:多个以英文逗号分隔
+	RegisterLimitCount  int    `mapstructure:"register-limit-count" json:"register-limit-count" yaml:"register-limit-count"`    // 同一IP在限制窗口内最多注册次数
+	RegisterLimitTime   int    `mapstructure:"register-limit-time" json:"register-limit-time" yaml:"register-limit-time"`       // 注册次数限制窗口，单位：s(秒)
+
+	PurgeGraceDays int `mapstructure:"purge-grace-days" json:"purge-grace-days" yaml:"purge-grace-days"` // 已删除用户的保留天数 超过后才能彻底清除
+}
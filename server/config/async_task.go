@@ -0,0 +1,10 @@
+package config
+
+type AsyncTask struct {
+	Workers      int    `mapstructure:"workers" json:"workers" yaml:"workers"`                   // 每个节点的工作协程数 为 0 时为 4
+	Backend      string `mapstructure:"backend" json:"backend" yaml:"backend"`                   // 队列 redis|db 为空时开启redis使用redis 否则使用db
+	PollInterval int    `mapstructure:"poll-interval" json:"poll-interval" yaml:"poll-interval"` // db 队列查询待执行任务与检查取消请求的间隔，单位：ms(毫秒)
+	StaleAfter   int    `mapstructure:"stale-after" json:"stale-after" yaml:"stale-after"`       // 执行中的任务超过该时间未更新心跳时视为中断，单位：s(秒) 为 0 时为 60
+	ArtifactDir  string `mapstructure:"artifact-dir" json:"artifact-dir" yaml:"artifact-dir"`    // 任务生成文件与上传文件的本地临时目录 生成的文件在任务结束后保存到对象存储
+	KeepDays     int    `mapstructure:"keep-days" json:"keep-days" yaml:"keep-days"`             // 已结束的任务及其文件保留天数 为 0 时不清理
+}
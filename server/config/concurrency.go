@@ -0,0 +1,14 @@
+package config
+
+type Concurrency struct {
+	Enable bool               `mapstructure:"enable" json:"enable" yaml:"enable"` // 是否开启并发限制
+	Groups []ConcurrencyGroup `mapstructure:"groups" json:"groups" yaml:"groups"` // 按路由组限制 路由通过 middleware.ConcurrencyLimit(name) 挂载
+}
+
+type ConcurrencyGroup struct {
+	Name    string `mapstructure:"name" json:"name" yaml:"name"`             // 路由组名称
+	Limit   int    `mapstructure:"limit" json:"limit" yaml:"limit"`          // 同时执行的请求数 为0时不限制
+	PerUser int    `mapstructure:"per-user" json:"per-user" yaml:"per-user"` // 同一用户同时执行及排队的请求数 为0时不限制
+	Queue   int    `mapstructure:"queue" json:"queue" yaml:"queue"`          // 排队的最大数量
+	MaxWait int    `mapstructure:"max-wait" json:"max-wait" yaml:"max-wait"` // 排队的最长时间，单位：s(秒)
+}
@@ -9,6 +9,15 @@ type Server struct {
 	Email     Email   `mapstructure:"email" json:"email" yaml:"email"`
 	System    System  `mapstructure:"system" json:"system" yaml:"system"`
 	Captcha   Captcha `mapstructure:"captcha" json:"captcha" yaml:"captcha"`
+	Account   Account `mapstructure:"account" json:"account" yaml:"account"`
+
+	OperationRecord OperationRecord `mapstructure:"operation-record" json:"operation-record" yaml:"operation-record"`
+	RateLimit       RateLimit       `mapstructure:"rate-limit" json:"rate-limit" yaml:"rate-limit"`
+	Concurrency     Concurrency     `mapstructure:"concurrency" json:"concurrency" yaml:"concurrency"`
+	Job             Job             `mapstructure:"job" json:"job" yaml:"job"`
+	Retention       Retention       `mapstructure:"retention" json:"retention" yaml:"retention"`
+	AsyncTask       AsyncTask       `mapstructure:"async-task" json:"async-task" yaml:"async-task"`
+	Dictionary      Dictionary      `mapstructure:"dictionary" json:"dictionary" yaml:"dictionary"`
 	// auto
 	AutoCode Autocode `mapstructure:"autocode" json:"autocode" yaml:"autocode"`
 	// gorm
@@ -0,0 +1,5 @@
+package config
+
+type Dictionary struct {
+	CacheTTL int `mapstructure:"cache-ttl" json:"cache-ttl" yaml:"cache-ttl"` // 按类型缓存字典的时间，单位：s(秒) 为0时默认600 小于0时不缓存
+}
@@ -2,4 +2,13 @@ package config
 
 type Excel struct {
 	Dir string `mapstructure:"dir" json:"dir" yaml:"dir"`
+	// UnrestrictedTables 关闭按表的权限检查 默认角色只能导出导入 TablePolicies 中允许的表
+	UnrestrictedTables bool               `mapstructure:"unrestricted-tables" json:"unrestricted-tables" yaml:"unrestricted-tables"`
+	TablePolicies      []ExcelTablePolicy `mapstructure:"table-policies" json:"table-policies" yaml:"table-policies"`
+}
+
+// ExcelTablePolicy 角色允许导出导入的表 Tables 中的 * 表示所有表
+type ExcelTablePolicy struct {
+	Authorities []uint   `mapstructure:"authorities" json:"authorities" yaml:"authorities"`
+	Tables      []string `mapstructure:"tables" json:"tables" yaml:"tables"`
 }
@@ -0,0 +1,8 @@
+package config
+
+type Job struct {
+	NodeID      string `mapstructure:"node-id" json:"node-id" yaml:"node-id"`                // 节点标识 记录在执行记录中 为空时使用 主机名-进程号
+	Locker      string `mapstructure:"locker" json:"locker" yaml:"locker"`                   // 单节点执行时使用的锁 redis|db 为空时开启redis使用redis 否则使用db
+	LockTTL     int    `mapstructure:"lock-ttl" json:"lock-ttl" yaml:"lock-ttl"`             // 未设置超时的任务的锁租期，单位：s(秒) 执行期间定时续期
+	NotifyEmail bool   `mapstructure:"notify-email" json:"notify-email" yaml:"notify-email"` // 任务重试后仍失败时通过邮件插件发送到 email.to
+}
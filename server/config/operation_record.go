@@ -0,0 +1,40 @@
+package config
+
+type OperationRecord struct {
+	Async         bool   `mapstructure:"async" json:"async" yaml:"async"`                            // 是否异步批量写入操作记录
+	BufferSize    int    `mapstructure:"buffer-size" json:"buffer-size" yaml:"buffer-size"`          // 缓冲队列长度
+	BatchSize     int    `mapstructure:"batch-size" json:"batch-size" yaml:"batch-size"`             // 每批写入的最大条数
+	FlushInterval int    `mapstructure:"flush-interval" json:"flush-interval" yaml:"flush-interval"` // 未满一批时的最长写入间隔，单位：ms(毫秒)
+	Overflow      string `mapstructure:"overflow" json:"overflow" yaml:"overflow"`                   // 队列满时的策略 drop:丢弃并计数 block:阻塞请求直到入队
+
+	RedactKeys        []string `mapstructure:"redact-keys" json:"redact-keys" yaml:"redact-keys"`                         // 按字段名脱敏 不区分大小写 字段名包含即命中
+	RedactPaths       []string `mapstructure:"redact-paths" json:"redact-paths" yaml:"redact-paths"`                      // 按JSON路径脱敏 如 data.user.phone * 匹配任意字段
+	RedactMask        string   `mapstructure:"redact-mask" json:"redact-mask" yaml:"redact-mask"`                         // 脱敏后的替换内容
+	SkipResponsePaths []string `mapstructure:"skip-response-paths" json:"skip-response-paths" yaml:"skip-response-paths"` // 不记录响应内容的路由
+
+	Sinks           []string            `mapstructure:"sinks" json:"sinks" yaml:"sinks"`                                  // 存储 db|file|mongo|http 可同时开启多个 为空时为 db
+	Primary         string              `mapstructure:"primary" json:"primary" yaml:"primary"`                            // 查询接口读取的存储 db|mongo
+	File            OperationRecordFile `mapstructure:"file" json:"file" yaml:"file"`                                     // 文件存储
+	MongoCollection string              `mapstructure:"mongo-collection" json:"mongo-collection" yaml:"mongo-collection"` // mongo 存储的集合名
+	Http            OperationRecordHttp `mapstructure:"http" json:"http" yaml:"http"`                                     // HTTP 批量推送
+
+	AppendOnly       bool   `mapstructure:"append-only" json:"append-only" yaml:"append-only"`                      // 只追加模式 记录以哈希链相连 禁止删除 仅能通过封存归档清理
+	ChainSecret      string `mapstructure:"chain-secret" json:"chain-secret" yaml:"chain-secret"`                   // 检查点与归档的签名密钥 只追加模式下必须配置 不能与 jwt.signing-key 相同
+	CheckpointDir    string `mapstructure:"checkpoint-dir" json:"checkpoint-dir" yaml:"checkpoint-dir"`             // 检查点导出目录
+	ArchiveDir       string `mapstructure:"archive-dir" json:"archive-dir" yaml:"archive-dir"`                      // 封存归档目录
+	ArchiveAfterDays int    `mapstructure:"archive-after-days" json:"archive-after-days" yaml:"archive-after-days"` // 只追加模式下超过天数的记录自动封存归档
+
+	Rollup bool `mapstructure:"rollup" json:"rollup" yaml:"rollup"` // 统计接口读取按小时预汇总的表 由定时任务每小时汇总
+}
+
+type OperationRecordFile struct {
+	Dir      string `mapstructure:"dir" json:"dir" yaml:"dir"`                   // 文件目录
+	MaxSize  int    `mapstructure:"max-size" json:"max-size" yaml:"max-size"`    // 单个文件最大大小，单位：MB
+	KeepDays int    `mapstructure:"keep-days" json:"keep-days" yaml:"keep-days"` // 文件保留天数
+}
+
+type OperationRecordHttp struct {
+	Url     string `mapstructure:"url" json:"url" yaml:"url"`             // 接收地址 以 JSON 数组批量 POST
+	Token   string `mapstructure:"token" json:"token" yaml:"token"`       // 以 Authorization: Bearer 方式携带
+	Timeout int    `mapstructure:"timeout" json:"timeout" yaml:"timeout"` // 超时时间，单位：ms(毫秒)
+}
@@ -0,0 +1,20 @@
+package config
+
+type RateLimit struct {
+	Enable    bool              `mapstructure:"enable" json:"enable" yaml:"enable"`          // 是否开启限流
+	Algorithm string            `mapstructure:"algorithm" json:"algorithm" yaml:"algorithm"` // 默认算法 token-bucket|sliding-window|fixed-window
+	Policies  []RateLimitPolicy `mapstructure:"policies" json:"policies" yaml:"policies"`    // 限流策略 请求命中的每条策略都需要通过
+}
+
+type RateLimitPolicy struct {
+	Name        string   `mapstructure:"name" json:"name" yaml:"name"`                      // 策略名称 作为计数key的一部分
+	Path        string   `mapstructure:"path" json:"path" yaml:"path"`                      // 路由 不含路由前缀 支持通配符 以*结尾时按前缀匹配 为空时匹配全部
+	Methods     []string `mapstructure:"methods" json:"methods" yaml:"methods"`             // 请求方法 为空时匹配全部
+	Authorities []uint   `mapstructure:"authorities" json:"authorities" yaml:"authorities"` // 只对这些角色生效 为空时不限
+	Users       []uint   `mapstructure:"users" json:"users" yaml:"users"`                   // 只对这些用户生效 为空时不限
+	By          string   `mapstructure:"by" json:"by" yaml:"by"`                            // 计数维度 ip|user|authority|token 为空时为ip
+	Algorithm   string   `mapstructure:"algorithm" json:"algorithm" yaml:"algorithm"`       // 算法 为空时使用默认算法
+	Limit       int      `mapstructure:"limit" json:"limit" yaml:"limit"`                   // 窗口内允许的次数 令牌桶为每个窗口补充的令牌数
+	Window      int      `mapstructure:"window" json:"window" yaml:"window"`                // 窗口，单位：s(秒)
+	Burst       int      `mapstructure:"burst" json:"burst" yaml:"burst"`                   // 令牌桶容量 为空时等于limit
+}
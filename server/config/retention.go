@@ -0,0 +1,25 @@
+package config
+
+type Retention struct {
+	BatchSize int               `mapstructure:"batch-size" json:"batch-size" yaml:"batch-size"` // 每批删除的最大条数 为 0 时为 1000
+	Pause     int               `mapstructure:"pause" json:"pause" yaml:"pause"`                // 两批之间的间隔，单位：ms(毫秒) 避免长时间占用锁
+	Archive   RetentionArchive  `mapstructure:"archive" json:"archive" yaml:"archive"`          // 删除前归档
+	Policies  []RetentionPolicy `mapstructure:"policies" json:"policies" yaml:"policies"`       // 清理策略 可被系统参数 retention.policies 中同表名的策略覆盖 系统参数只能添加日志与历史表的策略
+}
+
+// RetentionArchive 归档设置 开启归档的策略在删除前先将记录写入压缩文件
+type RetentionArchive struct {
+	Format  string `mapstructure:"format" json:"format" yaml:"format"`    // 文件格式 jsonl|csv 均以 gzip 压缩
+	Storage string `mapstructure:"storage" json:"storage" yaml:"storage"` // 存储位置 local:保存在 dir oss:通过 system.oss-type 上传后删除本地文件
+	Dir     string `mapstructure:"dir" json:"dir" yaml:"dir"`             // 本地目录
+}
+
+// RetentionPolicy 单表的清理策略 删除 compare-field 早于当前时间减 interval 的记录
+type RetentionPolicy struct {
+	TableName    string `mapstructure:"table-name" json:"table-name" yaml:"table-name"`          // 表名
+	CompareField string `mapstructure:"compare-field" json:"compare-field" yaml:"compare-field"` // 比较的时间字段
+	Interval     string `mapstructure:"interval" json:"interval" yaml:"interval"`                // 保留时长 如 2160h
+	PrimaryKey   string `mapstructure:"primary-key" json:"primary-key" yaml:"primary-key"`       // 主键字段 按主键分批删除 为空时为 id
+	Archive      bool   `mapstructure:"archive" json:"archive" yaml:"archive"`                   // 删除前是否归档
+	Disabled     bool   `mapstructure:"disabled" json:"disabled" yaml:"disabled"`                // 停用 用于在系统参数中关闭配置文件中的策略
+}
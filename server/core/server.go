@@ -28,6 +28,10 @@ func RunServer() {
 	if global.GVA_DB != nil {
 		system.LoadAll()
 	}
+	// 启动操作记录异步写入
+	system.OperationRecordServiceApp.StartRecordWriter()
+	// 开启redis时订阅字典变更
+	system.StartDictionaryCacheSync()
 
 	Router := initialize.Routers()
 
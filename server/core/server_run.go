@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -56,5 +57,10 @@ func initServer(address string, router *gin.Engine, readTimeout, writeTimeout ti
 		zap.L().Fatal("WEB服务关闭异常", zap.Error(err))
 	}
 
+	// 请求处理完毕后写入队列中剩余的操作记录
+	if err := system.OperationRecordServiceApp.CloseRecordWriter(ctx); err != nil {
+		zap.L().Error("操作记录写入未完成", zap.Error(err))
+	}
+
 	zap.L().Info("WEB服务已关闭")
 }
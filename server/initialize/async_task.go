@@ -0,0 +1,98 @@
+package initialize
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
+)
+
+// AsyncTaskHandlers 注册后台任务的执行方法
+func AsyncTaskHandlers() {
+	system.RegisterAsyncTask("ExportExcel", func(ctx context.Context, run *system.AsyncTaskRun, payload systemReq.ExportExcelTask) error {
+		export, err := system.SysExportTemplateServiceApp.PrepareExport(payload.TemplateID, payload.Query)
+		if err != nil {
+			return err
+		}
+		export.OnProgress = func(rows int) {
+			run.Progress(0, fmt.Sprintf("已导出 %d 行", rows)) // 总行数未知 只上报已导出的行数
+		}
+		out, err := run.CreateArtifact(export.FileName())
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		return export.Stream(ctx, out)
+	})
+
+	// 读取本节点上传的文件 只由入队节点执行 数据有误时不导入 任务仍为成功 结果中记录错误 生成的文件为错误工作簿
+	system.RegisterNodeAsyncTask("ImportExcel", func(ctx context.Context, run *system.AsyncTaskRun, payload systemReq.ImportExcelTask) error {
+		defer os.Remove(payload.File)
+		run.Progress(0, "正在校验")
+		result, err := system.SysExportTemplateServiceApp.ImportExcelFile(payload.TemplateID, payload.File, system.ImportExcelOptions{
+			DryRun: payload.DryRun,
+			OnProgress: func(done, total int) {
+				run.Progress(done*90/total, fmt.Sprintf("已校验 %d/%d 行", done, total))
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err = run.SetResult(result); err != nil {
+			return err
+		}
+		switch {
+		case result.Failed > 0:
+			out, err := run.CreateArtifact("导入错误.xlsx")
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			run.Progress(100, fmt.Sprintf("%d 行数据有误 未导入", result.Failed))
+			return result.WriteErrorWorkbook(out)
+		case result.DryRun:
+			run.Progress(100, "校验通过")
+		default:
+			run.Progress(100, fmt.Sprintf("新增 %d 行 更新 %d 行", result.Created, result.Updated))
+		}
+		return nil
+	})
+
+	system.RegisterAsyncTask("ImportVersion", func(ctx context.Context, run *system.AsyncTaskRun, payload systemReq.ImportVersionRequest) error {
+		return system.SysVersionServiceApp.ImportVersion(ctx, payload, run.Progress)
+	})
+
+	// 读取本节点上传的插件包并写入本节点源码 只由入队节点执行
+	system.RegisterNodeAsyncTask("InstallPlugin", func(ctx context.Context, run *system.AsyncTaskRun, payload systemReq.InstallPluginTask) error {
+		defer os.Remove(payload.File)
+		run.Progress(0, "正在安装")
+		if _, _, err := system.AutoCodePlugin.InstallFile(payload.File); err != nil {
+			return err
+		}
+		run.Progress(100, "插件安装成功")
+		return nil
+	})
+
+	// 代码生成会写入本节点的源码文件并注入注册代码 只由入队节点执行 入队前已校验参数
+	system.RegisterNodeAsyncTask("CreateAutoCode", func(ctx context.Context, run *system.AsyncTaskRun, payload systemReq.AutoCode) error {
+		if err := payload.Pretreatment(); err != nil {
+			return err
+		}
+		run.Progress(0, "正在生成代码")
+		if err := system.AutoCodeTemplate.Create(ctx, payload); err != nil {
+			return err
+		}
+		run.Progress(100, "代码生成成功")
+		return nil
+	})
+
+	// 其他后台任务的执行方法注册在这里 插件可在自身初始化时注册
+	// 入队: service.ServiceGroupApp.SystemServiceGroup.AsyncTaskService.Enqueue(userID, "任务类型", 参数)
+}
+
+// AsyncTasks 启动后台任务的工作协程
+func AsyncTasks() {
+	system.AsyncTaskServiceApp.StartAsyncTasks()
+}
@@ -0,0 +1,15 @@
+package initialize
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"go.uber.org/zap"
+)
+
+// ChangeHistory 注册变更历史的 gorm 回调
+// 代码生成的业务模型如需记录变更历史 在注册前调用 system.TrackChanges 追加 如 system.TrackChanges(example.ExaCustomer{})
+func ChangeHistory() {
+	if err := system.RegisterChangeHistory(global.GVA_DB); err != nil {
+		global.GVA_LOG.Error("register change history failed", zap.Error(err))
+	}
+}
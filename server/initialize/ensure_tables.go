@@ -54,6 +54,22 @@ func (e *ensureTables) MigrateTable(ctx context.Context) (context.Context, error
 		sysModel.JoinTemplate{},
 		sysModel.SysParams{},
 		sysModel.SysVersion{},
+		sysModel.SysUserGroup{},
+		sysModel.SysUserToken{},
+		sysModel.SysUserRegistration{},
+		sysModel.SysMigration{},
+		sysModel.SysOperationRecordCheckpoint{},
+		sysModel.SysOperationRecordArchive{},
+		sysModel.SysOperationRecordChainLock{},
+		sysModel.SysOperationRecordHourly{},
+		sysModel.SysOperationRecordUserHourly{},
+		sysModel.SysChangeHistory{},
+		sysModel.SysJob{},
+		sysModel.SysJobRun{},
+		sysModel.SysJobLock{},
+		sysModel.SysAsyncTask{},
+		sysModel.SysExportSubscription{},
+		sysModel.SysExportWorkbook{},
 		adapter.CasbinRule{},
 
 		example.ExaFile{},
@@ -62,6 +62,22 @@ func RegisterTables() {
 		system.JoinTemplate{},
 		system.SysParams{},
 		system.SysVersion{},
+		system.SysUserGroup{},
+		system.SysUserToken{},
+		system.SysUserRegistration{},
+		system.SysMigration{},
+		system.SysOperationRecordCheckpoint{},
+		system.SysOperationRecordArchive{},
+		system.SysOperationRecordChainLock{},
+		system.SysOperationRecordHourly{},
+		system.SysOperationRecordUserHourly{},
+		system.SysChangeHistory{},
+		system.SysJob{},
+		system.SysJobRun{},
+		system.SysJobLock{},
+		system.SysAsyncTask{},
+		system.SysExportSubscription{},
+		system.SysExportWorkbook{},
 
 		example.ExaFile{},
 		example.ExaCustomer{},
@@ -81,4 +97,6 @@ func RegisterTables() {
 		os.Exit(0)
 	}
 	global.GVA_LOG.Info("register table success")
+	RunMigrations()
+	RedactOperationRecords()
 }
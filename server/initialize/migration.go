@@ -0,0 +1,92 @@
+package initialize
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	sysModel "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type migration struct {
+	name string
+	run  func(db *gorm.DB) error
+}
+
+// migrations 一次性数据迁移 按名称记录 执行成功后不会再次执行 新迁移追加在末尾
+var migrations = []migration{
+	{
+		name: "structured_export_templates",
+		run: func(db *gorm.DB) error {
+			count, err := system.SysExportTemplateServiceApp.MigrateLegacyTemplates()
+			global.GVA_LOG.Info("migrate export templates", zap.Int("count", count))
+			return err
+		},
+	},
+}
+
+// RunMigrations 依次执行尚未执行的数据迁移 某个迁移失败时停止 下次启动时重试
+func RunMigrations() {
+	db := global.GVA_DB
+	for _, m := range migrations {
+		err := db.Where("name = ?", m.name).First(&sysModel.SysMigration{}).Error
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			global.GVA_LOG.Error("check migration failed", zap.String("name", m.name), zap.Error(err))
+			return
+		}
+		if err = m.run(db); err != nil {
+			global.GVA_LOG.Error("run migration failed", zap.String("name", m.name), zap.Error(err))
+			return
+		}
+		if err = db.Create(&sysModel.SysMigration{Name: m.name}).Error; err != nil {
+			global.GVA_LOG.Error("save migration failed", zap.String("name", m.name), zap.Error(err))
+			return
+		}
+		global.GVA_LOG.Info("migration applied", zap.String("name", m.name))
+	}
+}
+
+// redacting 重新加载配置时避免重复启动后台脱敏
+var redacting atomic.Bool
+
+// RedactOperationRecords 在后台按当前脱敏规则分批处理已有的操作记录 不阻塞启动
+// 以规则的哈希记录执行状态 规则变化后重新执行 没有配置规则时不执行也不记录 中断后下次启动从头重新处理
+func RedactOperationRecords() {
+	rules := system.OperationRecordServiceApp.RedactRulesHash()
+	if rules == "" {
+		return
+	}
+	name := "redact_operation_records_" + rules
+	db := global.GVA_DB
+	err := db.Where("name = ?", name).First(&sysModel.SysMigration{}).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		if err != nil {
+			global.GVA_LOG.Error("check migration failed", zap.String("name", name), zap.Error(err))
+		}
+		return
+	}
+	if !redacting.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer redacting.Store(false)
+		count, err := system.OperationRecordServiceApp.RedactExistingRecords(500, 100*time.Millisecond)
+		global.GVA_LOG.Info("redact operation records", zap.Int64("count", count))
+		if err != nil {
+			global.GVA_LOG.Error("run migration failed", zap.String("name", name), zap.Error(err))
+			return
+		}
+		if err = db.Create(&sysModel.SysMigration{Name: name}).Error; err != nil {
+			global.GVA_LOG.Error("save migration failed", zap.String("name", name), zap.Error(err))
+			return
+		}
+		global.GVA_LOG.Info("migration applied", zap.String("name", name))
+	}()
+}
@@ -35,10 +35,16 @@ func Reload() error {
 	if global.GVA_DB != nil {
 		// 确保数据库表结构是最新的
 		RegisterTables()
+		ChangeHistory()
 	}
 
 	// 重新初始化定时任务
 	Timer()
+	AsyncTaskHandlers()
+	if global.GVA_DB != nil {
+		Jobs()
+		AsyncTasks()
+	}
 
 	global.GVA_LOG.Info("系统配置重新加载完成")
 	return nil
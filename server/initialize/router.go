@@ -36,6 +36,7 @@ func (fs justFilesFilesystem) Open(name string) (http.File, error) {
 func Routers() *gin.Engine {
 	Router := gin.New()
 	Router.Use(gin.Recovery())
+	Router.Use(middleware.RequestID())
 	if gin.Mode() == gin.DebugMode {
 		Router.Use(gin.Logger())
 	}
@@ -77,7 +78,8 @@ func Routers() *gin.Engine {
 	PublicGroup := Router.Group(global.GVA_CONFIG.System.RouterPrefix)
 	PrivateGroup := Router.Group(global.GVA_CONFIG.System.RouterPrefix)
 
-	PrivateGroup.Use(middleware.JWTAuth()).Use(middleware.CasbinHandler())
+	PublicGroup.Use(middleware.RateLimit())
+	PrivateGroup.Use(middleware.JWTAuth()).Use(middleware.RateLimit()).Use(middleware.CasbinHandler())
 
 	{
 		// 健康监测
@@ -94,6 +96,7 @@ func Routers() *gin.Engine {
 		systemRouter.InitApiRouter(PrivateGroup, PublicGroup)               // 注册功能api路由
 		systemRouter.InitJwtRouter(PrivateGroup)                            // jwt相关路由
 		systemRouter.InitUserRouter(PrivateGroup)                           // 注册用户路由
+		systemRouter.InitUserGroupRouter(PrivateGroup)                      // 用户组路由
 		systemRouter.InitMenuRouter(PrivateGroup)                           // 注册menu路由
 		systemRouter.InitSystemRouter(PrivateGroup)                         // system相关路由
 		systemRouter.InitSysVersionRouter(PrivateGroup)                     // 发版相关路由
@@ -107,6 +110,11 @@ func Routers() *gin.Engine {
 		systemRouter.InitAuthorityBtnRouterRouter(PrivateGroup)             // 按钮权限管理
 		systemRouter.InitSysExportTemplateRouter(PrivateGroup, PublicGroup) // 导出模板
 		systemRouter.InitSysParamsRouter(PrivateGroup, PublicGroup)         // 参数管理
+		systemRouter.InitChangeHistoryRouter(PrivateGroup)                  // 变更历史
+		systemRouter.InitSysJobRouter(PrivateGroup)                         // 定时任务
+		systemRouter.InitSysAsyncTaskRouter(PrivateGroup)                   // 后台任务
+		systemRouter.InitSysExportSubscriptionRouter(PrivateGroup)          // 导出订阅
+		systemRouter.InitSysExportWorkbookRouter(PrivateGroup)              // 组合导出
 		exampleRouter.InitCustomerRouter(PrivateGroup)                      // 客户路由
 		exampleRouter.InitFileUploadAndDownloadRouter(PrivateGroup)         // 文件上传下载功能路由
 		exampleRouter.InitAttachmentCategoryRouterRouter(PrivateGroup)      // 文件上传下载分类
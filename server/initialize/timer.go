@@ -1,37 +1,129 @@
 package initialize
 
 import (
+	"context"
 	"fmt"
-	"github.com/flipped-aurora/gin-vue-admin/server/task"
-
-	"github.com/robfig/cron/v3"
+	"time"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/task"
+	"go.uber.org/zap"
 )
 
+// Timer 注册定时任务的执行方法 带默认执行周期的方法在首次启动时创建为定时任务 之后可在定时任务管理中修改
 func Timer() {
-	go func() {
-		var option []cron.Option
-		option = append(option, cron.WithSeconds())
-		// 清理DB定时任务
-		_, err := global.GVA_Timer.AddTaskByFunc("ClearDB", "@daily", func() {
-			err := task.ClearTable(global.GVA_DB) // 定时任务方法定在task文件包中
+	// 清理DB定时任务
+	task.Register(task.Definition{
+		Name:        "ClearDB",
+		Description: "按清理策略分批清理数据库过期数据 可在删除前归档",
+		Spec:        "@daily",
+		Handler: func(ctx context.Context, params string) (string, error) {
+			return task.ClearTable(global.GVA_DB.WithContext(ctx)) // 清理策略见配置 retention 与系统参数 retention.policies
+		},
+	})
+
+	// 彻底清除超过保留天数的已删除用户
+	task.Register(task.Definition{
+		Name:        "PurgeDeletedUsers",
+		Description: "定时彻底清除超过保留天数的已删除用户",
+		Spec:        "@daily",
+		Handler: func(ctx context.Context, params string) (string, error) {
+			count, err := system.UserServiceApp.PurgeExpiredUsers()
+			return fmt.Sprintf("清除用户 %d 个", count), err
+		},
+	})
+
+	// 只追加模式下每日生成操作记录检查点 并封存归档超过保留天数的记录
+	task.Register(task.Definition{
+		Name:        "OperationRecordChain",
+		Description: "只追加模式下生成操作记录检查点并封存归档过期记录",
+		Spec:        "@daily",
+		Handler: func(ctx context.Context, params string) (string, error) {
+			conf := global.GVA_CONFIG.OperationRecord
+			if !conf.AppendOnly {
+				return "未开启只追加模式", nil
+			}
+			checkpoint, err := system.OperationRecordServiceApp.CreateOperationRecordCheckpoint()
 			if err != nil {
-				fmt.Println("timer error:", err)
+				return "", err
+			}
+			output := fmt.Sprintf("检查点: %s", checkpoint.File)
+			if conf.ArchiveAfterDays > 0 {
+				before := time.Now().AddDate(0, 0, -conf.ArchiveAfterDays)
+				archive, err := system.OperationRecordServiceApp.SealOperationRecordArchive(before)
+				if err != nil {
+					return output, err
+				}
+				output += fmt.Sprintf("\n归档: %s 共 %d 条", archive.File, archive.Count)
+			}
+			return output, nil
+		},
+	})
+
+	// 每小时将上一小时的操作记录汇总到小时汇总表
+	task.Register(task.Definition{
+		Name:        "OperationRecordRollup",
+		Description: "每小时汇总操作记录统计数据",
+		Spec:        "0 5 * * * *",
+		Handler: func(ctx context.Context, params string) (string, error) {
+			if !global.GVA_CONFIG.OperationRecord.Rollup {
+				return "未开启统计汇总", nil
 			}
-		}, "定时清理数据库【日志，黑名单】内容", option...)
-		if err != nil {
-			fmt.Println("add timer error:", err)
-		}
-
-		// 其他定时任务定在这里 参考上方使用方法
-
-		//_, err := global.GVA_Timer.AddTaskByFunc("定时任务标识", "corn表达式", func() {
-		//	具体执行内容...
-		//  ......
-		//}, option...)
-		//if err != nil {
-		//	fmt.Println("add timer error:", err)
-		//}
-	}()
+			return "", system.OperationRecordServiceApp.RollupOperationRecords()
+		},
+	})
+
+	// 清理超过保留天数的后台任务及其生成的文件
+	task.Register(task.Definition{
+		Name:        "AsyncTaskCleanup",
+		Description: "定时清理超过保留天数的后台任务及其生成的文件",
+		Spec:        "@daily",
+		Handler: func(ctx context.Context, params string) (string, error) {
+			count, err := system.AsyncTaskServiceApp.CleanupAsyncTasks()
+			return fmt.Sprintf("清理任务 %d 个", count), err
+		},
+	})
+
+	// 回收已退出节点上中断的后台任务 节点标识随进程变化时重启后无法按节点回收
+	task.Register(task.Definition{
+		Name:        "AsyncTaskRecover",
+		Description: "将心跳超时的执行中后台任务标记为失败",
+		Spec:        "@every 1m",
+		Handler: func(ctx context.Context, params string) (string, error) {
+			count, err := system.AsyncTaskServiceApp.RecoverAsyncTasks()
+			return fmt.Sprintf("回收任务 %d 个", count), err
+		},
+	})
+
+	// 导出订阅 每个订阅对应一个定时任务 由导出订阅管理自动创建与更新
+	task.Register(task.Definition{
+		Name:        system.ExportSubscriptionHandler,
+		Description: "按导出订阅导出并通过邮件或OSS发送 由导出订阅自动创建 参数为订阅ID",
+		Handler:     system.ExportSubscriptionServiceApp.RunExportSubscription,
+		OnFailure:   system.NotifyExportSubscriptionFailure,
+	})
+
+	// 其他定时任务的执行方法注册在这里 参考上方使用方法 插件可在自身初始化时注册
+	// params 为任务配置的参数 返回的内容保存到执行记录中
+
+	//task.Register(task.Definition{
+	//	Name:        "执行方法标识",
+	//	Description: "说明",
+	//	Spec:        "cron表达式 支持秒 为空时不自动创建任务",
+	//	Handler: func(ctx context.Context, params string) (string, error) {
+	//		具体执行内容...
+	//		return "输出", nil
+	//	},
+	//})
+}
+
+// Jobs 调度数据库中启用的定时任务 并为还没有定时任务的导出订阅创建任务
+func Jobs() {
+	if err := system.JobServiceApp.StartJobs(); err != nil {
+		global.GVA_LOG.Error("start jobs failed", zap.Error(err))
+	}
+	if err := system.ExportSubscriptionServiceApp.StartExportSubscriptions(); err != nil {
+		global.GVA_LOG.Error("start export subscriptions failed", zap.Error(err))
+	}
 }
@@ -43,9 +43,13 @@ func initializeSystem() {
 	zap.ReplaceGlobals(global.GVA_LOG)
 	global.GVA_DB = initialize.Gorm() // gorm连接数据库
 	initialize.Timer()
+	initialize.AsyncTaskHandlers()
 	initialize.DBList()
 	initialize.SetupHandlers() // 注册全局函数
 	if global.GVA_DB != nil {
 		initialize.RegisterTables() // 初始化表
+		initialize.ChangeHistory()  // 注册变更历史回调
+		initialize.Jobs()           // 调度定时任务
+		initialize.AsyncTasks()     // 启动后台任务
 	}
 }
@@ -732,7 +732,7 @@ func (g *GVAExecutor) createDictionariesFromInfo(ctx context.Context, dictionari
 				Desc:   dictInfo.Description,
 			}
 
-			err = dictionaryService.CreateSysDictionary(dictionary)
+			err = dictionaryService.CreateSysDictionary(ctx, dictionary)
 			if err != nil {
 				messages = append(messages, fmt.Sprintf("创建字典 %s 失败: %v; ", dictInfo.DictType, err))
 				continue
@@ -240,7 +240,7 @@ func (m *MenuCreator) Handle(ctx context.Context, request mcp.CallToolRequest) (
 
 	// 创建菜单
 	menuService := service.ServiceGroupApp.SystemServiceGroup.MenuService
-	err := menuService.AddBaseMenu(menu)
+	err := menuService.AddBaseMenu(ctx, menu)
 	if err != nil {
 		return nil, fmt.Errorf("创建菜单失败: %v", err)
 	}
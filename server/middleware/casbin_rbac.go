@@ -22,6 +22,17 @@ func CasbinHandler() gin.HandlerFunc {
 		sub := strconv.Itoa(int(waitUse.AuthorityId))
 		e := utils.GetCasbin() // 判断策略中是否存在
 		success, _ := e.Enforce(sub, obj, act)
+		if !success {
+			// 有效权限为当前角色与用户组授予角色的并集
+			for _, id := range utils.GetUserGroupAuthorityIds(waitUse.BaseClaims.ID) {
+				if id == waitUse.AuthorityId {
+					continue
+				}
+				if success, _ = e.Enforce(strconv.Itoa(int(id)), obj, act); success {
+					break
+				}
+			}
+		}
 		if !success {
 			response.FailWithDetailed(gin.H{}, "权限不足", c)
 			c.Abort()
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/concurrency"
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimit 按 concurrency 配置中名称为 name 的路由组限制同时执行的请求数
+// 超出时排队等待 排队已满或等待超时返回 503
+func ConcurrencyLimit(name string) gin.HandlerFunc {
+	return ConcurrencyLimitBy(name, nil)
+}
+
+// ConcurrencyLimitBy 同 ConcurrencyLimit 用于不携带登录信息的路由 userID 从请求中取得用户 如导出token中记录的用户
+// 取不到用户时按IP限制
+func ConcurrencyLimitBy(name string, userID func(c *gin.Context) uint) gin.HandlerFunc {
+	limiter := concurrency.Get(name)
+	return func(c *gin.Context) {
+		conf := global.GVA_CONFIG.Concurrency
+		if !conf.Enable {
+			c.Next()
+			return
+		}
+		var group *config.ConcurrencyGroup
+		for i := range conf.Groups {
+			if conf.Groups[i].Name == name {
+				group = &conf.Groups[i]
+				break
+			}
+		}
+		if group == nil {
+			c.Next()
+			return
+		}
+		user := "ip:" + c.ClientIP()
+		if v, ok := c.Get("claims"); ok {
+			if claims, ok := v.(*systemReq.CustomClaims); ok {
+				user = "user:" + strconv.FormatUint(uint64(claims.BaseClaims.ID), 10)
+			}
+		} else if userID != nil {
+			if id := userID(c); id != 0 {
+				user = "user:" + strconv.FormatUint(uint64(id), 10)
+			}
+		}
+		release, err := limiter.Acquire(c.Request.Context(), user, concurrency.Options{
+			Limit:   group.Limit,
+			PerUser: group.PerUser,
+			Queue:   group.Queue,
+			MaxWait: time.Duration(group.MaxWait) * time.Second,
+		})
+		if err != nil {
+			msg := "当前执行的任务过多, 请稍后再试"
+			if errors.Is(err, concurrency.ErrUserLimit) {
+				msg = "您有任务正在执行, 请等待完成后再试"
+			}
+			retry := group.MaxWait
+			if retry < 1 {
+				retry = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retry))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, response.Response{Code: response.ERROR, Data: gin.H{}, Msg: msg})
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
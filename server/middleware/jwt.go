@@ -52,6 +52,9 @@ func JWTAuth() gin.HandlerFunc {
 		//	c.Abort()
 		//}
 		c.Set("claims", claims)
+		info := utils.GetRequestInfo(c.Request.Context())
+		info.UserID, info.Username = claims.BaseClaims.ID, claims.Username
+		c.Request = c.Request.WithContext(utils.WithRequestInfo(c.Request.Context(), info))
 		if claims.ExpiresAt.Unix()-time.Now().Unix() < claims.BufferTime {
 			dr, _ := utils.ParseDuration(global.GVA_CONFIG.JWT.ExpiresTime)
 			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(dr))
@@ -4,12 +4,12 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
-	"go.uber.org/zap"
-
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
@@ -27,8 +27,7 @@ type LimitConfig struct {
 func (l LimitConfig) LimitWithTime() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if err := l.CheckOrMark(l.GenerationKey(c), l.Expire, l.Limit); err != nil {
-			c.JSON(http.StatusOK, gin.H{"code": response.ERROR, "msg": err.Error()})
-			c.Abort()
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"code": response.ERROR, "msg": err.Error()})
 			return
 		} else {
 			c.Next()
@@ -41,15 +40,17 @@ func DefaultGenerationKey(c *gin.Context) string {
 	return "GVA_Limit" + c.ClientIP()
 }
 
+// DefaultCheckOrMark 固定窗口计数 未开启redis时使用进程内计数
 func DefaultCheckOrMark(key string, expire int, limit int) (err error) {
-	// 判断是否开启redis
-	if global.GVA_REDIS == nil {
-		return err
-	}
-	if err = SetLimitWithTime(key, limit, time.Duration(expire)*time.Second); err != nil {
-		global.GVA_LOG.Error("limit", zap.Error(err))
+	result := allowRequest(context.Background(), key, ratelimit.Rule{
+		Algorithm: ratelimit.AlgorithmFixedWindow,
+		Limit:     limit,
+		Window:    time.Duration(expire) * time.Second,
+	})
+	if !result.Allowed {
+		return errors.New("请求太过频繁, 请 " + strconv.Itoa(ceilSeconds(result.RetryAfter)) + " 秒后尝试")
 	}
-	return err
+	return nil
 }
 
 func DefaultLimit() gin.HandlerFunc {
@@ -61,32 +62,18 @@ func DefaultLimit() gin.HandlerFunc {
 	}.LimitWithTime()
 }
 
-// SetLimitWithTime 设置访问次数
+// SetLimitWithTime 设置访问次数 计数与判断在redis脚本中原子执行
 func SetLimitWithTime(key string, limit int, expiration time.Duration) error {
-	count, err := global.GVA_REDIS.Exists(context.Background(), key).Result()
+	result, err := ratelimit.NewRedisLimiter(global.GVA_REDIS).Allow(context.Background(), key, ratelimit.Rule{
+		Algorithm: ratelimit.AlgorithmFixedWindow,
+		Limit:     limit,
+		Window:    expiration,
+	})
 	if err != nil {
 		return err
 	}
-	if count == 0 {
-		pipe := global.GVA_REDIS.TxPipeline()
-		pipe.Incr(context.Background(), key)
-		pipe.Expire(context.Background(), key, expiration)
-		_, err = pipe.Exec(context.Background())
-		return err
-	} else {
-		// 次数
-		if times, err := global.GVA_REDIS.Get(context.Background(), key).Int(); err != nil {
-			return err
-		} else {
-			if times >= limit {
-				if t, err := global.GVA_REDIS.PTTL(context.Background(), key).Result(); err != nil {
-					return errors.New("请求太过频繁，请稍后再试")
-				} else {
-					return errors.New("请求太过频繁, 请 " + t.String() + " 秒后尝试")
-				}
-			} else {
-				return global.GVA_REDIS.Incr(context.Background(), key).Err()
-			}
-		}
+	if !result.Allowed {
+		return errors.New("请求太过频繁, 请 " + strconv.Itoa(ceilSeconds(result.RetryAfter)) + " 秒后尝试")
 	}
+	return nil
 }
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/ratelimit"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// memoryLimiter 未开启 redis 或 redis 出错时使用的进程内限流器
+var memoryLimiter = ratelimit.NewMemoryLimiter()
+
+// allowRequest 优先使用 redis 计数 redis 出错时退回进程内计数
+func allowRequest(ctx context.Context, key string, rule ratelimit.Rule) ratelimit.Result {
+	if global.GVA_REDIS != nil {
+		result, err := ratelimit.NewRedisLimiter(global.GVA_REDIS).Allow(ctx, key, rule)
+		if err == nil {
+			return result
+		}
+		global.GVA_LOG.Error("redis limit error, fallback to memory:", zap.Error(err))
+	}
+	result, _ := memoryLimiter.Allow(ctx, key, rule)
+	return result
+}
+
+// RateLimit 按 rate-limit 配置的策略限流 挂在 JWTAuth 之后时可按用户、角色限流
+// 命中的策略全部通过才放行 响应头返回剩余额度最少的策略
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := global.GVA_CONFIG.RateLimit
+		if !conf.Enable {
+			c.Next()
+			return
+		}
+		var claims *systemReq.CustomClaims
+		if v, ok := c.Get("claims"); ok {
+			claims, _ = v.(*systemReq.CustomClaims)
+		}
+		route := strings.TrimPrefix(c.Request.URL.Path, global.GVA_CONFIG.System.RouterPrefix)
+		var header *ratelimit.Result
+		for i, policy := range conf.Policies {
+			if !matchRatePolicy(policy, c.Request.Method, route, claims) {
+				continue
+			}
+			subject := rateLimitSubject(c, policy.By, claims)
+			if subject == "" {
+				continue
+			}
+			name := policy.Name
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+			rule := ratelimit.Rule{
+				Algorithm: policy.Algorithm,
+				Limit:     policy.Limit,
+				Window:    time.Duration(policy.Window) * time.Second,
+				Burst:     policy.Burst,
+			}
+			if rule.Algorithm == "" {
+				rule.Algorithm = conf.Algorithm
+			}
+			result := allowRequest(c.Request.Context(), "GVA_RateLimit:"+name+":"+subject, rule)
+			if !result.Allowed {
+				setRateLimitHeader(c, result)
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, response.Response{
+					Code: response.ERROR,
+					Data: gin.H{},
+					Msg:  "请求太过频繁, 请 " + strconv.Itoa(ceilSeconds(result.RetryAfter)) + " 秒后尝试",
+				})
+				return
+			}
+			if result.Limit > 0 && (header == nil || result.Remaining < header.Remaining) {
+				header = &result
+			}
+		}
+		if header != nil {
+			setRateLimitHeader(c, *header)
+		}
+		c.Next()
+	}
+}
+
+func matchRatePolicy(policy config.RateLimitPolicy, method, route string, claims *systemReq.CustomClaims) bool {
+	if len(policy.Methods) > 0 {
+		matched := false
+		for _, m := range policy.Methods {
+			if strings.EqualFold(m, method) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if policy.Path != "" {
+		if strings.HasSuffix(policy.Path, "*") {
+			if !strings.HasPrefix(route, strings.TrimSuffix(policy.Path, "*")) {
+				return false
+			}
+		} else if ok, _ := path.Match(policy.Path, route); !ok {
+			return false
+		}
+	}
+	if len(policy.Authorities) > 0 && (claims == nil || !slices.Contains(policy.Authorities, claims.AuthorityId)) {
+		return false
+	}
+	if len(policy.Users) > 0 && (claims == nil || !slices.Contains(policy.Users, claims.BaseClaims.ID)) {
+		return false
+	}
+	return true
+}
+
+// rateLimitSubject 计数维度的取值 未登录时无法按用户、角色、令牌计数 返回空字符串跳过该策略
+func rateLimitSubject(c *gin.Context, by string, claims *systemReq.CustomClaims) string {
+	switch by {
+	case "user":
+		if claims != nil {
+			return "user:" + strconv.FormatUint(uint64(claims.BaseClaims.ID), 10)
+		}
+	case "authority":
+		if claims != nil {
+			return "authority:" + strconv.FormatUint(uint64(claims.AuthorityId), 10)
+		}
+	case "token":
+		token := c.Request.Header.Get("x-token")
+		if token == "" {
+			token, _ = c.Cookie("x-token")
+		}
+		if token != "" {
+			sum := sha256.Sum256([]byte(token))
+			return "token:" + hex.EncodeToString(sum[:8])
+		}
+	default:
+		return "ip:" + c.ClientIP()
+	}
+	return ""
+}
+
+func setRateLimitHeader(c *gin.Context, result ratelimit.Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
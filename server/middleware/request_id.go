@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// RequestID 为每个请求生成请求ID 优先使用网关传入的 X-Request-Id 并写入响应头及请求的 context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
+		c.Header(requestIDHeader, requestID)
+		info := utils.GetRequestInfo(c.Request.Context())
+		info.RequestID = requestID
+		c.Request = c.Request.WithContext(utils.WithRequestInfo(c.Request.Context(), info))
+		c.Next()
+	}
+}
@@ -1,7 +1,6 @@
 package common
 
-type ClearDB struct {
-	TableName    string
-	CompareField string
-	Interval     string
-}
+import "github.com/flipped-aurora/gin-vue-admin/server/config"
+
+// ClearDB 单表的清理策略 即 config.RetentionPolicy 保留原名兼容已有代码
+type ClearDB = config.RetentionPolicy
@@ -0,0 +1,31 @@
+package request
+
+import (
+	"net/url"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+type SysAsyncTaskSearch struct {
+	Type   string `json:"type" form:"type"`
+	Status string `json:"status" form:"status"`
+	request.PageInfo
+}
+
+// ExportExcelTask 后台导出任务的参数
+type ExportExcelTask struct {
+	TemplateID string     `json:"templateID"`
+	Query      url.Values `json:"query"`
+}
+
+// ImportExcelTask 后台导入任务的参数 File 为上传后保存在服务端的文件
+type ImportExcelTask struct {
+	TemplateID string `json:"templateID"`
+	File       string `json:"file"`
+	DryRun     bool   `json:"dryRun"`
+}
+
+// InstallPluginTask 后台安装插件任务的参数 File 为上传后保存在服务端的插件压缩包
+type InstallPluginTask struct {
+	File string `json:"file"`
+}
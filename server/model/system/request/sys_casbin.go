@@ -21,6 +21,10 @@ func DefaultCasbin() []CasbinInfo {
 		{Path: "/user/setUserAuthority", Method: "POST"},
 		{Path: "/user/getUserInfo", Method: "GET"},
 		{Path: "/user/setSelfInfo", Method: "PUT"},
+		{Path: "/user/sendEmailVerification", Method: "POST"},
+		{Path: "/user/verifyEmail", Method: "POST"},
+		{Path: "/user/sendPhoneVerification", Method: "POST"},
+		{Path: "/user/verifyPhone", Method: "POST"},
 		{Path: "/fileUploadAndDownload/upload", Method: "POST"},
 		{Path: "/sysDictionary/findSysDictionary", Method: "GET"},
 	}
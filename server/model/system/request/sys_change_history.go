@@ -0,0 +1,10 @@
+package request
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+
+// ChangeHistorySearch 查询一条记录的变更历史
+type ChangeHistorySearch struct {
+	Table    string `json:"table" form:"table"`       // 表名
+	RecordID string `json:"recordId" form:"recordId"` // 记录主键
+	request.PageInfo
+}
@@ -0,0 +1,18 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+type SysExportSubscriptionSearch struct {
+	Name       string `json:"name" form:"name"`
+	TemplateID string `json:"templateID" form:"templateID"`
+	Enabled    *bool  `json:"enabled" form:"enabled"`
+	request.PageInfo
+}
+
+type SysExportSubscriptionRunSearch struct {
+	SubscriptionID uint   `json:"subscriptionId" form:"subscriptionId"`
+	Status         string `json:"status" form:"status"`
+	request.PageInfo
+}
@@ -12,3 +12,9 @@ type SysExportTemplateSearch struct {
 	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
 	request.PageInfo
 }
+
+type SysExportWorkbookSearch struct {
+	Name       string `json:"name" form:"name"`
+	WorkbookID string `json:"workbookID" form:"workbookID"`
+	request.PageInfo
+}
@@ -0,0 +1,24 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+type SysJobSearch struct {
+	Name    string `json:"name" form:"name"`
+	Handler string `json:"handler" form:"handler"`
+	Enabled *bool  `json:"enabled" form:"enabled"`
+	request.PageInfo
+}
+
+type SysJobRunSearch struct {
+	JobID  uint   `json:"jobId" form:"jobId"`
+	Status string `json:"status" form:"status"`
+	request.PageInfo
+}
+
+// SetJobEnabled 启用或停用定时任务
+type SetJobEnabled struct {
+	ID      uint `json:"ID" binding:"required"`
+	Enabled bool `json:"enabled"`
+}
@@ -1,6 +1,8 @@
 package request
 
 import (
+	"time"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 )
@@ -9,3 +11,20 @@ type SysOperationRecordSearch struct {
 	system.SysOperationRecord
 	request.PageInfo
 }
+
+// SealOperationRecordArchive 封存归档 归档并删除创建时间早于 Before 的操作记录
+type SealOperationRecordArchive struct {
+	Before time.Time `json:"before"`
+}
+
+// OperationRecordStatsSearch 操作记录统计条件 未指定时间范围时统计最近24小时
+type OperationRecordStatsSearch struct {
+	StartTime   *time.Time `json:"startTime" form:"startTime"`
+	EndTime     *time.Time `json:"endTime" form:"endTime"`
+	AuthorityID uint       `json:"authorityId" form:"authorityId"` // 按用户的主角色过滤
+	Method      string     `json:"method" form:"method"`
+	Path        string     `json:"path" form:"path"`
+	Interval    string     `json:"interval" form:"interval"` // 时间粒度 hour|day
+	GroupBy     string     `json:"groupBy" form:"groupBy"`   // 趋势的分组 method|path
+	Limit       int        `json:"limit" form:"limit"`       // 返回的条数 趋势中为分组数
+}
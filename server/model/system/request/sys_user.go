@@ -67,3 +67,43 @@ type GetUserList struct {
 	Phone    string `json:"phone" form:"phone"`
 	Email    string `json:"email" form:"email"`
 }
+
+// ForgotPassword 找回密码
+type ForgotPassword struct {
+	Username string `json:"username"` // 用户名
+}
+
+// ResetPasswordByToken 通过找回密码链接中的令牌重置密码
+type ResetPasswordByToken struct {
+	Token       string `json:"token"`       // 找回密码令牌
+	NewPassword string `json:"newPassword"` // 新密码
+}
+
+// VerifyCode 邮箱/手机验证码
+type VerifyCode struct {
+	Code string `json:"code"` // 验证码
+}
+
+// SelfRegister 自助注册申请
+type SelfRegister struct {
+	Username  string `json:"username"`  // 用户名
+	Password  string `json:"password"`  // 密码
+	NickName  string `json:"nickName"`  // 昵称
+	Phone     string `json:"phone"`     // 手机号
+	Email     string `json:"email"`     // 邮箱
+	Remark    string `json:"remark"`    // 申请说明
+	Captcha   string `json:"captcha"`   // 验证码
+	CaptchaId string `json:"captchaId"` // 验证码ID
+}
+
+// ReviewRegistration 审核注册申请
+type ReviewRegistration struct {
+	ID     uint   `json:"ID"`     // 申请ID
+	Reason string `json:"reason"` // 审核意见
+}
+
+type RegistrationSearch struct {
+	common.PageInfo
+	Status   string `json:"status" form:"status"`
+	Username string `json:"username" form:"username"`
+}
@@ -0,0 +1,22 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+type SysUserGroupSearch struct {
+	Name string `json:"name" form:"name"`
+	request.PageInfo
+}
+
+// SetUserGroupUsers 设置用户组成员
+type SetUserGroupUsers struct {
+	ID      uint   `json:"ID"`      // 用户组ID
+	UserIds []uint `json:"userIds"` // 用户ID
+}
+
+// SetUserGroupAuthorities 设置用户组角色
+type SetUserGroupAuthorities struct {
+	ID           uint   `json:"ID"`           // 用户组ID
+	AuthorityIds []uint `json:"authorityIds"` // 角色ID
+}
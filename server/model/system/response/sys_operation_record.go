@@ -0,0 +1,52 @@
+package response
+
+import "time"
+
+// OperationRecordChainResult 操作记录哈希链校验结果
+type OperationRecordChainResult struct {
+	Valid       bool   `json:"valid"`              // 是否完整
+	Checked     int64  `json:"checked"`            // 校验的记录数
+	AnchorID    uint   `json:"anchorId"`           // 校验起点 最后一次归档的链尾记录ID
+	LastID      uint   `json:"lastId"`             // 链尾记录ID
+	LastHash    string `json:"lastHash"`           // 链尾记录哈希
+	Checkpoints int    `json:"checkpoints"`        // 校验的检查点数
+	BrokenID    uint   `json:"brokenId,omitempty"` // 首个异常的记录或检查点对应的记录ID
+	Reason      string `json:"reason,omitempty"`   // 异常原因
+}
+
+// OperationRecordTimelinePoint 请求趋势中的一个点
+type OperationRecordTimelinePoint struct {
+	Time   time.Time `json:"time"`   // 时间桶的开始时间
+	Key    string    `json:"key"`    // 请求方法或路径
+	Count  int64     `json:"count"`  // 请求数
+	Errors int64     `json:"errors"` // 状态码不小于400的请求数
+}
+
+// OperationRecordEndpointStat 接口的请求数、错误率与延迟 延迟单位 ms 分位数由延迟直方图估算
+type OperationRecordEndpointStat struct {
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Count      int64   `json:"count"`
+	Errors     int64   `json:"errors"`
+	ErrorRate  float64 `json:"errorRate"`
+	AvgLatency float64 `json:"avgLatency"`
+	P50        float64 `json:"p50"`
+	P95        float64 `json:"p95"`
+	P99        float64 `json:"p99"`
+	MaxLatency float64 `json:"maxLatency"`
+}
+
+// OperationRecordUserStat 用户的请求数
+type OperationRecordUserStat struct {
+	UserID   int    `json:"userId"`
+	Username string `json:"userName"`
+	NickName string `json:"nickName"`
+	Count    int64  `json:"count"`
+	Errors   int64  `json:"errors"`
+}
+
+// OperationRecordStatusStat 状态码分布
+type OperationRecordStatusStat struct {
+	Status int   `json:"status"`
+	Count  int64 `json:"count"`
+}
@@ -1,6 +1,8 @@
 package response
 
 import (
+	"time"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 )
 
@@ -13,3 +15,42 @@ type LoginResponse struct {
 	Token     string         `json:"token"`
 	ExpiresAt int64          `json:"expiresAt"`
 }
+
+// UserImportRowError 导入失败的行 Row 为文件中的行号
+type UserImportRowError struct {
+	Row      int      `json:"row"`
+	Username string   `json:"username"`
+	Errors   []string `json:"errors"`
+	Cells    []string `json:"-"`
+}
+
+// UserImportResult 用户导入结果 存在错误时不会写入任何数据 ReportToken 用于下载错误报告
+type UserImportResult struct {
+	Total       int                  `json:"total"`
+	Created     int                  `json:"created"`
+	Updated     int                  `json:"updated"`
+	Errors      []UserImportRowError `json:"errors"`
+	ReportToken string               `json:"reportToken,omitempty"`
+	Titles      []string             `json:"-"`
+}
+
+// UserAnonymizeResult 匿名化结果 RetainedRecords 或 UnscrubbedSinks 不为空时仍有个人数据未能清除
+type UserAnonymizeResult struct {
+	ScrubbedRecords int64    `json:"scrubbedRecords"` // 已清除个人数据的操作记录数
+	RetainedRecords int64    `json:"retainedRecords"` // 只追加模式下在哈希链上 无法修改的操作记录数
+	UnscrubbedSinks []string `json:"unscrubbedSinks"` // 写出后无法修改的操作记录存储 如 file、http
+}
+
+// Complete 是否已清除全部个人数据
+func (r UserAnonymizeResult) Complete() bool {
+	return r.RetainedRecords == 0 && len(r.UnscrubbedSinks) == 0
+}
+
+// UserPersonalData 用户个人数据导出 包含系统中保存的与该用户相关的全部数据
+type UserPersonalData struct {
+	User             system.SysUser               `json:"user"`
+	Registrations    []system.SysUserRegistration `json:"registrations"`
+	Tokens           []system.SysUserToken        `json:"tokens"`
+	OperationRecords []system.SysOperationRecord  `json:"operationRecords"`
+	ExportedAt       time.Time                    `json:"exportedAt"`
+}
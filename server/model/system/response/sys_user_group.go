@@ -0,0 +1,12 @@
+package response
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+// SysUserAuthoritySource 用户角色来源 Direct 表示直接分配 Groups 为授予该角色的用户组
+type SysUserAuthoritySource struct {
+	Authority system.SysAuthority   `json:"authority"`
+	Direct    bool                  `json:"direct"`
+	Groups    []system.SysUserGroup `json:"groups"`
+}
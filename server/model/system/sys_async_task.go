@@ -0,0 +1,46 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+const (
+	AsyncTaskPending  = "pending"
+	AsyncTaskRunning  = "running"
+	AsyncTaskSuccess  = "success"
+	AsyncTaskFailed   = "failed"
+	AsyncTaskCanceled = "canceled"
+)
+
+// SysAsyncTask 后台任务 由工作协程从队列中取出执行 记录状态、进度与生成的文件
+type SysAsyncTask struct {
+	global.GVA_MODEL
+	Type            string     `json:"type" form:"type" gorm:"column:type;size:64;index;comment:任务类型"`
+	Payload         string     `json:"-" gorm:"column:payload;type:text;comment:任务参数"`
+	UserID          uint       `json:"userId" gorm:"column:user_id;index;comment:创建人"`
+	Status          string     `json:"status" form:"status" gorm:"column:status;size:16;index;comment:状态 pending|running|success|failed|canceled"`
+	Progress        int        `json:"progress" gorm:"column:progress;comment:进度百分比"`
+	Message         string     `json:"message" gorm:"column:message;size:255;comment:进度说明"`
+	Result          string     `json:"result" gorm:"column:result;type:text;comment:结果"`
+	Error           string     `json:"error" gorm:"column:error;type:text;comment:错误信息"`
+	ArtifactName    string     `json:"artifactName" gorm:"column:artifact_name;size:255;comment:生成文件名"`
+	ArtifactURL     string     `json:"-" gorm:"column:artifact_url;size:512;comment:生成文件在对象存储中的地址"`
+	ArtifactKey     string     `json:"-" gorm:"column:artifact_key;size:512;comment:生成文件在对象存储中的key"`
+	CancelRequested bool       `json:"cancelRequested" gorm:"column:cancel_requested;comment:是否已请求取消"`
+	PinNode         string     `json:"pinNode" gorm:"column:pin_node;size:128;comment:只能由该节点执行 为空时任意节点"`
+	Node            string     `json:"node" gorm:"column:node;size:128;comment:执行节点"`
+	StartedAt       *time.Time `json:"startedAt" gorm:"column:started_at;comment:开始时间"`
+	HeartbeatAt     *time.Time `json:"heartbeatAt" gorm:"column:heartbeat_at;comment:执行节点最后一次心跳"`
+	FinishedAt      *time.Time `json:"finishedAt" gorm:"column:finished_at;comment:结束时间"`
+}
+
+func (SysAsyncTask) TableName() string {
+	return "sys_async_tasks"
+}
+
+// Finished 任务是否已结束
+func (t SysAsyncTask) Finished() bool {
+	return t.Status == AsyncTaskSuccess || t.Status == AsyncTaskFailed || t.Status == AsyncTaskCanceled
+}
@@ -0,0 +1,34 @@
+package system
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	ChangeActionCreate = "create"
+	ChangeActionUpdate = "update"
+	ChangeActionDelete = "delete"
+)
+
+// SysChangeHistory 数据变更历史 由 gorm 回调在变更所在的事务中写入
+// Changes 为变更的字段 {"列名": {"old": 旧值, "new": 新值}} Data 为变更后的整行数据 删除时为删除前的数据
+// json 标签为 "-" 的字段(如密码)不保存内容 只标记是否变更
+type SysChangeHistory struct {
+	ID        uint           `json:"ID" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"createdAt" gorm:"comment:变更时间"`
+	Table     string         `json:"table" gorm:"column:table_name;size:64;index:idx_change_history_record;comment:表名"`
+	RecordID  string         `json:"recordId" gorm:"size:64;index:idx_change_history_record;comment:记录主键"`
+	Action    string         `json:"action" gorm:"size:16;comment:操作 create|update|delete"`
+	Changes   datatypes.JSON `json:"changes" gorm:"comment:变更的字段"`
+	Data      datatypes.JSON `json:"data" gorm:"comment:变更后的数据"`
+	UserID    uint           `json:"userId" gorm:"comment:操作人ID"`
+	Username  string         `json:"userName" gorm:"size:64;comment:操作人"`
+	RequestID string         `json:"requestId" gorm:"size:64;index;comment:请求ID"`
+	RevertOf  uint           `json:"revertOf" gorm:"comment:由回滚到该变更产生"`
+}
+
+func (SysChangeHistory) TableName() string {
+	return "sys_change_histories"
+}
@@ -0,0 +1,33 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysExportSubscription 导出订阅 按 Spec 定时以导出模板导出 通过邮件附件发送给 Recipients 或上传到 OSS
+// 每个订阅对应一个执行方法为 ExportSubscription 的定时任务 调度、执行记录、锁、超时与重试均由定时任务负责
+type SysExportSubscription struct {
+	global.GVA_MODEL
+	Name        string     `json:"name" form:"name" gorm:"column:name;size:64;comment:订阅名称" binding:"required"`
+	TemplateID  string     `json:"templateID" form:"templateID" gorm:"column:template_id;size:191;index;comment:导出模板标识" binding:"required"`
+	Params      string     `json:"params" form:"params" gorm:"column:params;type:text;comment:固定的导出参数 与导出接口的params相同 如 status=1&limit=1000"`
+	Format      string     `json:"format" form:"format" gorm:"column:format;size:16;comment:导出格式 xlsx|csv|ndjson 为空时为xlsx"`
+	Spec        string     `json:"spec" form:"spec" gorm:"column:spec;size:64;comment:cron表达式 支持秒" binding:"required"`
+	Enabled     bool       `json:"enabled" form:"enabled" gorm:"column:enabled;comment:是否启用"`
+	Recipients  string     `json:"recipients" form:"recipients" gorm:"column:recipients;type:text;comment:收件人 逗号分隔"`
+	Subject     string     `json:"subject" form:"subject" gorm:"column:subject;comment:邮件标题 为空时使用订阅名称"`
+	OssPath     string     `json:"ossPath" form:"ossPath" gorm:"column:oss_path;comment:上传到OSS的目录 为空时不上传"`
+	AlertTo     string     `json:"alertTo" form:"alertTo" gorm:"column:alert_to;comment:失败时通知的邮箱 逗号分隔 为空时按job.notify-email通知"`
+	CreatedBy   uint       `json:"createdBy" gorm:"column:created_by;comment:创建者"`
+	AuthorityID uint       `json:"authorityId" gorm:"column:authority_id;comment:创建者的角色 按该角色检查可导出的表"`
+	JobID       uint       `json:"jobId" gorm:"column:job_id;index;comment:对应的定时任务"`
+	LastRunAt   *time.Time `json:"lastRunAt" gorm:"-"` // 取自定时任务
+	LastStatus  string     `json:"lastStatus" gorm:"-"`
+	NextRunAt   *time.Time `json:"nextRunAt" gorm:"-"`
+}
+
+func (SysExportSubscription) TableName() string {
+	return "sys_export_subscriptions"
+}
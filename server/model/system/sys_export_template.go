@@ -2,29 +2,118 @@
 package system
 
 import (
+	"encoding/json"
+	"slices"
+	"strings"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 )
 
+const (
+	ExportFormatXlsx   = "xlsx"
+	ExportFormatCsv    = "csv"
+	ExportFormatNdjson = "ndjson"
+)
+
 // 导出模板 结构体  SysExportTemplate
 type SysExportTemplate struct {
 	global.GVA_MODEL
-	DBName       string         `json:"dbName" form:"dbName" gorm:"column:db_name;comment:数据库名称;"`               //数据库名称
-	Name         string         `json:"name" form:"name" gorm:"column:name;comment:模板名称;"`                       //模板名称
-	TableName    string         `json:"tableName" form:"tableName" gorm:"column:table_name;comment:表名称;"`        //表名称
-	TemplateID   string         `json:"templateID" form:"templateID" gorm:"column:template_id;comment:模板标识;"`    //模板标识
-	TemplateInfo string         `json:"templateInfo" form:"templateInfo" gorm:"column:template_info;type:text;"` //模板信息
-	Limit        *int           `json:"limit" form:"limit" gorm:"column:limit;comment:导出限制"`
-	Order        string         `json:"order" form:"order" gorm:"column:order;comment:排序"`
-	Conditions   []Condition    `json:"conditions" form:"conditions" gorm:"foreignKey:TemplateID;references:TemplateID;comment:条件"`
-	JoinTemplate []JoinTemplate `json:"joinTemplate" form:"joinTemplate" gorm:"foreignKey:TemplateID;references:TemplateID;comment:关联"`
+	DBName       string           `json:"dbName" form:"dbName" gorm:"column:db_name;comment:数据库名称;"`               //数据库名称
+	Name         string           `json:"name" form:"name" gorm:"column:name;comment:模板名称;"`                       //模板名称
+	TableName    string           `json:"tableName" form:"tableName" gorm:"column:table_name;comment:表名称;"`        //表名称
+	TemplateID   string           `json:"templateID" form:"templateID" gorm:"column:template_id;comment:模板标识;"`    //模板标识
+	TemplateInfo string           `json:"templateInfo" form:"templateInfo" gorm:"column:template_info;type:text;"` //模板信息
+	Limit        *int             `json:"limit" form:"limit" gorm:"column:limit;comment:导出限制"`
+	Order        string           `json:"order" form:"order" gorm:"column:order;comment:排序(已废弃 保存时转换为orderBy)"`
+	OrderBy      []ExportOrder    `json:"orderBy" form:"-" gorm:"column:order_by;type:text;serializer:json;comment:排序字段"`
+	Formats      string           `json:"formats" form:"formats" gorm:"column:formats;comment:允许的导出格式 xlsx,csv,ndjson 逗号分隔 为空时只允许xlsx"`
+	SheetStyle   ExportSheetStyle `json:"sheetStyle" form:"-" gorm:"column:sheet_style;type:text;serializer:json;comment:xlsx表头样式"`
+	ImportSheet  string           `json:"importSheet" form:"importSheet" gorm:"column:import_sheet;comment:导入的工作表 为空时读取第一个工作表"`
+	ImportKey    string           `json:"importKey" form:"importKey" gorm:"column:import_key;comment:导入时按此字段更新已有数据 为空时只新增"`
+	ImportRules  []ImportRule     `json:"importRules" form:"-" gorm:"column:import_rules;type:text;serializer:json;comment:导入校验规则"`
+	Conditions   []Condition      `json:"conditions" form:"conditions" gorm:"foreignKey:TemplateID;references:TemplateID;comment:条件"`
+	JoinTemplate []JoinTemplate   `json:"joinTemplate" form:"joinTemplate" gorm:"foreignKey:TemplateID;references:TemplateID;comment:关联"`
+}
+
+// AllowFormat 模板是否允许以 format 格式导出
+func (t SysExportTemplate) AllowFormat(format string) bool {
+	if strings.TrimSpace(t.Formats) == "" {
+		return format == ExportFormatXlsx
+	}
+	formats := strings.Split(t.Formats, ",")
+	for i := range formats {
+		formats[i] = strings.TrimSpace(formats[i])
+	}
+	return slices.Contains(formats, format)
+}
+
+// ExportColumn TemplateInfo 中一列的定义 TemplateInfo 的值为字符串时只有标题
+// 导出时按格式转换展示值 导入时反向转换为字段的值
+type ExportColumn struct {
+	Title  string   `json:"title"`
+	Dict   string   `json:"dict,omitempty"`   // 字典类型 导出字典标签
+	Date   string   `json:"date,omitempty"`   // 日期格式 如 2006-01-02 15:04:05 字段为时间或Unix时间戳(秒)
+	Number string   `json:"number,omitempty"` // Excel数字格式 如 #,##0.00 csv与ndjson按其中的小数位数输出
+	Bool   []string `json:"bool,omitempty"`   // 真、假的展示值 如 ["是","否"]
+	Lookup string   `json:"lookup,omitempty"` // 关联表的字段 如 sys_users.nick_name 导出该字段的值 本列须为与之关联的主表字段
+	Width  float64  `json:"width,omitempty"`  // xlsx列宽
+}
+
+// UnmarshalJSON 兼容只写标题的字符串
+func (c *ExportColumn) UnmarshalJSON(data []byte) error {
+	var title string
+	if err := json.Unmarshal(data, &title); err == nil {
+		*c = ExportColumn{Title: title}
+		return nil
+	}
+	type column ExportColumn
+	return json.Unmarshal(data, (*column)(c))
+}
+
+// ExportSheetStyle xlsx的表头样式
+type ExportSheetStyle struct {
+	FreezeHeader bool   `json:"freezeHeader"` // 冻结表头行
+	HeaderBold   bool   `json:"headerBold"`   // 表头加粗
+	HeaderFill   string `json:"headerFill"`   // 表头背景色 如 D9E1F2
+	HeaderColor  string `json:"headerColor"`  // 表头字体颜色
 }
 
+// ImportRule 导入时主表字段的校验与转换
+type ImportRule struct {
+	Column   string `json:"column"`   // 主表的字段名
+	Required bool   `json:"required"` // 不能为空
+	Unique   bool   `json:"unique"`   // 文件内及表中已有数据中不能重复
+	Dict     string `json:"dict"`     // 字典类型 导入时将字典标签转换为字典值
+}
+
+// ExportOrder 排序字段 Column 为 表名.字段名 或主表的字段名
+type ExportOrder struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+}
+
+const (
+	JoinTypeLeft  = "LEFT"
+	JoinTypeRight = "RIGHT"
+	JoinTypeInner = "INNER"
+)
+
+// JoinTemplate 关联表 按 OnColumns 中的字段对相等关联
+// JOINS 与 ON 为旧版的SQL片段 不再参与查询 保存或迁移时转换为 JoinType 与 OnColumns
 type JoinTemplate struct {
 	global.GVA_MODEL
-	TemplateID string `json:"templateID" form:"templateID" gorm:"column:template_id;comment:模板标识"`
-	JOINS      string `json:"joins" form:"joins" gorm:"column:joins;comment:关联"`
-	Table      string `json:"table" form:"table" gorm:"column:table;comment:关联表"`
-	ON         string `json:"on" form:"on" gorm:"column:on;comment:关联条件"`
+	TemplateID string   `json:"templateID" form:"templateID" gorm:"column:template_id;comment:模板标识"`
+	JoinType   string   `json:"joinType" form:"joinType" gorm:"column:join_type;size:16;comment:关联方式 LEFT|RIGHT|INNER"`
+	Table      string   `json:"table" form:"table" gorm:"column:table;comment:关联表"`
+	OnColumns  []JoinOn `json:"onColumns" form:"-" gorm:"column:on_columns;type:text;serializer:json;comment:关联字段"`
+	JOINS      string   `json:"joins" form:"joins" gorm:"column:joins;comment:关联(已废弃)"`
+	ON         string   `json:"on" form:"on" gorm:"column:on;comment:关联条件(已废弃)"`
+}
+
+// JoinOn 关联字段对 均为 表名.字段名 Left 为主表或之前关联的表 Right 为当前关联表
+type JoinOn struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
 }
 
 func (JoinTemplate) TableName() string {
@@ -39,6 +128,9 @@ type Condition struct {
 	Operator   string `json:"operator" form:"operator" gorm:"column:operator;comment:操作符"`
 }
 
+// ConditionOperators 条件允许的操作符 IN 的值以逗号分隔 BETWEEN 的值为逗号分隔的两个值
+var ConditionOperators = []string{"=", "<>", "!=", ">", ">=", "<", "<=", "LIKE", "IN", "NOT IN", "BETWEEN", "NOT BETWEEN"}
+
 func (Condition) TableName() string {
 	return "sys_export_template_condition"
 }
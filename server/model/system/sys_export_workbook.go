@@ -0,0 +1,50 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 合计、小计的计算方式
+const (
+	AggregateSum   = "sum"
+	AggregateCount = "count"
+	AggregateAvg   = "avg"
+)
+
+// 导出参数与导出时间块的位置
+const (
+	WorkbookInfoHeader = "header"
+	WorkbookInfoFooter = "footer"
+)
+
+// SysExportWorkbook 组合导出 将多个导出模板或按字段拆分的同一个模板导出为一个工作簿的多个工作表
+type SysExportWorkbook struct {
+	global.GVA_MODEL
+	Name       string          `json:"name" form:"name" gorm:"column:name;comment:工作簿名称 即导出的文件名" binding:"required"`
+	WorkbookID string          `json:"workbookID" form:"workbookID" gorm:"column:workbook_id;size:191;uniqueIndex;comment:组合导出标识" binding:"required"`
+	Sheets     []WorkbookSheet `json:"sheets" form:"-" gorm:"column:sheets;type:text;serializer:json;comment:工作表定义"`
+	Info       string          `json:"info" form:"info" gorm:"column:info;size:16;comment:导出参数与导出时间的位置 header|footer 为空时不输出"`
+}
+
+func (SysExportWorkbook) TableName() string {
+	return "sys_export_workbooks"
+}
+
+// WorkbookSheet 工作表定义 字段均为导出模板 TemplateInfo 中的字段
+type WorkbookSheet struct {
+	Name       string `json:"name"`       // 工作表名称 为空时为模板名称 拆分时为空则以拆分字段的值命名 否则为 名称-值
+	TemplateID string `json:"templateID"` // 导出模板标识
+	Params     string `json:"params"`     // 固定的导出参数 覆盖导出时传入的同名参数
+	SplitBy    string `json:"splitBy"`    // 按该字段的值拆分为多个工作表
+	GroupBy    string `json:"groupBy"`    // 按该字段分组 每组后输出小计行
+	// Aggregates 每个工作表末尾输出合计行 设置了 GroupBy 时每组后输出小计行
+	Aggregates []ExportAggregate `json:"aggregates"`
+	// Summary 拆分时汇总各工作表合计的工作表名称 为空时不输出
+	Summary string `json:"summary"`
+}
+
+// ExportAggregate 合计列 sum、avg 只计算数字 count 为非空值的个数
+type ExportAggregate struct {
+	Column string `json:"column"`
+	Func   string `json:"func"`
+}
@@ -0,0 +1,78 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+const (
+	JobRunRunning = "running"
+	JobRunSuccess = "success"
+	JobRunFailed  = "failed"
+
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+
+	JobPolicyAll    = "all"    // 每个节点都执行
+	JobPolicySingle = "single" // 同一次调度只由一个节点执行
+
+	JobOverlapAllow = "allow" // 上一次仍在执行时照常执行
+	JobOverlapSkip  = "skip"  // 上一次仍在执行时跳过本次
+	JobOverlapDelay = "delay" // 上一次仍在执行时等待其完成后执行
+)
+
+// SysJob 定时任务 按 Spec 周期执行 Handler 对应的执行方法
+type SysJob struct {
+	global.GVA_MODEL
+	Name        string     `json:"name" form:"name" gorm:"column:name;size:64;uniqueIndex;comment:任务名称" binding:"required"`
+	Handler     string     `json:"handler" form:"handler" gorm:"column:handler;size:64;comment:执行方法" binding:"required"`
+	Spec        string     `json:"spec" form:"spec" gorm:"column:spec;size:64;comment:cron表达式 支持秒" binding:"required"`
+	Params      string     `json:"params" form:"params" gorm:"column:params;type:text;comment:参数"`
+	Enabled     bool       `json:"enabled" form:"enabled" gorm:"column:enabled;comment:是否启用"`
+	Policy      string     `json:"policy" form:"policy" gorm:"column:policy;size:16;default:single;comment:执行策略 all:每个节点都执行 single:只由一个节点执行"`
+	Timeout     int        `json:"timeout" form:"timeout" gorm:"column:timeout;comment:超时时间(秒) 0为不限制"`
+	Retry       int        `json:"retry" form:"retry" gorm:"column:retry;comment:失败重试次数"`
+	Overlap     string     `json:"overlap" form:"overlap" gorm:"column:overlap;size:16;default:skip;comment:上一次仍在执行时的策略 allow|skip|delay"`
+	Description string     `json:"description" form:"description" gorm:"column:description;comment:说明"`
+	LastRunAt   *time.Time `json:"lastRunAt" gorm:"column:last_run_at;comment:最后执行时间"`
+	LastStatus  string     `json:"lastStatus" gorm:"column:last_status;size:16;comment:最后执行状态"`
+	NextRunAt   *time.Time `json:"nextRunAt" gorm:"-"`
+}
+
+func (SysJob) TableName() string {
+	return "sys_jobs"
+}
+
+// SysJobRun 定时任务执行记录
+type SysJobRun struct {
+	ID         uint       `json:"ID" gorm:"primarykey"`
+	CreatedAt  time.Time  `json:"CreatedAt"`
+	JobID      uint       `json:"jobId" gorm:"column:job_id;index;comment:任务ID"`
+	JobName    string     `json:"jobName" gorm:"column:job_name;size:64;comment:任务名称"`
+	Handler    string     `json:"handler" gorm:"column:handler;size:64;comment:执行方法"`
+	Trigger    string     `json:"trigger" gorm:"column:trigger_type;size:16;comment:触发方式 schedule|manual"`
+	Node       string     `json:"node" gorm:"column:node;size:128;comment:执行节点"`
+	Attempt    int        `json:"attempt" gorm:"column:attempt;default:1;comment:第几次尝试"`
+	Status     string     `json:"status" gorm:"column:status;size:16;index;comment:状态 running|success|failed"`
+	StartedAt  time.Time  `json:"startedAt" gorm:"column:started_at;comment:开始时间"`
+	FinishedAt *time.Time `json:"finishedAt" gorm:"column:finished_at;comment:结束时间"`
+	Duration   int64      `json:"duration" gorm:"column:duration;comment:耗时(毫秒)"`
+	Output     string     `json:"output" gorm:"column:output;type:text;comment:输出"`
+	Error      string     `json:"error" gorm:"column:error;type:text;comment:错误信息"`
+}
+
+func (SysJobRun) TableName() string {
+	return "sys_job_runs"
+}
+
+// SysJobLock 单节点执行任务的数据库锁 过期后可被其他节点获取
+type SysJobLock struct {
+	Name      string    `json:"name" gorm:"column:name;primarykey;size:128;comment:锁名称"`
+	Owner     string    `json:"owner" gorm:"column:owner;size:191;comment:持有者"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"column:expires_at;comment:过期时间"`
+}
+
+func (SysJobLock) TableName() string {
+	return "sys_job_locks"
+}
@@ -0,0 +1,14 @@
+package system
+
+import "time"
+
+// SysMigration 一次性数据迁移的执行记录
+type SysMigration struct {
+	ID        uint      `json:"ID" gorm:"primarykey"`
+	Name      string    `json:"name" gorm:"size:128;uniqueIndex;comment:迁移名称"`
+	CreatedAt time.Time `json:"createdAt" gorm:"comment:执行时间"`
+}
+
+func (SysMigration) TableName() string {
+	return "sys_migrations"
+}
@@ -20,5 +20,7 @@ type SysOperationRecord struct {
 	Body         string        `json:"body" form:"body" gorm:"type:text;column:body;comment:请求Body"`                 // 请求Body
 	Resp         string        `json:"resp" form:"resp" gorm:"type:text;column:resp;comment:响应Body"`                 // 响应Body
 	UserID       int           `json:"user_id" form:"user_id" gorm:"column:user_id;comment:用户id"`                    // 用户id
+	PrevHash     string        `json:"prev_hash" form:"-" gorm:"size:64;column:prev_hash;comment:上一条记录哈希"`           // 上一条记录哈希
+	Hash         string        `json:"hash" form:"-" gorm:"size:64;index;column:hash;comment:记录哈希"`                  // 记录哈希 只追加模式下写入
 	User         SysUser       `json:"user"`
 }
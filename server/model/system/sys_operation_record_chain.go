@@ -0,0 +1,46 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysOperationRecordCheckpoint 操作记录哈希链检查点 记录某一时刻链尾并签名 用于发现尾部记录被删除
+type SysOperationRecordCheckpoint struct {
+	global.GVA_MODEL
+	LastID    uint   `json:"lastId" gorm:"index;comment:链尾记录ID"`
+	LastHash  string `json:"lastHash" gorm:"size:64;comment:链尾记录哈希"`
+	File      string `json:"file" gorm:"comment:导出文件"`
+	Signature string `json:"signature" gorm:"size:64;comment:签名"`
+}
+
+func (SysOperationRecordCheckpoint) TableName() string {
+	return "sys_operation_record_checkpoints"
+}
+
+// SysOperationRecordArchive 操作记录封存归档 归档文件保存被清理的记录 链校验从最后一次归档的链尾开始
+type SysOperationRecordArchive struct {
+	global.GVA_MODEL
+	FirstID   uint   `json:"firstId" gorm:"comment:归档起始记录ID"`
+	LastID    uint   `json:"lastId" gorm:"index;comment:归档结束记录ID"`
+	LastHash  string `json:"lastHash" gorm:"size:64;comment:归档结束记录哈希"`
+	Count     int64  `json:"count" gorm:"comment:归档记录数"`
+	File      string `json:"file" gorm:"comment:归档文件"`
+	FileHash  string `json:"fileHash" gorm:"size:64;comment:归档文件SHA256"`
+	Signature string `json:"signature" gorm:"size:64;comment:签名"`
+}
+
+func (SysOperationRecordArchive) TableName() string {
+	return "sys_operation_record_archives"
+}
+
+// SysOperationRecordChainLock 只追加模式下追加记录前锁定的行 多实例追加时读取链尾与写入串行执行
+type SysOperationRecordChainLock struct {
+	ID       uint      `gorm:"primarykey"`
+	LockedAt time.Time `gorm:"comment:最后加锁时间"`
+}
+
+func (SysOperationRecordChainLock) TableName() string {
+	return "sys_operation_record_chain_locks"
+}
@@ -0,0 +1,54 @@
+package system
+
+import "time"
+
+// OperationRecordLatencyHistogram 延迟直方图 各桶数量不累计 上界依次为 10ms 50ms 100ms 250ms 500ms 1s 2.5s 5s 10s
+type OperationRecordLatencyHistogram struct {
+	Le10ms   int64 `json:"le10ms" gorm:"column:le_10ms"`
+	Le50ms   int64 `json:"le50ms" gorm:"column:le_50ms"`
+	Le100ms  int64 `json:"le100ms" gorm:"column:le_100ms"`
+	Le250ms  int64 `json:"le250ms" gorm:"column:le_250ms"`
+	Le500ms  int64 `json:"le500ms" gorm:"column:le_500ms"`
+	Le1s     int64 `json:"le1s" gorm:"column:le_1s"`
+	Le2500ms int64 `json:"le2500ms" gorm:"column:le_2500ms"`
+	Le5s     int64 `json:"le5s" gorm:"column:le_5s"`
+	Le10s    int64 `json:"le10s" gorm:"column:le_10s"`
+	Gt10s    int64 `json:"gt10s" gorm:"column:gt_10s"`
+}
+
+// Counts 按桶的顺序返回各桶数量
+func (h OperationRecordLatencyHistogram) Counts() []int64 {
+	return []int64{h.Le10ms, h.Le50ms, h.Le100ms, h.Le250ms, h.Le500ms, h.Le1s, h.Le2500ms, h.Le5s, h.Le10s, h.Gt10s}
+}
+
+// SysOperationRecordHourly 操作记录按小时汇总 按接口、状态码及用户角色分组
+type SysOperationRecordHourly struct {
+	ID           uint          `json:"ID" gorm:"primarykey"`
+	Hour         time.Time     `json:"hour" gorm:"index;comment:整点小时"`
+	Method       string        `json:"method" gorm:"size:16;comment:请求方法"`
+	Path         string        `json:"path" gorm:"comment:请求路径"`
+	Status       int           `json:"status" gorm:"comment:请求状态"`
+	AuthorityID  uint          `json:"authorityId" gorm:"comment:用户角色ID"`
+	RequestCount int64         `json:"requestCount" gorm:"comment:请求数"`
+	LatencySum   time.Duration `json:"latencySum" gorm:"comment:延迟合计"`
+	LatencyMax   time.Duration `json:"latencyMax" gorm:"comment:最大延迟"`
+	OperationRecordLatencyHistogram
+}
+
+func (SysOperationRecordHourly) TableName() string {
+	return "sys_operation_record_hourly"
+}
+
+// SysOperationRecordUserHourly 操作记录按小时汇总 按用户分组
+type SysOperationRecordUserHourly struct {
+	ID           uint      `json:"ID" gorm:"primarykey"`
+	Hour         time.Time `json:"hour" gorm:"index;comment:整点小时"`
+	UserID       int       `json:"userId" gorm:"comment:用户id"`
+	AuthorityID  uint      `json:"authorityId" gorm:"comment:用户角色ID"`
+	RequestCount int64     `json:"requestCount" gorm:"comment:请求数"`
+	ErrorCount   int64     `json:"errorCount" gorm:"comment:错误数"`
+}
+
+func (SysOperationRecordUserHourly) TableName() string {
+	return "sys_operation_record_user_hourly"
+}
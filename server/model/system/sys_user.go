@@ -27,9 +27,13 @@ type SysUser struct {
 	AuthorityId   uint           `json:"authorityId" gorm:"default:888;comment:用户角色ID"`                                                      // 用户角色ID
 	Authority     SysAuthority   `json:"authority" gorm:"foreignKey:AuthorityId;references:AuthorityId;comment:用户角色"`                        // 用户角色
 	Authorities   []SysAuthority `json:"authorities" gorm:"many2many:sys_user_authority;"`                                                   // 多用户角色
+	Groups        []SysUserGroup `json:"groups" gorm:"many2many:sys_user_group_users;"`                                                      // 所属用户组
 	Phone         string         `json:"phone"  gorm:"comment:用户手机号"`                                                                        // 用户手机号
 	Email         string         `json:"email"  gorm:"comment:用户邮箱"`                                                                         // 用户邮箱
-	Enable        int            `json:"enable" gorm:"default:1;comment:用户是否被冻结 1正常 2冻结"`                                                    //用户是否被冻结 1正常 2冻结
+	EmailVerified bool           `json:"emailVerified" gorm:"default:false;comment:邮箱是否已验证"`                                                 // 邮箱是否已验证
+	PhoneVerified bool           `json:"phoneVerified" gorm:"default:false;comment:手机号是否已验证"`                                                // 手机号是否已验证
+	Enable        int            `json:"enable" gorm:"default:1;comment:用户状态 1正常 2冻结 3待审核 4已拒绝"`                                             //用户状态 1正常 2冻结 3待审核 4已拒绝
+	Anonymized    bool           `json:"anonymized" gorm:"default:false;comment:是否已匿名化"`                                                     // 是否已匿名化
 	OriginSetting common.JSONMap `json:"originSetting" form:"originSetting" gorm:"type:text;default:null;column:origin_setting;comment:配置;"` //配置
 }
 
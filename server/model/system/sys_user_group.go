@@ -0,0 +1,38 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysUserGroup 用户组 组内成员共同获得组上分配的角色
+type SysUserGroup struct {
+	global.GVA_MODEL
+	Name        string         `json:"name" form:"name" gorm:"size:64;index;comment:用户组名称"`      // 用户组名称
+	Description string         `json:"description" form:"description" gorm:"comment:用户组描述"`      // 用户组描述
+	Users       []SysUser      `json:"users" gorm:"many2many:sys_user_group_users;"`             // 组成员
+	Authorities []SysAuthority `json:"authorities" gorm:"many2many:sys_user_group_authorities;"` // 组角色
+}
+
+func (SysUserGroup) TableName() string {
+	return "sys_user_groups"
+}
+
+// SysUserGroupUser 是 sysUserGroup 和 sysUser 的连接表
+type SysUserGroupUser struct {
+	SysUserGroupId uint `gorm:"column:sys_user_group_id"`
+	SysUserId      uint `gorm:"column:sys_user_id"`
+}
+
+func (s *SysUserGroupUser) TableName() string {
+	return "sys_user_group_users"
+}
+
+// SysUserGroupAuthority 是 sysUserGroup 和 sysAuthority 的连接表
+type SysUserGroupAuthority struct {
+	SysUserGroupId          uint `gorm:"column:sys_user_group_id"`
+	SysAuthorityAuthorityId uint `gorm:"column:sys_authority_authority_id"`
+}
+
+func (s *SysUserGroupAuthority) TableName() string {
+	return "sys_user_group_authorities"
+}
@@ -0,0 +1,36 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+const (
+	UserEnableNormal   = 1 // 正常
+	UserEnableFrozen   = 2 // 冻结
+	UserEnablePending  = 3 // 注册待审核
+	UserEnableRejected = 4 // 注册被拒绝
+)
+
+const (
+	RegistrationPending  = "pending"
+	RegistrationApproved = "approved"
+	RegistrationRejected = "rejected"
+)
+
+// SysUserRegistration 自助注册申请 记录审核结果与原因
+type SysUserRegistration struct {
+	global.GVA_MODEL
+	UserID     uint       `json:"userId" gorm:"index;comment:申请用户ID"`
+	User       SysUser    `json:"user" gorm:"foreignKey:UserID"`
+	Remark     string     `json:"remark" gorm:"comment:申请说明"`
+	Status     string     `json:"status" gorm:"size:16;index;comment:审核状态 pending/approved/rejected"`
+	Reason     string     `json:"reason" gorm:"comment:审核意见"`
+	ReviewerID uint       `json:"reviewerId" gorm:"comment:审核人ID"`
+	ReviewedAt *time.Time `json:"reviewedAt" gorm:"comment:审核时间"`
+}
+
+func (SysUserRegistration) TableName() string {
+	return "sys_user_registrations"
+}
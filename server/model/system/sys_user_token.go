@@ -0,0 +1,28 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+const (
+	UserTokenResetPassword = "reset_password" // 找回密码
+	UserTokenVerifyEmail   = "verify_email"   // 邮箱验证
+	UserTokenVerifyPhone   = "verify_phone"   // 手机验证
+)
+
+// SysUserToken 一次性令牌 只保存令牌的哈希值
+type SysUserToken struct {
+	global.GVA_MODEL
+	UserID    uint       `json:"userId" gorm:"index;comment:用户ID"`
+	Purpose   string     `json:"purpose" gorm:"size:32;index;comment:用途"`
+	TokenHash string     `json:"-" gorm:"size:64;index;comment:令牌哈希"`
+	Target    string     `json:"target" gorm:"comment:验证目标(邮箱/手机号)"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"comment:过期时间"`
+	UsedAt    *time.Time `json:"usedAt" gorm:"comment:使用时间"`
+}
+
+func (SysUserToken) TableName() string {
+	return "sys_user_tokens"
+}
@@ -47,13 +47,23 @@ func EmailTest(subject string, body string) error {
 	return send(to, subject, body)
 }
 
+//@function: EmailWithAttachments
+//@description: 发送带附件的邮件 附件名为文件名
+//@param: To string, subject string, body string, attachments ...string
+//@return: error
+
+func EmailWithAttachments(To, subject string, body string, attachments ...string) error {
+	to := strings.Split(To, ",")
+	return send(to, subject, body, attachments...)
+}
+
 //@author: [maplepie](https://github.com/maplepie)
 //@function: send
 //@description: Email发送方法
-//@param: subject string, body string
+//@param: subject string, body string, attachments ...string
 //@return: error
 
-func send(to []string, subject string, body string) error {
+func send(to []string, subject string, body string, attachments ...string) error {
 	from := global.GlobalConfig.From
 	nickname := global.GlobalConfig.Nickname
 	secret := global.GlobalConfig.Secret
@@ -77,6 +87,11 @@ func send(to []string, subject string, body string) error {
 	e.To = to
 	e.Subject = subject
 	e.HTML = []byte(body)
+	for _, attachment := range attachments {
+		if _, err := e.AttachFile(attachment); err != nil {
+			return err
+		}
+	}
 	var err error
 	hostAddr := fmt.Sprintf("%s:%d", host, port)
 	if isSSL {
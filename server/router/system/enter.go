@@ -10,6 +10,7 @@ type RouterGroup struct {
 	InitRouter
 	MenuRouter
 	UserRouter
+	UserGroupRouter
 	CasbinRouter
 	AutoCodeRouter
 	AuthorityRouter
@@ -20,6 +21,11 @@ type RouterGroup struct {
 	SysExportTemplateRouter
 	SysParamsRouter
 	SysVersionRouter
+	ChangeHistoryRouter
+	SysJobRouter
+	SysAsyncTaskRouter
+	SysExportSubscriptionRouter
+	SysExportWorkbookRouter
 }
 
 var (
@@ -43,4 +49,10 @@ var (
 	autoCodeTemplateApi = api.ApiGroupApp.SystemApiGroup.AutoCodeTemplateApi
 	exportTemplateApi   = api.ApiGroupApp.SystemApiGroup.SysExportTemplateApi
 	sysVersionApi       = api.ApiGroupApp.SystemApiGroup.SysVersionApi
+	userGroupApi        = api.ApiGroupApp.SystemApiGroup.UserGroupApi
+	changeHistoryApi    = api.ApiGroupApp.SystemApiGroup.ChangeHistoryApi
+	sysJobApi           = api.ApiGroupApp.SystemApiGroup.SysJobApi
+	sysAsyncTaskApi     = api.ApiGroupApp.SystemApiGroup.SysAsyncTaskApi
+	subscriptionApi     = api.ApiGroupApp.SystemApiGroup.SysExportSubscriptionApi
+	exportWorkbookApi   = api.ApiGroupApp.SystemApiGroup.SysExportWorkbookApi
 )
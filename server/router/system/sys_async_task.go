@@ -0,0 +1,23 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type SysAsyncTaskRouter struct{}
+
+// InitSysAsyncTaskRouter 初始化 后台任务 路由信息
+func (s *SysAsyncTaskRouter) InitSysAsyncTaskRouter(Router *gin.RouterGroup) {
+	sysAsyncTaskRouter := Router.Group("sysAsyncTask").Use(middleware.OperationRecord())
+	sysAsyncTaskRouterWithoutRecord := Router.Group("sysAsyncTask")
+	{
+		sysAsyncTaskRouter.POST("cancelSysAsyncTask", sysAsyncTaskApi.CancelSysAsyncTask) // 取消后台任务
+	}
+	{
+		sysAsyncTaskRouterWithoutRecord.GET("findSysAsyncTask", sysAsyncTaskApi.FindSysAsyncTask)                         // 获取后台任务状态与进度
+		sysAsyncTaskRouterWithoutRecord.GET("getSysAsyncTaskList", sysAsyncTaskApi.GetSysAsyncTaskList)                   // 获取当前用户的后台任务列表
+		sysAsyncTaskRouterWithoutRecord.GET("streamSysAsyncTask", sysAsyncTaskApi.StreamSysAsyncTask)                     // 以SSE推送后台任务进度
+		sysAsyncTaskRouterWithoutRecord.GET("downloadSysAsyncTaskArtifact", sysAsyncTaskApi.DownloadSysAsyncTaskArtifact) // 下载后台任务生成的文件
+	}
+}
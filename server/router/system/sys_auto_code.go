@@ -1,6 +1,7 @@
 package system
 
 import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,9 +16,10 @@ func (s *AutoCodeRouter) InitAutoCodeRouter(Router *gin.RouterGroup, RouterPubli
 		autoCodeRouter.GET("getColumn", autoCodeApi.GetColumn) // 获取指定表所有字段信息
 	}
 	{
-		autoCodeRouter.POST("preview", autoCodeTemplateApi.Preview)   // 获取自动创建代码预览
-		autoCodeRouter.POST("createTemp", autoCodeTemplateApi.Create) // 创建自动化代码
-		autoCodeRouter.POST("addFunc", autoCodeTemplateApi.AddFunc)   // 为代码插入方法
+		autoCodeRouter.POST("preview", autoCodeTemplateApi.Preview)                                            // 获取自动创建代码预览
+		autoCodeRouter.POST("createTemp", middleware.ConcurrencyLimit("autocode"), autoCodeTemplateApi.Create) // 创建自动化代码
+		autoCodeRouter.POST("createTempAsync", autoCodeTemplateApi.CreateAsync)                                // 创建后台代码生成任务
+		autoCodeRouter.POST("addFunc", middleware.ConcurrencyLimit("autocode"), autoCodeTemplateApi.AddFunc)   // 为代码插入方法
 	}
 	{
 		autoCodeRouter.POST("mcp", autoCodeTemplateApi.MCP)         // 自动创建Mcp Tool模板
@@ -33,8 +35,9 @@ func (s *AutoCodeRouter) InitAutoCodeRouter(Router *gin.RouterGroup, RouterPubli
 		autoCodeRouter.GET("getTemplates", autoCodePackageApi.Templates) // 创建package包
 	}
 	{
-		autoCodeRouter.POST("pubPlug", autoCodePluginApi.Packaged)      // 打包插件
-		autoCodeRouter.POST("installPlugin", autoCodePluginApi.Install) // 自动安装插件
+		autoCodeRouter.POST("pubPlug", middleware.ConcurrencyLimit("plugin"), autoCodePluginApi.Packaged)      // 打包插件
+		autoCodeRouter.POST("installPlugin", middleware.ConcurrencyLimit("plugin"), autoCodePluginApi.Install) // 自动安装插件
+		autoCodeRouter.POST("installPluginAsync", autoCodePluginApi.InstallAsync)                              // 创建后台安装插件任务
 
 	}
 	{
@@ -11,6 +11,9 @@ func (s *BaseRouter) InitBaseRouter(Router *gin.RouterGroup) (R gin.IRoutes) {
 	{
 		baseRouter.POST("login", baseApi.Login)
 		baseRouter.POST("captcha", baseApi.Captcha)
+		baseRouter.POST("forgotPassword", baseApi.ForgotPassword)             // 找回密码
+		baseRouter.POST("resetPasswordByToken", baseApi.ResetPasswordByToken) // 通过找回密码链接重置密码
+		baseRouter.POST("register", baseApi.SelfRegister)                     // 自助注册
 	}
 	return baseRouter
 }
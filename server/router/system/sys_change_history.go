@@ -0,0 +1,20 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ChangeHistoryRouter struct{}
+
+// InitChangeHistoryRouter 初始化 变更历史 路由信息
+func (s *ChangeHistoryRouter) InitChangeHistoryRouter(Router *gin.RouterGroup) {
+	changeHistoryRouter := Router.Group("changeHistory").Use(middleware.OperationRecord())
+	changeHistoryRouterWithoutRecord := Router.Group("changeHistory")
+	{
+		changeHistoryRouter.POST("revertChange", changeHistoryApi.RevertChange) // 恢复到某次变更后的版本
+	}
+	{
+		changeHistoryRouterWithoutRecord.GET("getChangeHistory", changeHistoryApi.GetChangeHistory) // 获取一条记录的变更历史
+	}
+}
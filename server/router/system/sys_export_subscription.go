@@ -0,0 +1,26 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type SysExportSubscriptionRouter struct{}
+
+// InitSysExportSubscriptionRouter 初始化 导出订阅 路由信息
+func (s *SysExportSubscriptionRouter) InitSysExportSubscriptionRouter(Router *gin.RouterGroup) {
+	subscriptionRouter := Router.Group("sysExportSubscription").Use(middleware.OperationRecord())
+	subscriptionRouterWithoutRecord := Router.Group("sysExportSubscription")
+	{
+		subscriptionRouter.POST("createSysExportSubscription", subscriptionApi.CreateSysExportSubscription)        // 新建导出订阅
+		subscriptionRouter.PUT("updateSysExportSubscription", subscriptionApi.UpdateSysExportSubscription)         // 更新导出订阅
+		subscriptionRouter.DELETE("deleteSysExportSubscription", subscriptionApi.DeleteSysExportSubscription)      // 删除导出订阅
+		subscriptionRouter.PUT("setSysExportSubscriptionEnabled", subscriptionApi.SetSysExportSubscriptionEnabled) // 启用或停用导出订阅
+		subscriptionRouter.POST("triggerSysExportSubscription", subscriptionApi.TriggerSysExportSubscription)      // 立即导出发送一次
+	}
+	{
+		subscriptionRouterWithoutRecord.GET("findSysExportSubscription", subscriptionApi.FindSysExportSubscription)             // 根据ID获取导出订阅
+		subscriptionRouterWithoutRecord.GET("getSysExportSubscriptionList", subscriptionApi.GetSysExportSubscriptionList)       // 获取导出订阅列表
+		subscriptionRouterWithoutRecord.GET("getSysExportSubscriptionRunList", subscriptionApi.GetSysExportSubscriptionRunList) // 获取导出订阅执行记录
+	}
+}
@@ -15,20 +15,23 @@ func (s *SysExportTemplateRouter) InitSysExportTemplateRouter(Router *gin.Router
 	sysExportTemplateRouterWithoutAuth := pubRouter.Group("sysExportTemplate")
 
 	{
-		sysExportTemplateRouter.POST("createSysExportTemplate", exportTemplateApi.CreateSysExportTemplate)             // 新建导出模板
-		sysExportTemplateRouter.DELETE("deleteSysExportTemplate", exportTemplateApi.DeleteSysExportTemplate)           // 删除导出模板
-		sysExportTemplateRouter.DELETE("deleteSysExportTemplateByIds", exportTemplateApi.DeleteSysExportTemplateByIds) // 批量删除导出模板
-		sysExportTemplateRouter.PUT("updateSysExportTemplate", exportTemplateApi.UpdateSysExportTemplate)              // 更新导出模板
-		sysExportTemplateRouter.POST("importExcel", exportTemplateApi.ImportExcel)                                     // 导入excel模板数据
+		sysExportTemplateRouter.POST("createSysExportTemplate", exportTemplateApi.CreateSysExportTemplate)                // 新建导出模板
+		sysExportTemplateRouter.DELETE("deleteSysExportTemplate", exportTemplateApi.DeleteSysExportTemplate)              // 删除导出模板
+		sysExportTemplateRouter.DELETE("deleteSysExportTemplateByIds", exportTemplateApi.DeleteSysExportTemplateByIds)    // 批量删除导出模板
+		sysExportTemplateRouter.PUT("updateSysExportTemplate", exportTemplateApi.UpdateSysExportTemplate)                 // 更新导出模板
+		sysExportTemplateRouter.POST("importExcel", middleware.ConcurrencyLimit("export"), exportTemplateApi.ImportExcel) // 导入excel模板数据
+		sysExportTemplateRouter.POST("importExcelAsync", exportTemplateApi.ImportExcelAsync)                              // 创建后台导入任务
+		sysExportTemplateRouter.POST("exportExcelAsync", exportTemplateApi.ExportExcelAsync)                              // 创建后台导出任务
 	}
 	{
 		sysExportTemplateRouterWithoutRecord.GET("findSysExportTemplate", exportTemplateApi.FindSysExportTemplate)       // 根据ID获取导出模板
 		sysExportTemplateRouterWithoutRecord.GET("getSysExportTemplateList", exportTemplateApi.GetSysExportTemplateList) // 获取导出模板列表
 		sysExportTemplateRouterWithoutRecord.GET("exportExcel", exportTemplateApi.ExportExcel)                           // 获取导出token
 		sysExportTemplateRouterWithoutRecord.GET("exportTemplate", exportTemplateApi.ExportTemplate)                     // 导出表格模板
+		sysExportTemplateRouterWithoutRecord.GET("downloadImportErrors", exportTemplateApi.DownloadImportErrors)         // 下载导入的错误工作簿
 	}
 	{
-		sysExportTemplateRouterWithoutAuth.GET("exportExcelByToken", exportTemplateApi.ExportExcelByToken)       // 通过token导出表格
-		sysExportTemplateRouterWithoutAuth.GET("exportTemplateByToken", exportTemplateApi.ExportTemplateByToken) // 通过token导出模板
+		sysExportTemplateRouterWithoutAuth.GET("exportExcelByToken", middleware.ConcurrencyLimitBy("export", exportTemplateApi.ExportTokenUserID), exportTemplateApi.ExportExcelByToken)       // 通过token导出表格
+		sysExportTemplateRouterWithoutAuth.GET("exportTemplateByToken", middleware.ConcurrencyLimitBy("export", exportTemplateApi.ExportTokenUserID), exportTemplateApi.ExportTemplateByToken) // 通过token导出模板
 	}
 }
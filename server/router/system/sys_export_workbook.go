@@ -0,0 +1,24 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type SysExportWorkbookRouter struct{}
+
+// InitSysExportWorkbookRouter 初始化 组合导出 路由信息
+func (s *SysExportWorkbookRouter) InitSysExportWorkbookRouter(Router *gin.RouterGroup) {
+	workbookRouter := Router.Group("sysExportWorkbook").Use(middleware.OperationRecord())
+	workbookRouterWithoutRecord := Router.Group("sysExportWorkbook")
+	{
+		workbookRouter.POST("createSysExportWorkbook", exportWorkbookApi.CreateSysExportWorkbook)   // 新建组合导出
+		workbookRouter.PUT("updateSysExportWorkbook", exportWorkbookApi.UpdateSysExportWorkbook)    // 更新组合导出
+		workbookRouter.DELETE("deleteSysExportWorkbook", exportWorkbookApi.DeleteSysExportWorkbook) // 删除组合导出
+	}
+	{
+		workbookRouterWithoutRecord.GET("findSysExportWorkbook", exportWorkbookApi.FindSysExportWorkbook)                                            // 根据ID获取组合导出
+		workbookRouterWithoutRecord.GET("getSysExportWorkbookList", exportWorkbookApi.GetSysExportWorkbookList)                                      // 获取组合导出列表
+		workbookRouterWithoutRecord.GET("exportSysExportWorkbook", middleware.ConcurrencyLimit("export"), exportWorkbookApi.ExportSysExportWorkbook) // 导出工作簿
+	}
+}
@@ -9,10 +9,17 @@ type OperationRecordRouter struct{}
 func (s *OperationRecordRouter) InitSysOperationRecordRouter(Router *gin.RouterGroup) {
 	operationRecordRouter := Router.Group("sysOperationRecord")
 	{
-		operationRecordRouter.DELETE("deleteSysOperationRecord", operationRecordApi.DeleteSysOperationRecord)           // 删除SysOperationRecord
-		operationRecordRouter.DELETE("deleteSysOperationRecordByIds", operationRecordApi.DeleteSysOperationRecordByIds) // 批量删除SysOperationRecord
-		operationRecordRouter.GET("findSysOperationRecord", operationRecordApi.FindSysOperationRecord)                  // 根据ID获取SysOperationRecord
-		operationRecordRouter.GET("getSysOperationRecordList", operationRecordApi.GetSysOperationRecordList)            // 获取SysOperationRecord列表
+		operationRecordRouter.DELETE("deleteSysOperationRecord", operationRecordApi.DeleteSysOperationRecord)             // 删除SysOperationRecord
+		operationRecordRouter.DELETE("deleteSysOperationRecordByIds", operationRecordApi.DeleteSysOperationRecordByIds)   // 批量删除SysOperationRecord
+		operationRecordRouter.GET("findSysOperationRecord", operationRecordApi.FindSysOperationRecord)                    // 根据ID获取SysOperationRecord
+		operationRecordRouter.GET("getSysOperationRecordList", operationRecordApi.GetSysOperationRecordList)              // 获取SysOperationRecord列表
+		operationRecordRouter.GET("verifyOperationRecordChain", operationRecordApi.VerifyOperationRecordChain)            // 校验操作记录哈希链
+		operationRecordRouter.POST("createOperationRecordCheckpoint", operationRecordApi.CreateOperationRecordCheckpoint) // 生成操作记录检查点
+		operationRecordRouter.POST("sealOperationRecordArchive", operationRecordApi.SealOperationRecordArchive)           // 封存归档操作记录
+		operationRecordRouter.GET("getOperationRecordTimeline", operationRecordApi.GetOperationRecordTimeline)            // 操作记录请求趋势
+		operationRecordRouter.GET("getOperationRecordEndpointStats", operationRecordApi.GetOperationRecordEndpointStats)  // 操作记录接口统计
+		operationRecordRouter.GET("getOperationRecordTopUsers", operationRecordApi.GetOperationRecordTopUsers)            // 操作记录活跃用户
+		operationRecordRouter.GET("getOperationRecordStatusStats", operationRecordApi.GetOperationRecordStatusStats)      // 操作记录状态码分布
 
 	}
 }
@@ -11,18 +11,33 @@ func (s *UserRouter) InitUserRouter(Router *gin.RouterGroup) {
 	userRouter := Router.Group("user").Use(middleware.OperationRecord())
 	userRouterWithoutRecord := Router.Group("user")
 	{
-		userRouter.POST("admin_register", baseApi.Register)               // 管理员注册账号
-		userRouter.POST("changePassword", baseApi.ChangePassword)         // 用户修改密码
-		userRouter.POST("setUserAuthority", baseApi.SetUserAuthority)     // 设置用户权限
-		userRouter.DELETE("deleteUser", baseApi.DeleteUser)               // 删除用户
-		userRouter.PUT("setUserInfo", baseApi.SetUserInfo)                // 设置用户信息
-		userRouter.PUT("setSelfInfo", baseApi.SetSelfInfo)                // 设置自身信息
-		userRouter.POST("setUserAuthorities", baseApi.SetUserAuthorities) // 设置用户权限组
-		userRouter.POST("resetPassword", baseApi.ResetPassword)           // 重置用户密码
-		userRouter.PUT("setSelfSetting", baseApi.SetSelfSetting)          // 用户界面配置
+		userRouter.POST("admin_register", baseApi.Register)                 // 管理员注册账号
+		userRouter.POST("changePassword", baseApi.ChangePassword)           // 用户修改密码
+		userRouter.POST("setUserAuthority", baseApi.SetUserAuthority)       // 设置用户权限
+		userRouter.DELETE("deleteUser", baseApi.DeleteUser)                 // 删除用户
+		userRouter.PUT("setUserInfo", baseApi.SetUserInfo)                  // 设置用户信息
+		userRouter.PUT("setSelfInfo", baseApi.SetSelfInfo)                  // 设置自身信息
+		userRouter.POST("setUserAuthorities", baseApi.SetUserAuthorities)   // 设置用户权限组
+		userRouter.POST("resetPassword", baseApi.ResetPassword)             // 重置用户密码
+		userRouter.PUT("setSelfSetting", baseApi.SetSelfSetting)            // 用户界面配置
+		userRouter.POST("importUsers", baseApi.ImportUsers)                 // 批量导入用户
+		userRouter.POST("verifyEmail", baseApi.VerifyEmail)                 // 验证邮箱
+		userRouter.POST("verifyPhone", baseApi.VerifyPhone)                 // 验证手机号
+		userRouter.POST("approveRegistration", baseApi.ApproveRegistration) // 通过注册申请
+		userRouter.POST("rejectRegistration", baseApi.RejectRegistration)   // 拒绝注册申请
+		userRouter.POST("restoreUser", baseApi.RestoreUser)                 // 恢复已删除的用户
+		userRouter.DELETE("purgeUser", baseApi.PurgeUser)                   // 彻底清除已删除的用户
+		userRouter.POST("anonymizeUser", baseApi.AnonymizeUser)             // 匿名化用户个人数据
 	}
 	{
-		userRouterWithoutRecord.POST("getUserList", baseApi.GetUserList) // 分页获取用户列表
-		userRouterWithoutRecord.GET("getUserInfo", baseApi.GetUserInfo)  // 获取自身信息
+		userRouterWithoutRecord.POST("getUserList", baseApi.GetUserList)                      // 分页获取用户列表
+		userRouterWithoutRecord.GET("getUserInfo", baseApi.GetUserInfo)                       // 获取自身信息
+		userRouterWithoutRecord.POST("exportUsers", baseApi.ExportUsers)                      // 导出用户
+		userRouterWithoutRecord.GET("downloadImportReport", baseApi.DownloadUserImportReport) // 下载导入错误报告
+		userRouterWithoutRecord.POST("sendEmailVerification", baseApi.SendEmailVerification)  // 发送邮箱验证码
+		userRouterWithoutRecord.POST("sendPhoneVerification", baseApi.SendPhoneVerification)  // 发送手机验证码
+		userRouterWithoutRecord.GET("getRegistrationList", baseApi.GetRegistrationList)       // 分页获取注册申请
+		userRouterWithoutRecord.POST("getDeletedUserList", baseApi.GetDeletedUserList)        // 分页获取已删除的用户
+		userRouterWithoutRecord.GET("exportUserData", baseApi.ExportUserData)                 // 导出用户个人数据
 	}
 }
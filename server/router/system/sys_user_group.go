@@ -0,0 +1,25 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type UserGroupRouter struct{}
+
+func (s *UserGroupRouter) InitUserGroupRouter(Router *gin.RouterGroup) {
+	userGroupRouter := Router.Group("userGroup").Use(middleware.OperationRecord())
+	userGroupRouterWithoutRecord := Router.Group("userGroup")
+	{
+		userGroupRouter.POST("createUserGroup", userGroupApi.CreateUserGroup)                 // 创建用户组
+		userGroupRouter.DELETE("deleteUserGroup", userGroupApi.DeleteUserGroup)               // 删除用户组
+		userGroupRouter.PUT("updateUserGroup", userGroupApi.UpdateUserGroup)                  // 更新用户组
+		userGroupRouter.POST("setUserGroupUsers", userGroupApi.SetUserGroupUsers)             // 设置用户组成员
+		userGroupRouter.POST("setUserGroupAuthorities", userGroupApi.SetUserGroupAuthorities) // 设置用户组角色
+	}
+	{
+		userGroupRouterWithoutRecord.GET("findUserGroup", userGroupApi.FindUserGroup)                     // 获取用户组详情
+		userGroupRouterWithoutRecord.GET("getUserGroupList", userGroupApi.GetUserGroupList)               // 分页获取用户组列表
+		userGroupRouterWithoutRecord.GET("getUserAuthoritySources", userGroupApi.GetUserAuthoritySources) // 获取用户角色来源
+	}
+}
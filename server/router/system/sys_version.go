@@ -16,6 +16,7 @@ func (s *SysVersionRouter) InitSysVersionRouter(Router *gin.RouterGroup) {
 		sysVersionRouter.DELETE("deleteSysVersionByIds", sysVersionApi.DeleteSysVersionByIds) // 批量删除版本管理
 		sysVersionRouter.POST("exportVersion", sysVersionApi.ExportVersion)                   // 导出版本数据
 		sysVersionRouter.POST("importVersion", sysVersionApi.ImportVersion)                   // 导入版本数据
+		sysVersionRouter.POST("importVersionAsync", sysVersionApi.ImportVersionAsync)         // 创建后台导入版本任务
 	}
 	{
 		sysVersionRouterWithoutRecord.GET("findSysVersion", sysVersionApi.FindSysVersion)           // 根据ID获取版本管理
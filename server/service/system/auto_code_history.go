@@ -81,7 +81,7 @@ func (s *autoCodeHistory) RollBack(ctx context.Context, info request.SysAutoHist
 		}
 	} // 清除API表
 	if info.DeleteMenu {
-		err = BaseMenuServiceApp.DeleteBaseMenu(int(history.MenuID))
+		err = BaseMenuServiceApp.DeleteBaseMenu(ctx, int(history.MenuID))
 		if err != nil {
 			return errors.Wrap(err, "删除菜单失败!")
 		}
@@ -21,28 +21,47 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 var AutoCodePlugin = new(autoCodePlugin)
 
 type autoCodePlugin struct{}
 
+// pluginInstallMu 安装时使用同一个临时目录 同时只能安装一个插件
+var pluginInstallMu sync.Mutex
+
 // Install 插件安装
 func (s *autoCodePlugin) Install(file *multipart.FileHeader) (web, server int, err error) {
-	const GVAPLUGPINATH = "./gva-plug-temp/"
-	defer os.RemoveAll(GVAPLUGPINATH)
-	_, err = os.Stat(GVAPLUGPINATH)
-	if os.IsNotExist(err) {
-		os.Mkdir(GVAPLUGPINATH, os.ModePerm)
+	src, err := file.Open()
+	if err != nil {
+		return -1, -1, err
 	}
+	defer src.Close()
+	return s.install(src, file.Filename)
+}
 
-	src, err := file.Open()
+// InstallFile 安装已保存在服务端的插件压缩包 用于后台任务
+func (s *autoCodePlugin) InstallFile(path string) (web, server int, err error) {
+	src, err := os.Open(path)
 	if err != nil {
 		return -1, -1, err
 	}
 	defer src.Close()
+	return s.install(src, filepath.Base(path))
+}
+
+func (s *autoCodePlugin) install(src io.Reader, filename string) (web, server int, err error) {
+	pluginInstallMu.Lock()
+	defer pluginInstallMu.Unlock()
+	const GVAPLUGPINATH = "./gva-plug-temp/"
+	defer os.RemoveAll(GVAPLUGPINATH)
+	_, err = os.Stat(GVAPLUGPINATH)
+	if os.IsNotExist(err) {
+		os.Mkdir(GVAPLUGPINATH, os.ModePerm)
+	}
 
-	out, err := os.Create(GVAPLUGPINATH + file.Filename)
+	out, err := os.Create(GVAPLUGPINATH + filename)
 	if err != nil {
 		return -1, -1, err
 	}
@@ -50,7 +69,7 @@ func (s *autoCodePlugin) Install(file *multipart.FileHeader) (web, server int, e
 
 	_, err = io.Copy(out, src)
 
-	paths, err := utils.Unzip(GVAPLUGPINATH+file.Filename, GVAPLUGPINATH)
+	paths, err := utils.Unzip(GVAPLUGPINATH+filename, GVAPLUGPINATH)
 	paths = filterFile(paths)
 	var webIndex = -1
 	var serverIndex = -1
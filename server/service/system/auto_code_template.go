@@ -164,7 +164,8 @@ func (s *autoCodeTemplate) Create(ctx context.Context, info request.AutoCode) er
 			TemplateID:   name,
 			TemplateInfo: string(templateInfo),
 		}
-		err = SysExportTemplateServiceApp.CreateSysExportTemplate(&sysExportTemplate)
+		// 生成代码时表尚未创建 无法按表结构校验 导出时再校验
+		err = global.GVA_DB.Create(&sysExportTemplate).Error
 		if err != nil {
 			return err
 		}
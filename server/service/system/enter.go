@@ -5,6 +5,7 @@ type ServiceGroup struct {
 	ApiService
 	MenuService
 	UserService
+	UserGroupService
 	CasbinService
 	InitDBService
 	AutoCodeService
@@ -18,6 +19,11 @@ type ServiceGroup struct {
 	SysExportTemplateService
 	SysParamsService
 	SysVersionService
+	ChangeHistoryService
+	JobService
+	AsyncTaskService
+	ExportSubscriptionService
+	ExportWorkbookService
 	AutoCodePlugin   autoCodePlugin
 	AutoCodePackage  autoCodePackage
 	AutoCodeHistory  autoCodeHistory
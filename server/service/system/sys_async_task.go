@@ -0,0 +1,496 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/upload"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AsyncTaskHandler 后台任务的执行方法 payload 为入队时参数的JSON
+type AsyncTaskHandler func(ctx context.Context, run *AsyncTaskRun, payload []byte) error
+
+var (
+	asyncTaskHandlers   = make(map[string]AsyncTaskHandler)
+	asyncTaskNodeTypes  = make(map[string]bool)
+	asyncTaskHandlersMu sync.RWMutex
+)
+
+// RegisterAsyncTask 注册后台任务的执行方法 入队时的参数解析为 T 后传入 同名注册会覆盖
+func RegisterAsyncTask[T any](taskType string, fn func(ctx context.Context, run *AsyncTaskRun, payload T) error) {
+	asyncTaskHandlersMu.Lock()
+	defer asyncTaskHandlersMu.Unlock()
+	asyncTaskHandlers[taskType] = func(ctx context.Context, run *AsyncTaskRun, payload []byte) error {
+		var p T
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return fmt.Errorf("解析任务参数失败: %w", err)
+		}
+		return fn(ctx, run, p)
+	}
+}
+
+// RegisterNodeAsyncTask 注册只能由入队节点执行的后台任务 用于读取本节点上传的文件或写入本节点源码的任务
+func RegisterNodeAsyncTask[T any](taskType string, fn func(ctx context.Context, run *AsyncTaskRun, payload T) error) {
+	RegisterAsyncTask(taskType, fn)
+	asyncTaskHandlersMu.Lock()
+	defer asyncTaskHandlersMu.Unlock()
+	asyncTaskNodeTypes[taskType] = true
+}
+
+func asyncTaskOnNode(taskType string) bool {
+	asyncTaskHandlersMu.RLock()
+	defer asyncTaskHandlersMu.RUnlock()
+	return asyncTaskNodeTypes[taskType]
+}
+
+func getAsyncTaskHandler(taskType string) (AsyncTaskHandler, bool) {
+	asyncTaskHandlersMu.RLock()
+	defer asyncTaskHandlersMu.RUnlock()
+	handler, ok := asyncTaskHandlers[taskType]
+	return handler, ok
+}
+
+// errAsyncTaskCanceled 用户取消任务时作为 context 的取消原因
+var errAsyncTaskCanceled = errors.New("任务已取消")
+
+var (
+	asyncTaskOnce    sync.Once
+	asyncTaskQ       asyncTaskQueue
+	asyncTaskCancels = make(map[uint]context.CancelCauseFunc)
+	asyncTaskMu      sync.Mutex
+)
+
+// AsyncTaskRun 执行中的后台任务 执行方法通过它上报进度、保存结果和生成文件
+type AsyncTaskRun struct {
+	Task system.SysAsyncTask
+
+	result       string
+	artifactName string
+	artifactPath string
+	artifactURL  string
+	artifactKey  string
+	reportedAt   time.Time
+}
+
+// Progress 上报进度 percent 为 0-100 写入数据库的频率限制为每 500ms 一次
+func (r *AsyncTaskRun) Progress(percent int, message string) {
+	percent = min(max(percent, 0), 100)
+	r.Task.Progress, r.Task.Message = percent, message
+	if time.Since(r.reportedAt) < 500*time.Millisecond && percent < 100 {
+		return
+	}
+	r.reportedAt = time.Now()
+	err := global.GVA_DB.Model(&system.SysAsyncTask{}).Where("id = ?", r.Task.ID).Updates(map[string]interface{}{
+		"progress": percent,
+		"message":  truncateAsyncTaskMessage(message),
+	}).Error
+	if err != nil {
+		global.GVA_LOG.Error("update async task progress error:", zap.Uint("id", r.Task.ID), zap.Error(err))
+	}
+}
+
+// SetResult 保存任务结果 字符串原样保存 其他类型保存为JSON
+func (r *AsyncTaskRun) SetResult(v interface{}) error {
+	if s, ok := v.(string); ok {
+		r.result = s
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.result = string(data)
+	return nil
+}
+
+// CreateArtifact 创建任务生成的文件 任务成功后上传到 system.oss-type 配置的存储 可通过下载接口获取
+// 同一任务只保留最后创建的文件
+func (r *AsyncTaskRun) CreateArtifact(name string) (*os.File, error) {
+	dir := filepath.Join(asyncTaskArtifactDir(), strconv.FormatUint(uint64(r.Task.ID), 10))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	name = filepath.Base(name)
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r.artifactName, r.artifactPath = name, path
+	return file, nil
+}
+
+// saveArtifact 将生成的文件上传到对象存储 使任意节点都能提供下载
+func (r *AsyncTaskRun) saveArtifact() (err error) {
+	r.artifactURL, r.artifactKey, err = upload.UploadLocalFileAs(upload.NewOss(), r.artifactPath, r.artifactName)
+	if err != nil {
+		return fmt.Errorf("保存生成的文件失败: %w", err)
+	}
+	return nil
+}
+
+func asyncTaskArtifactDir() string {
+	if dir := global.GVA_CONFIG.AsyncTask.ArtifactDir; dir != "" {
+		return dir
+	}
+	return "./uploads/tasks"
+}
+
+func truncateAsyncTaskMessage(s string) string {
+	if r := []rune(s); len(r) > 255 {
+		return string(r[:255])
+	}
+	return s
+}
+
+type AsyncTaskService struct{}
+
+var AsyncTaskServiceApp = new(AsyncTaskService)
+
+// StartAsyncTasks 启动工作协程 重复调用时只启动一次
+// 本节点上次退出时未结束的任务及心跳超时的任务标记为失败 使用 redis 队列时将待执行的任务重新入队
+func (asyncTaskService *AsyncTaskService) StartAsyncTasks() {
+	asyncTaskOnce.Do(func() {
+		queue := newAsyncTaskQueue()
+		if _, err := recoverAsyncTasks(true); err != nil {
+			global.GVA_LOG.Error("recover async tasks error:", zap.Error(err))
+		}
+		if _, ok := queue.(redisAsyncTaskQueue); ok {
+			var tasks []system.SysAsyncTask
+			global.GVA_DB.Select("id", "pin_node").Where("status = ?", system.AsyncTaskPending).Order("id").Find(&tasks)
+			for _, task := range tasks {
+				if err := queue.Push(context.Background(), task.ID, task.PinNode); err != nil {
+					global.GVA_LOG.Error("requeue async task error:", zap.Uint("id", task.ID), zap.Error(err))
+				}
+			}
+		}
+		workers := global.GVA_CONFIG.AsyncTask.Workers
+		if workers <= 0 {
+			workers = 4
+		}
+		for i := 0; i < workers; i++ {
+			go asyncTaskWorker(queue)
+		}
+		asyncTaskMu.Lock()
+		asyncTaskQ = queue
+		asyncTaskMu.Unlock()
+	})
+}
+
+// RecoverAsyncTasks 将心跳超时的执行中任务标记为失败 由定时任务调用 回收已退出节点上中断的任务
+func (asyncTaskService *AsyncTaskService) RecoverAsyncTasks() (int64, error) {
+	return recoverAsyncTasks(false)
+}
+
+func asyncTaskPollInterval() time.Duration {
+	interval := time.Duration(global.GVA_CONFIG.AsyncTask.PollInterval) * time.Millisecond
+	if interval <= 0 {
+		return time.Second
+	}
+	return interval
+}
+
+// asyncTaskRunnable 本节点可执行的任务 限定入队节点的任务只能由该节点执行
+func asyncTaskRunnable(db *gorm.DB) *gorm.DB {
+	return db.Where("pin_node IS NULL OR pin_node IN ?", []string{"", utils.NodeID()})
+}
+
+// asyncTaskStaleAfter 心跳超过该时间未更新的任务视为中断 至少为心跳间隔的 3 倍
+func asyncTaskStaleAfter() time.Duration {
+	stale := time.Duration(global.GVA_CONFIG.AsyncTask.StaleAfter) * time.Second
+	if stale <= 0 {
+		stale = time.Minute
+	}
+	return max(stale, 3*asyncTaskPollInterval())
+}
+
+// recoverAsyncTasks 将中断的任务标记为失败 未配置 job.node-id 时节点标识随进程变化 只能依靠心跳判断
+// startup 为 true 时本节点名下执行中的任务也视为中断
+func recoverAsyncTasks(startup bool) (int64, error) {
+	now := time.Now()
+	cutoff := now.Add(-asyncTaskStaleAfter())
+	db := global.GVA_DB.Model(&system.SysAsyncTask{}).Where("status = ?", system.AsyncTaskRunning)
+	stale := global.GVA_DB.Where("heartbeat_at < ?", cutoff).Or("heartbeat_at IS NULL AND started_at < ?", cutoff)
+	if startup {
+		db = db.Where(stale.Or("node = ?", utils.NodeID()))
+	} else {
+		db = db.Where(stale)
+	}
+	result := db.Updates(map[string]interface{}{"status": system.AsyncTaskFailed, "error": "执行节点已退出 任务中断", "finished_at": now})
+	return result.RowsAffected, result.Error
+}
+
+func asyncTaskWorker(queue asyncTaskQueue) {
+	for {
+		id, err := queue.Pop(context.Background())
+		if err != nil {
+			global.GVA_LOG.Error("pop async task error:", zap.Error(err))
+			time.Sleep(time.Second)
+			continue
+		}
+		if id != 0 {
+			runAsyncTask(id)
+		}
+	}
+}
+
+// runAsyncTask 抢占待执行的任务并执行 已被其他工作协程抢占或已取消的任务直接跳过
+func runAsyncTask(id uint) {
+	now := time.Now()
+	result := asyncTaskRunnable(global.GVA_DB.Model(&system.SysAsyncTask{})).Where("id = ? AND status = ?", id, system.AsyncTaskPending).
+		Updates(map[string]interface{}{"status": system.AsyncTaskRunning, "started_at": now, "heartbeat_at": now, "node": utils.NodeID()})
+	if result.Error != nil {
+		global.GVA_LOG.Error("claim async task error:", zap.Uint("id", id), zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	run := &AsyncTaskRun{}
+	if err := global.GVA_DB.Where("id = ?", id).First(&run.Task).Error; err != nil {
+		global.GVA_LOG.Error("load async task error:", zap.Uint("id", id), zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	asyncTaskMu.Lock()
+	asyncTaskCancels[id] = cancel
+	asyncTaskMu.Unlock()
+	stop := watchAsyncTask(id, cancel)
+
+	err := callAsyncTaskHandler(ctx, run)
+
+	stop()
+	asyncTaskMu.Lock()
+	delete(asyncTaskCancels, id)
+	asyncTaskMu.Unlock()
+	canceled := errors.Is(context.Cause(ctx), errAsyncTaskCanceled)
+	cancel(nil)
+
+	if err == nil && !canceled && run.artifactPath != "" {
+		err = run.saveArtifact()
+	}
+	if run.artifactPath != "" {
+		os.RemoveAll(filepath.Dir(run.artifactPath))
+	}
+
+	finishedAt := time.Now()
+	updates := map[string]interface{}{
+		"finished_at":   finishedAt,
+		"result":        run.result,
+		"artifact_name": run.artifactName,
+		"artifact_url":  run.artifactURL,
+		"artifact_key":  run.artifactKey,
+	}
+	switch {
+	case canceled:
+		updates["status"] = system.AsyncTaskCanceled
+		updates["error"] = errAsyncTaskCanceled.Error()
+	case err != nil:
+		updates["status"] = system.AsyncTaskFailed
+		updates["error"] = err.Error()
+	default:
+		updates["status"] = system.AsyncTaskSuccess
+		updates["progress"] = 100
+	}
+	if err = global.GVA_DB.Model(&system.SysAsyncTask{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		global.GVA_LOG.Error("save async task error:", zap.Uint("id", id), zap.Error(err))
+	}
+}
+
+func callAsyncTaskHandler(ctx context.Context, run *AsyncTaskRun) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			global.GVA_LOG.Error("async task panic", zap.String("type", run.Task.Type), zap.Any("panic", r), zap.Stack("stack"))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	handler, ok := getAsyncTaskHandler(run.Task.Type)
+	if !ok {
+		return fmt.Errorf("任务类型 %s 未注册", run.Task.Type)
+	}
+	return handler(ctx, run, []byte(run.Task.Payload))
+}
+
+// watchAsyncTask 定时更新心跳并检查取消请求 使其他节点发起的取消也能生效
+func watchAsyncTask(id uint, cancel context.CancelCauseFunc) (stop func()) {
+	interval := asyncTaskPollInterval()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := global.GVA_DB.Model(&system.SysAsyncTask{}).Where("id = ?", id).Update("heartbeat_at", time.Now()).Error
+				if err != nil {
+					global.GVA_LOG.Error("update async task heartbeat error:", zap.Uint("id", id), zap.Error(err))
+				}
+				var requested []bool
+				global.GVA_DB.Model(&system.SysAsyncTask{}).Where("id = ?", id).Pluck("cancel_requested", &requested)
+				if len(requested) > 0 && requested[0] {
+					cancel(errAsyncTaskCanceled)
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Enqueue 创建后台任务并入队 payload 保存为JSON 执行时按注册时的类型解析
+func (asyncTaskService *AsyncTaskService) Enqueue(userID uint, taskType string, payload interface{}) (task system.SysAsyncTask, err error) {
+	if _, ok := getAsyncTaskHandler(taskType); !ok {
+		return task, fmt.Errorf("任务类型 %s 未注册", taskType)
+	}
+	asyncTaskMu.Lock()
+	queue := asyncTaskQ
+	asyncTaskMu.Unlock()
+	if queue == nil {
+		return task, errors.New("后台任务未启动")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	task = system.SysAsyncTask{
+		Type:    taskType,
+		Payload: string(data),
+		UserID:  userID,
+		Status:  system.AsyncTaskPending,
+	}
+	if asyncTaskOnNode(taskType) {
+		task.PinNode = utils.NodeID()
+	}
+	if err = global.GVA_DB.Create(&task).Error; err != nil {
+		return
+	}
+	if err = queue.Push(context.Background(), task.ID, task.PinNode); err != nil {
+		global.GVA_DB.Model(&task).Updates(map[string]interface{}{"status": system.AsyncTaskFailed, "error": "入队失败: " + err.Error()})
+		return task, err
+	}
+	return task, nil
+}
+
+// SaveAsyncTaskUpload 保存上传的文件供后台任务读取 文件只保存在本节点 读取它的任务需使用 RegisterNodeAsyncTask 注册
+// 任务执行后应删除
+func (asyncTaskService *AsyncTaskService) SaveAsyncTaskUpload(file *multipart.FileHeader) (path string, err error) {
+	dir := filepath.Join(asyncTaskArtifactDir(), "uploads")
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	path = filepath.Join(dir, utils.RandomString(16)+filepath.Ext(file.Filename))
+	out, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(out, src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return
+}
+
+// CancelAsyncTask 取消任务 待执行的任务直接取消 执行中的任务通知执行方法停止
+func (asyncTaskService *AsyncTaskService) CancelAsyncTask(id uint, userID uint) error {
+	task, err := asyncTaskService.GetAsyncTask(id, userID)
+	if err != nil {
+		return err
+	}
+	if task.Finished() {
+		return errors.New("任务已结束")
+	}
+	result := global.GVA_DB.Model(&system.SysAsyncTask{}).Where("id = ? AND status = ?", id, system.AsyncTaskPending).
+		Updates(map[string]interface{}{"status": system.AsyncTaskCanceled, "cancel_requested": true, "error": errAsyncTaskCanceled.Error(), "finished_at": time.Now()})
+	if result.Error != nil || result.RowsAffected == 1 {
+		return result.Error
+	}
+	if err = global.GVA_DB.Model(&system.SysAsyncTask{}).Where("id = ?", id).Update("cancel_requested", true).Error; err != nil {
+		return err
+	}
+	asyncTaskMu.Lock()
+	cancel, ok := asyncTaskCancels[id]
+	asyncTaskMu.Unlock()
+	if ok {
+		cancel(errAsyncTaskCanceled)
+	}
+	return nil
+}
+
+// GetAsyncTask 获取当前用户的任务
+func (asyncTaskService *AsyncTaskService) GetAsyncTask(id uint, userID uint) (task system.SysAsyncTask, err error) {
+	err = global.GVA_DB.Where("id = ? AND user_id = ?", id, userID).First(&task).Error
+	return
+}
+
+// GetAsyncTaskList 分页获取当前用户的任务
+func (asyncTaskService *AsyncTaskService) GetAsyncTaskList(info systemReq.SysAsyncTaskSearch, userID uint) (list []system.SysAsyncTask, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysAsyncTask{}).Where("user_id = ?", userID)
+	if info.Type != "" {
+		db = db.Where("type = ?", info.Type)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Order("id desc").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
+
+// CleanupAsyncTasks 删除超过保留天数的已结束任务及其生成的文件
+func (asyncTaskService *AsyncTaskService) CleanupAsyncTasks() (count int, err error) {
+	keepDays := global.GVA_CONFIG.AsyncTask.KeepDays
+	if keepDays <= 0 {
+		return 0, nil
+	}
+	before := time.Now().AddDate(0, 0, -keepDays)
+	for {
+		var tasks []system.SysAsyncTask
+		err = global.GVA_DB.Unscoped().Where("finished_at < ?", before).Order("id").Limit(500).Find(&tasks).Error
+		if err != nil || len(tasks) == 0 {
+			return
+		}
+		ids := make([]uint, 0, len(tasks))
+		for _, task := range tasks {
+			if task.ArtifactKey != "" {
+				if err := upload.NewOss().DeleteFile(task.ArtifactKey); err != nil {
+					global.GVA_LOG.Error("remove async task artifact error:", zap.Uint("id", task.ID), zap.Error(err))
+				}
+			}
+			ids = append(ids, task.ID)
+		}
+		if err = global.GVA_DB.Unscoped().Delete(&system.SysAsyncTask{}, "id IN ?", ids).Error; err != nil {
+			return
+		}
+		count += len(ids)
+	}
+}
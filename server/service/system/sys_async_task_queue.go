@@ -0,0 +1,102 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// asyncTaskQueue 后台任务队列 只传递任务ID 任务由工作协程按状态抢占 重复投递不会重复执行
+type asyncTaskQueue interface {
+	// Push 投递任务 node 不为空时只由该节点取出
+	Push(ctx context.Context, id uint, node string) error
+	// Pop 等待下一个任务 一段时间内没有任务时返回 0
+	Pop(ctx context.Context) (uint, error)
+}
+
+// newAsyncTaskQueue 按配置选择队列 未配置时开启 redis 使用 redis 否则使用数据库
+func newAsyncTaskQueue() asyncTaskQueue {
+	db := &dbAsyncTaskQueue{wake: make(chan struct{}, 1), interval: asyncTaskPollInterval()}
+	switch global.GVA_CONFIG.AsyncTask.Backend {
+	case "db":
+		return db
+	case "redis":
+		if global.GVA_REDIS != nil {
+			return redisAsyncTaskQueue{client: global.GVA_REDIS}
+		}
+		return db
+	}
+	if global.GVA_REDIS != nil {
+		return redisAsyncTaskQueue{client: global.GVA_REDIS}
+	}
+	return db
+}
+
+const asyncTaskQueueKey = "GVA_AsyncTask:queue"
+
+type redisAsyncTaskQueue struct {
+	client redis.UniversalClient
+}
+
+// key 限定节点的任务投递到节点各自的队列
+func (q redisAsyncTaskQueue) key(node string) string {
+	if node == "" {
+		return asyncTaskQueueKey
+	}
+	return asyncTaskQueueKey + ":" + node
+}
+
+func (q redisAsyncTaskQueue) Push(ctx context.Context, id uint, node string) error {
+	return q.client.LPush(ctx, q.key(node), id).Err()
+}
+
+func (q redisAsyncTaskQueue) Pop(ctx context.Context) (uint, error) {
+	result, err := q.client.BRPop(ctx, 5*time.Second, q.key(utils.NodeID()), asyncTaskQueueKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(result[1], 10, 64)
+	return uint(id), err
+}
+
+// dbAsyncTaskQueue 定时查询待执行的任务 本节点入队时立即唤醒
+type dbAsyncTaskQueue struct {
+	wake     chan struct{}
+	interval time.Duration
+}
+
+func (q *dbAsyncTaskQueue) Push(ctx context.Context, id uint, node string) error {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *dbAsyncTaskQueue) Pop(ctx context.Context) (uint, error) {
+	var ids []uint
+	err := asyncTaskRunnable(global.GVA_DB.WithContext(ctx).Model(&system.SysAsyncTask{})).Where("status = ?", system.AsyncTaskPending).
+		Order("id").Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-q.wake:
+	case <-time.After(q.interval):
+	}
+	return 0, nil
+}
@@ -0,0 +1,236 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newAsyncTaskDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/async_task.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&system.SysAsyncTask{}); err != nil {
+		t.Fatal(err)
+	}
+	global.GVA_DB = db
+	global.GVA_LOG = zap.NewNop()
+	global.GVA_CONFIG.AsyncTask.PollInterval = 20
+	global.GVA_CONFIG.AsyncTask.StaleAfter = 0
+	global.GVA_CONFIG.AsyncTask.ArtifactDir = t.TempDir()
+	global.GVA_CONFIG.System.OssType = "local"
+	global.GVA_CONFIG.Local.StorePath = t.TempDir()
+}
+
+func createAsyncTask(t *testing.T, task system.SysAsyncTask) system.SysAsyncTask {
+	t.Helper()
+	if task.Status == "" {
+		task.Status = system.AsyncTaskPending
+	}
+	if task.Payload == "" {
+		task.Payload = "{}"
+	}
+	if err := global.GVA_DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func loadAsyncTask(t *testing.T, id uint) system.SysAsyncTask {
+	t.Helper()
+	var task system.SysAsyncTask
+	if err := global.GVA_DB.First(&task, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+type asyncTaskTestPayload struct {
+	Name string `json:"name"`
+}
+
+func TestRunAsyncTask(t *testing.T) {
+	newAsyncTaskDB(t)
+	runs := 0
+	RegisterAsyncTask("test.run", func(ctx context.Context, run *AsyncTaskRun, payload asyncTaskTestPayload) error {
+		runs++
+		run.Progress(50, "处理中")
+		out, err := run.CreateArtifact("../" + payload.Name + ".txt")
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		if _, err = out.WriteString("hello"); err != nil {
+			return err
+		}
+		return run.SetResult(map[string]int{"rows": 3})
+	})
+	RegisterAsyncTask("test.fail", func(ctx context.Context, run *AsyncTaskRun, payload asyncTaskTestPayload) error {
+		return errors.New("boom")
+	})
+	RegisterAsyncTask("test.panic", func(ctx context.Context, run *AsyncTaskRun, payload asyncTaskTestPayload) error {
+		panic("boom")
+	})
+
+	task := createAsyncTask(t, system.SysAsyncTask{Type: "test.run", Payload: `{"name":"report"}`})
+	runAsyncTask(task.ID)
+	// 已结束的任务不会被再次抢占
+	runAsyncTask(task.ID)
+	assert.Equal(t, 1, runs)
+
+	task = loadAsyncTask(t, task.ID)
+	assert.Equal(t, system.AsyncTaskSuccess, task.Status)
+	assert.Equal(t, 100, task.Progress)
+	assert.JSONEq(t, `{"rows":3}`, task.Result)
+	assert.Equal(t, utils.NodeID(), task.Node)
+	assert.NotNil(t, task.StartedAt)
+	assert.NotNil(t, task.HeartbeatAt)
+	assert.NotNil(t, task.FinishedAt)
+	assert.Equal(t, "report.txt", task.ArtifactName)
+	// 生成的文件保存到对象存储 本地临时文件已删除
+	data, err := os.ReadFile(filepath.Join(global.GVA_CONFIG.Local.StorePath, task.ArtifactKey))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	entries, _ := os.ReadDir(global.GVA_CONFIG.AsyncTask.ArtifactDir)
+	assert.Empty(t, entries)
+
+	for _, taskType := range []string{"test.fail", "test.panic", "test.unregistered"} {
+		task = createAsyncTask(t, system.SysAsyncTask{Type: taskType})
+		runAsyncTask(task.ID)
+		task = loadAsyncTask(t, task.ID)
+		assert.Equal(t, system.AsyncTaskFailed, task.Status, taskType)
+		assert.NotEmpty(t, task.Error, taskType)
+	}
+}
+
+func TestCancelAsyncTask(t *testing.T) {
+	newAsyncTaskDB(t)
+	started := make(chan struct{})
+	RegisterAsyncTask("test.wait", func(ctx context.Context, run *AsyncTaskRun, payload asyncTaskTestPayload) error {
+		close(started)
+		<-ctx.Done()
+		return context.Cause(ctx)
+	})
+
+	// 待执行的任务直接取消 之后不会再执行
+	pending := createAsyncTask(t, system.SysAsyncTask{Type: "test.wait", UserID: 1})
+	assert.NoError(t, AsyncTaskServiceApp.CancelAsyncTask(pending.ID, 1))
+	runAsyncTask(pending.ID)
+	pending = loadAsyncTask(t, pending.ID)
+	assert.Equal(t, system.AsyncTaskCanceled, pending.Status)
+	assert.Nil(t, pending.StartedAt)
+	assert.Error(t, AsyncTaskServiceApp.CancelAsyncTask(pending.ID, 1), "已结束的任务不能取消")
+
+	// 执行中的任务 其他用户不能取消
+	running := createAsyncTask(t, system.SysAsyncTask{Type: "test.wait", UserID: 1})
+	done := make(chan struct{})
+	go func() {
+		runAsyncTask(running.ID)
+		close(done)
+	}()
+	<-started
+	assert.Error(t, AsyncTaskServiceApp.CancelAsyncTask(running.ID, 2))
+	assert.NoError(t, AsyncTaskServiceApp.CancelAsyncTask(running.ID, 1))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("任务未响应取消")
+	}
+	running = loadAsyncTask(t, running.ID)
+	assert.Equal(t, system.AsyncTaskCanceled, running.Status)
+	assert.True(t, running.CancelRequested)
+}
+
+func TestCancelAsyncTaskFromOtherNode(t *testing.T) {
+	newAsyncTaskDB(t)
+	started := make(chan struct{})
+	RegisterAsyncTask("test.wait", func(ctx context.Context, run *AsyncTaskRun, payload asyncTaskTestPayload) error {
+		close(started)
+		<-ctx.Done()
+		return context.Cause(ctx)
+	})
+	task := createAsyncTask(t, system.SysAsyncTask{Type: "test.wait"})
+	done := make(chan struct{})
+	go func() {
+		runAsyncTask(task.ID)
+		close(done)
+	}()
+	<-started
+	// 其他节点只能写入取消请求 由执行节点定时检查
+	assert.NoError(t, global.GVA_DB.Model(&system.SysAsyncTask{}).Where("id = ?", task.ID).Update("cancel_requested", true).Error)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("任务未响应取消")
+	}
+	assert.Equal(t, system.AsyncTaskCanceled, loadAsyncTask(t, task.ID).Status)
+}
+
+func TestRecoverAsyncTasks(t *testing.T) {
+	newAsyncTaskDB(t)
+	global.GVA_CONFIG.AsyncTask.StaleAfter = 60
+	old := time.Now().Add(-2 * time.Minute)
+	recent := time.Now()
+
+	staleHeartbeat := createAsyncTask(t, system.SysAsyncTask{Status: system.AsyncTaskRunning, Node: "host-1", StartedAt: &old, HeartbeatAt: &old})
+	staleStart := createAsyncTask(t, system.SysAsyncTask{Status: system.AsyncTaskRunning, Node: "host-2", StartedAt: &old})
+	alive := createAsyncTask(t, system.SysAsyncTask{Status: system.AsyncTaskRunning, Node: "host-3", StartedAt: &old, HeartbeatAt: &recent})
+	local := createAsyncTask(t, system.SysAsyncTask{Status: system.AsyncTaskRunning, Node: utils.NodeID(), StartedAt: &recent, HeartbeatAt: &recent})
+	pending := createAsyncTask(t, system.SysAsyncTask{})
+
+	count, err := AsyncTaskServiceApp.RecoverAsyncTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, system.AsyncTaskFailed, loadAsyncTask(t, staleHeartbeat.ID).Status)
+	assert.Equal(t, system.AsyncTaskFailed, loadAsyncTask(t, staleStart.ID).Status)
+	assert.Equal(t, system.AsyncTaskRunning, loadAsyncTask(t, alive.ID).Status)
+	assert.Equal(t, system.AsyncTaskRunning, loadAsyncTask(t, local.ID).Status)
+	assert.Equal(t, system.AsyncTaskPending, loadAsyncTask(t, pending.ID).Status)
+
+	// 启动时本节点名下执行中的任务同样视为中断
+	count, err = recoverAsyncTasks(true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, system.AsyncTaskFailed, loadAsyncTask(t, local.ID).Status)
+	assert.Equal(t, system.AsyncTaskRunning, loadAsyncTask(t, alive.ID).Status)
+}
+
+func TestNodeAsyncTask(t *testing.T) {
+	newAsyncTaskDB(t)
+	runs := 0
+	RegisterNodeAsyncTask("test.node", func(ctx context.Context, run *AsyncTaskRun, payload asyncTaskTestPayload) error {
+		runs++
+		return nil
+	})
+	queue := &dbAsyncTaskQueue{wake: make(chan struct{}, 1), interval: time.Millisecond}
+
+	// 其他节点入队的任务不会被本节点取出或抢占
+	other := createAsyncTask(t, system.SysAsyncTask{Type: "test.node", PinNode: "other-node"})
+	id, err := queue.Pop(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, id)
+	runAsyncTask(other.ID)
+	assert.Equal(t, system.AsyncTaskPending, loadAsyncTask(t, other.ID).Status)
+
+	local := createAsyncTask(t, system.SysAsyncTask{Type: "test.node", PinNode: utils.NodeID()})
+	id, err = queue.Pop(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, local.ID, id)
+	runAsyncTask(id)
+	assert.Equal(t, system.AsyncTaskSuccess, loadAsyncTask(t, local.ID).Status)
+	assert.Equal(t, 1, runs)
+}
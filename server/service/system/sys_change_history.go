@@ -0,0 +1,525 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type ChangeHistoryService struct{}
+
+var ChangeHistoryServiceApp = new(ChangeHistoryService)
+
+const (
+	changeHistoryBeforeKey = "change_history:before"
+	// changeHistoryMaxRows 单条语句影响的行数超过该值时不记录变更历史 避免批量更新时加载过多数据
+	changeHistoryMaxRows = 1000
+	changeHistoryMask    = "******"
+)
+
+// changeHistoryModels 记录变更历史的模型 代码生成的业务模型可通过 TrackChanges 追加
+var changeHistoryModels = []interface{}{
+	system.SysUser{},
+	system.SysAuthority{},
+	system.SysBaseMenu{},
+	system.SysParams{},
+	system.SysDictionary{},
+}
+
+var (
+	changeHistorySchemas = map[string]*schema.Schema{}
+	changeHistoryLock    sync.RWMutex
+)
+
+type changeRevertKey struct{}
+
+type changeValue struct {
+	Old json.RawMessage `json:"old,omitempty"`
+	New json.RawMessage `json:"new,omitempty"`
+}
+
+// TrackChanges 为模型开启变更历史 需在 RegisterChangeHistory 之前调用
+func TrackChanges(models ...interface{}) {
+	changeHistoryLock.Lock()
+	defer changeHistoryLock.Unlock()
+	changeHistoryModels = append(changeHistoryModels, models...)
+}
+
+// RegisterChangeHistory 解析需要记录变更历史的模型并注册 gorm 回调 每个连接只注册一次
+// 只有主键为单列的模型会被记录 操作人和请求ID取自语句的 context 需要使用 WithContext 传入请求的 context
+func RegisterChangeHistory(db *gorm.DB) error {
+	schemas := make(map[string]*schema.Schema, len(changeHistoryModels))
+	changeHistoryLock.RLock()
+	models := changeHistoryModels
+	changeHistoryLock.RUnlock()
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if len(stmt.Schema.PrimaryFields) != 1 {
+			return fmt.Errorf("change history: %s must have a single primary key", stmt.Schema.Table)
+		}
+		schemas[stmt.Schema.Table] = stmt.Schema
+	}
+	changeHistoryLock.Lock()
+	changeHistorySchemas = schemas
+	changeHistoryLock.Unlock()
+
+	// 同一连接重复调用时只更新模型 回调只注册一次
+	if db.Callback().Create().Get("change_history:after_create") != nil {
+		return nil
+	}
+	callbacks := []error{
+		db.Callback().Create().After("gorm:create").Register("change_history:after_create", afterCreateChange),
+		db.Callback().Update().Before("gorm:update").Register("change_history:before_update", beforeChange),
+		db.Callback().Update().After("gorm:update").Register("change_history:after_update", afterUpdateChange),
+		db.Callback().Delete().Before("gorm:delete").Register("change_history:before_delete", beforeChange),
+		db.Callback().Delete().After("gorm:delete").Register("change_history:after_delete", afterDeleteChange),
+	}
+	return errors.Join(callbacks...)
+}
+
+func trackedSchema(table string) *schema.Schema {
+	changeHistoryLock.RLock()
+	defer changeHistoryLock.RUnlock()
+	return changeHistorySchemas[table]
+}
+
+// statementSchema 语句对应的模型开启了变更历史时返回模型的 schema
+func statementSchema(db *gorm.DB) *schema.Schema {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil
+	}
+	if s := trackedSchema(db.Statement.Schema.Table); s != nil && s.ModelType == db.Statement.Schema.ModelType {
+		return s
+	}
+	return nil
+}
+
+// changeConditions 语句的查询条件 包括 Where 条件和模型中的主键 都没有时返回 false
+func changeConditions(db *gorm.DB, s *schema.Schema) ([]clause.Expression, bool) {
+	var exprs []clause.Expression
+	if c, ok := db.Statement.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			exprs = append(exprs, where.Exprs...)
+		}
+	}
+	pk := s.PrioritizedPrimaryField
+	column := clause.Column{Table: clause.CurrentTable, Name: pk.DBName}
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv.Type() == s.ModelType {
+			if value, zero := pk.ValueOf(db.Statement.Context, rv); !zero {
+				exprs = append(exprs, clause.Eq{Column: column, Value: value})
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var values []interface{}
+		for i := 0; i < rv.Len(); i++ {
+			item := reflect.Indirect(rv.Index(i))
+			if item.Kind() != reflect.Struct || item.Type() != s.ModelType {
+				continue
+			}
+			if value, zero := pk.ValueOf(db.Statement.Context, item); !zero {
+				values = append(values, value)
+			}
+		}
+		if len(values) > 0 {
+			exprs = append(exprs, clause.IN{Column: column, Values: values})
+		}
+	}
+	return exprs, len(exprs) > 0
+}
+
+// loadChangeRows 在语句所在的事务中按条件加载整行数据 返回主键及各列的 JSON
+func loadChangeRows(db *gorm.DB, s *schema.Schema, exprs []clause.Expression, unscoped bool) ([]changeRow, error) {
+	rows := reflect.New(reflect.SliceOf(s.ModelType))
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Model(reflect.New(s.ModelType).Interface())
+	if unscoped {
+		tx = tx.Unscoped()
+	}
+	if err := tx.Clauses(clause.Where{Exprs: exprs}).Limit(changeHistoryMaxRows + 1).Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+	rv := rows.Elem()
+	if rv.Len() > changeHistoryMaxRows {
+		global.GVA_LOG.Warn("change history skipped: too many rows", zap.String("table", s.Table))
+		return nil, nil
+	}
+	result := make([]changeRow, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		result = append(result, newChangeRow(db.Statement.Context, s, rv.Index(i)))
+	}
+	return result, nil
+}
+
+type changeRow struct {
+	key    interface{} // 主键的原始值 用于再次查询
+	id     string
+	values map[string]json.RawMessage
+}
+
+func newChangeRow(ctx context.Context, s *schema.Schema, rv reflect.Value) changeRow {
+	row := changeRow{values: make(map[string]json.RawMessage, len(s.DBNames))}
+	row.key, _ = s.PrioritizedPrimaryField.ValueOf(ctx, rv)
+	row.id = fmt.Sprint(row.key)
+	for _, name := range s.DBNames {
+		value, _ := s.FieldsByDBName[name].ValueOf(ctx, rv)
+		b, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		row.values[name] = b
+	}
+	return row
+}
+
+func changeFieldHidden(s *schema.Schema, column string) bool {
+	field := s.LookUpField(column)
+	return field != nil && field.Tag.Get("json") == "-"
+}
+
+// changeHiddenFields 不记录到快照中的字段 软删除字段除外
+func changeHiddenFields(s *schema.Schema) (hidden []string) {
+	deleted := softDeleteField(s)
+	for _, field := range s.Fields {
+		if field.DBName != "" && field != deleted && changeFieldHidden(s, field.DBName) {
+			hidden = append(hidden, field.DBName)
+		}
+	}
+	return hidden
+}
+
+// softDeleteField 软删除字段 恢复记录时一并清空
+func softDeleteField(s *schema.Schema) *schema.Field {
+	for _, field := range s.Fields {
+		if field.DBName != "" && field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return field
+		}
+	}
+	return nil
+}
+
+// changeDiff 比较变更前后的各列 新增时 before 为空 删除时 after 为空
+func changeDiff(s *schema.Schema, before, after map[string]json.RawMessage) map[string]changeValue {
+	changes := make(map[string]changeValue)
+	mask, _ := json.Marshal(changeHistoryMask)
+	deleted := softDeleteField(s)
+	for _, name := range s.DBNames {
+		if name == "updated_at" {
+			continue
+		}
+		old, oldOk := before[name]
+		current, newOk := after[name]
+		if oldOk && newOk && string(old) == string(current) {
+			continue
+		}
+		if !oldOk && !newOk {
+			continue
+		}
+		if deleted != nil && name == deleted.DBName {
+			// 软删除字段只在恢复时记录
+			if oldOk && newOk {
+				changes[name] = changeValue{Old: old, New: current}
+			}
+			continue
+		}
+		value := changeValue{Old: old, New: current}
+		if changeFieldHidden(s, name) {
+			value = changeValue{}
+			if oldOk {
+				value.Old = mask
+			}
+			if newOk {
+				value.New = mask
+			}
+		}
+		changes[name] = value
+	}
+	return changes
+}
+
+// changeData 保存到历史中的整行数据 不包含隐藏字段
+func changeData(s *schema.Schema, values map[string]json.RawMessage) map[string]json.RawMessage {
+	data := make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		if !changeFieldHidden(s, name) {
+			data[name] = value
+		}
+	}
+	return data
+}
+
+func saveChangeHistory(db *gorm.DB, s *schema.Schema, action string, rows []changeRow, diffs []map[string]changeValue) {
+	if len(rows) == 0 {
+		return
+	}
+	info := utils.GetRequestInfo(db.Statement.Context)
+	revertOf, _ := db.Statement.Context.Value(changeRevertKey{}).(uint)
+	histories := make([]system.SysChangeHistory, 0, len(rows))
+	for i, row := range rows {
+		if len(diffs[i]) == 0 {
+			continue
+		}
+		changes, _ := json.Marshal(diffs[i])
+		data, _ := json.Marshal(changeData(s, row.values))
+		histories = append(histories, system.SysChangeHistory{
+			Table:     s.Table,
+			RecordID:  row.id,
+			Action:    action,
+			Changes:   changes,
+			Data:      data,
+			UserID:    info.UserID,
+			Username:  info.Username,
+			RequestID: info.RequestID,
+			RevertOf:  revertOf,
+		})
+	}
+	if len(histories) == 0 {
+		return
+	}
+	// 与数据变更在同一事务中写入 写入失败时数据变更一并回滚
+	if err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&histories).Error; err != nil {
+		_ = db.AddError(err)
+	}
+}
+
+func afterCreateChange(db *gorm.DB) {
+	s := statementSchema(db)
+	if s == nil {
+		return
+	}
+	exprs, ok := changeConditions(db, s)
+	if !ok {
+		return
+	}
+	rows, err := loadChangeRows(db, s, exprs, true)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	diffs := make([]map[string]changeValue, len(rows))
+	for i, row := range rows {
+		diffs[i] = changeDiff(s, nil, row.values)
+	}
+	saveChangeHistory(db, s, system.ChangeActionCreate, rows, diffs)
+}
+
+// beforeChange 更新和删除前加载受影响的行 没有任何条件的语句会被 gorm 拒绝 不做处理
+func beforeChange(db *gorm.DB) {
+	s := statementSchema(db)
+	if s == nil {
+		return
+	}
+	exprs, ok := changeConditions(db, s)
+	if !ok {
+		return
+	}
+	rows, err := loadChangeRows(db, s, exprs, db.Statement.Unscoped)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	db.InstanceSet(changeHistoryBeforeKey, rows)
+}
+
+func beforeChangeRows(db *gorm.DB) []changeRow {
+	value, ok := db.InstanceGet(changeHistoryBeforeKey)
+	if !ok {
+		return nil
+	}
+	rows, _ := value.([]changeRow)
+	return rows
+}
+
+// afterUpdateChange 按更新前加载的主键重新加载数据 与更新前比较
+func afterUpdateChange(db *gorm.DB) {
+	s := statementSchema(db)
+	if s == nil {
+		return
+	}
+	before := beforeChangeRows(db)
+	if len(before) == 0 {
+		return
+	}
+	ids := make([]interface{}, 0, len(before))
+	for _, row := range before {
+		ids = append(ids, row.key)
+	}
+	column := clause.Column{Table: clause.CurrentTable, Name: s.PrioritizedPrimaryField.DBName}
+	after, err := loadChangeRows(db, s, []clause.Expression{clause.IN{Column: column, Values: ids}}, true)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	beforeMap := make(map[string]changeRow, len(before))
+	for _, row := range before {
+		beforeMap[row.id] = row
+	}
+	diffs := make([]map[string]changeValue, len(after))
+	for i, row := range after {
+		diffs[i] = changeDiff(s, beforeMap[row.id].values, row.values)
+	}
+	saveChangeHistory(db, s, system.ChangeActionUpdate, after, diffs)
+}
+
+func afterDeleteChange(db *gorm.DB) {
+	s := statementSchema(db)
+	if s == nil {
+		return
+	}
+	rows := beforeChangeRows(db)
+	diffs := make([]map[string]changeValue, len(rows))
+	for i, row := range rows {
+		diffs[i] = changeDiff(s, row.values, nil)
+	}
+	saveChangeHistory(db, s, system.ChangeActionDelete, rows, diffs)
+}
+
+// GetChangeHistory 分页获取一条记录的变更历史 按时间倒序
+func (changeHistoryService *ChangeHistoryService) GetChangeHistory(info systemReq.ChangeHistorySearch) (list []system.SysChangeHistory, total int64, err error) {
+	if trackedSchema(info.Table) == nil {
+		return nil, 0, errors.New("该表未开启变更历史")
+	}
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysChangeHistory{}).Where("table_name = ? AND record_id = ?", info.Table, info.RecordID)
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Order("id desc").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
+
+// RevertChange 将记录恢复为该条变更之后的数据 删除的变更恢复为删除前的数据
+// 记录已被物理删除时重新创建 包含隐藏字段的记录无法从快照完整重建 不允许重新创建
+// 回滚用户或角色时操作者需有权管理回滚前后涉及的角色 回滚本身也作为一次变更记录下来
+func (changeHistoryService *ChangeHistoryService) RevertChange(ctx context.Context, adminAuthorityID, id uint) error {
+	var history system.SysChangeHistory
+	if err := global.GVA_DB.Where("id = ?", id).First(&history).Error; err != nil {
+		return errors.New("变更记录不存在")
+	}
+	s := trackedSchema(history.Table)
+	if s == nil {
+		return errors.New("该表未开启变更历史")
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(history.Data, &data); err != nil {
+		return err
+	}
+	pk := s.PrioritizedPrimaryField
+	target := reflect.New(s.ModelType)
+	columns := make([]string, 0, len(data))
+	for name, raw := range data {
+		field := s.LookUpField(name)
+		if field == nil || field.DBName == "" || changeFieldHidden(s, name) {
+			continue
+		}
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return fmt.Errorf("恢复字段 %s 失败: %w", name, err)
+		}
+		if err := field.Set(ctx, target.Elem(), value.Elem().Interface()); err != nil {
+			return fmt.Errorf("恢复字段 %s 失败: %w", name, err)
+		}
+		if field != pk && name != "updated_at" {
+			columns = append(columns, name)
+		}
+	}
+	key, zero := pk.ValueOf(ctx, target.Elem())
+	if zero {
+		return errors.New("变更记录缺少主键")
+	}
+	if deleted := softDeleteField(s); deleted != nil {
+		// 已被软删除的记录一并恢复
+		columns = append(columns, deleted.DBName)
+	}
+
+	if s.Table == (system.SysDictionary{}).TableName() {
+		// 回滚可能修改字典类型 回滚前后的类型都需要清除缓存
+		id, _ := strconv.ParseUint(history.RecordID, 10, 64)
+		defer func(before string) {
+			dictCache.invalidate(before, dictionaryTypeByID(uint(id)))
+		}(dictionaryTypeByID(uint(id)))
+	}
+
+	ctx = context.WithValue(ctx, changeRevertKey{}, history.ID)
+	return global.GVA_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Unscoped().Model(reflect.New(s.ModelType).Interface()).Where(pk.DBName+" = ?", key).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if err = checkRevertAuthority(ctx, tx, adminAuthorityID, s, key, target.Elem(), count > 0); err != nil {
+			return err
+		}
+		if count == 0 {
+			if hidden := changeHiddenFields(s); len(hidden) > 0 {
+				return fmt.Errorf("记录已被物理删除 快照中不包含 %s 无法重新创建", strings.Join(hidden, ", "))
+			}
+			return tx.Omit(clause.Associations).Create(target.Interface()).Error
+		}
+		return tx.Unscoped().Model(target.Interface()).Select(columns).Updates(target.Interface()).Error
+	})
+}
+
+// revertAuthorityColumns 回滚时需校验操作者权限的角色字段
+var revertAuthorityColumns = map[string][]string{
+	(system.SysUser{}).TableName():      {"authority_id"},
+	(system.SysAuthority{}).TableName(): {"authority_id", "parent_id"},
+}
+
+// checkRevertAuthority 与设置用户角色一致 回滚前后的角色都需在操作者有权管理的范围内
+func checkRevertAuthority(ctx context.Context, tx *gorm.DB, adminAuthorityID uint, s *schema.Schema, key interface{}, target reflect.Value, exists bool) error {
+	columns := revertAuthorityColumns[s.Table]
+	if len(columns) == 0 {
+		return nil
+	}
+	var ids []uint
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil {
+			continue
+		}
+		if value, zero := field.ValueOf(ctx, target); !zero {
+			ids = append(ids, reflect.Indirect(reflect.ValueOf(value)).Interface().(uint))
+		}
+		if exists {
+			var current []*uint
+			err := tx.Unscoped().Model(reflect.New(s.ModelType).Interface()).Where(s.PrioritizedPrimaryField.DBName+" = ?", key).Pluck(column, &current).Error
+			if err != nil {
+				return err
+			}
+			for _, id := range current {
+				if id != nil {
+					ids = append(ids, *id)
+				}
+			}
+		}
+	}
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+		if err := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
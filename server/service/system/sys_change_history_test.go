@@ -0,0 +1,207 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newChangeHistoryDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/change_history.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&system.SysAuthority{}, &system.SysUser{}, &system.SysParams{}, &system.SysChangeHistory{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = RegisterChangeHistory(db); err != nil {
+		t.Fatal(err)
+	}
+	global.GVA_DB = db
+	global.GVA_LOG = zap.NewNop()
+	return db
+}
+
+func changeHistories(t *testing.T, table string, id uint) []system.SysChangeHistory {
+	t.Helper()
+	var list []system.SysChangeHistory
+	err := global.GVA_DB.Where("table_name = ? AND record_id = ?", table, strconv.FormatUint(uint64(id), 10)).Order("id").Find(&list).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func changeValues(t *testing.T, h system.SysChangeHistory) map[string]changeValue {
+	t.Helper()
+	var changes map[string]changeValue
+	if err := json.Unmarshal(h.Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	return changes
+}
+
+func TestChangeHistoryCapture(t *testing.T) {
+	db := newChangeHistoryDB(t)
+	ctx := utils.WithRequestInfo(context.Background(), utils.RequestInfo{RequestID: "req-1", UserID: 1, Username: "admin"})
+
+	params := system.SysParams{Name: "站点名称", Key: "site.name", Value: "old"}
+	assert.NoError(t, db.WithContext(ctx).Create(&params).Error)
+	assert.NoError(t, db.WithContext(ctx).Model(&params).Update("value", "new").Error)
+	// 值未变化的更新不产生变更记录
+	assert.NoError(t, db.WithContext(ctx).Model(&params).Update("value", "new").Error)
+	assert.NoError(t, db.WithContext(ctx).Delete(&params).Error)
+
+	list := changeHistories(t, params.TableName(), params.ID)
+	if !assert.Len(t, list, 3) {
+		return
+	}
+	assert.Equal(t, system.ChangeActionCreate, list[0].Action)
+	assert.Equal(t, system.ChangeActionUpdate, list[1].Action)
+	assert.Equal(t, system.ChangeActionDelete, list[2].Action)
+	for _, h := range list {
+		assert.Equal(t, uint(1), h.UserID)
+		assert.Equal(t, "admin", h.Username)
+		assert.Equal(t, "req-1", h.RequestID)
+	}
+
+	created := changeValues(t, list[0])
+	assert.JSONEq(t, `"old"`, string(created["value"].New))
+	assert.Nil(t, created["value"].Old)
+
+	updated := changeValues(t, list[1])
+	assert.Len(t, updated, 1)
+	assert.JSONEq(t, `"old"`, string(updated["value"].Old))
+	assert.JSONEq(t, `"new"`, string(updated["value"].New))
+
+	deleted := changeValues(t, list[2])
+	assert.JSONEq(t, `"new"`, string(deleted["value"].Old))
+	assert.Nil(t, deleted["value"].New)
+	_, ok := deleted["deleted_at"]
+	assert.False(t, ok, "软删除字段只在恢复时记录")
+}
+
+func TestChangeHistoryMasksHiddenFields(t *testing.T) {
+	db := newChangeHistoryDB(t)
+
+	user := system.SysUser{Username: "tester", NickName: "tester", Password: "secret-1", AuthorityId: 888}
+	assert.NoError(t, db.Create(&user).Error)
+	assert.NoError(t, db.Model(&user).Update("password", "secret-2").Error)
+
+	list := changeHistories(t, user.TableName(), user.ID)
+	if !assert.Len(t, list, 2) {
+		return
+	}
+	for _, h := range list {
+		assert.NotContains(t, string(h.Changes), "secret")
+		assert.NotContains(t, string(h.Data), "secret")
+	}
+	updated := changeValues(t, list[1])
+	assert.JSONEq(t, `"******"`, string(updated["password"].Old))
+	assert.JSONEq(t, `"******"`, string(updated["password"].New))
+
+	var data map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(list[1].Data, &data))
+	_, ok := data["password"]
+	assert.False(t, ok)
+}
+
+func TestRevertChange(t *testing.T) {
+	db := newChangeHistoryDB(t)
+	ctx := context.Background()
+
+	params := system.SysParams{Name: "站点名称", Key: "site.name", Value: "v1"}
+	assert.NoError(t, db.Create(&params).Error)
+	assert.NoError(t, db.Model(&params).Updates(system.SysParams{Name: "名称", Value: "v2"}).Error)
+
+	list := changeHistories(t, params.TableName(), params.ID)
+	if !assert.Len(t, list, 2) {
+		return
+	}
+	// 回滚到新增之后的数据
+	assert.NoError(t, ChangeHistoryServiceApp.RevertChange(ctx, 888, list[0].ID))
+	var current system.SysParams
+	assert.NoError(t, db.First(&current, params.ID).Error)
+	assert.Equal(t, "站点名称", current.Name)
+	assert.Equal(t, "v1", current.Value)
+
+	list = changeHistories(t, params.TableName(), params.ID)
+	if !assert.Len(t, list, 3) {
+		return
+	}
+	assert.Equal(t, system.ChangeActionUpdate, list[2].Action)
+	assert.Equal(t, list[0].ID, list[2].RevertOf)
+
+	// 软删除后回滚删除 记录恢复为删除前的数据
+	assert.NoError(t, db.Delete(&params).Error)
+	list = changeHistories(t, params.TableName(), params.ID)
+	deleteHistory := list[len(list)-1]
+	assert.Equal(t, system.ChangeActionDelete, deleteHistory.Action)
+	assert.NoError(t, ChangeHistoryServiceApp.RevertChange(ctx, 888, deleteHistory.ID))
+	current = system.SysParams{}
+	assert.NoError(t, db.First(&current, params.ID).Error)
+	assert.Equal(t, "v1", current.Value)
+
+	// 物理删除后回滚 重新创建记录
+	assert.NoError(t, db.Unscoped().Delete(&params).Error)
+	list = changeHistories(t, params.TableName(), params.ID)
+	assert.NoError(t, ChangeHistoryServiceApp.RevertChange(ctx, 888, list[len(list)-1].ID))
+	current = system.SysParams{}
+	assert.NoError(t, db.First(&current, params.ID).Error)
+	assert.Equal(t, "site.name", current.Key)
+	assert.Equal(t, "v1", current.Value)
+}
+
+func TestRegisterChangeHistoryOnce(t *testing.T) {
+	db := newChangeHistoryDB(t)
+	assert.NoError(t, RegisterChangeHistory(db))
+
+	params := system.SysParams{Name: "站点名称", Key: "site.name", Value: "v1"}
+	assert.NoError(t, db.Create(&params).Error)
+	assert.Len(t, changeHistories(t, params.TableName(), params.ID), 1)
+}
+
+func TestRevertChangeChecksAuthority(t *testing.T) {
+	db := newChangeHistoryDB(t)
+	ctx := context.Background()
+	global.GVA_CONFIG.System.UseStrictAuth = true
+	t.Cleanup(func() { global.GVA_CONFIG.System.UseStrictAuth = false })
+	root, admin := uint(0), uint(888)
+	assert.NoError(t, db.Create(&[]system.SysAuthority{
+		{AuthorityId: 888, AuthorityName: "管理员", ParentId: &root},
+		{AuthorityId: 9528, AuthorityName: "普通用户", ParentId: &admin},
+	}).Error)
+
+	user := system.SysUser{Username: "tester", NickName: "tester", Password: "secret", AuthorityId: 888}
+	assert.NoError(t, db.Create(&user).Error)
+	assert.NoError(t, db.Model(&user).Update("authority_id", 9528).Error)
+	list := changeHistories(t, user.TableName(), user.ID)
+	if !assert.Len(t, list, 2) {
+		return
+	}
+	// 普通用户不能把用户恢复为管理员角色
+	assert.Error(t, ChangeHistoryServiceApp.RevertChange(ctx, 9528, list[0].ID))
+	var current system.SysUser
+	assert.NoError(t, db.First(&current, user.ID).Error)
+	assert.Equal(t, uint(9528), current.AuthorityId)
+	assert.NoError(t, ChangeHistoryServiceApp.RevertChange(ctx, 888, list[0].ID))
+	assert.NoError(t, db.First(&current, user.ID).Error)
+	assert.Equal(t, uint(888), current.AuthorityId)
+
+	// 快照中不包含密码 物理删除的用户不能重新创建
+	assert.NoError(t, db.Unscoped().Delete(&user).Error)
+	list = changeHistories(t, user.TableName(), user.ID)
+	assert.Error(t, ChangeHistoryServiceApp.RevertChange(ctx, 888, list[len(list)-1].ID))
+	assert.ErrorIs(t, db.Unscoped().First(&system.SysUser{}, user.ID).Error, gorm.ErrRecordNotFound)
+}
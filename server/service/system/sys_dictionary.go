@@ -1,6 +1,7 @@
 package system
 
 import (
+	"context"
 	"errors"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
@@ -21,11 +22,14 @@ type DictionaryService struct{}
 
 var DictionaryServiceApp = new(DictionaryService)
 
-func (dictionaryService *DictionaryService) CreateSysDictionary(sysDictionary system.SysDictionary) (err error) {
-	if (!errors.Is(global.GVA_DB.First(&system.SysDictionary{}, "type = ?", sysDictionary.Type).Error, gorm.ErrRecordNotFound)) {
+func (dictionaryService *DictionaryService) CreateSysDictionary(ctx context.Context, sysDictionary system.SysDictionary) (err error) {
+	if (!errors.Is(global.GVA_DB.WithContext(ctx).First(&system.SysDictionary{}, "type = ?", sysDictionary.Type).Error, gorm.ErrRecordNotFound)) {
 		return errors.New("存在相同的type，不允许创建")
 	}
-	err = global.GVA_DB.Create(&sysDictionary).Error
+	err = global.GVA_DB.WithContext(ctx).Create(&sysDictionary).Error
+	if err == nil {
+		dictCache.invalidate(sysDictionary.Type)
+	}
 	return err
 }
 
@@ -35,21 +39,22 @@ func (dictionaryService *DictionaryService) CreateSysDictionary(sysDictionary sy
 //@param: sysDictionary model.SysDictionary
 //@return: err error
 
-func (dictionaryService *DictionaryService) DeleteSysDictionary(sysDictionary system.SysDictionary) (err error) {
-	err = global.GVA_DB.Where("id = ?", sysDictionary.ID).Preload("SysDictionaryDetails").First(&sysDictionary).Error
+func (dictionaryService *DictionaryService) DeleteSysDictionary(ctx context.Context, sysDictionary system.SysDictionary) (err error) {
+	err = global.GVA_DB.WithContext(ctx).Where("id = ?", sysDictionary.ID).Preload("SysDictionaryDetails").First(&sysDictionary).Error
 	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
 		return errors.New("请不要搞事")
 	}
 	if err != nil {
 		return err
 	}
-	err = global.GVA_DB.Delete(&sysDictionary).Error
+	err = global.GVA_DB.WithContext(ctx).Delete(&sysDictionary).Error
 	if err != nil {
 		return err
 	}
+	defer dictCache.invalidate(sysDictionary.Type)
 
 	if sysDictionary.SysDictionaryDetails != nil {
-		return global.GVA_DB.Where("sys_dictionary_id=?", sysDictionary.ID).Delete(sysDictionary.SysDictionaryDetails).Error
+		return global.GVA_DB.WithContext(ctx).Where("sys_dictionary_id=?", sysDictionary.ID).Delete(sysDictionary.SysDictionaryDetails).Error
 	}
 	return
 }
@@ -60,7 +65,7 @@ func (dictionaryService *DictionaryService) DeleteSysDictionary(sysDictionary sy
 //@param: sysDictionary *model.SysDictionary
 //@return: err error
 
-func (dictionaryService *DictionaryService) UpdateSysDictionary(sysDictionary *system.SysDictionary) (err error) {
+func (dictionaryService *DictionaryService) UpdateSysDictionary(ctx context.Context, sysDictionary *system.SysDictionary) (err error) {
 	var dict system.SysDictionary
 	sysDictionaryMap := map[string]interface{}{
 		"Name":     sysDictionary.Name,
@@ -69,13 +74,13 @@ func (dictionaryService *DictionaryService) UpdateSysDictionary(sysDictionary *s
 		"Desc":     sysDictionary.Desc,
 		"ParentID": sysDictionary.ParentID,
 	}
-	err = global.GVA_DB.Where("id = ?", sysDictionary.ID).First(&dict).Error
+	err = global.GVA_DB.WithContext(ctx).Where("id = ?", sysDictionary.ID).First(&dict).Error
 	if err != nil {
 		global.GVA_LOG.Debug(err.Error())
 		return errors.New("查询字典数据失败")
 	}
 	if dict.Type != sysDictionary.Type {
-		if !errors.Is(global.GVA_DB.First(&system.SysDictionary{}, "type = ?", sysDictionary.Type).Error, gorm.ErrRecordNotFound) {
+		if !errors.Is(global.GVA_DB.WithContext(ctx).First(&system.SysDictionary{}, "type = ?", sysDictionary.Type).Error, gorm.ErrRecordNotFound) {
 			return errors.New("存在相同的type，不允许创建")
 		}
 	}
@@ -87,17 +92,25 @@ func (dictionaryService *DictionaryService) UpdateSysDictionary(sysDictionary *s
 		}
 	}
 
-	err = global.GVA_DB.Model(&dict).Updates(sysDictionaryMap).Error
+	oldType := dict.Type
+	err = global.GVA_DB.WithContext(ctx).Model(&dict).Updates(sysDictionaryMap).Error
+	if err == nil {
+		dictCache.invalidate(oldType, sysDictionary.Type)
+	}
 	return err
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: GetSysDictionary
-//@description: 根据id或者type获取字典单条数据
+//@description: 根据id或者type获取字典单条数据 只按type获取启用的字典时使用缓存
 //@param: Type string, Id uint
 //@return: err error, sysDictionary model.SysDictionary
 
 func (dictionaryService *DictionaryService) GetSysDictionary(Type string, Id uint, status *bool) (sysDictionary system.SysDictionary, err error) {
+	if Type != "" && Id == 0 && (status == nil || *status) {
+		sysDictionary, _, err = dictionaryService.GetSysDictionaryByType(context.Background(), Type)
+		return
+	}
 	var flag = false
 	if status == nil {
 		flag = true
@@ -0,0 +1,311 @@
+package system
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	dictionaryCacheKeyPrefix = "GVA_Dictionary:"           // redis 中每个字典类型一个键
+	dictionaryCacheChannel   = "GVA_Dictionary:invalidate" // 字典变更后通知各实例清除本地缓存
+	dictionaryCacheTTL       = 600 * time.Second
+)
+
+// dictionaryCacheEntry 一个字典类型的缓存 Dictionary 只包含启用的字典详情 Tree 包含全部字典详情
+// 字典不存在时 Dictionary.ID 为0 同样缓存 避免不存在的类型每次都查询数据库
+type dictionaryCacheEntry struct {
+	Dictionary system.SysDictionary         `json:"dictionary"`
+	Tree       []system.SysDictionaryDetail `json:"tree"`
+	ETag       string                       `json:"etag"`
+}
+
+type dictionaryCacheItem struct {
+	entry  *dictionaryCacheEntry
+	expire time.Time
+}
+
+// dictionaryCache 按字典类型缓存 本地缓存在前 开启redis时redis在后并由各实例共享
+// 写入字典的接口在成功后清除对应类型 开启redis时通过发布订阅清除其他实例的本地缓存
+type dictionaryCache struct {
+	mu    sync.RWMutex
+	items map[string]dictionaryCacheItem
+	// version 每次清除加一 清除前开始加载的数据可能已过期 不再写入缓存
+	version   uint64
+	group     singleflight.Group
+	subscribe sync.Once
+}
+
+var dictCache = &dictionaryCache{items: make(map[string]dictionaryCacheItem)}
+
+func (c *dictionaryCache) ttl() time.Duration {
+	ttl := global.GVA_CONFIG.Dictionary.CacheTTL
+	if ttl == 0 {
+		return dictionaryCacheTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// get 获取字典类型的缓存 未命中时从redis或数据库加载 同一类型同时只加载一次
+func (c *dictionaryCache) get(ctx context.Context, t string) (*dictionaryCacheEntry, error) {
+	ttl := c.ttl()
+	if ttl <= 0 {
+		return loadDictionaryCacheEntry(ctx, t)
+	}
+	c.mu.RLock()
+	item, ok := c.items[t]
+	version := c.version
+	c.mu.RUnlock()
+	if ok && time.Now().Before(item.expire) {
+		return item.entry, nil
+	}
+	// 加载结果由同时等待的请求共享 不随发起请求的取消而中断
+	ctx = context.WithoutCancel(ctx)
+	v, err, _ := c.group.Do(t, func() (interface{}, error) {
+		if entry := c.getRedis(ctx, t); entry != nil {
+			c.set(t, entry, version, ttl, false)
+			return entry, nil
+		}
+		entry, err := loadDictionaryCacheEntry(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		c.set(t, entry, version, ttl, true)
+		return entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*dictionaryCacheEntry), nil
+}
+
+// set 写入本地缓存 toRedis 时同时写入redis 加载期间发生过清除时放弃写入
+func (c *dictionaryCache) set(t string, entry *dictionaryCacheEntry, version uint64, ttl time.Duration, toRedis bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return
+	}
+	c.items[t] = dictionaryCacheItem{entry: entry, expire: time.Now().Add(ttl)}
+	if toRedis && global.GVA_REDIS != nil {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		if err = global.GVA_REDIS.Set(context.Background(), dictionaryCacheKeyPrefix+t, data, ttl).Err(); err != nil {
+			global.GVA_LOG.Warn("写入字典缓存失败", zap.String("type", t), zap.Error(err))
+		}
+	}
+}
+
+func (c *dictionaryCache) getRedis(ctx context.Context, t string) *dictionaryCacheEntry {
+	if global.GVA_REDIS == nil {
+		return nil
+	}
+	data, err := global.GVA_REDIS.Get(ctx, dictionaryCacheKeyPrefix+t).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			global.GVA_LOG.Warn("读取字典缓存失败", zap.String("type", t), zap.Error(err))
+		}
+		return nil
+	}
+	var entry dictionaryCacheEntry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// drop 清除本地缓存 types 为空时清除全部
+func (c *dictionaryCache) drop(types ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	if len(types) == 0 {
+		clear(c.items)
+		return
+	}
+	for _, t := range types {
+		delete(c.items, t)
+	}
+}
+
+// invalidate 字典写入成功后清除本地与redis中的缓存 并通知其他实例
+func (c *dictionaryCache) invalidate(types ...string) {
+	types = compactDictionaryTypes(types)
+	if len(types) == 0 {
+		return
+	}
+	c.drop(types...)
+	if global.GVA_REDIS == nil {
+		return
+	}
+	ctx := context.Background()
+	keys := make([]string, len(types))
+	for i, t := range types {
+		keys[i] = dictionaryCacheKeyPrefix + t
+	}
+	if err := global.GVA_REDIS.Del(ctx, keys...).Err(); err != nil {
+		global.GVA_LOG.Warn("清除字典缓存失败", zap.Strings("types", types), zap.Error(err))
+	}
+	data, _ := json.Marshal(types)
+	if err := global.GVA_REDIS.Publish(ctx, dictionaryCacheChannel, data).Err(); err != nil {
+		global.GVA_LOG.Warn("发布字典变更失败", zap.Strings("types", types), zap.Error(err))
+	}
+}
+
+// StartDictionaryCacheSync 开启redis时订阅字典变更 清除本实例的本地缓存 需在redis初始化后调用
+func StartDictionaryCacheSync() {
+	if global.GVA_REDIS == nil {
+		return
+	}
+	dictCache.subscribe.Do(func() {
+		pubsub := global.GVA_REDIS.Subscribe(context.Background(), dictionaryCacheChannel)
+		go func() {
+			for msg := range pubsub.Channel() {
+				var types []string
+				if err := json.Unmarshal([]byte(msg.Payload), &types); err != nil {
+					global.GVA_LOG.Warn("字典变更通知格式错误", zap.String("payload", msg.Payload))
+					continue
+				}
+				dictCache.drop(types...)
+			}
+		}()
+	})
+}
+
+func compactDictionaryTypes(types []string) []string {
+	result := make([]string, 0, len(types))
+	seen := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		if _, ok := seen[t]; ok || t == "" {
+			continue
+		}
+		seen[t] = struct{}{}
+		result = append(result, t)
+	}
+	return result
+}
+
+// loadDictionaryCacheEntry 从数据库加载字典类型 字典详情一次查出后在内存中组装为树
+func loadDictionaryCacheEntry(ctx context.Context, t string) (*dictionaryCacheEntry, error) {
+	entry := &dictionaryCacheEntry{Tree: []system.SysDictionaryDetail{}}
+	result := global.GVA_DB.WithContext(ctx).Where("type = ?", t).Limit(1).Find(&entry.Dictionary)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		var details []system.SysDictionaryDetail
+		err := global.GVA_DB.WithContext(ctx).Where("sys_dictionary_id = ?", entry.Dictionary.ID).Order("sort").Order("id").Find(&details).Error
+		if err != nil {
+			return nil, err
+		}
+		enabled := make([]system.SysDictionaryDetail, 0, len(details))
+		for _, detail := range details {
+			if detail.Status != nil && *detail.Status {
+				enabled = append(enabled, detail)
+			}
+		}
+		entry.Dictionary.SysDictionaryDetails = enabled
+		entry.Tree = buildDictionaryTree(details)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	entry.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	return entry, nil
+}
+
+// buildDictionaryTree 按 ParentID 组装字典详情树 details 需已排序 父级不存在的字典详情不输出
+func buildDictionaryTree(details []system.SysDictionaryDetail) []system.SysDictionaryDetail {
+	children := make(map[uint][]system.SysDictionaryDetail)
+	var roots []system.SysDictionaryDetail
+	for _, detail := range details {
+		detail.Disabled = detail.Status != nil && !*detail.Status
+		if detail.ParentID == nil {
+			roots = append(roots, detail)
+		} else {
+			children[*detail.ParentID] = append(children[*detail.ParentID], detail)
+		}
+	}
+	var attach func(nodes []system.SysDictionaryDetail) []system.SysDictionaryDetail
+	attach = func(nodes []system.SysDictionaryDetail) []system.SysDictionaryDetail {
+		result := make([]system.SysDictionaryDetail, len(nodes))
+		for i, node := range nodes {
+			node.Children = attach(children[node.ID])
+			result[i] = node
+		}
+		return result
+	}
+	return attach(roots)
+}
+
+// dictionaryTypeByID 字典ID对应的类型 包含已删除的字典 用于写入后清除缓存
+func dictionaryTypeByID(id uint) string {
+	var types []string
+	global.GVA_DB.Unscoped().Model(&system.SysDictionary{}).Where("id = ?", id).Limit(1).Pluck("type", &types)
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+// dictionaryTypeByDetailID 字典详情所属字典的类型 包含已删除的字典详情
+func dictionaryTypeByDetailID(id uint) string {
+	var dictionaryIDs []uint
+	global.GVA_DB.Unscoped().Model(&system.SysDictionaryDetail{}).Where("id = ?", id).Limit(1).Pluck("sys_dictionary_id", &dictionaryIDs)
+	if len(dictionaryIDs) == 0 {
+		return ""
+	}
+	return dictionaryTypeByID(dictionaryIDs[0])
+}
+
+// GetSysDictionaryByType 按类型获取启用的字典及其启用的字典详情 结果来自缓存 不要修改
+func (dictionaryService *DictionaryService) GetSysDictionaryByType(ctx context.Context, t string) (sysDictionary system.SysDictionary, etag string, err error) {
+	entry, err := dictCache.get(ctx, t)
+	if err != nil {
+		return sysDictionary, "", err
+	}
+	if entry.Dictionary.ID == 0 || entry.Dictionary.Status == nil || !*entry.Dictionary.Status {
+		return sysDictionary, "", gorm.ErrRecordNotFound
+	}
+	return entry.Dictionary, entry.ETag, nil
+}
+
+// GetDictionaryTreeByType 按类型获取字典详情树 包含停用的字典详情 结果来自缓存 不要修改
+func (dictionaryDetailService *DictionaryDetailService) GetDictionaryTreeByType(ctx context.Context, t string) (list []system.SysDictionaryDetail, etag string, err error) {
+	entry, err := dictCache.get(ctx, t)
+	if err != nil {
+		return nil, "", err
+	}
+	return entry.Tree, entry.ETag, nil
+}
+
+// GetDictionaryTreesByTypes 批量按类型获取字典详情树 返回类型到树的映射与所有类型合并的 ETag
+func (dictionaryDetailService *DictionaryDetailService) GetDictionaryTreesByTypes(ctx context.Context, types []string) (trees map[string][]system.SysDictionaryDetail, etag string, err error) {
+	types = compactDictionaryTypes(types)
+	trees = make(map[string][]system.SysDictionaryDetail, len(types))
+	hash := sha256.New()
+	for _, t := range types {
+		entry, err := dictCache.get(ctx, t)
+		if err != nil {
+			return nil, "", err
+		}
+		trees[t] = entry.Tree
+		hash.Write([]byte(t + "=" + entry.ETag + "\n"))
+	}
+	return trees, `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, nil
+}
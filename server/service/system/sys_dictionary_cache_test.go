@@ -0,0 +1,99 @@
+package system
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBuildDictionaryTree(t *testing.T) {
+	enabled, disabled := true, false
+	detail := func(id uint, parent uint, label string, status *bool) system.SysDictionaryDetail {
+		d := system.SysDictionaryDetail{Label: label, Status: status}
+		d.ID = id
+		if parent != 0 {
+			d.ParentID = &parent
+		}
+		return d
+	}
+	tree := buildDictionaryTree([]system.SysDictionaryDetail{
+		detail(1, 0, "华东", &enabled),
+		detail(2, 1, "上海", &enabled),
+		detail(3, 1, "杭州", &disabled),
+		detail(4, 0, "华北", &disabled),
+		detail(5, 4, "北京", &enabled),
+		// 父级不存在的字典详情不输出
+		detail(6, 99, "孤立", &enabled),
+	})
+	if !assert.Len(t, tree, 2) {
+		return
+	}
+	assert.Equal(t, "华东", tree[0].Label)
+	assert.False(t, tree[0].Disabled)
+	if assert.Len(t, tree[0].Children, 2) {
+		assert.Equal(t, "上海", tree[0].Children[0].Label)
+		assert.Equal(t, "杭州", tree[0].Children[1].Label)
+		assert.True(t, tree[0].Children[1].Disabled)
+		assert.Empty(t, tree[0].Children[0].Children)
+	}
+	assert.True(t, tree[1].Disabled)
+	if assert.Len(t, tree[1].Children, 1) {
+		assert.Equal(t, "北京", tree[1].Children[0].Label)
+	}
+	assert.Empty(t, buildDictionaryTree(nil))
+}
+
+func TestImportDictionariesInvalidatesCache(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/dictionary.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&system.SysDictionary{}, &system.SysDictionaryDetail{}); err != nil {
+		t.Fatal(err)
+	}
+	global.GVA_DB = db
+	global.GVA_LOG = zap.NewNop()
+	dictCache.drop()
+	t.Cleanup(func() { dictCache.drop() })
+
+	ctx := context.Background()
+	// 不存在的类型同样被缓存
+	_, _, err = DictionaryServiceApp.GetSysDictionaryByType(ctx, "gender")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	tree, emptyETag, err := DictionaryDetailServiceApp.GetDictionaryTreeByType(ctx, "gender")
+	assert.NoError(t, err)
+	assert.Empty(t, tree)
+
+	enabled := true
+	assert.NoError(t, SysVersionServiceApp.ImportDictionaries([]system.SysDictionary{{
+		Name:   "性别",
+		Type:   "gender",
+		Status: &enabled,
+		SysDictionaryDetails: []system.SysDictionaryDetail{
+			{Label: "男", Value: "1", Status: &enabled, Sort: 1},
+			{Label: "女", Value: "2", Status: &enabled, Sort: 2},
+		},
+	}}))
+
+	dictionary, etag, err := DictionaryServiceApp.GetSysDictionaryByType(ctx, "gender")
+	assert.NoError(t, err)
+	assert.Len(t, dictionary.SysDictionaryDetails, 2)
+	tree, treeETag, err := DictionaryDetailServiceApp.GetDictionaryTreeByType(ctx, "gender")
+	assert.NoError(t, err)
+	assert.Len(t, tree, 2)
+	assert.Equal(t, etag, treeETag)
+	assert.NotEqual(t, emptyETag, etag)
+
+	// 已存在的类型跳过 缓存不变
+	assert.NoError(t, SysVersionServiceApp.ImportDictionaries([]system.SysDictionary{{Name: "性别", Type: "gender", Status: &enabled}}))
+	_, again, err := DictionaryServiceApp.GetSysDictionaryByType(ctx, "gender")
+	assert.NoError(t, err)
+	assert.Equal(t, etag, again)
+}
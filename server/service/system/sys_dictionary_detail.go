@@ -1,6 +1,7 @@
 package system
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -39,6 +40,9 @@ func (dictionaryDetailService *DictionaryDetailService) CreateSysDictionaryDetai
 	}
 
 	err = global.GVA_DB.Create(&sysDictionaryDetail).Error
+	if err == nil {
+		dictCache.invalidate(dictionaryTypeByID(uint(sysDictionaryDetail.SysDictionaryID)))
+	}
 	return err
 }
 
@@ -60,6 +64,9 @@ func (dictionaryDetailService *DictionaryDetailService) DeleteSysDictionaryDetai
 	}
 
 	err = global.GVA_DB.Delete(&sysDictionaryDetail).Error
+	if err == nil {
+		dictCache.invalidate(dictionaryTypeByDetailID(sysDictionaryDetail.ID))
+	}
 	return err
 }
 
@@ -94,10 +101,13 @@ func (dictionaryDetailService *DictionaryDetailService) UpdateSysDictionaryDetai
 		sysDictionaryDetail.Path = ""
 	}
 
+	// 字典详情可能被移动到其他字典 新旧字典的缓存都需要清除
+	oldType := dictionaryTypeByDetailID(sysDictionaryDetail.ID)
 	err = global.GVA_DB.Save(sysDictionaryDetail).Error
 	if err != nil {
 		return err
 	}
+	defer dictCache.invalidate(oldType, dictionaryTypeByID(uint(sysDictionaryDetail.SysDictionaryID)))
 
 	// 更新所有子项的层级和路径
 	return dictionaryDetailService.updateChildrenLevelAndPath(sysDictionaryDetail.ID)
@@ -314,35 +324,10 @@ func (dictionaryDetailService *DictionaryDetailService) GetDictionaryListByType(
 	return sysDictionaryDetails, err
 }
 
-// GetDictionaryTreeListByType 根据字典类型获取树形结构
+// GetDictionaryTreeListByType 根据字典类型获取树形结构 结果来自缓存
 func (dictionaryDetailService *DictionaryDetailService) GetDictionaryTreeListByType(t string) (list []system.SysDictionaryDetail, err error) {
-	var sysDictionaryDetails []system.SysDictionaryDetail
-	db := global.GVA_DB.Model(&system.SysDictionaryDetail{}).
-		Joins("JOIN sys_dictionaries ON sys_dictionaries.id = sys_dictionary_details.sys_dictionary_id").
-		Where("sys_dictionaries.type = ? AND sys_dictionary_details.parent_id IS NULL", t).
-		Order("sys_dictionary_details.sort")
-
-	err = db.Find(&sysDictionaryDetails).Error
-	if err != nil {
-		return nil, err
-	}
-
-	// 递归加载子项并设置disabled属性
-	for i := range sysDictionaryDetails {
-		// 设置disabled属性：当status为false时，disabled为true
-		if sysDictionaryDetails[i].Status != nil {
-			sysDictionaryDetails[i].Disabled = !*sysDictionaryDetails[i].Status
-		} else {
-			sysDictionaryDetails[i].Disabled = false // 默认不禁用
-		}
-		
-		err = dictionaryDetailService.loadChildren(&sysDictionaryDetails[i])
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return sysDictionaryDetails, nil
+	list, _, err = dictionaryDetailService.GetDictionaryTreeByType(context.Background(), t)
+	return list, err
 }
 
 // 按照字典id+字典内容value获取单条字典内容
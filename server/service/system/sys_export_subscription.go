@@ -0,0 +1,453 @@
+package system
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/upload"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExportSubscriptionHandler 导出订阅对应的定时任务的执行方法 参数为订阅ID
+const ExportSubscriptionHandler = "ExportSubscription"
+
+// exportFileNameReplacer 订阅名称中不能用于文件名的字符
+var exportFileNameReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_")
+
+type ExportSubscriptionService struct{}
+
+var ExportSubscriptionServiceApp = new(ExportSubscriptionService)
+
+// StartExportSubscriptions 为还没有定时任务的订阅创建任务 订阅由定时任务调度执行
+// 多个节点同时启动时按任务名称只创建一次 其他节点更新并调度同一个任务
+func (exportSubscriptionService *ExportSubscriptionService) StartExportSubscriptions() error {
+	var subscriptions []system.SysExportSubscription
+	if err := global.GVA_DB.Where("job_id = 0 OR job_id IS NULL").Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	for i := range subscriptions {
+		if err := syncExportSubscriptionJob(&subscriptions[i]); err != nil {
+			global.GVA_LOG.Error("create export subscription job error:", zap.String("subscription", subscriptions[i].Name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func exportSubscriptionJobName(id uint) string {
+	return "导出订阅#" + strconv.FormatUint(uint64(id), 10)
+}
+
+// syncExportSubscriptionJob 按订阅的配置创建或更新对应的定时任务 任务在定时任务管理中被删除时恢复
+// 只同步名称、周期、参数、启用状态与说明 执行策略、超时、重试与重叠策略可在定时任务管理中调整
+// 任务名称唯一 同时创建时只有一个能创建成功 其余的按已创建的任务更新
+func syncExportSubscriptionJob(subscription *system.SysExportSubscription) error {
+	name := exportSubscriptionJobName(subscription.ID)
+	job, err := findExportSubscriptionJob(subscription.JobID, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		job = system.SysJob{Name: name}
+		setExportSubscriptionJob(&job, *subscription)
+		result := global.GVA_DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err = scheduleJob(job); err != nil {
+				return err
+			}
+			return setExportSubscriptionJobID(subscription, job.ID)
+		}
+		job, err = findExportSubscriptionJob(0, name)
+	}
+	if err != nil {
+		return err
+	}
+	if job.DeletedAt.Valid {
+		// 已删除的任务仍占用名称 恢复后按订阅更新
+		if err = global.GVA_DB.Unscoped().Model(&system.SysJob{}).Where("id = ?", job.ID).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+	}
+	setExportSubscriptionJob(&job, *subscription)
+	if err = JobServiceApp.UpdateJob(job); err != nil {
+		return err
+	}
+	return setExportSubscriptionJobID(subscription, job.ID)
+}
+
+// findExportSubscriptionJob 按任务ID查找订阅对应的定时任务 找不到时按任务名称查找 包括已删除的任务
+func findExportSubscriptionJob(id uint, name string) (job system.SysJob, err error) {
+	if id != 0 {
+		result := global.GVA_DB.Unscoped().Where("id = ?", id).Limit(1).Find(&job)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return job, result.Error
+		}
+	}
+	err = global.GVA_DB.Unscoped().Where("name = ?", name).First(&job).Error
+	return job, err
+}
+
+// setExportSubscriptionJob 将订阅的配置写入定时任务
+func setExportSubscriptionJob(job *system.SysJob, subscription system.SysExportSubscription) {
+	job.Name = exportSubscriptionJobName(subscription.ID)
+	job.Handler = ExportSubscriptionHandler
+	job.Spec = subscription.Spec
+	job.Params = strconv.FormatUint(uint64(subscription.ID), 10)
+	job.Enabled = subscription.Enabled
+	job.Description = "导出订阅 " + subscription.Name
+}
+
+func setExportSubscriptionJobID(subscription *system.SysExportSubscription, jobID uint) error {
+	if subscription.JobID == jobID {
+		return nil
+	}
+	subscription.JobID = jobID
+	return global.GVA_DB.Model(&system.SysExportSubscription{}).Where("id = ?", subscription.ID).Update("job_id", jobID).Error
+}
+
+// exportSubscriptionByParams 按定时任务的参数获取订阅
+func exportSubscriptionByParams(params string) (subscription system.SysExportSubscription, err error) {
+	id, err := strconv.ParseUint(strings.TrimSpace(params), 10, 64)
+	if err != nil {
+		return subscription, fmt.Errorf("订阅ID %q 无效", params)
+	}
+	if err = global.GVA_DB.Where("id = ?", id).First(&subscription).Error; err != nil {
+		return subscription, fmt.Errorf("订阅 %d 不存在", id)
+	}
+	return subscription, nil
+}
+
+// RunExportSubscription 定时任务的执行方法 按订阅导出并发送 返回的文件与发送结果保存在执行记录中
+func (exportSubscriptionService *ExportSubscriptionService) RunExportSubscription(ctx context.Context, params string) (string, error) {
+	subscription, err := exportSubscriptionByParams(params)
+	if err != nil {
+		return "", err
+	}
+	result, err := deliverExportSubscription(ctx, subscription, time.Now())
+	return result.String(), err
+}
+
+// exportSubscriptionResult 一次导出发送的结果
+type exportSubscriptionResult struct {
+	FileName string
+	Rows     int
+	Size     int64
+	Delivery []string
+}
+
+func (r exportSubscriptionResult) String() string {
+	if r.FileName == "" {
+		return ""
+	}
+	lines := append([]string{
+		"文件: " + r.FileName,
+		"行数: " + strconv.Itoa(r.Rows),
+		"大小: " + strconv.FormatInt(r.Size, 10),
+	}, r.Delivery...)
+	return strings.Join(lines, "\n")
+}
+
+// deliverExportSubscription 按订阅创建者的角色导出到临时文件 先上传 OSS 再发送邮件 邮件中附带 OSS 地址
+func deliverExportSubscription(ctx context.Context, subscription system.SysExportSubscription, startedAt time.Time) (result exportSubscriptionResult, err error) {
+	if err = SysExportTemplateServiceApp.CheckTemplateAuthority(subscription.TemplateID, subscription.AuthorityID); err != nil {
+		return
+	}
+	export, err := SysExportTemplateServiceApp.PrepareExport(subscription.TemplateID, url.Values{
+		"params": {subscription.Params},
+		"format": {subscription.Format},
+	})
+	if err != nil {
+		return
+	}
+	dir, err := os.MkdirTemp("", "export-subscription-*")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	result.FileName = exportFileNameReplacer.Replace(subscription.Name) + "_" + startedAt.Format("20060102150405") + "." + export.Format
+	filePath := filepath.Join(dir, result.FileName)
+	if err = writeExportFile(ctx, export, filePath); err != nil {
+		return
+	}
+	result.Rows = export.Rows
+	if info, err := os.Stat(filePath); err == nil {
+		result.Size = info.Size()
+	}
+
+	var ossURL string
+	if subscription.OssPath != "" {
+		if ossURL, _, err = upload.UploadLocalFileAs(upload.NewOss(), filePath, path.Join(subscription.OssPath, result.FileName)); err != nil {
+			return result, fmt.Errorf("上传OSS失败: %w", err)
+		}
+		result.Delivery = append(result.Delivery, "OSS: "+ossURL)
+	}
+	if subscription.Recipients != "" {
+		body := fmt.Sprintf("<p>%s 于 %s 导出 共 %d 行 见附件</p>", html.EscapeString(subscription.Name), startedAt.Format(time.DateTime), result.Rows)
+		if ossURL != "" {
+			body += fmt.Sprintf(`<p>OSS: <a href="%s">%s</a></p>`, html.EscapeString(ossURL), html.EscapeString(ossURL))
+		}
+		if err = emailUtils.EmailWithAttachments(subscription.Recipients, cmp.Or(subscription.Subject, subscription.Name), body, filePath); err != nil {
+			return result, fmt.Errorf("发送邮件失败: %w", err)
+		}
+		result.Delivery = append(result.Delivery, "邮件: "+subscription.Recipients)
+	}
+	return result, nil
+}
+
+func writeExportFile(ctx context.Context, export *TemplateExport, name string) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = export.Stream(ctx, file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// NotifyExportSubscriptionFailure 定时执行重试后仍失败时调用 设置了 AlertTo 时发送到 AlertTo 否则按 job.notify-email 通知
+func NotifyExportSubscriptionFailure(params string, err error) {
+	subscription, loadErr := exportSubscriptionByParams(params)
+	if loadErr != nil {
+		NotifyTaskFailure("导出订阅 "+params, err)
+		return
+	}
+	taskName := "导出订阅 " + subscription.Name
+	if subscription.AlertTo == "" {
+		NotifyTaskFailure(taskName, err)
+		return
+	}
+	subject := utils.NodeID() + " " + taskName + " 执行失败"
+	body := "任务: " + taskName + "\n节点: " + utils.NodeID() + "\n时间: " + time.Now().Format(time.DateTime) + "\n错误: " + err.Error() + "\n"
+	if err := emailUtils.Email(subscription.AlertTo, subject, body); err != nil {
+		global.GVA_LOG.Error("notify export subscription failure by email error:", zap.String("subscription", subscription.Name), zap.Error(err))
+	}
+}
+
+// normalizeEmails 校验逗号分隔的邮箱 去掉空白与空项
+func normalizeEmails(field, value string) (string, error) {
+	var emails []string
+	for _, address := range strings.Split(value, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(address); err != nil {
+			return "", fmt.Errorf("%s %s 不是有效的邮箱", field, address)
+		}
+		emails = append(emails, address)
+	}
+	return strings.Join(emails, ","), nil
+}
+
+// validateExportSubscription 校验并规范订阅配置 模板须存在、支持所选格式且订阅者的角色可以导出
+func validateExportSubscription(subscription *system.SysExportSubscription) (err error) {
+	if subscription.Recipients, err = normalizeEmails("收件人", subscription.Recipients); err != nil {
+		return err
+	}
+	if subscription.AlertTo, err = normalizeEmails("失败通知邮箱", subscription.AlertTo); err != nil {
+		return err
+	}
+	subscription.OssPath = strings.Trim(path.Clean("/"+strings.TrimSpace(subscription.OssPath)), "/")
+	if subscription.Recipients == "" && subscription.OssPath == "" {
+		return errors.New("收件人和OSS目录至少填写一项")
+	}
+	if _, err = jobParser.Parse(subscription.Spec); err != nil {
+		return fmt.Errorf("cron表达式无效: %w", err)
+	}
+	if _, err = url.ParseQuery(subscription.Params); err != nil {
+		return fmt.Errorf("解析 params 参数失败: %v", err)
+	}
+	subscription.Format = cmp.Or(subscription.Format, system.ExportFormatXlsx)
+	var template system.SysExportTemplate
+	if err = global.GVA_DB.Where("template_id = ?", subscription.TemplateID).First(&template).Error; err != nil {
+		return fmt.Errorf("导出模板 %s 不存在", subscription.TemplateID)
+	}
+	if !template.AllowFormat(subscription.Format) {
+		return fmt.Errorf("模板不支持导出格式 %s", subscription.Format)
+	}
+	return SysExportTemplateServiceApp.CheckTemplateAuthority(subscription.TemplateID, subscription.AuthorityID)
+}
+
+// CreateExportSubscription 创建导出订阅及对应的定时任务 启用时立即调度
+func (exportSubscriptionService *ExportSubscriptionService) CreateExportSubscription(subscription *system.SysExportSubscription) error {
+	if err := validateExportSubscription(subscription); err != nil {
+		return err
+	}
+	subscription.JobID = 0
+	if err := global.GVA_DB.Create(subscription).Error; err != nil {
+		return err
+	}
+	if err := syncExportSubscriptionJob(subscription); err != nil {
+		global.GVA_DB.Unscoped().Delete(&system.SysExportSubscription{}, "id = ?", subscription.ID)
+		return err
+	}
+	return nil
+}
+
+// UpdateExportSubscription 更新导出订阅 并按新的配置更新定时任务 之后按更新者的角色导出
+func (exportSubscriptionService *ExportSubscriptionService) UpdateExportSubscription(subscription system.SysExportSubscription) error {
+	if err := validateExportSubscription(&subscription); err != nil {
+		return err
+	}
+	err := global.GVA_DB.Model(&system.SysExportSubscription{}).Where("id = ?", subscription.ID).
+		Select("name", "template_id", "params", "format", "spec", "enabled", "recipients", "subject", "oss_path", "alert_to", "authority_id").Updates(&subscription).Error
+	if err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Where("id = ?", subscription.ID).First(&subscription).Error; err != nil {
+		return err
+	}
+	return syncExportSubscriptionJob(&subscription)
+}
+
+// DeleteExportSubscription 删除导出订阅及对应的定时任务 执行记录保留
+func (exportSubscriptionService *ExportSubscriptionService) DeleteExportSubscription(id uint) error {
+	var subscription system.SysExportSubscription
+	if err := global.GVA_DB.Where("id = ?", id).First(&subscription).Error; err != nil {
+		return err
+	}
+	if err := global.GVA_DB.Delete(&subscription).Error; err != nil {
+		return err
+	}
+	if subscription.JobID == 0 {
+		return nil
+	}
+	return JobServiceApp.DeleteJob(subscription.JobID)
+}
+
+// SetExportSubscriptionEnabled 启用或停用导出订阅
+func (exportSubscriptionService *ExportSubscriptionService) SetExportSubscriptionEnabled(id uint, enabled bool) error {
+	var subscription system.SysExportSubscription
+	if err := global.GVA_DB.Where("id = ?", id).First(&subscription).Error; err != nil {
+		return err
+	}
+	if err := global.GVA_DB.Model(&subscription).Update("enabled", enabled).Error; err != nil {
+		return err
+	}
+	subscription.Enabled = enabled
+	return syncExportSubscriptionJob(&subscription)
+}
+
+// TriggerExportSubscription 立即导出发送一次 不论是否启用 返回定时任务的执行记录 导出在后台执行
+func (exportSubscriptionService *ExportSubscriptionService) TriggerExportSubscription(id uint) (run system.SysJobRun, err error) {
+	var subscription system.SysExportSubscription
+	if err = global.GVA_DB.Where("id = ?", id).First(&subscription).Error; err != nil {
+		return
+	}
+	run, err = JobServiceApp.TriggerJob(subscription.JobID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 定时任务已在定时任务管理中被删除 按订阅重新创建
+		if err = syncExportSubscriptionJob(&subscription); err != nil {
+			return
+		}
+		run, err = JobServiceApp.TriggerJob(subscription.JobID)
+	}
+	return run, err
+}
+
+// GetExportSubscription 根据ID获取导出订阅
+func (exportSubscriptionService *ExportSubscriptionService) GetExportSubscription(id uint) (subscription system.SysExportSubscription, err error) {
+	if err = global.GVA_DB.Where("id = ?", id).First(&subscription).Error; err != nil {
+		return
+	}
+	list := []system.SysExportSubscription{subscription}
+	setExportSubscriptionJobs(list)
+	return list[0], nil
+}
+
+// GetExportSubscriptionList 分页获取导出订阅列表
+func (exportSubscriptionService *ExportSubscriptionService) GetExportSubscriptionList(info systemReq.SysExportSubscriptionSearch) (list []system.SysExportSubscription, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysExportSubscription{})
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.TemplateID != "" {
+		db = db.Where("template_id = ?", info.TemplateID)
+	}
+	if info.Enabled != nil {
+		db = db.Where("enabled = ?", *info.Enabled)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	if err = db.Order("id").Find(&list).Error; err != nil {
+		return
+	}
+	setExportSubscriptionJobs(list)
+	return list, total, nil
+}
+
+// setExportSubscriptionJobs 从对应的定时任务读取订阅的最后执行状态与下一次执行时间
+func setExportSubscriptionJobs(list []system.SysExportSubscription) {
+	ids := make([]uint, 0, len(list))
+	for _, subscription := range list {
+		if subscription.JobID != 0 {
+			ids = append(ids, subscription.JobID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	var jobs []system.SysJob
+	if err := global.GVA_DB.Where("id IN ?", ids).Find(&jobs).Error; err != nil {
+		global.GVA_LOG.Error("load export subscription jobs error:", zap.Error(err))
+		return
+	}
+	byID := make(map[uint]system.SysJob, len(jobs))
+	for _, job := range jobs {
+		setJobNextRun(&job)
+		byID[job.ID] = job
+	}
+	for i := range list {
+		if job, ok := byID[list[i].JobID]; ok {
+			list[i].LastRunAt, list[i].LastStatus, list[i].NextRunAt = job.LastRunAt, job.LastStatus, job.NextRunAt
+		}
+	}
+}
+
+// GetExportSubscriptionRunList 分页获取导出订阅的执行记录 即对应定时任务的执行记录
+func (exportSubscriptionService *ExportSubscriptionService) GetExportSubscriptionRunList(info systemReq.SysExportSubscriptionRunSearch) (list []system.SysJobRun, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysJobRun{}).Where("handler = ?", ExportSubscriptionHandler)
+	if info.SubscriptionID != 0 {
+		var jobIDs []uint
+		err = global.GVA_DB.Unscoped().Model(&system.SysExportSubscription{}).Where("id = ?", info.SubscriptionID).Pluck("job_id", &jobIDs).Error
+		if err != nil {
+			return
+		}
+		db = db.Where("job_id IN ?", jobIDs)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Order("id desc").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
@@ -0,0 +1,60 @@
+package system
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncExportSubscriptionJob(t *testing.T) {
+	db := newJobDB(t)
+	if err := db.AutoMigrate(&system.SysExportSubscription{}); err != nil {
+		t.Fatal(err)
+	}
+	task.Register(task.Definition{Name: ExportSubscriptionHandler, Handler: func(ctx context.Context, params string) (string, error) {
+		return "", nil
+	}})
+	subscription := system.SysExportSubscription{Name: "日报", TemplateID: "users", Spec: "0 0 8 * * *", Enabled: true, OssPath: "reports"}
+	assert.NoError(t, db.Create(&subscription).Error)
+
+	// 多个节点同时启动时只创建一个任务
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, ExportSubscriptionServiceApp.StartExportSubscriptions())
+		}()
+	}
+	wg.Wait()
+	var jobs []system.SysJob
+	assert.NoError(t, db.Where("handler = ?", ExportSubscriptionHandler).Find(&jobs).Error)
+	if !assert.Len(t, jobs, 1) {
+		return
+	}
+	job := jobs[0]
+	assert.Equal(t, exportSubscriptionJobName(subscription.ID), job.Name)
+	assert.NoError(t, db.First(&subscription, subscription.ID).Error)
+	assert.Equal(t, job.ID, subscription.JobID)
+
+	// 任务在定时任务管理中被删除后 按订阅恢复原来的任务
+	assert.NoError(t, JobServiceApp.DeleteJob(job.ID))
+	assert.NoError(t, ExportSubscriptionServiceApp.SetExportSubscriptionEnabled(subscription.ID, false))
+	var restored system.SysJob
+	assert.NoError(t, db.First(&restored, job.ID).Error)
+	assert.False(t, restored.Enabled)
+
+	// 订阅记录的任务ID丢失时按名称找到已删除的任务
+	assert.NoError(t, JobServiceApp.DeleteJob(job.ID))
+	assert.NoError(t, db.Model(&subscription).Update("job_id", 0).Error)
+	assert.NoError(t, ExportSubscriptionServiceApp.StartExportSubscriptions())
+	assert.NoError(t, db.First(&subscription, subscription.ID).Error)
+	assert.Equal(t, job.ID, subscription.JobID)
+	var count int64
+	assert.NoError(t, db.Unscoped().Model(&system.SysJob{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
@@ -2,22 +2,20 @@ package system
 
 import (
 	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"mime/multipart"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
-	"github.com/flipped-aurora/gin-vue-admin/server/utils"
-	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SysExportTemplateService struct {
@@ -28,6 +26,9 @@ var SysExportTemplateServiceApp = new(SysExportTemplateService)
 // CreateSysExportTemplate 创建导出模板记录
 // Author [piexlmax](https://github.com/piexlmax)
 func (sysExportTemplateService *SysExportTemplateService) CreateSysExportTemplate(sysExportTemplate *system.SysExportTemplate) (err error) {
+	if err = checkExportTemplate(sysExportTemplate); err != nil {
+		return err
+	}
 	err = global.GVA_DB.Create(sysExportTemplate).Error
 	return err
 }
@@ -49,6 +50,9 @@ func (sysExportTemplateService *SysExportTemplateService) DeleteSysExportTemplat
 // UpdateSysExportTemplate 更新导出模板记录
 // Author [piexlmax](https://github.com/piexlmax)
 func (sysExportTemplateService *SysExportTemplateService) UpdateSysExportTemplate(sysExportTemplate system.SysExportTemplate) (err error) {
+	if err = checkExportTemplate(&sysExportTemplate); err != nil {
+		return err
+	}
 	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		conditions := sysExportTemplate.Conditions
 		e := tx.Delete(&[]system.Condition{}, "template_id = ?", sysExportTemplate.TemplateID).Error
@@ -68,6 +72,11 @@ func (sysExportTemplateService *SysExportTemplateService) UpdateSysExportTemplat
 		if e != nil {
 			return e
 		}
+		// 排序可以清空 需单独更新零值
+		e = tx.Model(&sysExportTemplate).Select("order", "order_by").Updates(&sysExportTemplate).Error
+		if e != nil {
+			return e
+		}
 		if len(conditions) > 0 {
 			for i := range conditions {
 				conditions[i].ID = 0
@@ -128,110 +137,116 @@ func (sysExportTemplateService *SysExportTemplateService) GetSysExportTemplateIn
 // ExportExcel 导出Excel
 // Author [piexlmax](https://github.com/piexlmax)
 func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID string, values url.Values) (file *bytes.Buffer, name string, err error) {
+	export, err := sysExportTemplateService.PrepareExport(templateID, values)
+	if err != nil {
+		return nil, "", err
+	}
+	file = new(bytes.Buffer)
+	if err = export.Stream(context.Background(), file); err != nil {
+		return nil, "", err
+	}
+	return file, export.Name, nil
+}
+
+// PrepareExport 校验导出参数并构建查询 格式由 values 中的 format 指定 为空时为 xlsx
+// 返回的导出在 WriteTo 时才执行查询 逐行写出 不在内存中保存全部数据
+func (sysExportTemplateService *SysExportTemplateService) PrepareExport(templateID string, values url.Values) (export *TemplateExport, err error) {
 	var params = values.Get("params")
 	paramsValues, err := url.ParseQuery(params)
 	if err != nil {
-		return nil, "", fmt.Errorf("解析 params 参数失败: %v", err)
+		return nil, fmt.Errorf("解析 params 参数失败: %v", err)
 	}
 	var template system.SysExportTemplate
 	err = global.GVA_DB.Preload("Conditions").Preload("JoinTemplate").First(&template, "template_id = ?", templateID).Error
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-	// Create a new sheet.
-	index, err := f.NewSheet("Sheet1")
-	if err != nil {
-		fmt.Println(err)
-		return
+	format := values.Get("format")
+	if format == "" {
+		format = system.ExportFormatXlsx
 	}
-	var templateInfoMap = make(map[string]string)
-	columns, err := utils.GetJSONKeys(template.TemplateInfo)
-	if err != nil {
-		return nil, "", err
+	if !template.AllowFormat(format) {
+		return nil, fmt.Errorf("模板不支持导出格式 %s", format)
 	}
-	err = json.Unmarshal([]byte(template.TemplateInfo), &templateInfoMap)
+	db := templateDB(template)
+	schema, err := validateExportTemplate(db, template)
 	if err != nil {
-		return nil, "", err
-	}
-	var tableTitle []string
-	var selectKeyFmt []string
-	for _, key := range columns {
-		selectKeyFmt = append(selectKeyFmt, key)
-		tableTitle = append(tableTitle, templateInfoMap[key])
+		return nil, err
 	}
-
-	selects := strings.Join(selectKeyFmt, ", ")
-	var tableMap []map[string]interface{}
-	db := global.GVA_DB
-	if template.DBName != "" {
-		db = global.MustGetGlobalDBByDBName(template.DBName)
-	}
-
-	if len(template.JoinTemplate) > 0 {
-		for _, join := range template.JoinTemplate {
-			db = db.Joins(join.JOINS + " " + join.Table + " ON " + join.ON)
+	keys, columns, err := templateColumns(template)
+	if err != nil {
+		return nil, err
+	}
+	quote := db.Statement.Quote
+	export = &TemplateExport{Name: template.Name, Format: format, style: template.SheetStyle}
+	selects := make([]string, 0, len(keys))
+	for _, key := range keys {
+		sel, _ := schema.selectColumn(key)
+		column := columns[key]
+		// 设置了 lookup 时导出关联表字段的值
+		if column.Lookup != "" {
+			sel.Column, _ = schema.column(column.Lookup)
 		}
+		selects = append(selects, quote(sel.Column)+" AS "+quote(sel.Alias))
+		export.columns = append(export.columns, column)
+		export.formatters = append(export.formatters, newExportFormatter(column))
+		export.fields = append(export.fields, key)
+		export.keys = append(export.keys, sel.Alias)
+	}
+
+	for _, join := range template.JoinTemplate {
+		on := make([]string, 0, len(join.OnColumns))
+		for _, pair := range join.OnColumns {
+			left, _ := schema.column(pair.Left)
+			right, _ := schema.column(pair.Right)
+			on = append(on, quote(left)+" = "+quote(right))
+		}
+		db = db.Joins(fmt.Sprintf("%s JOIN %s ON %s", join.JoinType, quote(join.Table), strings.Join(on, " AND ")))
 	}
 
-	db = db.Select(selects).Table(template.TableName)
-
-	filterDeleted := false
-
-	filterParam := paramsValues.Get("filterDeleted")
-	if filterParam == "true" {
-		filterDeleted = true
-	}
-
-	if filterDeleted {
-		// 自动过滤主表的软删除
-		db = db.Where(fmt.Sprintf("%s.deleted_at IS NULL", template.TableName))
+	db = db.Select(strings.Join(selects, ", ")).Table(quote(template.TableName))
 
-		// 过滤关联表的软删除(如果有)
-		if len(template.JoinTemplate) > 0 {
-			for _, join := range template.JoinTemplate {
-				// 检查关联表是否有deleted_at字段
-				hasDeletedAt := sysExportTemplateService.hasDeletedAtColumn(join.Table)
-				if hasDeletedAt {
-					db = db.Where(fmt.Sprintf("%s.deleted_at IS NULL", join.Table))
-				}
+	if paramsValues.Get("filterDeleted") == "true" {
+		// 过滤主表与关联表的软删除 没有 deleted_at 字段的表不过滤
+		for _, table := range schema.tables {
+			if schema.hasColumn(table, "deleted_at") {
+				db = db.Where(quote(table+".deleted_at") + " IS NULL")
 			}
 		}
 	}
 
-	if len(template.Conditions) > 0 {
-		for _, condition := range template.Conditions {
-			sql := fmt.Sprintf("%s %s ?", condition.Column, condition.Operator)
-			value := paramsValues.Get(condition.From)
-
-			if condition.Operator == "IN" || condition.Operator == "NOT IN" {
-				sql = fmt.Sprintf("%s %s (?)", condition.Column, condition.Operator)
-			}
-
-			if value != "" {
-				if condition.Operator == "LIKE" {
-					value = "%" + value + "%"
-				}
-				db = db.Where(sql, value)
+	for _, condition := range template.Conditions {
+		value := paramsValues.Get(condition.From)
+		if value == "" {
+			continue
+		}
+		column, _ := schema.column(condition.Column)
+		column = quote(column)
+		switch condition.Operator {
+		case "IN", "NOT IN":
+			db = db.Where(fmt.Sprintf("%s %s (?)", column, condition.Operator), strings.Split(value, ","))
+		case "BETWEEN", "NOT BETWEEN":
+			bounds := strings.SplitN(value, ",", 2)
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("条件 %s 的值应为逗号分隔的两个值", condition.From)
 			}
+			db = db.Where(fmt.Sprintf("%s %s ? AND ?", column, condition.Operator), bounds[0], bounds[1])
+		case "LIKE":
+			db = db.Where(column+" LIKE ?", "%"+value+"%")
+		default:
+			db = db.Where(fmt.Sprintf("%s %s ?", column, condition.Operator), value)
 		}
 	}
-	// 通过参数传入limit
-	limit := paramsValues.Get("limit")
-	if limit != "" {
-		l, e := strconv.Atoi(limit)
-		if e == nil {
-			db = db.Limit(l)
-		}
+	// 通过参数传入limit 模板设置了limit时不能超过模板的limit
+	limit := 0
+	if template.Limit != nil && *template.Limit > 0 {
+		limit = *template.Limit
+	}
+	if l, e := strconv.Atoi(paramsValues.Get("limit")); e == nil && l > 0 && (limit == 0 || l < limit) {
+		limit = l
 	}
-	// 模板的默认limit
-	if limit == "" && template.Limit != nil && *template.Limit != 0 {
-		db = db.Limit(*template.Limit)
+	if limit > 0 {
+		db = db.Limit(limit)
 	}
 
 	// 通过参数传入offset
@@ -243,101 +258,22 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 		}
 	}
 
-	// 获取当前表的所有字段
-	table := template.TableName
-	orderColumns, err := db.Migrator().ColumnTypes(table)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// 创建一个 map 来存储字段名
-	fields := make(map[string]bool)
-
-	for _, column := range orderColumns {
-		fields[column.Name()] = true
-	}
-
-	// 通过参数传入order
-	order := paramsValues.Get("order")
-
-	if order == "" && template.Order != "" {
-		// 如果没有order入参，这里会使用模板的默认排序
-		order = template.Order
-	}
-
-	if order != "" {
-		checkOrderArr := strings.Split(order, " ")
-		orderStr := ""
-		// 检查请求的排序字段是否在字段列表中
-		if _, ok := fields[checkOrderArr[0]]; !ok {
-			return nil, "", fmt.Errorf("order by %s is not in the fields", order)
-		}
-		orderStr = checkOrderArr[0]
-		if len(checkOrderArr) > 1 {
-			if checkOrderArr[1] != "asc" && checkOrderArr[1] != "desc" {
-				return nil, "", fmt.Errorf("order by %s is not secure", order)
-			}
-			orderStr = orderStr + " " + checkOrderArr[1]
-		}
-		db = db.Order(orderStr)
-	}
-
-	err = db.Debug().Find(&tableMap).Error
-	if err != nil {
-		return nil, "", err
-	}
-	var rows [][]string
-	rows = append(rows, tableTitle)
-	for _, exTable := range tableMap {
-		var row []string
-		for _, column := range columns {
-			column = strings.ReplaceAll(column, "\"", "")
-			column = strings.ReplaceAll(column, "`", "")
-			if len(template.JoinTemplate) > 0 {
-				columnAs := strings.Split(column, " as ")
-				if len(columnAs) > 1 {
-					column = strings.TrimSpace(strings.Split(column, " as ")[1])
-				} else {
-					columnArr := strings.Split(column, ".")
-					if len(columnArr) > 1 {
-						column = strings.Split(column, ".")[1]
-					}
-				}
-			}
-			// 需要对时间类型特殊处理
-			if t, ok := exTable[column].(time.Time); ok {
-				row = append(row, t.Format("2006-01-02 15:04:05"))
-			} else {
-				row = append(row, fmt.Sprintf("%v", exTable[column]))
-			}
+	// 通过参数传入order 没有时使用模板的默认排序
+	orderBy := template.OrderBy
+	if order := paramsValues.Get("order"); order != "" {
+		if orderBy, err = parseLegacyOrder(order); err != nil {
+			return nil, err
 		}
-		rows = append(rows, row)
 	}
-	for i, row := range rows {
-		for j, colCell := range row {
-			cell := fmt.Sprintf("%s%d", getColumnName(j+1), i+1)
-
- 			var sErr error
- 			if v, err := strconv.ParseFloat(colCell, 64); err == nil {
- 			    sErr = f.SetCellValue("Sheet1", cell, v)
- 			} else if v, err := strconv.ParseInt(colCell, 10, 64); err == nil {
- 			    sErr = f.SetCellValue("Sheet1", cell, v)
- 			} else {
- 			    sErr = f.SetCellValue("Sheet1", cell, colCell)
- 			}
-
-			if sErr != nil {
-				return nil, "", sErr
-			}
+	for _, order := range orderBy {
+		column, err := schema.column(order.Column)
+		if err != nil {
+			return nil, err
 		}
+		export.orders = append(export.orders, clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: order.Desc})
 	}
-	f.SetActiveSheet(index)
-	file, err = f.WriteToBuffer()
-	if err != nil {
-		return nil, "", err
-	}
-
-	return file, template.Name, nil
+	export.db = db
+	return export, nil
 }
 
 // ExportTemplate 导出Excel模板
@@ -348,129 +284,49 @@ func (sysExportTemplateService *SysExportTemplateService) ExportTemplate(templat
 	if err != nil {
 		return nil, "", err
 	}
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-	// Create a new sheet.
-	index, err := f.NewSheet("Sheet1")
+	keys, columns, err := templateColumns(template)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, "", err
 	}
-	var templateInfoMap = make(map[string]string)
-
-	columns, err := utils.GetJSONKeys(template.TemplateInfo)
-
-	err = json.Unmarshal([]byte(template.TemplateInfo), &templateInfoMap)
+	titles := make([]system.ExportColumn, 0, len(keys))
+	for _, key := range keys {
+		titles = append(titles, columns[key])
+	}
+	file = new(bytes.Buffer)
+	writer, err := newXlsxExportWriter(file, titles, template.SheetStyle)
 	if err != nil {
 		return nil, "", err
 	}
-	var tableTitle []string
-	for _, key := range columns {
-		tableTitle = append(tableTitle, templateInfoMap[key])
-	}
-
-	for i := range tableTitle {
-		fErr := f.SetCellValue("Sheet1", fmt.Sprintf("%s%d", getColumnName(i+1), 1), tableTitle[i])
-		if fErr != nil {
-			return nil, "", fErr
-		}
+	if err = writer.Header(titles); err != nil {
+		writer.file.Close()
+		return nil, "", err
 	}
-	f.SetActiveSheet(index)
-	file, err = f.WriteToBuffer()
-	if err != nil {
+	if err = writer.Close(); err != nil {
 		return nil, "", err
 	}
 
 	return file, template.Name, nil
 }
 
-// 辅助函数：检查表是否有deleted_at列
-func (s *SysExportTemplateService) hasDeletedAtColumn(tableName string) bool {
-	var count int64
-	global.GVA_DB.Raw("SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_NAME = ? AND COLUMN_NAME = 'deleted_at'", tableName).Count(&count)
-	return count > 0
-}
-
-// ImportExcel 导入Excel
+// ImportExcel 导入Excel dryRun 为 true 时只校验不写入 数据有误时 result.Failed 大于0 且不写入任何数据
 // Author [piexlmax](https://github.com/piexlmax)
-func (sysExportTemplateService *SysExportTemplateService) ImportExcel(templateID string, file *multipart.FileHeader) (err error) {
-	var template system.SysExportTemplate
-	err = global.GVA_DB.First(&template, "template_id = ?", templateID).Error
-	if err != nil {
-		return err
-	}
-
+func (sysExportTemplateService *SysExportTemplateService) ImportExcel(templateID string, file *multipart.FileHeader, dryRun bool) (result ImportResult, err error) {
 	src, err := file.Open()
 	if err != nil {
-		return err
+		return
 	}
 	defer src.Close()
+	return sysExportTemplateService.importExcel(templateID, src, ImportExcelOptions{DryRun: dryRun})
+}
 
-	f, err := excelize.OpenReader(src)
-	if err != nil {
-		return err
-	}
-
-	rows, err := f.GetRows("Sheet1")
-	if err != nil {
-		return err
-	}
-	if len(rows) < 2 {
-		return errors.New("Excel data is not enough.\nIt should contain title row and data")
-	}
-
-	var templateInfoMap = make(map[string]string)
-	err = json.Unmarshal([]byte(template.TemplateInfo), &templateInfoMap)
+// ImportExcelFile 导入服务端保存的Excel文件 供后台任务使用
+func (sysExportTemplateService *SysExportTemplateService) ImportExcelFile(templateID string, path string, options ImportExcelOptions) (result ImportResult, err error) {
+	src, err := os.Open(path)
 	if err != nil {
-		return err
-	}
-
-	var titleKeyMap = make(map[string]string)
-	for key, title := range templateInfoMap {
-		titleKeyMap[title] = key
-	}
-
-	db := global.GVA_DB
-	if template.DBName != "" {
-		db = global.MustGetGlobalDBByDBName(template.DBName)
+		return
 	}
-
-	return db.Transaction(func(tx *gorm.DB) error {
-		excelTitle := rows[0]
-		for i, str := range excelTitle {
-			excelTitle[i] = strings.TrimSpace(str)
-		}
-		values := rows[1:]
-		items := make([]map[string]interface{}, 0, len(values))
-		for _, row := range values {
-			var item = make(map[string]interface{})
-			for ii, value := range row {
-				if _, ok := titleKeyMap[excelTitle[ii]]; !ok {
-					continue // excel中多余的标题，在模板信息中没有对应的字段，因此key为空，必须跳过
-				}
-				key := titleKeyMap[excelTitle[ii]]
-				item[key] = value
-			}
-
-			needCreated := tx.Migrator().HasColumn(template.TableName, "created_at")
-			needUpdated := tx.Migrator().HasColumn(template.TableName, "updated_at")
-
-			if item["created_at"] == nil && needCreated {
-				item["created_at"] = time.Now()
-			}
-			if item["updated_at"] == nil && needUpdated {
-				item["updated_at"] = time.Now()
-			}
-
-			items = append(items, item)
-		}
-		cErr := tx.Table(template.TableName).CreateInBatches(&items, 1000).Error
-		return cErr
-	})
+	defer src.Close()
+	return sysExportTemplateService.importExcel(templateID, src, options)
 }
 
 func getColumnName(n int) string {
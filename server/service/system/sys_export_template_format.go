@@ -0,0 +1,156 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+// templateColumns 解析 TemplateInfo 返回按模板顺序的字段与每个字段的列定义
+func templateColumns(template system.SysExportTemplate) (keys []string, columns map[string]system.ExportColumn, err error) {
+	keys, err = utils.GetJSONKeys(template.TemplateInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = json.Unmarshal([]byte(template.TemplateInfo), &columns); err != nil {
+		return nil, nil, err
+	}
+	return keys, columns, nil
+}
+
+// validateExportColumn 校验列定义 lookup 须为关联表的字段 且本列为与之关联的主表字段
+func validateExportColumn(schema *exportSchema, template system.SysExportTemplate, key string, column system.ExportColumn) error {
+	if column.Bool != nil && len(column.Bool) != 2 {
+		return fmt.Errorf("字段 %s 的 bool 应为真、假两个展示值", key)
+	}
+	if column.Lookup == "" {
+		return nil
+	}
+	_, err := exportLookupKey(schema, template, key, column.Lookup)
+	return err
+}
+
+// exportLookupKey 返回 lookup 所在关联表中与本列关联的字段 导入时按 lookup 的值查询该字段得到本列的值
+func exportLookupKey(schema *exportSchema, template system.SysExportTemplate, key string, lookup string) (string, error) {
+	sel, err := schema.selectColumn(key)
+	if err != nil {
+		return "", err
+	}
+	lookupColumn, err := schema.column(lookup)
+	if err != nil {
+		return "", err
+	}
+	table, _, _ := strings.Cut(lookupColumn, ".")
+	for _, join := range template.JoinTemplate {
+		if join.Table != table {
+			continue
+		}
+		for _, on := range join.OnColumns {
+			left, _ := schema.column(on.Left)
+			right, _ := schema.column(on.Right)
+			if left == sel.Column {
+				return right, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("字段 %s 的 lookup %s 应为与该字段关联的表的字段", key, lookup)
+}
+
+// numberDecimals 数字格式中的小数位数
+var numberDecimals = regexp.MustCompile(`\.([0#]+)`)
+
+// exportFormatter 按列定义转换导出的值 字典标签按需查询并缓存
+type exportFormatter struct {
+	column   system.ExportColumn
+	decimals int
+	labels   map[string]string
+}
+
+// newExportFormatter 列定义中没有需要转换的格式时返回 nil
+func newExportFormatter(column system.ExportColumn) *exportFormatter {
+	if column.Dict == "" && column.Date == "" && column.Number == "" && len(column.Bool) != 2 {
+		return nil
+	}
+	f := &exportFormatter{column: column, labels: make(map[string]string)}
+	if match := numberDecimals.FindStringSubmatch(column.Number); match != nil {
+		f.decimals = len(match[1])
+	}
+	return f
+}
+
+// format 转换导出的值 xlsx 的数字格式由单元格样式处理 其他格式按小数位数输出
+func (f *exportFormatter) format(v interface{}, xlsx bool) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch {
+	case f.column.Dict != "":
+		value := exportString(v)
+		label, ok := f.labels[value]
+		if !ok {
+			label = value
+			if detail, err := DictionaryDetailServiceApp.GetDictionaryInfoByTypeValue(f.column.Dict, value); err == nil {
+				label = detail.Label
+			}
+			f.labels[value] = label
+		}
+		return label
+	case f.column.Date != "":
+		if t, ok := exportTime(v); ok {
+			return t.Format(f.column.Date)
+		}
+	case len(f.column.Bool) == 2:
+		if b, ok := exportBool(v); ok {
+			if b {
+				return f.column.Bool[0]
+			}
+			return f.column.Bool[1]
+		}
+	case f.column.Number != "" && !xlsx:
+		if n, err := strconv.ParseFloat(exportString(v), 64); err == nil {
+			return strconv.FormatFloat(n, 'f', f.decimals, 64)
+		}
+	}
+	return v
+}
+
+// exportTime 时间、时间字符串或Unix时间戳 大于 1e11 的时间戳按毫秒处理
+func exportTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, true
+	}
+	s := exportString(v)
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		for _, layout := range importTimeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+	if n > 1e11 {
+		return time.UnixMilli(int64(n)), true
+	}
+	return time.Unix(int64(n), 0), true
+}
+
+func exportBool(v interface{}) (bool, bool) {
+	if b, ok := v.(bool); ok {
+		return b, true
+	}
+	b, err := strconv.ParseBool(exportString(v))
+	return b, err == nil
+}
@@ -0,0 +1,639 @@
+package system
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// importMaxErrors 导入结果中最多返回的错误数 全部错误见错误工作簿
+const importMaxErrors = 200
+
+// ImportExcelOptions 导入选项
+type ImportExcelOptions struct {
+	DryRun bool // 只校验不写入
+	// OnProgress 不为空时每校验 1000 行调用一次
+	OnProgress func(done, total int)
+}
+
+// ImportRowError 导入数据的错误 Row 为工作表中的行号 标题为第1行
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"` // 列标题 为空时为整行的错误
+	Message string `json:"message"`
+}
+
+// ImportResult 导入结果 有任意一行数据有误时不写入任何数据
+type ImportResult struct {
+	DryRun    bool             `json:"dryRun"`
+	Total     int              `json:"total"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Failed    int              `json:"failed"` // 有误的行数
+	Errors    []ImportRowError `json:"errors"`
+	ErrorFile string           `json:"errorFile,omitempty"` // 错误工作簿 通过 downloadImportErrors 下载
+
+	sheet     string
+	titles    []string
+	rows      [][]string
+	rowErrors map[int][]ImportRowError // 按数据行的下标
+}
+
+func (r *ImportResult) addError(index int, column string, message string) {
+	if r.rowErrors == nil {
+		r.rowErrors = make(map[int][]ImportRowError)
+	}
+	r.rowErrors[index] = append(r.rowErrors[index], ImportRowError{Row: index + 2, Column: column, Message: message})
+}
+
+// finish 汇总错误 按行号排序
+func (r *ImportResult) finish() {
+	r.Failed = len(r.rowErrors)
+	indexes := make([]int, 0, len(r.rowErrors))
+	for index := range r.rowErrors {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	r.Errors = make([]ImportRowError, 0)
+	for _, index := range indexes {
+		for _, e := range r.rowErrors[index] {
+			if len(r.Errors) == importMaxErrors {
+				return
+			}
+			r.Errors = append(r.Errors, e)
+		}
+	}
+}
+
+// WriteErrorWorkbook 写出有误的数据行 最后一列为错误信息 有误的单元格标红 修改后可直接重新导入
+func (r *ImportResult) WriteErrorWorkbook(w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := r.sheet
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+	style, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Color: []string{"FFC7CE"}, Pattern: 1}})
+	if err != nil {
+		return err
+	}
+	header := make([]interface{}, 0, len(r.titles)+1)
+	for _, title := range r.titles {
+		header = append(header, title)
+	}
+	header = append(header, "错误信息")
+	if err = f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+	indexes := make([]int, 0, len(r.rowErrors))
+	for index := range r.rowErrors {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for i, index := range indexes {
+		line := i + 2
+		values := make([]interface{}, len(r.titles)+1)
+		for j := range r.titles {
+			if j < len(r.rows[index]) {
+				values[j] = r.rows[index][j]
+			}
+		}
+		messages := make([]string, 0, len(r.rowErrors[index]))
+		for _, e := range r.rowErrors[index] {
+			if e.Column == "" {
+				messages = append(messages, e.Message)
+				continue
+			}
+			messages = append(messages, e.Column+": "+e.Message)
+			if col := slices.Index(r.titles, e.Column); col >= 0 {
+				cell, _ := excelize.CoordinatesToCellName(col+1, line)
+				if err = f.SetCellStyle(sheet, cell, cell, style); err != nil {
+					return err
+				}
+			}
+		}
+		values[len(r.titles)] = strings.Join(messages, "; ")
+		cell, _ := excelize.CoordinatesToCellName(1, line)
+		if err = f.SetSheetRow(sheet, cell, &values); err != nil {
+			return err
+		}
+	}
+	return f.Write(w)
+}
+
+const (
+	importKindString  = "string"
+	importKindInt     = "int"
+	importKindFloat   = "float"
+	importKindDecimal = "decimal"
+	importKindBool    = "bool"
+	importKindTime    = "time"
+)
+
+// importColumnKind 按数据库字段类型确定导入时的转换方式
+func importColumnKind(columnType gorm.ColumnType) string {
+	name := strings.ToLower(columnType.DatabaseTypeName())
+	full, _ := columnType.ColumnType()
+	full = strings.ToLower(full)
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSuffix(name, " unsigned"), "unsigned "))
+	switch {
+	case name == "bool" || name == "boolean" || strings.HasPrefix(full, "tinyint(1)"):
+		return importKindBool
+	case slices.Contains([]string{"tinyint", "smallint", "mediumint", "int", "integer", "bigint", "int2", "int4", "int8", "serial", "bigserial", "smallserial"}, name):
+		return importKindInt
+	case name == "decimal" || name == "numeric":
+		return importKindDecimal
+	case slices.Contains([]string{"float", "double", "real", "float4", "float8", "double precision"}, name):
+		return importKindFloat
+	case strings.HasPrefix(name, "date") || strings.HasPrefix(name, "time"):
+		return importKindTime
+	}
+	return importKindString
+}
+
+// importColumn 工作表中的一列对应的主表字段
+type importColumn struct {
+	index int
+	title string
+	name  string
+	kind  string
+	size  int64
+	rule  system.ImportRule
+	// format 模板中的列定义 导入时按导出的格式反向转换
+	format system.ExportColumn
+	// dict 字典标签到字典值 values 为全部字典值
+	dict   map[string]string
+	values map[string]bool
+	// lookup 关联表字段的值到本列的值 为 nil 时不需要查询
+	lookup map[string]interface{}
+}
+
+var importTimeLayouts = []string{
+	"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006/01/02 15:04:05", "2006/01/02 15:04", "2006/01/02", time.RFC3339,
+}
+
+// convert 将单元格的值转换为字段类型 先将导出的展示值转换回字段的值
+func (c *importColumn) convert(raw string) (interface{}, error) {
+	if c.lookup != nil {
+		value, ok := c.lookup[raw]
+		if !ok {
+			return nil, fmt.Errorf("%q 在 %s 中不存在或不唯一", raw, c.format.Lookup)
+		}
+		return value, nil
+	}
+	if c.dict != nil {
+		if value, ok := c.dict[raw]; ok {
+			raw = value
+		} else if !c.values[raw] {
+			return nil, fmt.Errorf("%q 不是字典中的选项", raw)
+		}
+	}
+	if len(c.format.Bool) == 2 {
+		switch raw {
+		case c.format.Bool[0]:
+			raw = "1"
+		case c.format.Bool[1]:
+			raw = "0"
+		}
+	}
+	if c.format.Date != "" && (c.kind == importKindInt || c.kind == importKindTime) {
+		if t, err := time.ParseInLocation(c.format.Date, raw, time.Local); err == nil {
+			if c.kind == importKindInt {
+				return t.Unix(), nil
+			}
+			return t, nil
+		}
+	}
+	switch c.kind {
+	case importKindInt:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是整数", raw)
+		}
+		return v, nil
+	case importKindFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是数字", raw)
+		}
+		return v, nil
+	case importKindDecimal:
+		// 原样写入 避免精度损失
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, fmt.Errorf("%q 不是数字", raw)
+		}
+		return raw, nil
+	case importKindBool:
+		switch strings.ToLower(raw) {
+		case "1", "true", "yes", "y", "是":
+			return true, nil
+		case "0", "false", "no", "n", "否":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q 不是 是/否", raw)
+	case importKindTime:
+		// 读取的是单元格的原始值 日期单元格为 Excel 的日期序列号
+		if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+			return excelize.ExcelDateToTime(serial, false)
+		}
+		for _, layout := range importTimeLayouts {
+			if v, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("%q 不是有效的日期", raw)
+	}
+	if c.size > 0 && int64(utf8.RuneCountInString(raw)) > c.size {
+		return nil, fmt.Errorf("长度超过 %d", c.size)
+	}
+	return raw, nil
+}
+
+// importColumns 按标题行匹配模板中的主表字段 模板中没有的标题忽略
+func importColumns(db *gorm.DB, schema *exportSchema, template system.SysExportTemplate, rows [][]string) ([]*importColumn, error) {
+	keys, info, err := templateColumns(template)
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := db.Migrator().ColumnTypes(template.TableName)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, columnType := range columnTypes {
+		types[columnType.Name()] = columnType
+	}
+	titleKey := make(map[string]string, len(keys))
+	for _, key := range keys {
+		sel, err := schema.selectColumn(key)
+		if err != nil {
+			return nil, err
+		}
+		table, _, _ := strings.Cut(sel.Column, ".")
+		if table != template.TableName {
+			return nil, fmt.Errorf("%w: 字段 %s 不属于主表", errImportJoinColumn, key)
+		}
+		titleKey[info[key].Title] = key
+	}
+
+	var columns []*importColumn
+	for i, title := range rows[0] {
+		key, ok := titleKey[strings.TrimSpace(title)]
+		if !ok {
+			continue // excel中多余的标题，在模板信息中没有对应的字段，必须跳过
+		}
+		sel, _ := schema.selectColumn(key)
+		_, name, _ := strings.Cut(sel.Column, ".")
+		column := &importColumn{index: i, title: strings.TrimSpace(title), name: name, kind: importColumnKind(types[name]), format: info[key]}
+		if column.kind == importKindString {
+			column.size, _ = types[name].Length()
+		}
+		for _, rule := range template.ImportRules {
+			if rule.Column == name {
+				column.rule = rule
+			}
+		}
+		if column.format.Lookup != "" {
+			if err = column.loadLookup(db, schema, template, key, rows[1:]); err != nil {
+				return nil, err
+			}
+		} else if dict := cmp.Or(column.rule.Dict, column.format.Dict); dict != "" {
+			details, err := DictionaryDetailServiceApp.GetDictionaryListByType(dict)
+			if err != nil {
+				return nil, err
+			}
+			if len(details) == 0 {
+				return nil, fmt.Errorf("字典 %s 不存在或没有选项", dict)
+			}
+			column.dict, column.values = make(map[string]string, len(details)), make(map[string]bool, len(details))
+			for _, detail := range details {
+				column.dict[detail.Label] = detail.Value
+				column.values[detail.Value] = true
+			}
+		}
+		columns = append(columns, column)
+	}
+
+	for _, rule := range template.ImportRules {
+		if rule.Required && !slices.ContainsFunc(columns, func(c *importColumn) bool { return c.name == rule.Column }) {
+			return nil, fmt.Errorf("缺少必填字段 %s 对应的列", rule.Column)
+		}
+	}
+	if template.ImportKey != "" && !slices.ContainsFunc(columns, func(c *importColumn) bool { return c.name == template.ImportKey }) {
+		return nil, fmt.Errorf("缺少更新依据字段 %s 对应的列", template.ImportKey)
+	}
+	return columns, nil
+}
+
+// loadLookup 按工作表中本列的值批量查询关联表 值不唯一时不加入 转换时报错
+func (c *importColumn) loadLookup(db *gorm.DB, schema *exportSchema, template system.SysExportTemplate, key string, rows [][]string) error {
+	right, err := exportLookupKey(schema, template, key, c.format.Lookup)
+	if err != nil {
+		return err
+	}
+	lookup, _ := schema.column(c.format.Lookup)
+	table, _, _ := strings.Cut(lookup, ".")
+	seen := make(map[string]bool)
+	var values []interface{}
+	for _, row := range rows {
+		if c.index < len(row) {
+			if raw := strings.TrimSpace(row[c.index]); raw != "" && !seen[raw] {
+				seen[raw] = true
+				values = append(values, raw)
+			}
+		}
+	}
+	quote := db.Statement.Quote
+	c.lookup = make(map[string]interface{}, len(values))
+	duplicated := make(map[string]bool)
+	for chunk := range slices.Chunk(values, 500) {
+		var found []map[string]interface{}
+		err = db.Table(table).Select(quote(right)+" AS k, "+quote(lookup)+" AS v").Where(quote(lookup)+" IN ?", chunk).Find(&found).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range found {
+			v := exportString(row["v"])
+			if _, ok := c.lookup[v]; ok {
+				duplicated[v] = true
+			}
+			c.lookup[v] = row["k"]
+		}
+	}
+	for v := range duplicated {
+		delete(c.lookup, v)
+	}
+	return nil
+}
+
+// importLookup 查询表中已有数据 返回 column 的值到 key 的值 key 为空时值为空字符串
+// 包含已软删除的数据 唯一索引同样约束这些数据 按更新依据字段导入时恢复
+func importLookup(db *gorm.DB, schema *exportSchema, column string, key string, values []interface{}) (map[string]string, error) {
+	quote := db.Statement.Quote
+	found := make(map[string]string)
+	for chunk := range slices.Chunk(values, 500) {
+		query := db.Table(schema.main).Where(quote(column)+" IN ?", chunk)
+		selects := quote(column) + " AS v"
+		if key != "" {
+			selects += ", " + quote(key) + " AS k"
+		}
+		var rows []map[string]interface{}
+		if err := query.Select(selects).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			found[importValueKey(row["v"])] = importValueKey(row["k"])
+		}
+	}
+	return found, nil
+}
+
+// importValueKey 比较导入的值与表中的值时使用的字符串
+func importValueKey(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return exportString(v)
+}
+
+func (sysExportTemplateService *SysExportTemplateService) importExcel(templateID string, src io.Reader, options ImportExcelOptions) (result ImportResult, err error) {
+	var template system.SysExportTemplate
+	err = global.GVA_DB.Preload("Conditions").Preload("JoinTemplate").First(&template, "template_id = ?", templateID).Error
+	if err != nil {
+		return
+	}
+
+	f, err := excelize.OpenReader(src)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	sheet := template.ImportSheet
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	} else if idx, _ := f.GetSheetIndex(sheet); idx < 0 {
+		return result, fmt.Errorf("工作表 %s 不存在", sheet)
+	}
+	// 读取原始值 日期不受单元格格式影响
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return
+	}
+	if len(rows) < 2 {
+		return result, errors.New("Excel data is not enough.\nIt should contain title row and data")
+	}
+
+	db := templateDB(template)
+	schema, err := validateExportTemplate(db, template)
+	if err != nil {
+		return
+	}
+	columns, err := importColumns(db, schema, template, rows)
+	if err != nil {
+		return
+	}
+
+	result = ImportResult{DryRun: options.DryRun, Total: len(rows) - 1, sheet: sheet, titles: rows[0], rows: rows[1:]}
+	items := make([]map[string]interface{}, len(result.rows))
+	for i, row := range result.rows {
+		if !slices.ContainsFunc(row, func(cell string) bool { return strings.TrimSpace(cell) != "" }) {
+			continue // 跳过空行
+		}
+		item := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			raw := ""
+			if column.index < len(row) {
+				raw = strings.TrimSpace(row[column.index])
+			}
+			if raw == "" {
+				if column.rule.Required || column.name == template.ImportKey {
+					result.addError(i, column.title, "不能为空")
+				}
+				continue
+			}
+			v, err := column.convert(raw)
+			if err != nil {
+				result.addError(i, column.title, err.Error())
+				continue
+			}
+			item[column.name] = v
+		}
+		items[i] = item
+		if options.OnProgress != nil && (i+1)%1000 == 0 {
+			options.OnProgress(i+1, result.Total)
+		}
+	}
+
+	// 唯一字段与更新依据字段在文件内不能重复 唯一字段不能与表中其他数据重复
+	for _, column := range columns {
+		if !column.rule.Unique && column.name != template.ImportKey {
+			continue
+		}
+		seen := make(map[string]int)
+		var values []interface{}
+		for i, item := range items {
+			v, ok := item[column.name]
+			if !ok {
+				continue
+			}
+			k := importValueKey(v)
+			if first, ok := seen[k]; ok {
+				result.addError(i, column.title, fmt.Sprintf("与第 %d 行重复", first+2))
+				continue
+			}
+			seen[k] = i
+			values = append(values, v)
+		}
+		if !column.rule.Unique || column.name == template.ImportKey || len(values) == 0 {
+			continue
+		}
+		found, err := importLookup(db, schema, column.name, template.ImportKey, values)
+		if err != nil {
+			return result, err
+		}
+		for i, item := range items {
+			v, ok := item[column.name]
+			if !ok {
+				continue
+			}
+			key, ok := found[importValueKey(v)]
+			// 按更新依据字段更新的是同一条数据时不算重复
+			if ok && (template.ImportKey == "" || key != importValueKey(item[template.ImportKey])) {
+				result.addError(i, column.title, "已存在")
+			}
+		}
+	}
+
+	existing := map[string]string{}
+	if template.ImportKey != "" {
+		var keys []interface{}
+		for _, item := range items {
+			if v, ok := item[template.ImportKey]; ok {
+				keys = append(keys, v)
+			}
+		}
+		if len(keys) > 0 {
+			if existing, err = importLookup(db, schema, template.ImportKey, "", keys); err != nil {
+				return
+			}
+		}
+	}
+
+	result.finish()
+	if result.Failed > 0 {
+		return result, nil
+	}
+	var creates, updates []map[string]interface{}
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if _, ok := existing[importValueKey(item[template.ImportKey])]; ok && template.ImportKey != "" {
+			updates = append(updates, item)
+		} else {
+			creates = append(creates, item)
+		}
+	}
+	result.Created, result.Updated = len(creates), len(updates)
+	if options.DryRun {
+		return result, nil
+	}
+
+	now := time.Now()
+	hasCreated := schema.hasColumn(template.TableName, "created_at")
+	hasUpdated := schema.hasColumn(template.TableName, "updated_at")
+	hasDeleted := schema.hasColumn(template.TableName, "deleted_at")
+	quote := db.Statement.Quote
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range updates {
+			if item["updated_at"] == nil && hasUpdated {
+				item["updated_at"] = now
+			}
+			// 已软删除的数据导入后恢复
+			if hasDeleted {
+				item["deleted_at"] = nil
+			}
+			query := tx.Table(template.TableName).Where(quote(template.ImportKey)+" = ?", item[template.ImportKey])
+			if err := query.Updates(item).Error; err != nil {
+				return err
+			}
+		}
+		for _, item := range creates {
+			if item["created_at"] == nil && hasCreated {
+				item["created_at"] = now
+			}
+			if item["updated_at"] == nil && hasUpdated {
+				item["updated_at"] = now
+			}
+		}
+		if len(creates) == 0 {
+			return nil
+		}
+		return tx.Table(template.TableName).CreateInBatches(&creates, 1000).Error
+	})
+	return result, err
+}
+
+// importErrorName 错误工作簿的文件名
+var importErrorName = regexp.MustCompile(`^[A-Za-z0-9]{32}$`)
+
+func importErrorDir() string {
+	return filepath.Join(global.GVA_CONFIG.Excel.Dir, "import_errors")
+}
+
+// SaveImportErrors 将导入的错误工作簿保存在 excel 目录下 保留一天
+func (sysExportTemplateService *SysExportTemplateService) SaveImportErrors(result *ImportResult) error {
+	dir := importErrorDir()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > 24*time.Hour {
+				_ = os.Remove(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	name := utils.RandomString(32)
+	file, err := os.Create(filepath.Join(dir, name+".xlsx"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = result.WriteErrorWorkbook(file); err != nil {
+		return err
+	}
+	result.ErrorFile = name
+	return nil
+}
+
+// GetImportErrorFile 获取错误工作簿的路径
+func (sysExportTemplateService *SysExportTemplateService) GetImportErrorFile(name string) (string, error) {
+	if !importErrorName.MatchString(name) {
+		return "", errors.New("文件不存在或已过期")
+	}
+	path := filepath.Join(importErrorDir(), name+".xlsx")
+	if _, err := os.Stat(path); err != nil {
+		return "", errors.New("文件不存在或已过期")
+	}
+	return path, nil
+}
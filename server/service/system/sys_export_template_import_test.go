@@ -0,0 +1,136 @@
+package system
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// newImportDB 在导出测试库中增加导入的目标表 members 其中 B2 已软删除
+func newImportDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := newExportDB(t)
+	for _, sql := range []string{
+		"CREATE TABLE members (id integer primary key, code varchar(16) not null unique, name varchar(32), email varchar(64) unique, age integer, dept_id integer, created_at datetime, updated_at datetime, deleted_at datetime)",
+		"INSERT INTO members (code, name, email, age, dept_id) VALUES ('A1', 'old', 'a@example.com', 20, 1)",
+		"INSERT INTO members (code, name, email, age, dept_id, deleted_at) VALUES ('B2', 'deleted', 'b@example.com', 30, 1, '2026-01-01 00:00:00')",
+	} {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	template := system.SysExportTemplate{
+		Name:         "members",
+		TableName:    "members",
+		TemplateID:   "members",
+		TemplateInfo: `{"code":"编号","name":"姓名","email":"邮箱","age":"年龄","dept_id":{"title":"部门","lookup":"depts.name"}}`,
+		ImportKey:    "code",
+		ImportRules:  []system.ImportRule{{Column: "name", Required: true}, {Column: "email", Unique: true}},
+		JoinTemplate: []system.JoinTemplate{{
+			Table:     "depts",
+			JoinType:  system.JoinTypeLeft,
+			OnColumns: []system.JoinOn{{Left: "members.dept_id", Right: "depts.id"}},
+		}},
+	}
+	if err := db.Create(&template).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func writeImportFile(t *testing.T, rows [][]interface{}) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "import.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var importTestTitles = []interface{}{"编号", "姓名", "邮箱", "年龄", "部门", "备注"}
+
+func TestImportExcelValidatesRows(t *testing.T) {
+	db := newImportDB(t)
+	path := writeImportFile(t, [][]interface{}{
+		importTestTitles,
+		{"C3", "carol", "c@example.com", "abc", "dev"},
+		{"D4", "dave", "d@example.com", 40, "qa"},
+		{"E5", "", "e@example.com", 50, "ops"},
+		{},
+		{"C3", "carol", "c2@example.com", 30, "dev"},
+		// 与已软删除的数据重复 表中的唯一索引同样约束已删除的数据
+		{"F6", "frank", "b@example.com", 60, "dev"},
+	})
+
+	result, err := SysExportTemplateServiceApp.ImportExcelFile("members", path, ImportExcelOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, result.Total)
+	assert.Equal(t, 5, result.Failed)
+	assert.Equal(t, []ImportRowError{
+		{Row: 2, Column: "年龄", Message: `"abc" 不是整数`},
+		{Row: 3, Column: "部门", Message: `"qa" 在 depts.name 中不存在或不唯一`},
+		{Row: 4, Column: "姓名", Message: "不能为空"},
+		{Row: 6, Column: "编号", Message: "与第 2 行重复"},
+		{Row: 7, Column: "邮箱", Message: "已存在"},
+	}, result.Errors)
+
+	// 有误时不写入任何数据
+	var count int64
+	assert.NoError(t, db.Table("members").Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestImportExcelUpsertRestoresDeleted(t *testing.T) {
+	db := newImportDB(t)
+	path := writeImportFile(t, [][]interface{}{
+		importTestTitles,
+		{"A1", "alice", "a@example.com", 21, "ops", "忽略"},
+		{"B2", "bob", "b@example.com", 31, "dev"},
+		{"C3", "carol", "c@example.com", 41, "dev"},
+	})
+
+	result, err := SysExportTemplateServiceApp.ImportExcelFile("members", path, ImportExcelOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Zero(t, result.Failed)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 2, result.Updated)
+
+	result, err = SysExportTemplateServiceApp.ImportExcelFile("members", path, ImportExcelOptions{})
+	assert.NoError(t, err)
+	assert.Zero(t, result.Failed)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 2, result.Updated)
+
+	var members []struct {
+		Code      string
+		Name      string
+		Age       int
+		DeptID    int
+		CreatedAt *string
+		DeletedAt *string
+	}
+	assert.NoError(t, db.Table("members").Order("code").Find(&members).Error)
+	if assert.Len(t, members, 3) {
+		assert.Equal(t, "alice", members[0].Name)
+		assert.Equal(t, 21, members[0].Age)
+		assert.Equal(t, 2, members[0].DeptID)
+		// 已软删除的数据按更新依据字段恢复
+		assert.Equal(t, "bob", members[1].Name)
+		assert.Nil(t, members[1].DeletedAt)
+		assert.Equal(t, "carol", members[2].Name)
+		assert.Equal(t, 1, members[2].DeptID)
+		assert.NotNil(t, members[2].CreatedAt)
+	}
+}
@@ -0,0 +1,387 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	exportIdentifier = `[A-Za-z_][A-Za-z0-9_]*`
+	exportTableName  = regexp.MustCompile(`^` + exportIdentifier + `$`)
+	// exportColumnRef 字段引用 字段名 或 表名.字段名
+	exportColumnRef = regexp.MustCompile(`^(?:(` + exportIdentifier + `)\.)?(` + exportIdentifier + `)$`)
+	// exportSelectRef 导出字段 字段引用后可带 as 别名
+	exportSelectRef = regexp.MustCompile(`^(?:(` + exportIdentifier + `)\.)?(` + exportIdentifier + `)(?:\s+(?i:as)\s+(` + exportIdentifier + `))?$`)
+	// legacyJoinOn 旧版关联条件中的一组相等条件
+	legacyJoinOn = regexp.MustCompile(`^(` + exportIdentifier + `\.` + exportIdentifier + `)\s*=\s*(` + exportIdentifier + `\.` + exportIdentifier + `)$`)
+	legacyAnd    = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// unquoteExportRef 去掉引用中的引号与首尾空白
+func unquoteExportRef(ref string) string {
+	ref = strings.ReplaceAll(ref, "\"", "")
+	ref = strings.ReplaceAll(ref, "`", "")
+	return strings.TrimSpace(ref)
+}
+
+// normalizeExportTemplate 将旧版的SQL片段转换为结构化定义 并回填旧字段便于旧版页面展示
+func normalizeExportTemplate(template *system.SysExportTemplate) error {
+	if len(template.OrderBy) == 0 && strings.TrimSpace(template.Order) != "" {
+		orderBy, err := parseLegacyOrder(template.Order)
+		if err != nil {
+			return err
+		}
+		template.OrderBy = orderBy
+	}
+	orders := make([]string, 0, len(template.OrderBy))
+	for _, order := range template.OrderBy {
+		if order.Desc {
+			orders = append(orders, order.Column+" desc")
+		} else {
+			orders = append(orders, order.Column)
+		}
+	}
+	template.Order = strings.Join(orders, ", ")
+
+	for i := range template.JoinTemplate {
+		join := &template.JoinTemplate[i]
+		if join.JoinType == "" && join.JOINS != "" {
+			join.JoinType = strings.TrimSpace(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(join.JOINS)), "JOIN"))
+		}
+		join.JoinType = strings.ToUpper(strings.TrimSpace(join.JoinType))
+		if join.JoinType == "" {
+			join.JoinType = system.JoinTypeLeft
+		}
+		if len(join.OnColumns) == 0 && strings.TrimSpace(join.ON) != "" {
+			on, err := parseLegacyJoinOn(join.ON)
+			if err != nil {
+				return fmt.Errorf("关联表 %s: %w", join.Table, err)
+			}
+			join.OnColumns = on
+		}
+		join.JOINS = join.JoinType + " JOIN"
+		pairs := make([]string, 0, len(join.OnColumns))
+		for _, on := range join.OnColumns {
+			pairs = append(pairs, on.Left+" = "+on.Right)
+		}
+		join.ON = strings.Join(pairs, " AND ")
+	}
+
+	for i := range template.Conditions {
+		template.Conditions[i].Operator = normalizeConditionOperator(template.Conditions[i].Operator)
+	}
+	return nil
+}
+
+// normalizeConditionOperator 操作符统一为大写 多个空白合并为一个空格 如 "not  in" 转为 "NOT IN"
+func normalizeConditionOperator(operator string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(operator), " "))
+}
+
+// parseLegacyOrder 解析 "字段 [asc|desc], ..." 格式的排序
+func parseLegacyOrder(order string) ([]system.ExportOrder, error) {
+	var orderBy []system.ExportOrder
+	for _, part := range strings.Split(order, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("无法解析排序 %q", order)
+		}
+		item := system.ExportOrder{Column: unquoteExportRef(fields[0])}
+		if len(fields) == 2 {
+			switch strings.ToLower(fields[1]) {
+			case "asc":
+			case "desc":
+				item.Desc = true
+			default:
+				return nil, fmt.Errorf("无法解析排序 %q", order)
+			}
+		}
+		orderBy = append(orderBy, item)
+	}
+	return orderBy, nil
+}
+
+// parseLegacyJoinOn 解析 "a.x = b.y AND ..." 格式的关联条件 其他写法需在页面中重新配置
+func parseLegacyJoinOn(on string) ([]system.JoinOn, error) {
+	var pairs []system.JoinOn
+	for _, part := range legacyAnd.Split(unquoteExportRef(on), -1) {
+		match := legacyJoinOn.FindStringSubmatch(strings.TrimSpace(part))
+		if match == nil {
+			return nil, fmt.Errorf("无法解析关联条件 %q 只支持 表.字段 = 表.字段 并以 AND 连接", on)
+		}
+		pairs = append(pairs, system.JoinOn{Left: match[1], Right: match[2]})
+	}
+	return pairs, nil
+}
+
+// exportSchema 按实际表结构校验模板中的表与字段 结果按表缓存
+type exportSchema struct {
+	db      *gorm.DB
+	main    string
+	tables  []string
+	columns map[string]map[string]bool
+}
+
+func newExportSchema(db *gorm.DB, main string) (*exportSchema, error) {
+	s := &exportSchema{db: db, columns: make(map[string]map[string]bool)}
+	if err := s.addTable(main); err != nil {
+		return nil, err
+	}
+	s.main = main
+	return s, nil
+}
+
+// addTable 将表加入可引用的范围
+func (s *exportSchema) addTable(table string) error {
+	if !exportTableName.MatchString(table) {
+		return fmt.Errorf("表名 %q 无效", table)
+	}
+	if slices.Contains(s.tables, table) {
+		return fmt.Errorf("表 %s 重复关联", table)
+	}
+	if !s.db.Migrator().HasTable(table) {
+		return fmt.Errorf("表 %s 不存在", table)
+	}
+	columnTypes, err := s.db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool, len(columnTypes))
+	for _, column := range columnTypes {
+		columns[column.Name()] = true
+	}
+	s.columns[table] = columns
+	s.tables = append(s.tables, table)
+	return nil
+}
+
+func (s *exportSchema) hasColumn(table, column string) bool {
+	return s.columns[table][column]
+}
+
+// column 校验字段引用 未写表名时为主表的字段 返回 表名.字段名
+func (s *exportSchema) column(ref string) (string, error) {
+	match := exportColumnRef.FindStringSubmatch(unquoteExportRef(ref))
+	if match == nil {
+		return "", fmt.Errorf("字段 %q 无效", ref)
+	}
+	table, column := match[1], match[2]
+	if table == "" {
+		table = s.main
+	}
+	if _, ok := s.columns[table]; !ok {
+		return "", fmt.Errorf("字段 %s 所在的表 %s 不在模板中", ref, table)
+	}
+	if !s.hasColumn(table, column) {
+		return "", fmt.Errorf("表 %s 不存在字段 %s", table, column)
+	}
+	return table + "." + column, nil
+}
+
+// exportSelect 校验后的导出字段
+type exportSelect struct {
+	Column string // 表名.字段名
+	Alias  string // 查询结果中的列名
+}
+
+// selectColumn 校验导出字段 返回字段与结果列名 未写别名时结果列名为字段名
+func (s *exportSchema) selectColumn(key string) (exportSelect, error) {
+	match := exportSelectRef.FindStringSubmatch(unquoteExportRef(key))
+	if match == nil {
+		return exportSelect{}, fmt.Errorf("导出字段 %q 无效 只支持 字段、表.字段 及 as 别名", key)
+	}
+	ref := match[2]
+	if match[1] != "" {
+		ref = match[1] + "." + match[2]
+	}
+	column, err := s.column(ref)
+	if err != nil {
+		return exportSelect{}, err
+	}
+	alias := match[3]
+	if alias == "" {
+		alias = match[2]
+	}
+	return exportSelect{Column: column, Alias: alias}, nil
+}
+
+// validateExportTemplate 按实际表结构校验模板 返回包含主表与关联表的结构
+func validateExportTemplate(db *gorm.DB, template system.SysExportTemplate) (*exportSchema, error) {
+	schema, err := newExportSchema(db, template.TableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, join := range template.JoinTemplate {
+		if len(join.OnColumns) == 0 {
+			if join.ON != "" {
+				return nil, fmt.Errorf("关联表 %s 的关联条件为旧版格式 请重新保存模板", join.Table)
+			}
+			return nil, fmt.Errorf("关联表 %s 缺少关联字段", join.Table)
+		}
+		if join.JoinType != system.JoinTypeLeft && join.JoinType != system.JoinTypeRight && join.JoinType != system.JoinTypeInner {
+			return nil, fmt.Errorf("关联方式 %q 无效", join.JoinType)
+		}
+		if err = schema.addTable(join.Table); err != nil {
+			return nil, err
+		}
+		for _, on := range join.OnColumns {
+			left, err := schema.column(on.Left)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(left, join.Table+".") {
+				return nil, fmt.Errorf("关联字段 %s 应属于主表或之前关联的表", on.Left)
+			}
+			right, err := schema.column(on.Right)
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(right, join.Table+".") {
+				return nil, fmt.Errorf("关联字段 %s 应属于关联表 %s", on.Right, join.Table)
+			}
+		}
+	}
+	keys, columns, err := templateColumns(template)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if _, err = schema.selectColumn(key); err != nil {
+			return nil, err
+		}
+		if err = validateExportColumn(schema, template, key, columns[key]); err != nil {
+			return nil, err
+		}
+	}
+	for _, condition := range template.Conditions {
+		if _, err = schema.column(condition.Column); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(system.ConditionOperators, condition.Operator) {
+			return nil, fmt.Errorf("条件操作符 %q 无效", condition.Operator)
+		}
+	}
+	for _, order := range template.OrderBy {
+		if _, err = schema.column(order.Column); err != nil {
+			return nil, err
+		}
+	}
+	if template.ImportKey != "" && !schema.hasColumn(template.TableName, template.ImportKey) {
+		return nil, fmt.Errorf("更新依据字段 %s 不是主表的字段", template.ImportKey)
+	}
+	for _, rule := range template.ImportRules {
+		if !schema.hasColumn(template.TableName, rule.Column) {
+			return nil, fmt.Errorf("导入规则的字段 %s 不是主表的字段", rule.Column)
+		}
+	}
+	return schema, nil
+}
+
+// checkExportTemplate 保存前转换旧版定义并按实际表结构校验
+func checkExportTemplate(template *system.SysExportTemplate) error {
+	if err := normalizeExportTemplate(template); err != nil {
+		return err
+	}
+	_, err := validateExportTemplate(templateDB(*template), *template)
+	return err
+}
+
+// templateDB 模板使用的数据库
+func templateDB(template system.SysExportTemplate) *gorm.DB {
+	if template.DBName != "" {
+		return global.MustGetGlobalDBByDBName(template.DBName)
+	}
+	return global.GVA_DB
+}
+
+// CheckTemplateAuthority 检查角色是否可以导出导入模板涉及的所有表 excel.unrestricted-tables 为 true 时不检查
+func (sysExportTemplateService *SysExportTemplateService) CheckTemplateAuthority(templateID string, authorityID uint) error {
+	conf := global.GVA_CONFIG.Excel
+	if conf.UnrestrictedTables {
+		return nil
+	}
+	var template system.SysExportTemplate
+	if err := global.GVA_DB.Preload("JoinTemplate").First(&template, "template_id = ?", templateID).Error; err != nil {
+		return err
+	}
+	var allowed []string
+	for _, policy := range conf.TablePolicies {
+		if slices.Contains(policy.Authorities, authorityID) {
+			allowed = append(allowed, policy.Tables...)
+		}
+	}
+	if slices.Contains(allowed, "*") {
+		return nil
+	}
+	tables := []string{template.TableName}
+	for _, join := range template.JoinTemplate {
+		tables = append(tables, join.Table)
+	}
+	for _, table := range tables {
+		if !slices.Contains(allowed, table) {
+			return fmt.Errorf("角色 %s 无权导出或导入表 %s", strconv.FormatUint(uint64(authorityID), 10), table)
+		}
+	}
+	return nil
+}
+
+// MigrateLegacyTemplates 将已有模板中旧版的关联、排序转换为结构化定义 无法转换的模板记录日志 导出时提示重新保存
+func (sysExportTemplateService *SysExportTemplateService) MigrateLegacyTemplates() (migrated int, err error) {
+	var templates []system.SysExportTemplate
+	if err = global.GVA_DB.Preload("JoinTemplate").Preload("Conditions").Find(&templates).Error; err != nil {
+		return
+	}
+	var errs []string
+	for _, template := range templates {
+		legacy := len(template.OrderBy) == 0 && template.Order != ""
+		for _, join := range template.JoinTemplate {
+			legacy = legacy || (len(join.OnColumns) == 0 && join.ON != "")
+		}
+		for _, condition := range template.Conditions {
+			legacy = legacy || condition.Operator != normalizeConditionOperator(condition.Operator)
+		}
+		if !legacy {
+			continue
+		}
+		if err := normalizeExportTemplate(&template); err != nil {
+			errs = append(errs, template.TemplateID+": "+err.Error())
+			continue
+		}
+		err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&template).Select("order", "order_by").Updates(&template).Error; err != nil {
+				return err
+			}
+			for _, join := range template.JoinTemplate {
+				if err := tx.Model(&join).Select("join_type", "on_columns", "joins", "on").Updates(&join).Error; err != nil {
+					return err
+				}
+			}
+			for _, condition := range template.Conditions {
+				if err := tx.Model(&condition).Select("operator").Updates(&condition).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		migrated++
+	}
+	if len(errs) > 0 {
+		global.GVA_LOG.Warn("export templates need to be re-saved", zap.Strings("templates", errs))
+	}
+	return migrated, nil
+}
+
+// errImportJoinColumn 导入只写入主表
+var errImportJoinColumn = errors.New("导入不支持关联表的字段")
@@ -0,0 +1,215 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newExportDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/export.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&system.SysExportTemplate{}, &system.Condition{}, &system.JoinTemplate{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range []string{
+		"CREATE TABLE depts (id integer primary key, name text)",
+		"CREATE TABLE users (id integer primary key, name text, dept_id integer, deleted_at datetime)",
+		"INSERT INTO depts (id, name) VALUES (1, 'dev'), (2, 'ops')",
+		"INSERT INTO users (id, name, dept_id) VALUES (1, 'alice', 1), (2, 'bob', 2)",
+	} {
+		if err = db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	global.GVA_DB = db
+	global.GVA_LOG = zap.NewNop()
+	return db
+}
+
+// exportTestTemplate 导出 users 并关联 depts 的模板
+func exportTestTemplate() system.SysExportTemplate {
+	return system.SysExportTemplate{
+		Name:         "users",
+		TableName:    "users",
+		TemplateID:   "users",
+		TemplateInfo: `{"name":"姓名","depts.name as dept":"部门"}`,
+		Formats:      system.ExportFormatNdjson,
+		OrderBy:      []system.ExportOrder{{Column: "id"}},
+		Conditions:   []system.Condition{{From: "name", Column: "users.name", Operator: "="}},
+		JoinTemplate: []system.JoinTemplate{{
+			Table:     "depts",
+			JoinType:  system.JoinTypeLeft,
+			OnColumns: []system.JoinOn{{Left: "users.dept_id", Right: "depts.id"}},
+		}},
+	}
+}
+
+func TestValidateExportTemplateRejectsHostileInput(t *testing.T) {
+	db := newExportDB(t)
+	tests := []struct {
+		name   string
+		modify func(template *system.SysExportTemplate)
+	}{
+		{"主表名注入", func(template *system.SysExportTemplate) { template.TableName = "users; DROP TABLE users" }},
+		{"主表名带引号", func(template *system.SysExportTemplate) { template.TableName = "users`" }},
+		{"主表不存在", func(template *system.SysExportTemplate) { template.TableName = "missing" }},
+		{"导出字段子查询", func(template *system.SysExportTemplate) {
+			template.TemplateInfo = `{"(select password from users)":"密码"}`
+		}},
+		{"导出字段注入", func(template *system.SysExportTemplate) { template.TemplateInfo = `{"name FROM users; --":"姓名"}` }},
+		{"导出别名注入", func(template *system.SysExportTemplate) { template.TemplateInfo = `{"name as x, password":"姓名"}` }},
+		{"导出字段不存在", func(template *system.SysExportTemplate) { template.TemplateInfo = `{"password":"密码"}` }},
+		{"导出未关联表的字段", func(template *system.SysExportTemplate) { template.TemplateInfo = `{"sys_users.password":"密码"}` }},
+		{"关联表名注入", func(template *system.SysExportTemplate) { template.JoinTemplate[0].Table = "depts ON 1=1 --" }},
+		{"关联方式注入", func(template *system.SysExportTemplate) { template.JoinTemplate[0].JoinType = "LEFT JOIN depts; --" }},
+		{"关联方式无效", func(template *system.SysExportTemplate) { template.JoinTemplate[0].JoinType = "CROSS" }},
+		{"关联字段注入", func(template *system.SysExportTemplate) {
+			template.JoinTemplate[0].OnColumns[0].Left = "users.dept_id OR 1=1"
+		}},
+		{"关联字段不属于关联表", func(template *system.SysExportTemplate) {
+			template.JoinTemplate[0].OnColumns[0].Right = "users.id"
+		}},
+		{"关联字段属于关联表自身", func(template *system.SysExportTemplate) {
+			template.JoinTemplate[0].OnColumns[0].Left = "depts.name"
+		}},
+		{"关联表重复", func(template *system.SysExportTemplate) {
+			template.JoinTemplate = append(template.JoinTemplate, template.JoinTemplate[0])
+		}},
+		{"旧版关联条件", func(template *system.SysExportTemplate) {
+			template.JoinTemplate[0].OnColumns = nil
+			template.JoinTemplate[0].ON = "users.dept_id = depts.id OR 1=1"
+		}},
+		{"条件字段注入", func(template *system.SysExportTemplate) { template.Conditions[0].Column = "name = name OR 1" }},
+		{"条件操作符注入", func(template *system.SysExportTemplate) { template.Conditions[0].Operator = "= 1 OR 1 =" }},
+		{"排序字段注入", func(template *system.SysExportTemplate) {
+			template.OrderBy = []system.ExportOrder{{Column: "id; DROP TABLE users"}}
+		}},
+		{"更新依据字段不是主表字段", func(template *system.SysExportTemplate) { template.ImportKey = "depts.name" }},
+	}
+	_, err := validateExportTemplate(db, exportTestTemplate())
+	assert.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := exportTestTemplate()
+			tt.modify(&template)
+			_, err := validateExportTemplate(db, template)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPrepareExportQuotesIdentifiers(t *testing.T) {
+	db := newExportDB(t)
+	template := exportTestTemplate()
+	assert.NoError(t, db.Create(&template).Error)
+
+	// 条件的值作为参数传入 不拼接到SQL中
+	for value, want := range map[string]string{
+		"alice":                `{"dept":"dev","name":"alice"}` + "\n",
+		"bob' OR '1'='1":       "",
+		"alice\" OR \"1\"=\"1": "",
+	} {
+		export, err := SysExportTemplateServiceApp.PrepareExport("users", url.Values{
+			"format": {system.ExportFormatNdjson},
+			"params": {url.Values{"name": {value}}.Encode()},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		sql := export.db.Session(&gorm.Session{DryRun: true}).Find(&[]map[string]interface{}{}).Statement.SQL.String()
+		quote := db.Statement.Quote
+		for _, column := range []string{"users.name", "depts.name", "users.dept_id", "depts.id", "depts"} {
+			assert.Contains(t, sql, quote(column))
+		}
+		assert.NotContains(t, sql, value)
+
+		var out bytes.Buffer
+		assert.NoError(t, export.Stream(context.Background(), &out))
+		assert.Equal(t, want, out.String(), value)
+	}
+
+	// 排序参数同样按表结构校验
+	_, err := SysExportTemplateServiceApp.PrepareExport("users", url.Values{
+		"format": {system.ExportFormatNdjson},
+		"params": {url.Values{"order": {"(select 1) desc"}}.Encode()},
+	})
+	assert.Error(t, err)
+	var count int64
+	assert.NoError(t, db.Table("users").Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestMigrateLegacyTemplatesNormalizesOperators(t *testing.T) {
+	db := newExportDB(t)
+	legacy := exportTestTemplate()
+	legacy.OrderBy = nil
+	legacy.Order = "id desc"
+	legacy.Conditions = []system.Condition{{From: "dept", Column: "dept_id", Operator: "not  in"}}
+	assert.NoError(t, db.Create(&legacy).Error)
+	// 只有操作符为小写的模板同样需要迁移
+	lower := exportTestTemplate()
+	lower.TemplateID = "lower"
+	lower.Conditions = []system.Condition{{From: "name", Column: "name", Operator: "like"}}
+	assert.NoError(t, db.Create(&lower).Error)
+	current := exportTestTemplate()
+	current.TemplateID = "current"
+	assert.NoError(t, db.Create(&current).Error)
+
+	migrated, err := SysExportTemplateServiceApp.MigrateLegacyTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+
+	for id, operator := range map[string]string{"users": "NOT IN", "lower": "LIKE", "current": "="} {
+		var template system.SysExportTemplate
+		assert.NoError(t, db.Preload("Conditions").Preload("JoinTemplate").First(&template, "template_id = ?", id).Error)
+		assert.Equal(t, operator, template.Conditions[0].Operator)
+		_, err = validateExportTemplate(db, template)
+		assert.NoError(t, err, id)
+	}
+}
+
+func TestCheckTemplateAuthority(t *testing.T) {
+	db := newExportDB(t)
+	conf := global.GVA_CONFIG.Excel
+	defer func() { global.GVA_CONFIG.Excel = conf }()
+	global.GVA_CONFIG.Excel = config.Excel{TablePolicies: []config.ExcelTablePolicy{
+		{Authorities: []uint{888}, Tables: []string{"*"}},
+		{Authorities: []uint{9528}, Tables: []string{"users"}},
+	}}
+	joined := exportTestTemplate()
+	assert.NoError(t, db.Create(&joined).Error)
+	single := exportTestTemplate()
+	single.TemplateID = "single"
+	single.TemplateInfo = `{"name":"姓名"}`
+	single.JoinTemplate = nil
+	assert.NoError(t, db.Create(&single).Error)
+
+	service := SysExportTemplateServiceApp
+	assert.NoError(t, service.CheckTemplateAuthority("users", 888))
+	assert.NoError(t, service.CheckTemplateAuthority("single", 9528))
+	// 关联表不在允许的范围内
+	err := service.CheckTemplateAuthority("users", 9528)
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "depts"))
+	}
+	// 默认检查 没有配置的角色不能导出
+	assert.Error(t, service.CheckTemplateAuthority("single", 1))
+
+	global.GVA_CONFIG.Excel.UnrestrictedTables = true
+	assert.NoError(t, service.CheckTemplateAuthority("users", 1))
+}
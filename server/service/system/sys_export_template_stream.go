@@ -0,0 +1,325 @@
+package system
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TemplateExport 按导出模板构建好的查询 Stream 时从游标逐行读取并写出
+type TemplateExport struct {
+	Name   string
+	Format string
+	// OnProgress 不为空时每写出 1000 行调用一次
+	OnProgress func(rows int)
+	// Rows Stream 后为已写出的数据行数
+	Rows int
+
+	db         *gorm.DB
+	orders     []clause.OrderByColumn
+	style      system.ExportSheetStyle
+	columns    []system.ExportColumn
+	formatters []*exportFormatter
+	// fields 为模板中的字段 keys 为查询结果中对应的别名
+	fields []string
+	keys   []string
+}
+
+// FileName 导出文件名
+func (e *TemplateExport) FileName() string {
+	return e.Name + "." + e.Format
+}
+
+// ContentType 导出文件的 Content-Type
+func (e *TemplateExport) ContentType() string {
+	switch e.Format {
+	case system.ExportFormatCsv:
+		return "text/csv; charset=utf-8"
+	case system.ExportFormatNdjson:
+		return "application/x-ndjson"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+// exportRowWriter 按格式写出标题与数据行
+type exportRowWriter interface {
+	Header(columns []system.ExportColumn) error
+	Row(keys []string, row map[string]interface{}) error
+	Close() error
+}
+
+// Stream 执行查询并逐行写入 w
+func (e *TemplateExport) Stream(ctx context.Context, w io.Writer) error {
+	var writer exportRowWriter
+	switch e.Format {
+	case system.ExportFormatCsv:
+		writer = newCsvExportWriter(w)
+	case system.ExportFormatNdjson:
+		writer = &ndjsonExportWriter{encoder: json.NewEncoder(w)}
+	default:
+		xlsx, err := newXlsxExportWriter(w, e.columns, e.style)
+		if err != nil {
+			return err
+		}
+		writer = xlsx
+	}
+	if err := e.stream(ctx, writer); err != nil {
+		if xlsx, ok := writer.(*xlsxExportWriter); ok {
+			xlsx.file.Close()
+		}
+		return err
+	}
+	return writer.Close()
+}
+
+func (e *TemplateExport) stream(ctx context.Context, writer exportRowWriter) error {
+	if err := writer.Header(e.columns); err != nil {
+		return err
+	}
+	return e.each(ctx, func(row map[string]interface{}) error {
+		return writer.Row(e.keys, row)
+	})
+}
+
+// each 执行查询 逐行转换格式后调用 fn
+func (e *TemplateExport) each(ctx context.Context, fn func(row map[string]interface{}) error) error {
+	db := e.db.WithContext(ctx)
+	for _, order := range e.orders {
+		db = db.Order(order)
+	}
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row := make(map[string]interface{}, len(e.keys))
+		if err = db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		for i, formatter := range e.formatters {
+			if formatter != nil {
+				row[e.keys[i]] = formatter.format(row[e.keys[i]], e.Format == system.ExportFormatXlsx)
+			}
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+		e.Rows++
+		if e.OnProgress != nil && e.Rows%1000 == 0 {
+			e.OnProgress(e.Rows)
+		}
+	}
+	return rows.Err()
+}
+
+// exportValue 时间格式化为 2006-01-02 15:04:05 字节转为字符串 其他原样返回
+func exportValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case []byte:
+		return string(v)
+	}
+	return v
+}
+
+func exportString(v interface{}) string {
+	v = exportValue(v)
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+type xlsxExportWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+	// header 表头样式 styles 为每列的数字格式样式 0 表示没有 text 为转换为展示值的列 不按数字写入
+	header int
+	styles []int
+	text   []bool
+}
+
+// newXlsxExportWriter 列宽、冻结表头须在写入数据行之前设置
+func newXlsxExportWriter(w io.Writer, columns []system.ExportColumn, style system.ExportSheetStyle) (*xlsxExportWriter, error) {
+	f := excelize.NewFile()
+	x, err := newXlsxSheetWriter(f, "Sheet1", columns, style, 0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	x.w = w
+	return x, nil
+}
+
+// newXlsxSheetWriter 在 f 中创建工作表并写入 表头前有 top 行其他内容时冻结窗格下移
+// 同一个文件中的工作表须依次写入 写完后调用 Flush 再创建下一个
+func newXlsxSheetWriter(f *excelize.File, sheet string, columns []system.ExportColumn, style system.ExportSheetStyle, top int) (*xlsxExportWriter, error) {
+	if index, err := f.GetSheetIndex(sheet); err != nil {
+		return nil, err
+	} else if index == -1 {
+		if _, err = f.NewSheet(sheet); err != nil {
+			return nil, err
+		}
+	}
+	x := &xlsxExportWriter{file: f, styles: make([]int, len(columns)), text: make([]bool, len(columns))}
+	if err := x.init(sheet, columns, style, top); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxExportWriter) init(sheet string, columns []system.ExportColumn, style system.ExportSheetStyle, top int) (err error) {
+	if x.stream, err = x.file.NewStreamWriter(sheet); err != nil {
+		return err
+	}
+	for i, column := range columns {
+		x.text[i] = column.Dict != "" || column.Date != "" || len(column.Bool) == 2
+		if column.Width > 0 {
+			if err = x.stream.SetColWidth(i+1, i+1, column.Width); err != nil {
+				return err
+			}
+		}
+		if column.Number != "" {
+			numFmt := column.Number
+			if x.styles[i], err = x.file.NewStyle(&excelize.Style{CustomNumFmt: &numFmt}); err != nil {
+				return err
+			}
+		}
+	}
+	if style.FreezeHeader {
+		topLeft, _ := excelize.CoordinatesToCellName(1, top+2)
+		err = x.stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: top + 1, TopLeftCell: topLeft, ActivePane: "bottomLeft"})
+		if err != nil {
+			return err
+		}
+	}
+	if style.HeaderBold || style.HeaderFill != "" || style.HeaderColor != "" {
+		headerStyle := &excelize.Style{Font: &excelize.Font{Bold: style.HeaderBold, Color: style.HeaderColor}}
+		if style.HeaderFill != "" {
+			headerStyle.Fill = excelize.Fill{Type: "pattern", Color: []string{style.HeaderFill}, Pattern: 1}
+		}
+		if x.header, err = x.file.NewStyle(headerStyle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *xlsxExportWriter) setRow(values []interface{}) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, values)
+}
+
+func (x *xlsxExportWriter) Header(columns []system.ExportColumn) error {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = excelize.Cell{StyleID: x.header, Value: column.Title}
+	}
+	return x.setRow(values)
+}
+
+func (x *xlsxExportWriter) Row(keys []string, row map[string]interface{}) error {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		var value interface{}
+		s := exportString(row[key])
+		if v, err := strconv.ParseFloat(s, 64); err == nil && !x.text[i] {
+			value = v
+		} else {
+			value = s
+		}
+		if x.styles[i] != 0 {
+			value = excelize.Cell{StyleID: x.styles[i], Value: value}
+		}
+		values[i] = value
+	}
+	return x.setRow(values)
+}
+
+func (x *xlsxExportWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.w)
+}
+
+type csvExportWriter struct {
+	w      io.Writer
+	writer *csv.Writer
+}
+
+func newCsvExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{w: w, writer: csv.NewWriter(w)}
+}
+
+func (c *csvExportWriter) Header(columns []system.ExportColumn) error {
+	// 写入 BOM 使 Excel 以 UTF-8 打开
+	if _, err := io.WriteString(c.w, "\xEF\xBB\xBF"); err != nil {
+		return err
+	}
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		titles[i] = column.Title
+	}
+	return c.writer.Write(titles)
+}
+
+func (c *csvExportWriter) Row(keys []string, row map[string]interface{}) error {
+	record := make([]string, len(keys))
+	for i, key := range keys {
+		record[i] = exportString(row[key])
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvExportWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// ndjsonExportWriter 每行一个JSON对象 键为模板字段 不写标题
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonExportWriter) Header([]system.ExportColumn) error {
+	return nil
+}
+
+func (n *ndjsonExportWriter) Row(keys []string, row map[string]interface{}) error {
+	record := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		record[key] = exportValue(row[key])
+	}
+	return n.encoder.Encode(record)
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return nil
+}
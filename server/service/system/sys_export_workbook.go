@@ -0,0 +1,637 @@
+package system
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm/clause"
+)
+
+// workbookMaxSheets 一个工作簿最多的工作表数量 防止按取值很多的字段拆分
+const workbookMaxSheets = 200
+
+// workbookSheetNameReplacer 工作表名称中不能包含的字符
+var workbookSheetNameReplacer = strings.NewReplacer(":", "_", "\\", "_", "/", "_", "?", "_", "*", "_", "[", "_", "]", "_")
+
+// aggregateTitles 汇总工作表中合计列标题的后缀
+var aggregateTitles = map[string]string{
+	system.AggregateSum:   " 合计",
+	system.AggregateCount: " 计数",
+	system.AggregateAvg:   " 平均",
+}
+
+type ExportWorkbookService struct{}
+
+var ExportWorkbookServiceApp = new(ExportWorkbookService)
+
+// validateExportWorkbook 校验各工作表的模板与字段 工作簿标识不能重复
+func validateExportWorkbook(workbook system.SysExportWorkbook) error {
+	if len(workbook.Sheets) == 0 {
+		return errors.New("至少需要一个工作表")
+	}
+	if workbook.Info != "" && workbook.Info != system.WorkbookInfoHeader && workbook.Info != system.WorkbookInfoFooter {
+		return fmt.Errorf("导出参数的位置 %s 无效", workbook.Info)
+	}
+	for i, sheet := range workbook.Sheets {
+		if err := validateWorkbookSheet(sheet); err != nil {
+			return fmt.Errorf("第 %d 个工作表: %w", i+1, err)
+		}
+	}
+	var count int64
+	err := global.GVA_DB.Model(&system.SysExportWorkbook{}).Where("workbook_id = ? AND id <> ?", workbook.WorkbookID, workbook.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("存在相同标识的组合导出")
+	}
+	return nil
+}
+
+func validateWorkbookSheet(sheet system.WorkbookSheet) error {
+	var template system.SysExportTemplate
+	if err := global.GVA_DB.Where("template_id = ?", sheet.TemplateID).First(&template).Error; err != nil {
+		return fmt.Errorf("导出模板 %s 不存在", sheet.TemplateID)
+	}
+	if !template.AllowFormat(system.ExportFormatXlsx) {
+		return fmt.Errorf("模板 %s 不支持导出 xlsx", sheet.TemplateID)
+	}
+	if _, err := url.ParseQuery(sheet.Params); err != nil {
+		return fmt.Errorf("解析 params 参数失败: %v", err)
+	}
+	keys, _, err := templateColumns(template)
+	if err != nil {
+		return err
+	}
+	for _, field := range []string{sheet.SplitBy, sheet.GroupBy} {
+		if field != "" && !slices.Contains(keys, field) {
+			return fmt.Errorf("字段 %s 不在模板 %s 中", field, sheet.TemplateID)
+		}
+	}
+	columns := make(map[string]bool, len(sheet.Aggregates))
+	for _, aggregate := range sheet.Aggregates {
+		if !slices.Contains(keys, aggregate.Column) {
+			return fmt.Errorf("合计字段 %s 不在模板 %s 中", aggregate.Column, sheet.TemplateID)
+		}
+		if columns[aggregate.Column] {
+			return fmt.Errorf("合计字段 %s 重复", aggregate.Column)
+		}
+		columns[aggregate.Column] = true
+		if _, ok := aggregateTitles[aggregate.Func]; !ok {
+			return fmt.Errorf("合计方式 %s 无效 只支持 sum、count、avg", aggregate.Func)
+		}
+	}
+	if sheet.GroupBy != "" && len(sheet.Aggregates) == 0 {
+		return errors.New("按字段分组时需要设置合计字段")
+	}
+	if sheet.Summary != "" && (sheet.SplitBy == "" || len(sheet.Aggregates) == 0) {
+		return errors.New("汇总工作表需要设置拆分字段与合计字段")
+	}
+	return nil
+}
+
+// CreateExportWorkbook 创建组合导出
+func (exportWorkbookService *ExportWorkbookService) CreateExportWorkbook(workbook *system.SysExportWorkbook) error {
+	if err := validateExportWorkbook(*workbook); err != nil {
+		return err
+	}
+	return global.GVA_DB.Create(workbook).Error
+}
+
+// UpdateExportWorkbook 更新组合导出
+func (exportWorkbookService *ExportWorkbookService) UpdateExportWorkbook(workbook system.SysExportWorkbook) error {
+	if err := validateExportWorkbook(workbook); err != nil {
+		return err
+	}
+	return global.GVA_DB.Model(&system.SysExportWorkbook{}).Where("id = ?", workbook.ID).
+		Select("name", "workbook_id", "sheets", "info").Updates(&workbook).Error
+}
+
+// DeleteExportWorkbook 删除组合导出
+func (exportWorkbookService *ExportWorkbookService) DeleteExportWorkbook(id uint) error {
+	return global.GVA_DB.Delete(&system.SysExportWorkbook{}, "id = ?", id).Error
+}
+
+// GetExportWorkbook 根据ID获取组合导出
+func (exportWorkbookService *ExportWorkbookService) GetExportWorkbook(id uint) (workbook system.SysExportWorkbook, err error) {
+	err = global.GVA_DB.Where("id = ?", id).First(&workbook).Error
+	return
+}
+
+// GetExportWorkbookList 分页获取组合导出列表
+func (exportWorkbookService *ExportWorkbookService) GetExportWorkbookList(info systemReq.SysExportWorkbookSearch) (list []system.SysExportWorkbook, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysExportWorkbook{})
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.WorkbookID != "" {
+		db = db.Where("workbook_id = ?", info.WorkbookID)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	err = db.Order("id").Find(&list).Error
+	return list, total, err
+}
+
+// CheckWorkbookAuthority 检查角色是否可以导出组合导出中的所有模板
+func (exportWorkbookService *ExportWorkbookService) CheckWorkbookAuthority(workbookID string, authorityID uint) error {
+	var workbook system.SysExportWorkbook
+	if err := global.GVA_DB.Where("workbook_id = ?", workbookID).First(&workbook).Error; err != nil {
+		return err
+	}
+	for _, sheet := range workbook.Sheets {
+		if err := SysExportTemplateServiceApp.CheckTemplateAuthority(sheet.TemplateID, authorityID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WorkbookExport 按组合导出构建好的各工作表的查询 Stream 时依次查询写入同一个工作簿
+type WorkbookExport struct {
+	Name string
+
+	info   string
+	time   time.Time
+	sheets []*workbookSheetExport
+}
+
+// workbookSheetExport 一个工作表定义的导出 split、group 为拆分、分组字段的下标 没有时为 -1 aggregates 为合计字段的下标
+type workbookSheetExport struct {
+	system.WorkbookSheet
+	export     *TemplateExport
+	params     url.Values
+	split      int
+	group      int
+	aggregates []int
+}
+
+// FileName 导出文件名
+func (e *WorkbookExport) FileName() string {
+	return e.Name + ".xlsx"
+}
+
+// PrepareWorkbook 构建各工作表的导出 values 中的 params 传给每个模板 工作表的固定参数优先
+// 拆分、分组的行须相邻 按拆分、分组字段排序后再按模板的排序 此时 limit 取的是按该顺序的前几行
+func (exportWorkbookService *ExportWorkbookService) PrepareWorkbook(workbookID string, values url.Values) (*WorkbookExport, error) {
+	params, err := url.ParseQuery(values.Get("params"))
+	if err != nil {
+		return nil, fmt.Errorf("解析 params 参数失败: %v", err)
+	}
+	var workbook system.SysExportWorkbook
+	if err = global.GVA_DB.Where("workbook_id = ?", workbookID).First(&workbook).Error; err != nil {
+		return nil, err
+	}
+	e := &WorkbookExport{Name: workbook.Name, info: workbook.Info, time: time.Now()}
+	for _, sheet := range workbook.Sheets {
+		fixed, err := url.ParseQuery(sheet.Params)
+		if err != nil {
+			return nil, fmt.Errorf("解析 params 参数失败: %v", err)
+		}
+		merged := maps.Clone(params)
+		maps.Copy(merged, fixed)
+		export, err := SysExportTemplateServiceApp.PrepareExport(sheet.TemplateID, url.Values{
+			"params": {merged.Encode()},
+			"format": {system.ExportFormatXlsx},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("模板 %s: %w", sheet.TemplateID, err)
+		}
+		s := &workbookSheetExport{WorkbookSheet: sheet, export: export, params: merged, split: -1, group: -1}
+		if s.Name == "" && s.SplitBy == "" {
+			s.Name = export.Name
+		}
+		var orders []clause.OrderByColumn
+		for _, field := range []struct {
+			name  string
+			index *int
+		}{{sheet.SplitBy, &s.split}, {sheet.GroupBy, &s.group}} {
+			if field.name == "" {
+				continue
+			}
+			if *field.index = slices.Index(export.fields, field.name); *field.index == -1 {
+				return nil, fmt.Errorf("字段 %s 不在模板 %s 中", field.name, sheet.TemplateID)
+			}
+			orders = append(orders, clause.OrderByColumn{Column: clause.Column{Name: export.keys[*field.index]}})
+		}
+		export.orders = append(orders, export.orders...)
+		for _, aggregate := range sheet.Aggregates {
+			index := slices.Index(export.fields, aggregate.Column)
+			if index == -1 {
+				return nil, fmt.Errorf("合计字段 %s 不在模板 %s 中", aggregate.Column, sheet.TemplateID)
+			}
+			s.aggregates = append(s.aggregates, index)
+		}
+		e.sheets = append(e.sheets, s)
+	}
+	return e, nil
+}
+
+// Stream 依次导出各工作表 写完后写入 w
+func (e *WorkbookExport) Stream(ctx context.Context, w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	b := &workbookBuilder{WorkbookExport: e, file: f, used: make(map[string]bool)}
+	for _, s := range e.sheets {
+		if err := b.writeSheets(ctx, s); err != nil {
+			return err
+		}
+	}
+	if !b.used["sheet1"] {
+		if err := f.DeleteSheet("Sheet1"); err != nil {
+			return err
+		}
+	}
+	f.SetActiveSheet(0)
+	_, err := f.WriteTo(w)
+	return err
+}
+
+// workbookBuilder 写入工作簿时的状态 used 为已使用的工作表名称 不区分大小写
+type workbookBuilder struct {
+	*WorkbookExport
+	file   *excelize.File
+	used   map[string]bool
+	sheets int
+}
+
+// workbookSummaryRow 汇总工作表中的一行 为拆分出的一个工作表的合计
+type workbookSummaryRow struct {
+	value string
+	total *exportAggregator
+}
+
+// writeSheets 写入一个工作表定义 拆分时拆分字段的值变化后换到新的工作表
+func (b *workbookBuilder) writeSheets(ctx context.Context, s *workbookSheetExport) error {
+	var (
+		w       *workbookSheetWriter
+		split   string
+		first   string
+		summary []workbookSummaryRow
+	)
+	open := func(name string) (err error) {
+		if w, err = b.openSheet(s, name); err == nil && first == "" {
+			first = w.name
+		}
+		return err
+	}
+	closeSheet := func() error {
+		if err := w.close(); err != nil {
+			return err
+		}
+		if w.rows > 0 {
+			summary = append(summary, workbookSummaryRow{value: split, total: w.total})
+		}
+		return nil
+	}
+	err := s.export.each(ctx, func(row map[string]interface{}) error {
+		if s.split >= 0 {
+			value := exportString(row[s.export.keys[s.split]])
+			if w == nil || value != split {
+				if w != nil {
+					if err := closeSheet(); err != nil {
+						return err
+					}
+				}
+				split = value
+				if err := open(s.splitSheetName(value)); err != nil {
+					return err
+				}
+			}
+		} else if w == nil {
+			if err := open(s.Name); err != nil {
+				return err
+			}
+		}
+		return w.row(row)
+	})
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		// 没有数据时输出只有表头的工作表
+		if err = open(cmp.Or(s.Name, s.export.Name)); err != nil {
+			return err
+		}
+	}
+	if err = closeSheet(); err != nil {
+		return err
+	}
+	if s.Summary != "" && s.split >= 0 {
+		return b.writeSummary(s, summary, first)
+	}
+	return nil
+}
+
+func (s *workbookSheetExport) funcs() []string {
+	funcs := make([]string, len(s.Aggregates))
+	for i, aggregate := range s.Aggregates {
+		funcs[i] = aggregate.Func
+	}
+	return funcs
+}
+
+func (s *workbookSheetExport) splitSheetName(value string) string {
+	value = cmp.Or(value, "(空)")
+	if s.Name == "" {
+		return value
+	}
+	return s.Name + "-" + value
+}
+
+// sheetName Excel 的工作表名称不能包含 :\/?*[] 最长 31 个字符 不区分大小写且不能重复 重复时加上序号
+func (b *workbookBuilder) sheetName(name string) string {
+	name = strings.Trim(workbookSheetNameReplacer.Replace(name), "'")
+	name = truncateRunes(name, 31)
+	if name == "" {
+		name = "Sheet"
+	}
+	unique := name
+	for i := 2; b.used[strings.ToLower(unique)]; i++ {
+		suffix := "(" + strconv.Itoa(i) + ")"
+		unique = truncateRunes(name, 31-len(suffix)) + suffix
+	}
+	b.used[strings.ToLower(unique)] = true
+	return unique
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// infoRows 导出时间与导出参数 每个参数一行
+func (b *workbookBuilder) infoRows(params url.Values) [][]interface{} {
+	rows := [][]interface{}{{"导出时间", b.time.Format(time.DateTime)}}
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		rows = append(rows, []interface{}{key, strings.Join(params[key], ",")})
+	}
+	return rows
+}
+
+// newSheet 创建工作表 在表头前写入导出参数
+func (b *workbookBuilder) newSheet(name string, columns []system.ExportColumn, style system.ExportSheetStyle, params url.Values) (*xlsxExportWriter, string, error) {
+	b.sheets++
+	if b.sheets > workbookMaxSheets {
+		return nil, "", fmt.Errorf("工作表超过 %d 个", workbookMaxSheets)
+	}
+	name = b.sheetName(name)
+	var info [][]interface{}
+	top := 0
+	if b.info == system.WorkbookInfoHeader {
+		info = b.infoRows(params)
+		top = len(info) + 1
+	}
+	x, err := newXlsxSheetWriter(b.file, name, columns, style, top)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, row := range info {
+		if err = x.setRow(row); err != nil {
+			return nil, "", err
+		}
+	}
+	if top > 0 {
+		x.row++ // 与表头之间空一行
+	}
+	if err = x.Header(columns); err != nil {
+		return nil, "", err
+	}
+	return x, name, nil
+}
+
+// closeSheet 在末尾写入导出参数 然后结束工作表
+func (b *workbookBuilder) closeSheet(x *xlsxExportWriter, params url.Values) error {
+	if b.info == system.WorkbookInfoFooter {
+		x.row++
+		for _, row := range b.infoRows(params) {
+			if err := x.setRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return x.stream.Flush()
+}
+
+func (b *workbookBuilder) openSheet(s *workbookSheetExport, name string) (*workbookSheetWriter, error) {
+	x, name, err := b.newSheet(name, s.export.columns, s.export.style, s.params)
+	if err != nil {
+		return nil, err
+	}
+	return &workbookSheetWriter{
+		xlsxExportWriter: x,
+		builder:          b,
+		sheet:            s,
+		name:             name,
+		total:            newExportAggregator(s.funcs()),
+		subtotal:         newExportAggregator(s.funcs()),
+	}, nil
+}
+
+// writeSummary 汇总工作表 每个拆分出的工作表一行合计 最后一行为总计 放在拆分出的工作表之前
+func (b *workbookBuilder) writeSummary(s *workbookSheetExport, rows []workbookSummaryRow, before string) error {
+	splitColumn := s.export.columns[s.split]
+	columns := []system.ExportColumn{{Title: splitColumn.Title, Width: splitColumn.Width}}
+	keys := []string{"0"}
+	for i, aggregate := range s.Aggregates {
+		column := s.export.columns[s.aggregates[i]]
+		summaryColumn := system.ExportColumn{Title: column.Title + aggregateTitles[aggregate.Func], Width: column.Width}
+		if aggregate.Func != system.AggregateCount {
+			summaryColumn.Number = column.Number
+		}
+		columns = append(columns, summaryColumn)
+		keys = append(keys, strconv.Itoa(i+1))
+	}
+	x, name, err := b.newSheet(s.Summary, columns, s.export.style, s.params)
+	if err != nil {
+		return err
+	}
+	x.text[0] = true // 拆分字段的值按文本写入
+	total := newExportAggregator(s.funcs())
+	write := func(label string, a *exportAggregator) error {
+		row := map[string]interface{}{"0": label}
+		for i, value := range a.results() {
+			row[keys[i+1]] = value
+		}
+		return x.Row(keys, row)
+	}
+	for _, row := range rows {
+		if err = write(cmp.Or(row.value, "(空)"), row.total); err != nil {
+			return err
+		}
+		total.merge(row.total)
+	}
+	if err = write("合计", total); err != nil {
+		return err
+	}
+	if err = b.closeSheet(x, s.params); err != nil {
+		return err
+	}
+	if before != "" {
+		return b.file.MoveSheet(name, before)
+	}
+	return nil
+}
+
+// workbookSheetWriter 写入一个工作表 累计合计与当前分组的小计
+type workbookSheetWriter struct {
+	*xlsxExportWriter
+	builder  *workbookBuilder
+	sheet    *workbookSheetExport
+	name     string
+	rows     int
+	group    string
+	grouped  bool
+	total    *exportAggregator
+	subtotal *exportAggregator
+}
+
+func (w *workbookSheetWriter) row(row map[string]interface{}) error {
+	if w.sheet.group >= 0 {
+		value := exportString(row[w.sheet.export.keys[w.sheet.group]])
+		if w.grouped && value != w.group {
+			if err := w.subtotalRow(); err != nil {
+				return err
+			}
+		}
+		w.group = value
+	}
+	values := make([]interface{}, len(w.sheet.aggregates))
+	for i, index := range w.sheet.aggregates {
+		values[i] = row[w.sheet.export.keys[index]]
+	}
+	w.total.add(values)
+	w.subtotal.add(values)
+	w.grouped = true
+	w.rows++
+	return w.Row(w.sheet.export.keys, row)
+}
+
+func (w *workbookSheetWriter) subtotalRow() error {
+	if !w.grouped {
+		return nil
+	}
+	err := w.aggregateRow(w.sheet.group, "小计 "+w.group, w.subtotal)
+	w.subtotal.reset()
+	w.grouped = false
+	return err
+}
+
+// aggregateRow 合计字段列写入合计 label 写在 labelAt 列 该列为合计字段时写在第一个不是合计字段的列
+func (w *workbookSheetWriter) aggregateRow(labelAt int, label string, a *exportAggregator) error {
+	keys := w.sheet.export.keys
+	row := make(map[string]interface{}, len(keys))
+	for i, value := range a.results() {
+		row[keys[w.sheet.aggregates[i]]] = value
+	}
+	if labelAt < 0 || slices.Contains(w.sheet.aggregates, labelAt) {
+		labelAt = -1
+		for i := range keys {
+			if !slices.Contains(w.sheet.aggregates, i) {
+				labelAt = i
+				break
+			}
+		}
+	}
+	if labelAt >= 0 {
+		row[keys[labelAt]] = label
+	}
+	return w.Row(keys, row)
+}
+
+// close 写入最后一组的小计与合计行 然后结束工作表
+func (w *workbookSheetWriter) close() error {
+	if len(w.sheet.aggregates) > 0 {
+		if w.sheet.group >= 0 {
+			if err := w.subtotalRow(); err != nil {
+				return err
+			}
+		}
+		if err := w.aggregateRow(-1, "合计", w.total); err != nil {
+			return err
+		}
+	}
+	return w.builder.closeSheet(w.xlsxExportWriter, w.sheet.params)
+}
+
+// exportAggregator 累计合计值 sum、avg 只计算数字 count 为非空值的个数
+type exportAggregator struct {
+	funcs   []string
+	sums    []float64
+	numbers []int
+	counts  []int
+}
+
+func newExportAggregator(funcs []string) *exportAggregator {
+	a := &exportAggregator{funcs: funcs}
+	a.reset()
+	return a
+}
+
+func (a *exportAggregator) reset() {
+	a.sums = make([]float64, len(a.funcs))
+	a.numbers = make([]int, len(a.funcs))
+	a.counts = make([]int, len(a.funcs))
+}
+
+func (a *exportAggregator) add(values []interface{}) {
+	for i, v := range values {
+		s := exportString(v)
+		if s == "" {
+			continue
+		}
+		a.counts[i]++
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			a.sums[i] += n
+			a.numbers[i]++
+		}
+	}
+}
+
+func (a *exportAggregator) merge(b *exportAggregator) {
+	for i := range a.funcs {
+		a.sums[i] += b.sums[i]
+		a.numbers[i] += b.numbers[i]
+		a.counts[i] += b.counts[i]
+	}
+}
+
+// results 没有数字时平均值为空
+func (a *exportAggregator) results() []interface{} {
+	results := make([]interface{}, len(a.funcs))
+	for i, f := range a.funcs {
+		switch f {
+		case system.AggregateCount:
+			results[i] = a.counts[i]
+		case system.AggregateAvg:
+			if a.numbers[i] > 0 {
+				results[i] = a.sums[i] / float64(a.numbers[i])
+			}
+		default:
+			results[i] = a.sums[i]
+		}
+	}
+	return results
+}
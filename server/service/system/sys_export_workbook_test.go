@@ -0,0 +1,147 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// newWorkbookDB 在导出测试库中增加 orders 表及其模板 组合导出包含 users 与按部门拆分的 orders
+func newWorkbookDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := newExportDB(t)
+	if err := db.AutoMigrate(&system.SysExportWorkbook{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range []string{
+		"CREATE TABLE orders (id integer primary key, dept text, status text, amount real)",
+		"INSERT INTO orders (dept, status, amount) VALUES ('dev', 'paid', 10), ('ops', 'paid', 5), ('dev', 'open', 20.5)",
+	} {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	users := exportTestTemplate()
+	users.Formats = ""
+	orders := system.SysExportTemplate{
+		Name:         "订单",
+		TableName:    "orders",
+		TemplateID:   "orders",
+		TemplateInfo: `{"dept":"部门","status":"状态","amount":"金额"}`,
+		OrderBy:      []system.ExportOrder{{Column: "id"}},
+	}
+	for _, template := range []*system.SysExportTemplate{&users, &orders} {
+		if err := db.Create(template).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestExportWorkbookCombinesSheets(t *testing.T) {
+	newWorkbookDB(t)
+	workbook := system.SysExportWorkbook{
+		Name:       "月报",
+		WorkbookID: "monthly",
+		Info:       system.WorkbookInfoFooter,
+		Sheets: []system.WorkbookSheet{
+			{Name: "用户", TemplateID: "users"},
+			{
+				TemplateID: "orders",
+				SplitBy:    "dept",
+				Aggregates: []system.ExportAggregate{{Column: "amount", Func: system.AggregateSum}},
+				Summary:    "汇总",
+			},
+			// 固定参数覆盖导出时传入的同名参数 重名的工作表加上序号
+			{Name: "用户", TemplateID: "users", Params: "name=alice"},
+		},
+	}
+	service := ExportWorkbookServiceApp
+	assert.NoError(t, service.CreateExportWorkbook(&workbook))
+
+	export, err := service.PrepareWorkbook("monthly", url.Values{"params": {"name=bob"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var out bytes.Buffer
+	assert.NoError(t, export.Stream(context.Background(), &out))
+	f, err := excelize.OpenReader(&out)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	// 汇总工作表在拆分出的工作表之前
+	assert.Equal(t, []string{"用户", "汇总", "dev", "ops", "用户(2)"}, f.GetSheetList())
+	rows := func(sheet string) [][]string {
+		rows, err := f.GetRows(sheet)
+		assert.NoError(t, err)
+		return rows
+	}
+	first := rows("用户")
+	if assert.GreaterOrEqual(t, len(first), 2) {
+		assert.Equal(t, []string{"姓名", "部门"}, first[0])
+		assert.Equal(t, []string{"bob", "ops"}, first[1])
+		assert.Equal(t, "导出时间", first[3][0])
+		assert.Equal(t, []string{"name", "bob"}, first[4])
+	}
+	dev := rows("dev")
+	if assert.Len(t, dev, 7) {
+		assert.Equal(t, []string{"部门", "状态", "金额"}, dev[0])
+		assert.Equal(t, []string{"dev", "paid", "10"}, dev[1])
+		assert.Equal(t, []string{"dev", "open", "20.5"}, dev[2])
+		assert.Equal(t, []string{"合计", "", "30.5"}, dev[3])
+		assert.Equal(t, []string{"name", "bob"}, dev[6])
+	}
+	summary := rows("汇总")
+	if assert.GreaterOrEqual(t, len(summary), 4) {
+		assert.Equal(t, []string{"部门", "金额 合计"}, summary[0])
+		assert.Equal(t, []string{"dev", "30.5"}, summary[1])
+		assert.Equal(t, []string{"ops", "5"}, summary[2])
+		assert.Equal(t, []string{"合计", "35.5"}, summary[3])
+	}
+	fixed := rows("用户(2)")
+	if assert.GreaterOrEqual(t, len(fixed), 2) {
+		assert.Equal(t, []string{"alice", "dev"}, fixed[1])
+		assert.Equal(t, []string{"name", "alice"}, fixed[4])
+	}
+}
+
+func TestCheckWorkbookAuthority(t *testing.T) {
+	newWorkbookDB(t)
+	conf := global.GVA_CONFIG.Excel
+	defer func() { global.GVA_CONFIG.Excel = conf }()
+	global.GVA_CONFIG.Excel = config.Excel{TablePolicies: []config.ExcelTablePolicy{
+		{Authorities: []uint{888}, Tables: []string{"*"}},
+		{Authorities: []uint{9528}, Tables: []string{"users", "depts"}},
+	}}
+	service := ExportWorkbookServiceApp
+	assert.NoError(t, service.CreateExportWorkbook(&system.SysExportWorkbook{
+		Name:       "用户",
+		WorkbookID: "users",
+		Sheets:     []system.WorkbookSheet{{TemplateID: "users"}, {TemplateID: "users", Params: "name=alice"}},
+	}))
+	assert.NoError(t, service.CreateExportWorkbook(&system.SysExportWorkbook{
+		Name:       "月报",
+		WorkbookID: "monthly",
+		Sheets:     []system.WorkbookSheet{{TemplateID: "users"}, {TemplateID: "orders"}},
+	}))
+
+	assert.NoError(t, service.CheckWorkbookAuthority("users", 9528))
+	assert.NoError(t, service.CheckWorkbookAuthority("monthly", 888))
+	// 任意一个工作表的模板无权导出时整个工作簿都不能导出
+	err := service.CheckWorkbookAuthority("monthly", 9528)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "orders")
+	}
+	assert.Error(t, service.CheckWorkbookAuthority("users", 1))
+	assert.Error(t, service.CheckWorkbookAuthority("missing", 888))
+}
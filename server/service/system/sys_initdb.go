@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"sort"
 )
@@ -129,6 +130,17 @@ func (initDBService *InitDBService) InitDB(conf request.InitDB) (err error) {
 	if err = initHandler.InitData(ctx, initializers); err != nil {
 		return err
 	}
+	// 初始数据写入后再注册变更历史回调
+	if err = RegisterChangeHistory(db); err != nil {
+		return err
+	}
+	if err = JobServiceApp.StartJobs(); err != nil {
+		global.GVA_LOG.Error("start jobs failed", zap.Error(err))
+	}
+	if err = ExportSubscriptionServiceApp.StartExportSubscriptions(); err != nil {
+		global.GVA_LOG.Error("start export subscriptions failed", zap.Error(err))
+	}
+	AsyncTaskServiceApp.StartAsyncTasks()
 
 	if err = initHandler.WriteConfig(ctx); err != nil {
 		return err
@@ -0,0 +1,505 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/task"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/timer"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// jobCronName 定时任务在 GVA_Timer 中的 cron 名称
+const jobCronName = "SysJob"
+
+// jobOutputLimit 执行记录保存的输出与错误信息的最大长度
+const jobOutputLimit = 64 * 1024
+
+var jobParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+var (
+	jobEntries   = make(map[uint]cron.EntryID)
+	jobEntriesMu sync.Mutex
+)
+
+type JobService struct{}
+
+var JobServiceApp = new(JobService)
+
+// StartJobs 为带默认执行周期的执行方法创建任务 然后调度所有启用的任务 重复调用时重新调度
+func (jobService *JobService) StartJobs() error {
+	if err := ensureDefaultJobs(); err != nil {
+		return err
+	}
+	jobEntriesMu.Lock()
+	global.GVA_Timer.Clear(jobCronName)
+	jobEntries = make(map[uint]cron.EntryID)
+	jobEntriesMu.Unlock()
+
+	var jobs []system.SysJob
+	if err := global.GVA_DB.Where("enabled = ?", true).Find(&jobs).Error; err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if err := scheduleJob(job); err != nil {
+			global.GVA_LOG.Error("schedule job error:", zap.String("job", job.Name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// ensureDefaultJobs 注册时带默认执行周期的执行方法 若从未创建过同名任务则创建 已删除的任务不会重新创建
+// 任务名称唯一 多个节点同时启动时只有一个节点能创建成功
+func ensureDefaultJobs() error {
+	for _, def := range task.Definitions() {
+		if def.Spec == "" {
+			continue
+		}
+		job := system.SysJob{
+			Name:        def.Name,
+			Handler:     def.Name,
+			Spec:        def.Spec,
+			Params:      def.Params,
+			Enabled:     true,
+			Description: def.Description,
+		}
+		if err := global.GVA_DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&job).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleJob 按任务当前的配置重新调度 停用的任务只取消调度
+func scheduleJob(job system.SysJob) error {
+	jobEntriesMu.Lock()
+	defer jobEntriesMu.Unlock()
+	if id, ok := jobEntries[job.ID]; ok {
+		global.GVA_Timer.RemoveTask(jobCronName, int(id))
+		delete(jobEntries, job.ID)
+	}
+	if !job.Enabled || job.DeletedAt.Valid {
+		return nil
+	}
+	jobID := job.ID
+	overlap := timer.OverlapSkip
+	switch job.Overlap {
+	case system.JobOverlapAllow:
+		overlap = timer.OverlapAllow
+	case system.JobOverlapDelay:
+		overlap = timer.OverlapDelay
+	}
+	onFailure := NotifyTaskFailure
+	if def, ok := task.Get(job.Handler); ok && def.OnFailure != nil {
+		params := job.Params
+		onFailure = func(taskName string, err error) { def.OnFailure(params, err) }
+	}
+	// 每次尝试单独记录 失败后按 1s、2s、4s... 的间隔重试 单节点执行的任务在全部重试期间持有锁
+	id, err := global.GVA_Timer.AddTaskByFuncWithOptions(jobCronName, job.Spec, func(ctx context.Context) error {
+		return executeScheduledJob(ctx, jobID)
+	}, job.Name, timer.TaskOptions{
+		Timeout:   time.Duration(job.Timeout) * time.Second,
+		Retry:     job.Retry,
+		Overlap:   overlap,
+		OnFailure: onFailure,
+		Logger:    global.GVA_LOG,
+		Wrap: func(run func(ctx context.Context) error) error {
+			return runScheduledJob(jobID, run)
+		},
+	}, cron.WithSeconds())
+	if err != nil {
+		return err
+	}
+	jobEntries[job.ID] = id
+	return nil
+}
+
+func unscheduleJob(id uint) {
+	scheduleJob(system.SysJob{GVA_MODEL: global.GVA_MODEL{ID: id}})
+}
+
+// runScheduledJob 到达执行时间时读取最新的任务配置 单节点执行的任务需先获取锁 run 执行任务并重试 返回重试后仍失败的错误
+func runScheduledJob(id uint, run func(ctx context.Context) error) error {
+	var job system.SysJob
+	if err := global.GVA_DB.Where("id = ?", id).First(&job).Error; err != nil || !job.Enabled {
+		return nil
+	}
+	if job.Policy == system.JobPolicyAll {
+		return run(context.Background())
+	}
+
+	locker := newJobLocker()
+	name := "job:" + strconv.FormatUint(uint64(job.ID), 10)
+	owner := utils.NodeID() + ":" + utils.RandomString(8)
+	ttl := jobLockTTL(job)
+	ok, err := locker.Acquire(context.Background(), name, owner, ttl)
+	if err != nil {
+		global.GVA_LOG.Error("acquire job lock error:", zap.String("job", job.Name), zap.Error(err))
+		return nil
+	}
+	if !ok {
+		// 其他节点正在执行或已执行本次调度
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := renewJobLock(locker, name, owner, ttl, job.Name, cancel)
+	runErr := run(ctx)
+	stop()
+	holdJobLock(locker, name, owner, ttl, job.Spec, job.Name)
+	return runErr
+}
+
+// holdJobLock 执行结束后锁保留到下一次调度 时钟稍慢的节点随后触发时不会重复执行 最长保留一个租期
+func holdJobLock(locker jobLocker, name, owner string, ttl time.Duration, spec string, jobName string) {
+	hold := ttl
+	if schedule, err := jobParser.Parse(spec); err == nil {
+		if next := time.Until(schedule.Next(time.Now())); next < hold {
+			hold = next
+		}
+	}
+	var err error
+	if hold > 0 {
+		_, err = locker.Renew(context.Background(), name, owner, hold)
+	} else {
+		err = locker.Release(context.Background(), name, owner)
+	}
+	if err != nil {
+		global.GVA_LOG.Error("release job lock error:", zap.String("job", jobName), zap.Error(err))
+	}
+}
+
+// executeScheduledJob 定时执行的一次尝试 按最新的任务配置执行 尝试次数由 timer.Attempt 获取
+func executeScheduledJob(ctx context.Context, id uint) error {
+	var job system.SysJob
+	result := global.GVA_DB.Where("id = ?", id).Limit(1).Find(&job)
+	if result.Error != nil || result.RowsAffected == 0 {
+		// 重试期间任务已被删除时不再执行
+		return result.Error
+	}
+	return executeJob(ctx, job, system.JobTriggerSchedule, timer.Attempt(ctx))
+}
+
+// jobContext 设置了超时的任务在超时后取消
+func jobContext(parent context.Context, job system.SysJob) (context.Context, context.CancelFunc) {
+	if job.Timeout > 0 {
+		return context.WithTimeout(parent, time.Duration(job.Timeout)*time.Second)
+	}
+	return context.WithCancel(parent)
+}
+
+// jobLockTTL 锁租期 设置了超时的任务为超时时间 否则使用 job.lock-ttl
+func jobLockTTL(job system.SysJob) time.Duration {
+	if job.Timeout > 0 {
+		return time.Duration(job.Timeout) * time.Second
+	}
+	if ttl := global.GVA_CONFIG.Job.LockTTL; ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return time.Minute
+}
+
+// renewJobLock 执行期间每 1/3 租期续期一次 续期失败说明锁已被其他节点获取 取消本次执行
+func renewJobLock(locker jobLocker, name, owner string, ttl time.Duration, jobName string, cancel context.CancelFunc) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ok, err := locker.Renew(context.Background(), name, owner, ttl)
+				if err != nil {
+					global.GVA_LOG.Error("renew job lock error:", zap.String("job", jobName), zap.Error(err))
+					continue
+				}
+				if !ok {
+					global.GVA_LOG.Warn("job lock lost, cancel run", zap.String("job", jobName))
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// executeJob 创建执行记录并执行 返回执行方法的错误
+func executeJob(ctx context.Context, job system.SysJob, trigger string, attempt int) error {
+	run, def, err := startJobRun(job, trigger, attempt)
+	if err != nil {
+		global.GVA_LOG.Error("start job run error:", zap.String("job", job.Name), zap.Error(err))
+		return err
+	}
+	return finishJobRun(ctx, run, def, job.Params)
+}
+
+// startJobRun 创建执行记录 执行方法未注册时记录为失败
+func startJobRun(job system.SysJob, trigger string, attempt int) (run system.SysJobRun, def task.Definition, err error) {
+	run = system.SysJobRun{
+		JobID:     job.ID,
+		JobName:   job.Name,
+		Handler:   job.Handler,
+		Trigger:   trigger,
+		Node:      utils.NodeID(),
+		Attempt:   attempt,
+		Status:    system.JobRunRunning,
+		StartedAt: time.Now(),
+	}
+	def, ok := task.Get(job.Handler)
+	if !ok {
+		run.Status = system.JobRunFailed
+		run.FinishedAt = &run.StartedAt
+		run.Error = fmt.Sprintf("执行方法 %s 未注册", job.Handler)
+	}
+	if err = global.GVA_DB.Create(&run).Error; err != nil {
+		return
+	}
+	if !ok {
+		updateJobLastRun(run)
+		return run, def, errors.New(run.Error)
+	}
+	return run, def, nil
+}
+
+// finishJobRun 执行任务并保存结果 执行方法 panic 时记录为失败
+func finishJobRun(ctx context.Context, run system.SysJobRun, def task.Definition, params string) error {
+	output, runErr := callJobHandler(ctx, def, params)
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Duration = now.Sub(run.StartedAt).Milliseconds()
+	run.Output = truncateJobOutput(output)
+	run.Status = system.JobRunSuccess
+	if runErr != nil {
+		run.Status = system.JobRunFailed
+		run.Error = truncateJobOutput(runErr.Error())
+	}
+	err := global.GVA_DB.Model(&system.SysJobRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"status":      run.Status,
+		"finished_at": run.FinishedAt,
+		"duration":    run.Duration,
+		"output":      run.Output,
+		"error":       run.Error,
+	}).Error
+	if err != nil {
+		global.GVA_LOG.Error("save job run error:", zap.String("job", run.JobName), zap.Error(err))
+	}
+	updateJobLastRun(run)
+	return runErr
+}
+
+func callJobHandler(ctx context.Context, def task.Definition, params string) (output string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			global.GVA_LOG.Error("job handler panic", zap.String("handler", def.Name), zap.Any("panic", r), zap.Stack("stack"))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return def.Handler(ctx, params)
+}
+
+// NotifyTaskFailure 定时任务重试后仍失败时调用 开启 job.notify-email 时通过邮件插件通知
+func NotifyTaskFailure(taskName string, err error) {
+	if !global.GVA_CONFIG.Job.NotifyEmail {
+		return
+	}
+	subject := utils.NodeID() + " 定时任务 " + taskName + " 执行失败"
+	body := "任务: " + taskName + "\n节点: " + utils.NodeID() + "\n时间: " + time.Now().Format(time.DateTime) + "\n错误: " + err.Error() + "\n"
+	if err := emailUtils.ErrorToEmail(subject, body); err != nil {
+		global.GVA_LOG.Error("notify task failure by email error:", zap.String("task", taskName), zap.Error(err))
+	}
+}
+
+func updateJobLastRun(run system.SysJobRun) {
+	err := global.GVA_DB.Model(&system.SysJob{}).Where("id = ?", run.JobID).Updates(map[string]interface{}{
+		"last_run_at": run.StartedAt,
+		"last_status": run.Status,
+	}).Error
+	if err != nil {
+		global.GVA_LOG.Error("update job last run error:", zap.String("job", run.JobName), zap.Error(err))
+	}
+}
+
+func truncateJobOutput(s string) string {
+	if len(s) > jobOutputLimit {
+		return s[:jobOutputLimit]
+	}
+	return s
+}
+
+// validateJob 校验执行方法已注册、cron 表达式有效且名称不重复
+func validateJob(job system.SysJob) error {
+	if job.Policy != "" && job.Policy != system.JobPolicyAll && job.Policy != system.JobPolicySingle {
+		return fmt.Errorf("执行策略 %s 无效", job.Policy)
+	}
+	if job.Overlap != "" && job.Overlap != system.JobOverlapAllow && job.Overlap != system.JobOverlapSkip && job.Overlap != system.JobOverlapDelay {
+		return fmt.Errorf("重叠策略 %s 无效", job.Overlap)
+	}
+	if job.Retry < 0 || job.Timeout < 0 {
+		return errors.New("重试次数和超时时间不能为负数")
+	}
+	if _, ok := task.Get(job.Handler); !ok {
+		return fmt.Errorf("执行方法 %s 未注册", job.Handler)
+	}
+	if _, err := jobParser.Parse(job.Spec); err != nil {
+		return fmt.Errorf("cron表达式无效: %w", err)
+	}
+	// 任务名称唯一 已删除的任务同样占用名称
+	var count int64
+	if err := global.GVA_DB.Unscoped().Model(&system.SysJob{}).Where("name = ? AND id <> ?", job.Name, job.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("存在相同名称的任务 包括已删除的任务")
+	}
+	return nil
+}
+
+// CreateJob 创建定时任务 启用时立即调度
+func (jobService *JobService) CreateJob(job *system.SysJob) error {
+	if err := validateJob(*job); err != nil {
+		return err
+	}
+	job.LastRunAt, job.LastStatus = nil, ""
+	if err := global.GVA_DB.Create(job).Error; err != nil {
+		return err
+	}
+	return scheduleJob(*job)
+}
+
+// UpdateJob 更新定时任务 并按新的配置重新调度
+func (jobService *JobService) UpdateJob(job system.SysJob) error {
+	if err := validateJob(job); err != nil {
+		return err
+	}
+	err := global.GVA_DB.Model(&system.SysJob{}).Where("id = ?", job.ID).
+		Select("name", "handler", "spec", "params", "enabled", "policy", "timeout", "retry", "overlap", "description").Updates(&job).Error
+	if err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Where("id = ?", job.ID).First(&job).Error; err != nil {
+		return err
+	}
+	return scheduleJob(job)
+}
+
+// DeleteJob 删除定时任务并取消调度 执行记录保留
+func (jobService *JobService) DeleteJob(id uint) error {
+	if err := global.GVA_DB.Delete(&system.SysJob{}, "id = ?", id).Error; err != nil {
+		return err
+	}
+	unscheduleJob(id)
+	return nil
+}
+
+// SetJobEnabled 启用或停用定时任务
+func (jobService *JobService) SetJobEnabled(id uint, enabled bool) error {
+	var job system.SysJob
+	if err := global.GVA_DB.Where("id = ?", id).First(&job).Error; err != nil {
+		return err
+	}
+	if err := global.GVA_DB.Model(&job).Update("enabled", enabled).Error; err != nil {
+		return err
+	}
+	job.Enabled = enabled
+	return scheduleJob(job)
+}
+
+// TriggerJob 立即执行一次定时任务 不论是否启用 返回执行记录 任务在后台执行
+func (jobService *JobService) TriggerJob(id uint) (run system.SysJobRun, err error) {
+	var job system.SysJob
+	if err = global.GVA_DB.Where("id = ?", id).First(&job).Error; err != nil {
+		return
+	}
+	run, def, err := startJobRun(job, system.JobTriggerManual, 1)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := jobContext(context.Background(), job)
+		defer cancel()
+		finishJobRun(ctx, run, def, job.Params)
+	}()
+	return run, nil
+}
+
+// GetJob 根据ID获取定时任务
+func (jobService *JobService) GetJob(id uint) (job system.SysJob, err error) {
+	err = global.GVA_DB.Where("id = ?", id).First(&job).Error
+	setJobNextRun(&job)
+	return
+}
+
+// GetJobList 分页获取定时任务列表
+func (jobService *JobService) GetJobList(info systemReq.SysJobSearch) (list []system.SysJob, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysJob{})
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.Handler != "" {
+		db = db.Where("handler = ?", info.Handler)
+	}
+	if info.Enabled != nil {
+		db = db.Where("enabled = ?", *info.Enabled)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	err = db.Order("id").Find(&list).Error
+	for i := range list {
+		setJobNextRun(&list[i])
+	}
+	return list, total, err
+}
+
+// setJobNextRun 计算启用的任务下一次执行的时间
+func setJobNextRun(job *system.SysJob) {
+	if !job.Enabled {
+		return
+	}
+	if schedule, err := jobParser.Parse(job.Spec); err == nil {
+		next := schedule.Next(time.Now())
+		job.NextRunAt = &next
+	}
+}
+
+// GetJobRunList 分页获取执行记录
+func (jobService *JobService) GetJobRunList(info systemReq.SysJobRunSearch) (list []system.SysJobRun, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysJobRun{})
+	if info.JobID != 0 {
+		db = db.Where("job_id = ?", info.JobID)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Order("id desc").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
+
+// GetJobHandlers 获取所有已注册的执行方法
+func (jobService *JobService) GetJobHandlers() []task.Definition {
+	return task.Definitions()
+}
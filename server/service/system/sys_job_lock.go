@@ -0,0 +1,99 @@
+package system
+
+import (
+	"context"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm/clause"
+)
+
+// jobLocker 基于租期的锁 只有持有者可以续期和释放 持有者崩溃后锁在租期结束时自动失效
+type jobLocker interface {
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, owner string) error
+}
+
+// newJobLocker 按配置选择锁 未配置时开启 redis 使用 redis 否则使用数据库
+func newJobLocker() jobLocker {
+	switch global.GVA_CONFIG.Job.Locker {
+	case "db":
+		return dbJobLocker{}
+	case "redis":
+		if global.GVA_REDIS != nil {
+			return redisJobLocker{client: global.GVA_REDIS}
+		}
+		return dbJobLocker{}
+	}
+	if global.GVA_REDIS != nil {
+		return redisJobLocker{client: global.GVA_REDIS}
+	}
+	return dbJobLocker{}
+}
+
+var jobLockRenewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var jobLockReleaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type redisJobLocker struct {
+	client redis.UniversalClient
+}
+
+func (l redisJobLocker) key(name string) string {
+	return "GVA_JobLock:" + name
+}
+
+func (l redisJobLocker) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	return l.client.SetNX(ctx, l.key(name), owner, ttl).Result()
+}
+
+func (l redisJobLocker) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	n, err := jobLockRenewScript.Run(ctx, l.client, []string{l.key(name)}, owner, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (l redisJobLocker) Release(ctx context.Context, name, owner string) error {
+	return jobLockReleaseScript.Run(ctx, l.client, []string{l.key(name)}, owner).Err()
+}
+
+// dbJobLocker 使用 sys_job_locks 表 过期时间取各节点本地时间 各节点需同步时钟
+type dbJobLocker struct{}
+
+func (dbJobLocker) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	db := global.GVA_DB.WithContext(ctx)
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&system.SysJobLock{Name: name, Owner: owner, ExpiresAt: now.Add(ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+	// 锁已存在 已过期时抢占
+	result = db.Model(&system.SysJobLock{}).Where("name = ? AND expires_at < ?", name, now).
+		Updates(map[string]interface{}{"owner": owner, "expires_at": now.Add(ttl)})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (dbJobLocker) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	result := global.GVA_DB.WithContext(ctx).Model(&system.SysJobLock{}).Where("name = ? AND owner = ?", name, owner).
+		Update("expires_at", time.Now().Add(ttl))
+	return result.RowsAffected == 1, result.Error
+}
+
+func (dbJobLocker) Release(ctx context.Context, name, owner string) error {
+	return global.GVA_DB.WithContext(ctx).Where("name = ? AND owner = ?", name, owner).Delete(&system.SysJobLock{}).Error
+}
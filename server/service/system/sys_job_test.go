@@ -0,0 +1,96 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/task"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/timer"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newJobDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/job.db?_pragma=busy_timeout(5000)"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&system.SysJob{}, &system.SysJobRun{}, &system.SysJobLock{}); err != nil {
+		t.Fatal(err)
+	}
+	global.GVA_DB = db
+	global.GVA_LOG = zap.NewNop()
+	global.GVA_Timer = timer.NewTimerTask()
+	return db
+}
+
+func TestEnsureDefaultJobs(t *testing.T) {
+	db := newJobDB(t)
+	task.Register(task.Definition{Name: "test.default", Spec: "@daily", Handler: func(ctx context.Context, params string) (string, error) {
+		return "", nil
+	}})
+
+	// 多个节点同时启动时只创建一次
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, ensureDefaultJobs())
+		}()
+	}
+	wg.Wait()
+	var jobs []system.SysJob
+	assert.NoError(t, db.Where("name = ?", "test.default").Find(&jobs).Error)
+	if !assert.Len(t, jobs, 1) {
+		return
+	}
+
+	// 已删除的任务不会重新创建 名称仍被占用
+	assert.NoError(t, JobServiceApp.DeleteJob(jobs[0].ID))
+	assert.NoError(t, ensureDefaultJobs())
+	var count int64
+	db.Model(&system.SysJob{}).Where("name = ?", "test.default").Count(&count)
+	assert.Zero(t, count)
+	assert.Error(t, JobServiceApp.CreateJob(&system.SysJob{Name: "test.default", Handler: "test.default", Spec: "@daily"}))
+}
+
+func TestScheduledJobRetry(t *testing.T) {
+	db := newJobDB(t)
+	var calls int32
+	task.Register(task.Definition{Name: "test.retry", Handler: func(ctx context.Context, params string) (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return "", errors.New("boom")
+		}
+		return "ok", nil
+	}})
+	job := system.SysJob{Name: "retry", Handler: "test.retry", Spec: "* * * * * *", Enabled: true, Retry: 1, Timeout: 5}
+	assert.NoError(t, JobServiceApp.CreateJob(&job))
+	defer JobServiceApp.DeleteJob(job.ID)
+
+	// 一次调度的两次尝试分别记录 重试期间持有锁
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) >= 2 }, 10*time.Second, 50*time.Millisecond)
+	assert.NoError(t, JobServiceApp.SetJobEnabled(job.ID, false))
+	var runs []system.SysJobRun
+	assert.Eventually(t, func() bool {
+		db.Where("job_id = ? AND status <> ?", job.ID, system.JobRunRunning).Order("id").Find(&runs)
+		return len(runs) >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+	if assert.GreaterOrEqual(t, len(runs), 2) {
+		assert.Equal(t, 1, runs[0].Attempt)
+		assert.Equal(t, system.JobTriggerSchedule, runs[0].Trigger)
+		assert.Equal(t, "boom", runs[0].Error)
+		assert.Equal(t, 2, runs[1].Attempt)
+		assert.Equal(t, "ok", runs[1].Output)
+	}
+}
@@ -25,8 +25,15 @@ var OperationRecordServiceApp = new(OperationRecordService)
 //@return: err error
 
 func (operationRecordService *OperationRecordService) DeleteSysOperationRecordByIds(ids request.IdsReq) (err error) {
-	err = global.GVA_DB.Delete(&[]system.SysOperationRecord{}, "id in (?)", ids.Ids).Error
-	return err
+	if global.GVA_CONFIG.OperationRecord.AppendOnly {
+		return ErrOperationRecordAppendOnly
+	}
+	_, primary, _ := currentRecordSinks()
+	recordIds := make([]uint, 0, len(ids.Ids))
+	for _, id := range ids.Ids {
+		recordIds = append(recordIds, uint(id))
+	}
+	return primary.Delete(recordIds)
 }
 
 //@author: [granty1](https://github.com/granty1)
@@ -36,8 +43,11 @@ func (operationRecordService *OperationRecordService) DeleteSysOperationRecordBy
 //@return: err error
 
 func (operationRecordService *OperationRecordService) DeleteSysOperationRecord(sysOperationRecord system.SysOperationRecord) (err error) {
-	err = global.GVA_DB.Delete(&sysOperationRecord).Error
-	return err
+	if global.GVA_CONFIG.OperationRecord.AppendOnly {
+		return ErrOperationRecordAppendOnly
+	}
+	_, primary, _ := currentRecordSinks()
+	return primary.Delete([]uint{sysOperationRecord.ID})
 }
 
 //@author: [granty1](https://github.com/granty1)
@@ -47,8 +57,8 @@ func (operationRecordService *OperationRecordService) DeleteSysOperationRecord(s
 //@return: sysOperationRecord system.SysOperationRecord, err error
 
 func (operationRecordService *OperationRecordService) GetSysOperationRecord(id uint) (sysOperationRecord system.SysOperationRecord, err error) {
-	err = global.GVA_DB.Where("id = ?", id).First(&sysOperationRecord).Error
-	return
+	_, primary, _ := currentRecordSinks()
+	return primary.Get(id)
 }
 
 //@author: [granty1](https://github.com/granty1)
@@ -59,25 +69,7 @@ func (operationRecordService *OperationRecordService) GetSysOperationRecord(id u
 //@return: list interface{}, total int64, err error
 
 func (operationRecordService *OperationRecordService) GetSysOperationRecordInfoList(info systemReq.SysOperationRecordSearch) (list interface{}, total int64, err error) {
-	limit := info.PageSize
-	offset := info.PageSize * (info.Page - 1)
-	// 创建db
-	db := global.GVA_DB.Model(&system.SysOperationRecord{})
-	var sysOperationRecords []system.SysOperationRecord
-	// 如果有条件搜索 下方会自动创建搜索语句
-	if info.Method != "" {
-		db = db.Where("method = ?", info.Method)
-	}
-	if info.Path != "" {
-		db = db.Where("path LIKE ?", "%"+info.Path+"%")
-	}
-	if info.Status != 0 {
-		db = db.Where("status = ?", info.Status)
-	}
-	err = db.Count(&total).Error
-	if err != nil {
-		return
-	}
-	err = db.Order("id desc").Limit(limit).Offset(offset).Preload("User").Find(&sysOperationRecords).Error
-	return sysOperationRecords, total, err
+	// 从配置的主存储读取
+	_, primary, _ := currentRecordSinks()
+	return primary.List(info)
 }
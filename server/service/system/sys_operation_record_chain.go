@@ -0,0 +1,342 @@
+package system
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/record"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOperationRecordAppendOnly = errors.New("操作记录为只追加模式,不允许删除")
+	errAppendOnlyDisabled        = errors.New("未开启操作记录只追加模式")
+)
+
+// chainLock 保证同一实例内链尾的读取与追加串行执行 多实例之间由 lockChainTail 的数据库行锁保证
+var chainLock sync.Mutex
+
+// operationRecordChainContent 参与哈希计算的字段 字段顺序固定
+type operationRecordChainContent struct {
+	CreatedAt    int64  `json:"created_at"`
+	Ip           string `json:"ip"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Status       int    `json:"status"`
+	Latency      int64  `json:"latency"`
+	Agent        string `json:"agent"`
+	ErrorMessage string `json:"error_message"`
+	Body         string `json:"body"`
+	Resp         string `json:"resp"`
+	UserID       int    `json:"user_id"`
+}
+
+func operationRecordHash(r *system.SysOperationRecord) string {
+	content, _ := json.Marshal(operationRecordChainContent{
+		CreatedAt:    r.CreatedAt.Unix(),
+		Ip:           r.Ip,
+		Method:       r.Method,
+		Path:         r.Path,
+		Status:       r.Status,
+		Latency:      int64(r.Latency),
+		Agent:        r.Agent,
+		ErrorMessage: r.ErrorMessage,
+		Body:         r.Body,
+		Resp:         r.Resp,
+		UserID:       r.UserID,
+	})
+	return record.ChainHash(r.PrevHash, content)
+}
+
+// chainSecret 检查点与归档的签名密钥 必须单独配置 不能与 jwt.signing-key 相同
+func chainSecret() (string, error) {
+	secret := global.GVA_CONFIG.OperationRecord.ChainSecret
+	if secret == "" {
+		return "", errors.New("未配置 operation-record.chain-secret 无法签名检查点与归档")
+	}
+	if secret == global.GVA_CONFIG.JWT.SigningKey {
+		return "", errors.New("operation-record.chain-secret 不能与 jwt.signing-key 相同")
+	}
+	return secret, nil
+}
+
+func checkpointPayload(c system.SysOperationRecordCheckpoint) []byte {
+	return []byte(fmt.Sprintf("checkpoint|%d|%s|%d", c.LastID, c.LastHash, c.CreatedAt.Unix()))
+}
+
+func archivePayload(a system.SysOperationRecordArchive) []byte {
+	return []byte(fmt.Sprintf("archive|%d|%d|%s|%d|%s", a.FirstID, a.LastID, a.LastHash, a.Count, a.FileHash))
+}
+
+// chainTail 获取链尾哈希 没有链上记录时使用最后一次归档的链尾
+func chainTail(tx *gorm.DB) (string, error) {
+	var last system.SysOperationRecord
+	if err := tx.Select("id", "hash").Where("hash <> ''").Order("id desc").Limit(1).Find(&last).Error; err != nil {
+		return "", err
+	}
+	if last.ID != 0 {
+		return last.Hash, nil
+	}
+	var archive system.SysOperationRecordArchive
+	if err := tx.Order("last_id desc").Limit(1).Find(&archive).Error; err != nil {
+		return "", err
+	}
+	return archive.LastHash, nil
+}
+
+// lockChainTail 更新锁行取得数据库行锁 直到事务结束 多实例追加时链尾的读取与写入串行执行
+// 使用 UPDATE 加锁而不是 SELECT ... FOR UPDATE 各数据库均支持
+func lockChainTail(tx *gorm.DB) error {
+	result := tx.Model(&system.SysOperationRecordChainLock{}).Where("id = ?", 1).Update("locked_at", time.Now())
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	// 首次追加时创建锁行 并发创建时冲突的一方等待另一方提交后再加锁
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&system.SysOperationRecordChainLock{ID: 1, LockedAt: time.Now()}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&system.SysOperationRecordChainLock{}).Where("id = ?", 1).Update("locked_at", time.Now()).Error
+}
+
+// appendChainedRecords 只追加模式下写入数据库 每条记录保存自身哈希与上一条记录的哈希
+func appendChainedRecords(records []system.SysOperationRecord) error {
+	chainLock.Lock()
+	defer chainLock.Unlock()
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockChainTail(tx); err != nil {
+			return err
+		}
+		prev, err := chainTail(tx)
+		if err != nil {
+			return err
+		}
+		// 时间精确到秒 避免不同数据库的时间精度导致校验不一致
+		now := time.Now().Truncate(time.Second)
+		for i := range records {
+			records[i].CreatedAt = now
+			records[i].UpdatedAt = now
+			records[i].PrevHash = prev
+			records[i].Hash = operationRecordHash(&records[i])
+			prev = records[i].Hash
+		}
+		return tx.CreateInBatches(&records, len(records)).Error
+	})
+}
+
+// VerifyOperationRecordChain 校验操作记录哈希链 发现被修改、被删除的记录以及无效的检查点
+func (operationRecordService *OperationRecordService) VerifyOperationRecordChain() (result systemRes.OperationRecordChainResult, err error) {
+	var archive system.SysOperationRecordArchive
+	if err = global.GVA_DB.Order("last_id desc").Limit(1).Find(&archive).Error; err != nil {
+		return
+	}
+	secret, err := chainSecret()
+	if err != nil {
+		return
+	}
+	fail := func(id uint, reason string) (systemRes.OperationRecordChainResult, error) {
+		result.Valid, result.BrokenID, result.Reason = false, id, reason
+		return result, nil
+	}
+	if archive.ID != 0 && !record.VerifySign(secret, archivePayload(archive), archive.Signature) {
+		return fail(archive.LastID, "归档签名无效")
+	}
+	result.AnchorID = archive.LastID
+	prev, started := archive.LastHash, archive.ID != 0 && archive.LastHash != ""
+	lastID := archive.LastID
+	for {
+		var records []system.SysOperationRecord
+		err = global.GVA_DB.Where("id > ?", lastID).Order("id").Limit(500).Find(&records).Error
+		if err != nil {
+			return
+		}
+		if len(records) == 0 {
+			break
+		}
+		for i := range records {
+			r := &records[i]
+			lastID = r.ID
+			if r.Hash == "" {
+				if !started {
+					// 开启只追加模式之前的记录没有哈希
+					continue
+				}
+				return fail(r.ID, "记录缺少哈希")
+			}
+			if !started {
+				started, prev = true, ""
+			}
+			if r.PrevHash != prev {
+				return fail(r.ID, "与上一条记录的哈希不连续,记录可能被删除")
+			}
+			if operationRecordHash(r) != r.Hash {
+				return fail(r.ID, "记录内容与哈希不一致,记录可能被修改")
+			}
+			prev = r.Hash
+			result.LastID = r.ID
+			result.Checked++
+		}
+	}
+	result.LastHash = prev
+
+	var checkpoints []system.SysOperationRecordCheckpoint
+	if err = global.GVA_DB.Where("last_id > ?", archive.LastID).Order("id").Find(&checkpoints).Error; err != nil {
+		return
+	}
+	for _, checkpoint := range checkpoints {
+		if !record.VerifySign(secret, checkpointPayload(checkpoint), checkpoint.Signature) {
+			return fail(checkpoint.LastID, "检查点签名无效")
+		}
+		var r system.SysOperationRecord
+		if e := global.GVA_DB.Select("id", "hash").Where("id = ?", checkpoint.LastID).First(&r).Error; e != nil || r.Hash != checkpoint.LastHash {
+			return fail(checkpoint.LastID, "检查点对应的记录缺失或被修改")
+		}
+		result.Checkpoints++
+	}
+	result.Valid = true
+	return result, nil
+}
+
+// CreateOperationRecordCheckpoint 生成检查点 签名后保存并导出到文件
+func (operationRecordService *OperationRecordService) CreateOperationRecordCheckpoint() (checkpoint system.SysOperationRecordCheckpoint, err error) {
+	conf := global.GVA_CONFIG.OperationRecord
+	if !conf.AppendOnly {
+		return checkpoint, errAppendOnlyDisabled
+	}
+	var last system.SysOperationRecord
+	if err = global.GVA_DB.Select("id", "hash").Where("hash <> ''").Order("id desc").Limit(1).Find(&last).Error; err != nil {
+		return
+	}
+	if last.ID == 0 {
+		return checkpoint, errors.New("暂无可生成检查点的记录")
+	}
+	checkpoint.LastID = last.ID
+	checkpoint.LastHash = last.Hash
+	checkpoint.CreatedAt = time.Now().Truncate(time.Second)
+	secret, err := chainSecret()
+	if err != nil {
+		return
+	}
+	checkpoint.Signature = record.Sign(secret, checkpointPayload(checkpoint))
+
+	dir := conf.CheckpointDir
+	if dir == "" {
+		dir = "./log/operation/checkpoint"
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+	checkpoint.File = filepath.Join(dir, fmt.Sprintf("checkpoint-%s.json", checkpoint.CreatedAt.Format("20060102150405")))
+	content, err := json.MarshalIndent(map[string]interface{}{
+		"lastId":    checkpoint.LastID,
+		"lastHash":  checkpoint.LastHash,
+		"createdAt": checkpoint.CreatedAt.Unix(),
+		"signature": checkpoint.Signature,
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = os.WriteFile(checkpoint.File, content, 0644); err != nil {
+		return
+	}
+	err = global.GVA_DB.Create(&checkpoint).Error
+	return checkpoint, err
+}
+
+// SealOperationRecordArchive 封存归档 将早于 before 的记录写入归档文件并签名 然后从数据库中删除
+// 只追加模式下这是清理操作记录的唯一方式 归档始终是从上一次归档之后开始的连续记录
+func (operationRecordService *OperationRecordService) SealOperationRecordArchive(before time.Time) (archive system.SysOperationRecordArchive, err error) {
+	conf := global.GVA_CONFIG.OperationRecord
+	if !conf.AppendOnly {
+		return archive, errAppendOnlyDisabled
+	}
+	secret, err := chainSecret()
+	if err != nil {
+		return
+	}
+	chainLock.Lock()
+	defer chainLock.Unlock()
+
+	var prevArchive system.SysOperationRecordArchive
+	if err = global.GVA_DB.Order("last_id desc").Limit(1).Find(&prevArchive).Error; err != nil {
+		return
+	}
+	var cutoff system.SysOperationRecord
+	err = global.GVA_DB.Select("id", "hash").Where("id > ? AND created_at < ?", prevArchive.LastID, before).Order("id desc").Limit(1).Find(&cutoff).Error
+	if err != nil {
+		return
+	}
+	if cutoff.ID == 0 {
+		return archive, errors.New("没有需要归档的记录")
+	}
+
+	dir := conf.ArchiveDir
+	if dir == "" {
+		dir = "./log/operation/archive"
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+	archive.LastID, archive.LastHash = cutoff.ID, cutoff.Hash
+	archive.File = filepath.Join(dir, fmt.Sprintf("archive-%d-%d.jsonl", prevArchive.LastID+1, cutoff.ID))
+	file, err := os.OpenFile(archive.File, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	hash := sha256.New()
+	buf := bufio.NewWriter(io.MultiWriter(file, hash))
+	encoder := json.NewEncoder(buf)
+	lastID := prevArchive.LastID
+	for {
+		var records []system.SysOperationRecord
+		err = global.GVA_DB.Unscoped().Where("id > ? AND id <= ?", lastID, cutoff.ID).Order("id").Limit(500).Find(&records).Error
+		if err != nil || len(records) == 0 {
+			break
+		}
+		for i := range records {
+			if archive.FirstID == 0 {
+				archive.FirstID = records[i].ID
+			}
+			if err = encoder.Encode(records[i]); err != nil {
+				break
+			}
+			archive.Count++
+		}
+		if err != nil {
+			break
+		}
+		lastID = records[len(records)-1].ID
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(archive.File)
+		return archive, err
+	}
+	archive.FileHash = hex.EncodeToString(hash.Sum(nil))
+	archive.Signature = record.Sign(secret, archivePayload(archive))
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&archive).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id >= ? AND id <= ?", archive.FirstID, archive.LastID).Delete(&system.SysOperationRecord{}).Error
+	})
+	return archive, err
+}
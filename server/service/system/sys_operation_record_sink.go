@@ -0,0 +1,371 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/record"
+	"github.com/qiniu/qmgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+const (
+	OperationRecordSinkDB    = "db"
+	OperationRecordSinkFile  = "file"
+	OperationRecordSinkMongo = "mongo"
+	OperationRecordSinkHttp  = "http"
+)
+
+// OperationRecordSink 操作记录存储 按批写入
+type OperationRecordSink interface {
+	Name() string
+	Write(records []system.SysOperationRecord) error
+	Close() error
+}
+
+// OperationRecordStore 可作为主存储的 sink 操作记录的查询接口从主存储读取
+type OperationRecordStore interface {
+	OperationRecordSink
+	Get(id uint) (system.SysOperationRecord, error)
+	List(info systemReq.SysOperationRecordSearch) ([]system.SysOperationRecord, int64, error)
+	Delete(ids []uint) error
+}
+
+// OperationRecordScrubber 可清除某个用户个人数据的 sink 文件与HTTP推送的记录写出后无法修改 不实现该接口
+// 数据库中的操作记录总是由 AnonymizeUser 清除 不论是否开启了 db sink
+type OperationRecordScrubber interface {
+	ScrubUser(userID uint) (int64, error)
+}
+
+// newOperationRecordSinks 按配置创建所有 sink 并返回主存储
+func newOperationRecordSinks() (sinks []OperationRecordSink, primary OperationRecordStore, err error) {
+	conf := global.GVA_CONFIG.OperationRecord
+	names := conf.Sinks
+	if len(names) == 0 {
+		names = []string{OperationRecordSinkDB}
+	}
+	for _, name := range names {
+		var sink OperationRecordSink
+		switch name {
+		case OperationRecordSinkDB:
+			sink = &dbRecordSink{}
+		case OperationRecordSinkFile:
+			dir := conf.File.Dir
+			if dir == "" {
+				dir = "./log/operation"
+			}
+			sink = &fileRecordSink{writer: record.NewFileWriter(dir, "operation_record", conf.File.MaxSize, conf.File.KeepDays)}
+		case OperationRecordSinkMongo:
+			if global.GVA_MONGO == nil {
+				return nil, nil, errors.New("operation record mongo sink requires system.use-mongo")
+			}
+			collection := conf.MongoCollection
+			if collection == "" {
+				collection = "sys_operation_records"
+			}
+			sink = &mongoRecordSink{collection: collection}
+		case OperationRecordSinkHttp:
+			if conf.Http.Url == "" {
+				return nil, nil, errors.New("operation record http sink requires url")
+			}
+			timeout := time.Duration(conf.Http.Timeout) * time.Millisecond
+			if timeout <= 0 {
+				timeout = 5 * time.Second
+			}
+			sink = &httpRecordSink{url: conf.Http.Url, token: conf.Http.Token, client: &http.Client{Timeout: timeout}}
+		default:
+			return nil, nil, fmt.Errorf("unknown operation record sink: %s", name)
+		}
+		sinks = append(sinks, sink)
+	}
+	primaryName := conf.Primary
+	if primaryName == "" {
+		primaryName = OperationRecordSinkDB
+	}
+	for _, sink := range sinks {
+		if store, ok := sink.(OperationRecordStore); ok && sink.Name() == primaryName {
+			primary = store
+		}
+	}
+	if primary == nil {
+		return nil, nil, fmt.Errorf("operation record primary sink %s must be db or mongo and enabled in sinks", primaryName)
+	}
+	return sinks, primary, nil
+}
+
+// dbRecordSink 写入主数据库 sys_operation_records 表
+type dbRecordSink struct{}
+
+func (s *dbRecordSink) Name() string { return OperationRecordSinkDB }
+
+func (s *dbRecordSink) Write(records []system.SysOperationRecord) error {
+	if global.GVA_CONFIG.OperationRecord.AppendOnly {
+		return appendChainedRecords(records)
+	}
+	return global.GVA_DB.CreateInBatches(&records, len(records)).Error
+}
+
+func (s *dbRecordSink) Close() error { return nil }
+
+func (s *dbRecordSink) Get(id uint) (sysOperationRecord system.SysOperationRecord, err error) {
+	err = global.GVA_DB.Where("id = ?", id).First(&sysOperationRecord).Error
+	return
+}
+
+func (s *dbRecordSink) List(info systemReq.SysOperationRecordSearch) (list []system.SysOperationRecord, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysOperationRecord{})
+	if info.Method != "" {
+		db = db.Where("method = ?", info.Method)
+	}
+	if info.Path != "" {
+		db = db.Where("path LIKE ?", "%"+info.Path+"%")
+	}
+	if info.Status != 0 {
+		db = db.Where("status = ?", info.Status)
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+	err = db.Order("id desc").Limit(limit).Offset(offset).Preload("User").Find(&list).Error
+	return list, total, err
+}
+
+func (s *dbRecordSink) Delete(ids []uint) error {
+	return global.GVA_DB.Delete(&[]system.SysOperationRecord{}, "id in (?)", ids).Error
+}
+
+// fileRecordSink 写入按天及大小切割的 JSON Lines 文件
+type fileRecordSink struct {
+	writer *record.FileWriter
+}
+
+func (s *fileRecordSink) Name() string { return OperationRecordSinkFile }
+
+func (s *fileRecordSink) Write(records []system.SysOperationRecord) error {
+	items := make([]interface{}, 0, len(records))
+	for i := range records {
+		items = append(items, records[i])
+	}
+	return s.writer.WriteLines(items...)
+}
+
+func (s *fileRecordSink) Close() error { return s.writer.Close() }
+
+// operationRecordDocument mongo 中的操作记录文档
+type operationRecordDocument struct {
+	ID           uint          `bson:"_id"`
+	CreatedAt    time.Time     `bson:"created_at"`
+	Ip           string        `bson:"ip"`
+	Method       string        `bson:"method"`
+	Path         string        `bson:"path"`
+	Status       int           `bson:"status"`
+	Latency      time.Duration `bson:"latency"`
+	Agent        string        `bson:"agent"`
+	ErrorMessage string        `bson:"error_message"`
+	Body         string        `bson:"body"`
+	Resp         string        `bson:"resp"`
+	UserID       int           `bson:"user_id"`
+}
+
+// operationRecordCounterCollection mongo 中保存自增ID计数器的集合 以存储的集合名为 _id
+const operationRecordCounterCollection = "gva_counters"
+
+// mongoRecordSink 写入 mongo 使用 global.GVA_MONGO
+type mongoRecordSink struct {
+	collection string
+}
+
+func (s *mongoRecordSink) Name() string { return OperationRecordSinkMongo }
+
+// reserveIDs mongo 中没有自增ID 通过计数器原子递增为一批记录预留连续的数字ID 多实例写入时不会重复
+// 保持与数据库存储一致的接口 返回这一批的第一个ID
+func (s *mongoRecordSink) reserveIDs(ctx context.Context, n int) (uint, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := global.GVA_MONGO.Database.Collection(operationRecordCounterCollection).Find(ctx, bson.M{"_id": s.collection}).Apply(qmgo.Change{
+		Update:    bson.M{"$inc": bson.M{"seq": n}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &counter)
+	if err != nil {
+		return 0, err
+	}
+	return uint(counter.Seq) - uint(n) + 1, nil
+}
+
+func (s *mongoRecordSink) Write(records []system.SysOperationRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	firstID, err := s.reserveIDs(ctx, len(records))
+	if err != nil {
+		return err
+	}
+	docs := make([]operationRecordDocument, 0, len(records))
+	for i, r := range records {
+		createdAt := r.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		docs = append(docs, operationRecordDocument{
+			ID:           firstID + uint(i),
+			CreatedAt:    createdAt,
+			Ip:           r.Ip,
+			Method:       r.Method,
+			Path:         r.Path,
+			Status:       r.Status,
+			Latency:      r.Latency,
+			Agent:        r.Agent,
+			ErrorMessage: r.ErrorMessage,
+			Body:         r.Body,
+			Resp:         r.Resp,
+			UserID:       r.UserID,
+		})
+	}
+	_, err = global.GVA_MONGO.Database.Collection(s.collection).InsertMany(ctx, docs)
+	return err
+}
+
+func (s *mongoRecordSink) Close() error { return nil }
+
+func (s *mongoRecordSink) Get(id uint) (sysOperationRecord system.SysOperationRecord, err error) {
+	var doc operationRecordDocument
+	err = global.GVA_MONGO.Database.Collection(s.collection).Find(context.Background(), bson.M{"_id": id}).One(&doc)
+	if err != nil {
+		return sysOperationRecord, gorm.ErrRecordNotFound
+	}
+	return doc.toRecord(), nil
+}
+
+func (s *mongoRecordSink) List(info systemReq.SysOperationRecordSearch) (list []system.SysOperationRecord, total int64, err error) {
+	filter := bson.M{}
+	if info.Method != "" {
+		filter["method"] = info.Method
+	}
+	if info.Path != "" {
+		filter["path"] = bson.M{"$regex": regexp.QuoteMeta(info.Path)}
+	}
+	if info.Status != 0 {
+		filter["status"] = info.Status
+	}
+	collection := global.GVA_MONGO.Database.Collection(s.collection)
+	total, err = collection.Find(context.Background(), filter).Count()
+	if err != nil {
+		return
+	}
+	query := collection.Find(context.Background(), filter).Sort("-_id")
+	if info.PageSize > 0 {
+		query = query.Skip(int64(info.PageSize * (info.Page - 1))).Limit(int64(info.PageSize))
+	}
+	var docs []operationRecordDocument
+	if err = query.All(&docs); err != nil {
+		return
+	}
+	userIds := make([]int, 0, len(docs))
+	for _, doc := range docs {
+		list = append(list, doc.toRecord())
+		userIds = append(userIds, doc.UserID)
+	}
+	// 与数据库存储保持一致 补充操作用户信息
+	var users []system.SysUser
+	if len(userIds) > 0 {
+		if err = global.GVA_DB.Where("id in ?", userIds).Find(&users).Error; err != nil {
+			return
+		}
+	}
+	userMap := make(map[int]system.SysUser, len(users))
+	for _, u := range users {
+		userMap[int(u.ID)] = u
+	}
+	for i := range list {
+		list[i].User = userMap[list[i].UserID]
+	}
+	return list, total, nil
+}
+
+func (s *mongoRecordSink) ScrubUser(userID uint) (int64, error) {
+	result, err := global.GVA_MONGO.Database.Collection(s.collection).UpdateAll(context.Background(), bson.M{"user_id": userID}, bson.M{"$set": bson.M{
+		"ip":    anonymizedPlaceholder,
+		"agent": anonymizedPlaceholder,
+		"body":  anonymizedPlaceholder,
+		"resp":  anonymizedPlaceholder,
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (s *mongoRecordSink) Delete(ids []uint) error {
+	_, err := global.GVA_MONGO.Database.Collection(s.collection).RemoveAll(context.Background(), bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+func (doc operationRecordDocument) toRecord() system.SysOperationRecord {
+	r := system.SysOperationRecord{
+		Ip:           doc.Ip,
+		Method:       doc.Method,
+		Path:         doc.Path,
+		Status:       doc.Status,
+		Latency:      doc.Latency,
+		Agent:        doc.Agent,
+		ErrorMessage: doc.ErrorMessage,
+		Body:         doc.Body,
+		Resp:         doc.Resp,
+		UserID:       doc.UserID,
+	}
+	r.ID = doc.ID
+	r.CreatedAt = doc.CreatedAt
+	r.UpdatedAt = doc.CreatedAt
+	return r
+}
+
+// httpRecordSink 以 JSON 数组批量推送到 SIEM 等采集端
+type httpRecordSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (s *httpRecordSink) Name() string { return OperationRecordSinkHttp }
+
+func (s *httpRecordSink) Write(records []system.SysOperationRecord) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("operation record http sink: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpRecordSink) Close() error { return nil }
@@ -0,0 +1,403 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/record"
+	"gorm.io/gorm"
+)
+
+// latencyColumns 延迟直方图各桶的列名 与 record.LatencyBounds 对应 最后一列为超出最大上界的数量
+var latencyColumns = []string{"le_10ms", "le_50ms", "le_100ms", "le_250ms", "le_500ms", "le_1s", "le_2500ms", "le_5s", "le_10s", "gt_10s"}
+
+const otherTimelineKey = "其他"
+
+// operationRecordStats 统计查询 rollup 为 true 时读取小时汇总表 否则直接查询操作记录表
+// 汇总表中的请求数、延迟等已经是合计值 所以两种来源的聚合表达式不同
+type operationRecordStats struct {
+	rollup bool
+}
+
+func (s operationRecordStats) count() string {
+	if s.rollup {
+		return "SUM(r.request_count)"
+	}
+	return "COUNT(*)"
+}
+
+func (s operationRecordStats) errors() string {
+	if s.rollup {
+		return "SUM(CASE WHEN r.status >= 400 THEN r.request_count ELSE 0 END)"
+	}
+	return "SUM(CASE WHEN r.status >= 400 THEN 1 ELSE 0 END)"
+}
+
+func (s operationRecordStats) latency() string {
+	if s.rollup {
+		return "SUM(r.latency_sum) AS latency_sum, MAX(r.latency_max) AS latency_max"
+	}
+	return "SUM(r.latency) AS latency_sum, MAX(r.latency) AS latency_max"
+}
+
+func (s operationRecordStats) histogram() string {
+	columns := make([]string, 0, len(latencyColumns))
+	for i, column := range latencyColumns {
+		if s.rollup {
+			columns = append(columns, fmt.Sprintf("SUM(r.%s) AS %s", column, column))
+			continue
+		}
+		var cond string
+		switch {
+		case i == 0:
+			cond = fmt.Sprintf("r.latency <= %d", record.LatencyBounds[0])
+		case i == len(record.LatencyBounds):
+			cond = fmt.Sprintf("r.latency > %d", record.LatencyBounds[i-1])
+		default:
+			cond = fmt.Sprintf("r.latency > %d AND r.latency <= %d", record.LatencyBounds[i-1], record.LatencyBounds[i])
+		}
+		columns = append(columns, fmt.Sprintf("SUM(CASE WHEN %s THEN 1 ELSE 0 END) AS %s", cond, column))
+	}
+	return strings.Join(columns, ", ")
+}
+
+// hour 整点小时的字符串表达式 各数据库的日期函数不同
+func (s operationRecordStats) hour() string {
+	column := "r.created_at"
+	if s.rollup {
+		column = "r.hour"
+	}
+	switch global.GVA_CONFIG.System.DbType {
+	case "pgsql":
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD HH24:00:00')", column)
+	case "sqlite":
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00', %s, 'localtime')", column)
+	case "mssql":
+		return fmt.Sprintf("FORMAT(%s, 'yyyy-MM-dd HH:00:00')", column)
+	case "oracle":
+		return fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM-DD HH24') || ':00:00'", column)
+	default:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00')", column)
+	}
+}
+
+// query 按统计条件过滤 table 为汇总表名
+func (s operationRecordStats) query(info systemReq.OperationRecordStatsSearch, table string) *gorm.DB {
+	start, end := operationRecordStatsRange(info)
+	var db *gorm.DB
+	if s.rollup {
+		db = global.GVA_DB.Table(table+" r").Where("r.hour >= ? AND r.hour < ?", start.Truncate(time.Hour), end)
+		if info.AuthorityID != 0 {
+			db = db.Where("r.authority_id = ?", info.AuthorityID)
+		}
+	} else {
+		db = global.GVA_DB.Table("sys_operation_records r").
+			Where("r.deleted_at IS NULL AND r.created_at >= ? AND r.created_at < ?", start, end)
+		if info.AuthorityID != 0 {
+			db = db.Joins("JOIN sys_users u ON u.id = r.user_id").Where("u.authority_id = ?", info.AuthorityID)
+		}
+	}
+	if info.Method != "" {
+		db = db.Where("r.method = ?", info.Method)
+	}
+	if info.Path != "" {
+		db = db.Where("r.path LIKE ?", "%"+info.Path+"%")
+	}
+	return db
+}
+
+func operationRecordStatsRange(info systemReq.OperationRecordStatsSearch) (start, end time.Time) {
+	end = time.Now()
+	if info.EndTime != nil && !info.EndTime.IsZero() {
+		end = *info.EndTime
+	}
+	start = end.Add(-24 * time.Hour)
+	if info.StartTime != nil && !info.StartTime.IsZero() {
+		start = *info.StartTime
+	}
+	return
+}
+
+// newOperationRecordStats 统计基于数据库存储 开启 rollup 时读取汇总表
+func newOperationRecordStats() (operationRecordStats, error) {
+	conf := global.GVA_CONFIG.OperationRecord
+	if len(conf.Sinks) > 0 {
+		enabled := false
+		for _, name := range conf.Sinks {
+			enabled = enabled || name == OperationRecordSinkDB
+		}
+		if !enabled {
+			return operationRecordStats{}, errors.New("操作记录统计需要开启 db 存储")
+		}
+	}
+	return operationRecordStats{rollup: conf.Rollup}, nil
+}
+
+func statsLimit(limit int, def int) int {
+	if limit <= 0 {
+		return def
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
+
+// GetOperationRecordTimeline 按时间桶统计各请求方法或路径的请求数 超出 Limit 的分组合并为"其他"
+func (operationRecordService *OperationRecordService) GetOperationRecordTimeline(info systemReq.OperationRecordStatsSearch) (list []systemRes.OperationRecordTimelinePoint, err error) {
+	stats, err := newOperationRecordStats()
+	if err != nil {
+		return
+	}
+	key := "r.method"
+	if info.GroupBy == "path" {
+		key = "r.path"
+	}
+	var rows []struct {
+		Bucket       string
+		Name         string
+		RequestCount int64
+		ErrorCount   int64
+	}
+	err = stats.query(info, system.SysOperationRecordHourly{}.TableName()).
+		Select(fmt.Sprintf("%s AS bucket, %s AS name, %s AS request_count, %s AS error_count", stats.hour(), key, stats.count(), stats.errors())).
+		Group(stats.hour() + ", " + key).Scan(&rows).Error
+	if err != nil {
+		return
+	}
+
+	totals := make(map[string]int64)
+	for _, row := range rows {
+		totals[row.Name] += row.RequestCount
+	}
+	keys := make([]string, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return totals[keys[i]] > totals[keys[j]] })
+	kept := make(map[string]bool)
+	for _, k := range keys[:min(len(keys), statsLimit(info.Limit, 10))] {
+		kept[k] = true
+	}
+
+	points := make(map[string]*systemRes.OperationRecordTimelinePoint)
+	for _, row := range rows {
+		t, e := time.ParseInLocation(time.DateTime, row.Bucket, time.Local)
+		if e != nil {
+			continue
+		}
+		if info.Interval == "day" {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		}
+		name := row.Name
+		if !kept[name] {
+			name = otherTimelineKey
+		}
+		id := t.Format(time.DateTime) + "|" + name
+		point, ok := points[id]
+		if !ok {
+			point = &systemRes.OperationRecordTimelinePoint{Time: t, Key: name}
+			points[id] = point
+		}
+		point.Count += row.RequestCount
+		point.Errors += row.ErrorCount
+	}
+	list = make([]systemRes.OperationRecordTimelinePoint, 0, len(points))
+	for _, point := range points {
+		list = append(list, *point)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Time.Equal(list[j].Time) {
+			return list[i].Time.Before(list[j].Time)
+		}
+		return totals[list[i].Key] > totals[list[j].Key]
+	})
+	return list, nil
+}
+
+// GetOperationRecordEndpointStats 按接口统计请求数、错误率及延迟分位数 按请求数倒序
+func (operationRecordService *OperationRecordService) GetOperationRecordEndpointStats(info systemReq.OperationRecordStatsSearch) (list []systemRes.OperationRecordEndpointStat, err error) {
+	stats, err := newOperationRecordStats()
+	if err != nil {
+		return
+	}
+	var rows []struct {
+		Method       string
+		Path         string
+		RequestCount int64
+		ErrorCount   int64
+		LatencySum   int64
+		LatencyMax   int64
+		system.OperationRecordLatencyHistogram
+	}
+	err = stats.query(info, system.SysOperationRecordHourly{}.TableName()).
+		Select(fmt.Sprintf("r.method, r.path, %s AS request_count, %s AS error_count, %s, %s", stats.count(), stats.errors(), stats.latency(), stats.histogram())).
+		Group("r.method, r.path").Order("request_count desc").Limit(statsLimit(info.Limit, 20)).Scan(&rows).Error
+	if err != nil {
+		return
+	}
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+	list = make([]systemRes.OperationRecordEndpointStat, 0, len(rows))
+	for _, row := range rows {
+		if row.RequestCount == 0 {
+			continue
+		}
+		counts, maxLatency := row.Counts(), time.Duration(row.LatencyMax)
+		list = append(list, systemRes.OperationRecordEndpointStat{
+			Method:     row.Method,
+			Path:       row.Path,
+			Count:      row.RequestCount,
+			Errors:     row.ErrorCount,
+			ErrorRate:  float64(row.ErrorCount) / float64(row.RequestCount),
+			AvgLatency: ms(time.Duration(row.LatencySum / row.RequestCount)),
+			P50:        ms(record.LatencyPercentile(counts, maxLatency, 0.5)),
+			P95:        ms(record.LatencyPercentile(counts, maxLatency, 0.95)),
+			P99:        ms(record.LatencyPercentile(counts, maxLatency, 0.99)),
+			MaxLatency: ms(maxLatency),
+		})
+	}
+	return list, nil
+}
+
+// GetOperationRecordTopUsers 请求数最多的用户 汇总表不区分接口 按方法或路径过滤时直接查询操作记录表
+func (operationRecordService *OperationRecordService) GetOperationRecordTopUsers(info systemReq.OperationRecordStatsSearch) (list []systemRes.OperationRecordUserStat, err error) {
+	stats, err := newOperationRecordStats()
+	if err != nil {
+		return
+	}
+	if info.Method != "" || info.Path != "" {
+		stats.rollup = false
+	}
+	errorCount := stats.errors()
+	if stats.rollup {
+		errorCount = "SUM(r.error_count)"
+	}
+	var rows []struct {
+		UserID       int
+		RequestCount int64
+		ErrorCount   int64
+	}
+	err = stats.query(info, system.SysOperationRecordUserHourly{}.TableName()).Where("r.user_id <> 0").
+		Select(fmt.Sprintf("r.user_id, %s AS request_count, %s AS error_count", stats.count(), errorCount)).
+		Group("r.user_id").Order("request_count desc").Limit(statsLimit(info.Limit, 10)).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return
+	}
+	ids := make([]int, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.UserID)
+	}
+	var users []system.SysUser
+	if err = global.GVA_DB.Unscoped().Select("id", "username", "nick_name").Where("id in ?", ids).Find(&users).Error; err != nil {
+		return
+	}
+	userMap := make(map[int]system.SysUser, len(users))
+	for _, u := range users {
+		userMap[int(u.ID)] = u
+	}
+	for _, row := range rows {
+		u := userMap[row.UserID]
+		list = append(list, systemRes.OperationRecordUserStat{
+			UserID:   row.UserID,
+			Username: u.Username,
+			NickName: u.NickName,
+			Count:    row.RequestCount,
+			Errors:   row.ErrorCount,
+		})
+	}
+	return list, nil
+}
+
+// GetOperationRecordStatusStats 状态码分布
+func (operationRecordService *OperationRecordService) GetOperationRecordStatusStats(info systemReq.OperationRecordStatsSearch) (list []systemRes.OperationRecordStatusStat, err error) {
+	stats, err := newOperationRecordStats()
+	if err != nil {
+		return
+	}
+	err = stats.query(info, system.SysOperationRecordHourly{}.TableName()).
+		Select(fmt.Sprintf("r.status, %s AS count", stats.count())).
+		Group("r.status").Order("r.status").Scan(&list).Error
+	return list, err
+}
+
+// RollupOperationRecords 将已结束的小时汇总到小时汇总表 没有记录的小时直接跳过
+// 从上一次汇总的最后一个小时开始 重新汇总该小时 补上汇总后才写入的记录(如异步存储批量写入的记录)
+// 每个小时先删除再写入 重复执行不会重复计数
+func (operationRecordService *OperationRecordService) RollupOperationRecords() error {
+	var last system.SysOperationRecordHourly
+	if err := global.GVA_DB.Select("hour").Order("hour desc").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	from, to := last.Hour, time.Now().Truncate(time.Hour)
+	for from.Before(to) {
+		var next system.SysOperationRecord
+		err := global.GVA_DB.Select("id", "created_at").Where("created_at >= ? AND created_at < ?", from, to).
+			Order("created_at").Limit(1).Find(&next).Error
+		if err != nil {
+			return err
+		}
+		if next.ID == 0 {
+			return nil
+		}
+		hour := next.CreatedAt.Truncate(time.Hour)
+		if err = rollupOperationRecordHour(hour); err != nil {
+			return err
+		}
+		from = hour.Add(time.Hour)
+	}
+	return nil
+}
+
+func rollupOperationRecordHour(hour time.Time) error {
+	stats := operationRecordStats{}
+	info := systemReq.OperationRecordStatsSearch{StartTime: &hour}
+	end := hour.Add(time.Hour)
+	info.EndTime = &end
+
+	var endpoints []system.SysOperationRecordHourly
+	err := stats.query(info, "").Joins("LEFT JOIN sys_users u ON u.id = r.user_id").
+		Select(fmt.Sprintf("r.method, r.path, r.status, COALESCE(u.authority_id, 0) AS authority_id, %s AS request_count, %s, %s", stats.count(), stats.latency(), stats.histogram())).
+		Group("r.method, r.path, r.status, u.authority_id").Scan(&endpoints).Error
+	if err != nil {
+		return err
+	}
+	var users []system.SysOperationRecordUserHourly
+	err = stats.query(info, "").Joins("LEFT JOIN sys_users u ON u.id = r.user_id").
+		Select(fmt.Sprintf("r.user_id, COALESCE(u.authority_id, 0) AS authority_id, %s AS request_count, %s AS error_count", stats.count(), stats.errors())).
+		Group("r.user_id, u.authority_id").Scan(&users).Error
+	if err != nil {
+		return err
+	}
+	for i := range endpoints {
+		endpoints[i].Hour = hour
+	}
+	for i := range users {
+		users[i].Hour = hour
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("hour = ?", hour).Delete(&system.SysOperationRecordHourly{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hour = ?", hour).Delete(&system.SysOperationRecordUserHourly{}).Error; err != nil {
+			return err
+		}
+		if len(endpoints) > 0 {
+			if err := tx.CreateInBatches(&endpoints, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(users) > 0 {
+			return tx.CreateInBatches(&users, 500).Error
+		}
+		return nil
+	})
+}
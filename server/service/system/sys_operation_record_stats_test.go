@@ -0,0 +1,155 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestOperationRecordStatsSQL(t *testing.T) {
+	dbType := global.GVA_CONFIG.System.DbType
+	defer func() { global.GVA_CONFIG.System.DbType = dbType }()
+	tests := []struct {
+		dbType string
+		raw    string
+		rollup string
+	}{
+		{"mysql", "DATE_FORMAT(r.created_at, '%Y-%m-%d %H:00:00')", "DATE_FORMAT(r.hour, '%Y-%m-%d %H:00:00')"},
+		{"pgsql", "to_char(r.created_at, 'YYYY-MM-DD HH24:00:00')", "to_char(r.hour, 'YYYY-MM-DD HH24:00:00')"},
+		{"sqlite", "strftime('%Y-%m-%d %H:00:00', r.created_at, 'localtime')", "strftime('%Y-%m-%d %H:00:00', r.hour, 'localtime')"},
+		{"mssql", "FORMAT(r.created_at, 'yyyy-MM-dd HH:00:00')", "FORMAT(r.hour, 'yyyy-MM-dd HH:00:00')"},
+		{"oracle", "TO_CHAR(r.created_at, 'YYYY-MM-DD HH24') || ':00:00'", "TO_CHAR(r.hour, 'YYYY-MM-DD HH24') || ':00:00'"},
+	}
+	for _, tt := range tests {
+		global.GVA_CONFIG.System.DbType = tt.dbType
+		assert.Equal(t, tt.raw, operationRecordStats{}.hour(), tt.dbType)
+		assert.Equal(t, tt.rollup, operationRecordStats{rollup: true}.hour(), tt.dbType)
+	}
+
+	// 直方图各桶的上界与 record.LatencyBounds 对应 汇总表直接求和
+	histogram := operationRecordStats{}.histogram()
+	assert.Contains(t, histogram, "SUM(CASE WHEN r.latency <= 10000000 THEN 1 ELSE 0 END) AS le_10ms")
+	assert.Contains(t, histogram, "SUM(CASE WHEN r.latency > 10000000 AND r.latency <= 50000000 THEN 1 ELSE 0 END) AS le_50ms")
+	assert.Contains(t, histogram, "SUM(CASE WHEN r.latency > 10000000000 THEN 1 ELSE 0 END) AS gt_10s")
+	assert.Contains(t, operationRecordStats{rollup: true}.histogram(), "SUM(r.gt_10s) AS gt_10s")
+}
+
+func newOperationRecordStatsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/stats.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&system.SysUser{}, &system.SysOperationRecord{}, &system.SysOperationRecordHourly{}, &system.SysOperationRecordUserHourly{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	global.GVA_DB = db
+	global.GVA_LOG = zap.NewNop()
+	conf, dbType := global.GVA_CONFIG.OperationRecord, global.GVA_CONFIG.System.DbType
+	t.Cleanup(func() {
+		global.GVA_CONFIG.OperationRecord, global.GVA_CONFIG.System.DbType = conf, dbType
+	})
+	global.GVA_CONFIG.OperationRecord.Sinks = nil
+	global.GVA_CONFIG.System.DbType = "sqlite"
+	return db
+}
+
+func TestRollupOperationRecords(t *testing.T) {
+	db := newOperationRecordStatsDB(t)
+	assert.NoError(t, db.Create(&[]system.SysUser{
+		{Username: "admin", AuthorityId: 888},
+		{Username: "user", AuthorityId: 9528},
+	}).Error)
+	current := time.Now().Truncate(time.Hour)
+	record := func(hour time.Time, minute int, method, path string, status int, latency time.Duration, userID int) system.SysOperationRecord {
+		r := system.SysOperationRecord{Method: method, Path: path, Status: status, Latency: latency, UserID: userID}
+		r.CreatedAt = hour.Add(time.Duration(minute) * time.Minute)
+		return r
+	}
+	assert.NoError(t, db.Create(&[]system.SysOperationRecord{
+		record(current.Add(-3*time.Hour), 5, "GET", "/user/list", 200, 5*time.Millisecond, 1),
+		record(current.Add(-3*time.Hour), 30, "GET", "/user/list", 500, 80*time.Millisecond, 2),
+		record(current.Add(-2*time.Hour), 10, "POST", "/user/create", 200, 300*time.Millisecond, 1),
+		record(current.Add(-2*time.Hour), 50, "GET", "/user/list", 200, 12*time.Second, 0),
+		// 当前小时尚未结束 不汇总
+		record(current, 0, "GET", "/user/list", 200, time.Millisecond, 1),
+	}).Error)
+
+	service := OperationRecordServiceApp
+	assert.NoError(t, service.RollupOperationRecords())
+	var hours []time.Time
+	assert.NoError(t, db.Model(&system.SysOperationRecordHourly{}).Distinct("hour").Order("hour").Pluck("hour", &hours).Error)
+	if assert.Len(t, hours, 2) {
+		assert.True(t, hours[0].Equal(current.Add(-3*time.Hour)))
+		assert.True(t, hours[1].Equal(current.Add(-2*time.Hour)))
+	}
+
+	// 汇总后才写入的记录 下一次汇总时补上 重复汇总不会重复计数
+	assert.NoError(t, db.Create(&[]system.SysOperationRecord{
+		record(current.Add(-2*time.Hour), 59, "POST", "/user/create", 400, 20*time.Millisecond, 2),
+	}).Error)
+	assert.NoError(t, service.RollupOperationRecords())
+	assert.NoError(t, service.RollupOperationRecords())
+	var total int64
+	assert.NoError(t, db.Model(&system.SysOperationRecordHourly{}).Select("SUM(request_count)").Scan(&total).Error)
+	assert.EqualValues(t, 5, total)
+	var users []system.SysOperationRecordUserHourly
+	assert.NoError(t, db.Where("hour = ? AND user_id = ?", current.Add(-2*time.Hour), 2).Find(&users).Error)
+	if assert.Len(t, users, 1) {
+		assert.EqualValues(t, 9528, users[0].AuthorityID)
+		assert.EqualValues(t, 1, users[0].ErrorCount)
+	}
+
+	// 已结束的小时 两种来源的统计结果一致
+	start, end := current.Add(-3*time.Hour), current
+	for _, info := range []systemReq.OperationRecordStatsSearch{
+		{StartTime: &start, EndTime: &end},
+		{StartTime: &start, EndTime: &end, AuthorityID: 9528},
+		{StartTime: &start, EndTime: &end, Method: "GET", GroupBy: "path"},
+	} {
+		global.GVA_CONFIG.OperationRecord.Rollup = false
+		rawTimeline, err := service.GetOperationRecordTimeline(info)
+		assert.NoError(t, err)
+		rawEndpoints, err := service.GetOperationRecordEndpointStats(info)
+		assert.NoError(t, err)
+		rawStatus, err := service.GetOperationRecordStatusStats(info)
+		assert.NoError(t, err)
+		rawUsers, err := service.GetOperationRecordTopUsers(info)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, rawEndpoints)
+
+		global.GVA_CONFIG.OperationRecord.Rollup = true
+		timeline, err := service.GetOperationRecordTimeline(info)
+		assert.NoError(t, err)
+		endpoints, err := service.GetOperationRecordEndpointStats(info)
+		assert.NoError(t, err)
+		status, err := service.GetOperationRecordStatusStats(info)
+		assert.NoError(t, err)
+		users, err := service.GetOperationRecordTopUsers(info)
+		assert.NoError(t, err)
+
+		assert.Equal(t, rawTimeline, timeline)
+		assert.Equal(t, rawEndpoints, endpoints)
+		assert.Equal(t, rawStatus, status)
+		assert.Equal(t, rawUsers, users)
+	}
+
+	info := systemReq.OperationRecordStatsSearch{StartTime: &start, EndTime: &end}
+	endpoints, err := service.GetOperationRecordEndpointStats(info)
+	assert.NoError(t, err)
+	if assert.Len(t, endpoints, 2) {
+		assert.Equal(t, "/user/list", endpoints[0].Path)
+		assert.EqualValues(t, 3, endpoints[0].Count)
+		assert.EqualValues(t, 1, endpoints[0].Errors)
+		assert.Equal(t, float64(12000), endpoints[0].MaxLatency)
+	}
+}
@@ -0,0 +1,187 @@
+package system
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/record"
+	"go.uber.org/zap"
+)
+
+var (
+	recordSinks      []OperationRecordSink
+	recordPrimary    OperationRecordStore
+	recordWriter     *record.Writer[system.SysOperationRecord]
+	recordWriterLock sync.RWMutex
+)
+
+// StartRecordWriter 按配置创建操作记录存储 开启异步时启动批量写入 否则每条记录同步写入
+func (operationRecordService *OperationRecordService) StartRecordWriter() {
+	conf := global.GVA_CONFIG.OperationRecord
+	recordWriterLock.Lock()
+	defer recordWriterLock.Unlock()
+	if recordSinks != nil {
+		return
+	}
+	sinks, primary, err := newOperationRecordSinks()
+	if err != nil {
+		global.GVA_LOG.Error("init operation record sinks error, fallback to db:", zap.Error(err))
+		db := &dbRecordSink{}
+		sinks, primary = []OperationRecordSink{db}, db
+	}
+	recordSinks, recordPrimary = sinks, primary
+	if !conf.Async {
+		return
+	}
+	recordWriter = record.NewWriter(record.Options{
+		BufferSize:    conf.BufferSize,
+		BatchSize:     conf.BatchSize,
+		FlushInterval: time.Duration(conf.FlushInterval) * time.Millisecond,
+		Overflow:      conf.Overflow,
+		OnError: func(err error, n int) {
+			global.GVA_LOG.Error("batch create operation record error:", zap.Int("count", n), zap.Error(err))
+		},
+	}, func(records []system.SysOperationRecord) error {
+		return writeRecordSinks(sinks, primary, records)
+	})
+}
+
+// CloseRecordWriter 停止异步写入并写入队列中剩余的操作记录 然后关闭所有存储 服务关闭时调用
+func (operationRecordService *OperationRecordService) CloseRecordWriter(ctx context.Context) error {
+	recordWriterLock.Lock()
+	w, sinks := recordWriter, recordSinks
+	recordWriter, recordSinks, recordPrimary = nil, nil, nil
+	recordWriterLock.Unlock()
+	var err error
+	if w != nil {
+		err = w.Close(ctx)
+	}
+	for _, sink := range sinks {
+		if e := sink.Close(); e != nil {
+			global.GVA_LOG.Error("close operation record sink error:", zap.String("sink", sink.Name()), zap.Error(e))
+		}
+	}
+	return err
+}
+
+// RecordOperation 写入一条操作记录 写入前按配置脱敏 开启异步时入队 否则同步写入所有存储
+func (operationRecordService *OperationRecordService) RecordOperation(sysOperationRecord system.SysOperationRecord) {
+	operationRecordService.redactRecord(&sysOperationRecord)
+	sinks, primary, w := currentRecordSinks()
+	if w != nil {
+		if !w.Write(sysOperationRecord) {
+			global.GVA_LOG.Warn("operation record dropped: queue is full", zap.String("path", sysOperationRecord.Path))
+		}
+		return
+	}
+	if err := writeRecordSinks(sinks, primary, []system.SysOperationRecord{sysOperationRecord}); err != nil {
+		global.GVA_LOG.Error("create operation record error:", zap.Error(err))
+	}
+}
+
+// currentRecordSinks 获取当前的存储 尚未初始化时使用数据库存储
+func currentRecordSinks() ([]OperationRecordSink, OperationRecordStore, *record.Writer[system.SysOperationRecord]) {
+	recordWriterLock.RLock()
+	defer recordWriterLock.RUnlock()
+	if recordSinks == nil {
+		db := &dbRecordSink{}
+		return []OperationRecordSink{db}, db, nil
+	}
+	return recordSinks, recordPrimary, recordWriter
+}
+
+// writeRecordSinks 依次写入所有存储 只返回主存储的错误 其余存储的错误仅记录日志
+func writeRecordSinks(sinks []OperationRecordSink, primary OperationRecordStore, records []system.SysOperationRecord) error {
+	var primaryErr error
+	for _, sink := range sinks {
+		err := sink.Write(records)
+		if sink == OperationRecordSink(primary) {
+			primaryErr = err
+			continue
+		}
+		if err != nil {
+			global.GVA_LOG.Error("write operation record sink error:", zap.String("sink", sink.Name()), zap.Int("count", len(records)), zap.Error(err))
+		}
+	}
+	return primaryErr
+}
+
+// RecordWriterStats 获取异步写入的运行指标 未开启异步时返回 nil
+func (operationRecordService *OperationRecordService) RecordWriterStats() *record.Stats {
+	recordWriterLock.RLock()
+	defer recordWriterLock.RUnlock()
+	if recordWriter == nil {
+		return nil
+	}
+	stats := recordWriter.Stats()
+	return &stats
+}
+
+// RedactRulesHash 当前脱敏规则的哈希 规则变化后已有记录需要重新处理 没有任何规则时返回空
+func (operationRecordService *OperationRecordService) RedactRulesHash() string {
+	conf := global.GVA_CONFIG.OperationRecord
+	if len(conf.RedactKeys) == 0 && len(conf.RedactPaths) == 0 && len(conf.SkipResponsePaths) == 0 {
+		return ""
+	}
+	content, _ := json.Marshal([]interface{}{conf.RedactKeys, conf.RedactPaths, conf.RedactMask, conf.SkipResponsePaths})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// RedactExistingRecords 按当前脱敏规则分批处理已有的操作记录 每批之间暂停 pause 避免长时间占用数据库 返回被修改的条数
+func (operationRecordService *OperationRecordService) RedactExistingRecords(batchSize int, pause time.Duration) (count int64, err error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	var lastID uint
+	for {
+		var records []system.SysOperationRecord
+		err = global.GVA_DB.Select("id", "path", "body", "resp", "hash").
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&records).Error
+		if err != nil || len(records) == 0 {
+			return count, err
+		}
+		for i := range records {
+			if records[i].Hash != "" {
+				// 哈希链上的记录不能修改
+				continue
+			}
+			body, resp := records[i].Body, records[i].Resp
+			operationRecordService.redactRecord(&records[i])
+			if records[i].Body == body && records[i].Resp == resp {
+				continue
+			}
+			err = global.GVA_DB.Model(&system.SysOperationRecord{}).Where("id = ?", records[i].ID).
+				Updates(map[string]interface{}{"body": records[i].Body, "resp": records[i].Resp}).Error
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+		lastID = records[len(records)-1].ID
+		if pause > 0 {
+			time.Sleep(pause)
+		}
+	}
+}
+
+// redactRecord 对请求与响应内容脱敏 配置为不记录响应的路由清空响应
+func (operationRecordService *OperationRecordService) redactRecord(sysOperationRecord *system.SysOperationRecord) {
+	conf := global.GVA_CONFIG.OperationRecord
+	for _, p := range conf.SkipResponsePaths {
+		if p != "" && strings.HasSuffix(sysOperationRecord.Path, p) {
+			sysOperationRecord.Resp = ""
+			break
+		}
+	}
+	redactor := record.NewRedactor(conf.RedactKeys, conf.RedactPaths, conf.RedactMask)
+	sysOperationRecord.Body = redactor.Redact(sysOperationRecord.Body)
+	sysOperationRecord.Resp = redactor.Redact(sysOperationRecord.Resp)
+}
@@ -1,41 +1,58 @@
 package system
 
 import (
+	"context"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/task"
 )
 
 type SysParamsService struct{}
 
 // CreateSysParams 创建参数记录
 // Author [Mr.奇淼](https://github.com/pixelmaxQm)
-func (sysParamsService *SysParamsService) CreateSysParams(sysParams *system.SysParams) (err error) {
-	err = global.GVA_DB.Create(sysParams).Error
+func (sysParamsService *SysParamsService) CreateSysParams(ctx context.Context, sysParams *system.SysParams) (err error) {
+	if err = validateSysParam(*sysParams); err != nil {
+		return err
+	}
+	err = global.GVA_DB.WithContext(ctx).Create(sysParams).Error
 	return err
 }
 
 // DeleteSysParams 删除参数记录
 // Author [Mr.奇淼](https://github.com/pixelmaxQm)
-func (sysParamsService *SysParamsService) DeleteSysParams(ID string) (err error) {
-	err = global.GVA_DB.Delete(&system.SysParams{}, "id = ?", ID).Error
+func (sysParamsService *SysParamsService) DeleteSysParams(ctx context.Context, ID string) (err error) {
+	err = global.GVA_DB.WithContext(ctx).Delete(&system.SysParams{}, "id = ?", ID).Error
 	return err
 }
 
 // DeleteSysParamsByIds 批量删除参数记录
 // Author [Mr.奇淼](https://github.com/pixelmaxQm)
-func (sysParamsService *SysParamsService) DeleteSysParamsByIds(IDs []string) (err error) {
-	err = global.GVA_DB.Delete(&[]system.SysParams{}, "id in ?", IDs).Error
+func (sysParamsService *SysParamsService) DeleteSysParamsByIds(ctx context.Context, IDs []string) (err error) {
+	err = global.GVA_DB.WithContext(ctx).Delete(&[]system.SysParams{}, "id in ?", IDs).Error
 	return err
 }
 
 // UpdateSysParams 更新参数记录
 // Author [Mr.奇淼](https://github.com/pixelmaxQm)
-func (sysParamsService *SysParamsService) UpdateSysParams(sysParams system.SysParams) (err error) {
-	err = global.GVA_DB.Model(&system.SysParams{}).Where("id = ?", sysParams.ID).Updates(&sysParams).Error
+func (sysParamsService *SysParamsService) UpdateSysParams(ctx context.Context, sysParams system.SysParams) (err error) {
+	if err = validateSysParam(sysParams); err != nil {
+		return err
+	}
+	err = global.GVA_DB.WithContext(ctx).Model(&system.SysParams{}).Where("id = ?", sysParams.ID).Updates(&sysParams).Error
 	return err
 }
 
+// validateSysParam 校验由系统使用的参数 清理策略只允许清理日志与历史表
+func validateSysParam(sysParams system.SysParams) error {
+	if sysParams.Key == task.RetentionParamKey && sysParams.Value != "" {
+		_, err := task.ParseRetentionOverrides(sysParams.Value)
+		return err
+	}
+	return nil
+}
+
 // GetSysParams 根据ID获取参数记录
 // Author [Mr.奇淼](https://github.com/pixelmaxQm)
 func (sysParamsService *SysParamsService) GetSysParams(ID string) (sysParams system.SysParams, err error) {
@@ -5,6 +5,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/concurrency"
 	"go.uber.org/zap"
 )
 
@@ -57,6 +58,8 @@ func (systemConfigService *SystemConfigService) GetServerInfo() (server *utils.S
 		global.GVA_LOG.Error("func utils.InitDisk() Failed", zap.String("err", err.Error()))
 		return &s, err
 	}
+	s.OperationRecord = OperationRecordServiceApp.RecordWriterStats()
+	s.Concurrency = concurrency.AllStats()
 
 	return &s, nil
 }
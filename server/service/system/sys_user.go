@@ -1,6 +1,7 @@
 package system
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -55,6 +56,9 @@ func (userService *UserService) Login(u *system.SysUser) (userInter *system.SysU
 		if ok := utils.BcryptCheck(u.Password, user.Password); !ok {
 			return nil, errors.New("密码错误")
 		}
+		if err = UserGroupServiceApp.MergeGroupAuthorities(&user); err != nil {
+			return nil, err
+		}
 		MenuServiceApp.UserAuthorityDefaultRouter(&user)
 	}
 	return &user, err
@@ -109,7 +113,7 @@ func (userService *UserService) GetUserInfoList(info systemReq.GetUserList) (lis
 	if err != nil {
 		return
 	}
-	err = db.Limit(limit).Offset(offset).Preload("Authorities").Preload("Authority").Find(&userList).Error
+	err = db.Limit(limit).Offset(offset).Preload("Authorities").Preload("Authority").Preload("Groups").Find(&userList).Error
 	return userList, total, err
 }
 
@@ -121,8 +125,8 @@ func (userService *UserService) GetUserInfoList(info systemReq.GetUserList) (lis
 
 func (userService *UserService) SetUserAuthority(id uint, authorityId uint) (err error) {
 
-	assignErr := global.GVA_DB.Where("sys_user_id = ? AND sys_authority_authority_id = ?", id, authorityId).First(&system.SysUserAuthority{}).Error
-	if errors.Is(assignErr, gorm.ErrRecordNotFound) {
+	// 直接分配和用户组授予的角色均可切换
+	if !UserGroupServiceApp.HasAuthority(id, authorityId) {
 		return errors.New("该用户无此角色")
 	}
 
@@ -209,14 +213,18 @@ func (userService *UserService) SetUserAuthorities(adminAuthorityID, id uint, au
 //@param: id float64
 //@return: err error
 
-func (userService *UserService) DeleteUser(id int) (err error) {
-	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+func (userService *UserService) DeleteUser(ctx context.Context, id int) (err error) {
+	return global.GVA_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("id = ?", id).Delete(&system.SysUser{}).Error; err != nil {
 			return err
 		}
 		if err := tx.Delete(&[]system.SysUserAuthority{}, "sys_user_id = ?", id).Error; err != nil {
 			return err
 		}
+		if err := tx.Delete(&[]system.SysUserGroupUser{}, "sys_user_id = ?", id).Error; err != nil {
+			return err
+		}
+		utils.ClearUserGroupAuthorityCache(uint(id))
 		return nil
 	})
 }
@@ -227,18 +235,21 @@ func (userService *UserService) DeleteUser(id int) (err error) {
 //@param: reqUser model.SysUser
 //@return: err error, user model.SysUser
 
-func (userService *UserService) SetUserInfo(req system.SysUser) error {
-	return global.GVA_DB.Model(&system.SysUser{}).
-		Select("updated_at", "nick_name", "header_img", "phone", "email", "enable").
+func (userService *UserService) SetUserInfo(ctx context.Context, req system.SysUser) error {
+	updates := map[string]interface{}{
+		"updated_at": time.Now(),
+		"nick_name":  req.NickName,
+		"header_img": req.HeaderImg,
+		"phone":      req.Phone,
+		"email":      req.Email,
+		"enable":     req.Enable,
+	}
+	if err := userService.resetContactVerified(req, updates); err != nil {
+		return err
+	}
+	return global.GVA_DB.WithContext(ctx).Model(&system.SysUser{}).
 		Where("id=?", req.ID).
-		Updates(map[string]interface{}{
-			"updated_at": time.Now(),
-			"nick_name":  req.NickName,
-			"header_img": req.HeaderImg,
-			"phone":      req.Phone,
-			"email":      req.Email,
-			"enable":     req.Enable,
-		}).Error
+		Updates(updates).Error
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -247,10 +258,35 @@ func (userService *UserService) SetUserInfo(req system.SysUser) error {
 //@param: reqUser model.SysUser
 //@return: err error, user model.SysUser
 
-func (userService *UserService) SetSelfInfo(req system.SysUser) error {
-	return global.GVA_DB.Model(&system.SysUser{}).
-		Where("id=?", req.ID).
-		Updates(req).Error
+func (userService *UserService) SetSelfInfo(ctx context.Context, req system.SysUser) error {
+	updates := make(map[string]interface{})
+	if err := userService.resetContactVerified(req, updates); err != nil {
+		return err
+	}
+	return global.GVA_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&system.SysUser{}).Where("id=?", req.ID).Updates(req).Error; err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&system.SysUser{}).Where("id=?", req.ID).Updates(updates).Error
+	})
+}
+
+// resetContactVerified 邮箱或手机号变更后需要重新验证
+func (userService *UserService) resetContactVerified(req system.SysUser, updates map[string]interface{}) error {
+	var user system.SysUser
+	if err := global.GVA_DB.Select("id, phone, email").Where("id = ?", req.ID).First(&user).Error; err != nil {
+		return err
+	}
+	if req.Email != "" && req.Email != user.Email {
+		updates["email_verified"] = false
+	}
+	if req.Phone != "" && req.Phone != user.Phone {
+		updates["phone_verified"] = false
+	}
+	return nil
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -272,10 +308,13 @@ func (userService *UserService) SetSelfSetting(req common.JSONMap, uid uint) err
 
 func (userService *UserService) GetUserInfo(uuid uuid.UUID) (user system.SysUser, err error) {
 	var reqUser system.SysUser
-	err = global.GVA_DB.Preload("Authorities").Preload("Authority").First(&reqUser, "uuid = ?", uuid).Error
+	err = global.GVA_DB.Preload("Authorities").Preload("Authority").Preload("Groups").First(&reqUser, "uuid = ?", uuid).Error
 	if err != nil {
 		return reqUser, err
 	}
+	if err = UserGroupServiceApp.MergeGroupAuthorities(&reqUser); err != nil {
+		return reqUser, err
+	}
 	MenuServiceApp.UserAuthorityDefaultRouter(&reqUser)
 	return reqUser, err
 }
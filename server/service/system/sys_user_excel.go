@@ -0,0 +1,344 @@
+package system
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// 用户导入导出使用的表头 导出文件可直接作为导入模板使用
+var userExcelTitles = []string{"用户名", "昵称", "密码", "角色", "手机号", "邮箱", "启用"}
+
+const userExcelSheet = "Sheet1"
+
+var errUserImportInvalid = errors.New("导入数据校验未通过")
+
+type userImportRow struct {
+	line         int
+	username     string
+	nickName     string
+	password     string
+	authorityIds []uint
+	phone        string
+	email        string
+	enable       int
+	existing     *system.SysUser
+}
+
+// ImportUsers 批量导入用户 所有行校验通过后才会在同一事务中写入 upsert 为 true 时更新已存在的用户
+func (userService *UserService) ImportUsers(adminAuthorityID uint, file *multipart.FileHeader, upsert bool) (result systemRes.UserImportResult, err error) {
+	records, err := readUserImportFile(file)
+	if err != nil {
+		return result, err
+	}
+	if len(records) < 2 {
+		return result, errors.New("导入文件需包含表头和数据")
+	}
+	titleIndex := make(map[string]int)
+	for i, title := range records[0] {
+		titleIndex[strings.TrimSpace(title)] = i
+	}
+	for _, title := range []string{"用户名", "角色"} {
+		if _, ok := titleIndex[title]; !ok {
+			return result, fmt.Errorf("导入文件缺少表头: %s", title)
+		}
+	}
+
+	var authorities []system.SysAuthority
+	if err = global.GVA_DB.Find(&authorities).Error; err != nil {
+		return result, err
+	}
+	authorityByName := make(map[string]uint, len(authorities))
+	for _, authority := range authorities {
+		authorityByName[authority.AuthorityName] = authority.AuthorityId
+	}
+
+	result.Titles = records[0]
+	result.Total = len(records) - 1
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		rows := make([]*userImportRow, 0, result.Total)
+		seen := make(map[string]int)
+		for i, record := range records[1:] {
+			row := &userImportRow{line: i + 2}
+			cell := func(title string) string {
+				if idx, ok := titleIndex[title]; ok && idx < len(record) {
+					return strings.TrimSpace(record[idx])
+				}
+				return ""
+			}
+			row.username = cell("用户名")
+			row.nickName = cell("昵称")
+			row.password = cell("密码")
+			row.phone = cell("手机号")
+			row.email = cell("邮箱")
+
+			var rowErrors []string
+			exists := false
+			if row.username == "" {
+				rowErrors = append(rowErrors, "用户名不能为空")
+			} else if line, ok := seen[row.username]; ok {
+				rowErrors = append(rowErrors, fmt.Sprintf("用户名与第%d行重复", line))
+			} else {
+				seen[row.username] = row.line
+				var user system.SysUser
+				e := tx.Preload("Authorities").Where("username = ?", row.username).First(&user).Error
+				if e == nil {
+					exists = true
+					if !upsert {
+						rowErrors = append(rowErrors, "用户名已注册")
+					} else if !canManageImportedUser(adminAuthorityID, &user) {
+						rowErrors = append(rowErrors, "无权修改该用户")
+					} else {
+						row.existing = &user
+					}
+				} else if !errors.Is(e, gorm.ErrRecordNotFound) {
+					return e
+				}
+			}
+
+			for _, name := range strings.FieldsFunc(cell("角色"), func(r rune) bool { return r == ',' || r == '，' }) {
+				name = strings.TrimSpace(name)
+				authorityId, ok := authorityByName[name]
+				if !ok {
+					rowErrors = append(rowErrors, "角色不存在: "+name)
+					continue
+				}
+				if e := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, authorityId); e != nil {
+					rowErrors = append(rowErrors, "无权分配角色: "+name)
+					continue
+				}
+				row.authorityIds = append(row.authorityIds, authorityId)
+			}
+			if len(row.authorityIds) == 0 && !exists {
+				rowErrors = append(rowErrors, "角色不能为空")
+			}
+
+			if row.phone != "" && !utils.IsPhone(row.phone) {
+				rowErrors = append(rowErrors, "手机号格式错误")
+			}
+			if row.email != "" && !utils.IsEmail(row.email) {
+				rowErrors = append(rowErrors, "邮箱格式错误")
+			}
+			if row.password == "" && !exists {
+				rowErrors = append(rowErrors, "密码不能为空")
+			} else if row.password != "" {
+				if e := utils.VerifyPassword(row.password); e != nil {
+					rowErrors = append(rowErrors, e.Error())
+				}
+			}
+			switch cell("启用") {
+			case "", "1", "是", "正常", "启用":
+				row.enable = 1
+			case "2", "否", "冻结", "禁用":
+				row.enable = 2
+			default:
+				rowErrors = append(rowErrors, "启用状态只能为 1(正常) 或 2(冻结)")
+			}
+
+			if len(rowErrors) > 0 {
+				result.Errors = append(result.Errors, systemRes.UserImportRowError{
+					Row:      row.line,
+					Username: row.username,
+					Errors:   rowErrors,
+					Cells:    record,
+				})
+			}
+			rows = append(rows, row)
+		}
+		if len(result.Errors) > 0 {
+			return errUserImportInvalid
+		}
+
+		for _, row := range rows {
+			if row.existing != nil {
+				if e := updateImportedUser(tx, row); e != nil {
+					return e
+				}
+				result.Updated++
+				continue
+			}
+			if row.nickName == "" {
+				row.nickName = row.username
+			}
+			user := system.SysUser{
+				UUID:        uuid.New(),
+				Username:    row.username,
+				NickName:    row.nickName,
+				Password:    utils.BcryptHash(row.password),
+				AuthorityId: row.authorityIds[0],
+				Phone:       row.phone,
+				Email:       row.email,
+				Enable:      row.enable,
+			}
+			if e := tx.Create(&user).Error; e != nil {
+				return e
+			}
+			if e := createUserAuthorities(tx, user.ID, row.authorityIds); e != nil {
+				return e
+			}
+			result.Created++
+		}
+		return nil
+	})
+	if errors.Is(err, errUserImportInvalid) {
+		return result, nil
+	}
+	return result, err
+}
+
+// canManageImportedUser 覆盖已存在的用户前检查操作者对其主角色与全部角色都有权限 避免覆盖上级角色的用户
+func canManageImportedUser(adminAuthorityID uint, user *system.SysUser) bool {
+	if AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, user.AuthorityId) != nil {
+		return false
+	}
+	for _, authority := range user.Authorities {
+		if AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, authority.AuthorityId) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// updateImportedUser 更新已存在的用户 密码等为空的列保持原值
+func updateImportedUser(tx *gorm.DB, row *userImportRow) error {
+	updates := map[string]interface{}{
+		"enable": row.enable,
+	}
+	if row.nickName != "" {
+		updates["nick_name"] = row.nickName
+	}
+	if row.phone != "" {
+		updates["phone"] = row.phone
+	}
+	if row.email != "" {
+		updates["email"] = row.email
+	}
+	if row.password != "" {
+		updates["password"] = utils.BcryptHash(row.password)
+	}
+	if len(row.authorityIds) > 0 {
+		updates["authority_id"] = row.authorityIds[0]
+		if err := tx.Delete(&[]system.SysUserAuthority{}, "sys_user_id = ?", row.existing.ID).Error; err != nil {
+			return err
+		}
+		if err := createUserAuthorities(tx, row.existing.ID, row.authorityIds); err != nil {
+			return err
+		}
+	}
+	return tx.Model(&system.SysUser{}).Where("id = ?", row.existing.ID).Updates(updates).Error
+}
+
+func createUserAuthorities(tx *gorm.DB, userID uint, authorityIds []uint) error {
+	userAuthorities := make([]system.SysUserAuthority, 0, len(authorityIds))
+	for _, id := range authorityIds {
+		userAuthorities = append(userAuthorities, system.SysUserAuthority{SysUserId: userID, SysAuthorityAuthorityId: id})
+	}
+	return tx.Create(&userAuthorities).Error
+}
+
+// readUserImportFile 读取 xlsx 或 csv 文件的全部行
+func readUserImportFile(file *multipart.FileHeader) ([][]string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".csv":
+		reader := csv.NewReader(src)
+		reader.FieldsPerRecord = -1
+		return reader.ReadAll()
+	case ".xlsx":
+		f, err := excelize.OpenReader(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return f.GetRows(f.GetSheetName(0))
+	default:
+		return nil, errors.New("仅支持 xlsx 或 csv 文件")
+	}
+}
+
+// UserImportReport 生成导入错误报告 原始数据后追加错误说明列
+func (userService *UserService) UserImportReport(result systemRes.UserImportResult) (*bytes.Buffer, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	titles := append(append([]string{"行号"}, result.Titles...), "错误信息")
+	for i, title := range titles {
+		if err := f.SetCellValue(userExcelSheet, fmt.Sprintf("%s%d", getColumnName(i+1), 1), title); err != nil {
+			return nil, err
+		}
+	}
+	for i, rowErr := range result.Errors {
+		line := i + 2
+		values := []interface{}{rowErr.Row}
+		for _, c := range rowErr.Cells {
+			values = append(values, c)
+		}
+		for len(values) < len(titles)-1 {
+			values = append(values, "")
+		}
+		values = append(values[:len(titles)-1], strings.Join(rowErr.Errors, "; "))
+		if err := f.SetSheetRow(userExcelSheet, fmt.Sprintf("A%d", line), &values); err != nil {
+			return nil, err
+		}
+	}
+	return f.WriteToBuffer()
+}
+
+// ExportUsers 按用户列表的筛选条件导出用户及其角色
+func (userService *UserService) ExportUsers(info systemReq.GetUserList) (*bytes.Buffer, error) {
+	db := global.GVA_DB.Model(&system.SysUser{})
+	if info.NickName != "" {
+		db = db.Where("nick_name LIKE ?", "%"+info.NickName+"%")
+	}
+	if info.Phone != "" {
+		db = db.Where("phone LIKE ?", "%"+info.Phone+"%")
+	}
+	if info.Username != "" {
+		db = db.Where("username LIKE ?", "%"+info.Username+"%")
+	}
+	if info.Email != "" {
+		db = db.Where("email LIKE ?", "%"+info.Email+"%")
+	}
+	var users []system.SysUser
+	if err := db.Preload("Authorities").Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, title := range userExcelTitles {
+		if err := f.SetCellValue(userExcelSheet, fmt.Sprintf("%s%d", getColumnName(i+1), 1), title); err != nil {
+			return nil, err
+		}
+	}
+	for i, user := range users {
+		names := make([]string, 0, len(user.Authorities))
+		for _, authority := range user.Authorities {
+			names = append(names, authority.AuthorityName)
+		}
+		// 密码列留空 导出文件以 upsert 方式重新导入时不会修改密码
+		row := []interface{}{user.Username, user.NickName, "", strings.Join(names, ","), user.Phone, user.Email, strconv.Itoa(user.Enable)}
+		if err := f.SetSheetRow(userExcelSheet, fmt.Sprintf("A%d", i+2), &row); err != nil {
+			return nil, err
+		}
+	}
+	return f.WriteToBuffer()
+}
@@ -0,0 +1,228 @@
+package system
+
+import (
+	"errors"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+)
+
+type UserGroupService struct{}
+
+var UserGroupServiceApp = new(UserGroupService)
+
+// CreateUserGroup 创建用户组
+func (userGroupService *UserGroupService) CreateUserGroup(group system.SysUserGroup) error {
+	if !errors.Is(global.GVA_DB.Where("name = ?", group.Name).First(&system.SysUserGroup{}).Error, gorm.ErrRecordNotFound) {
+		return errors.New("存在相同名称的用户组")
+	}
+	group.Users = nil
+	group.Authorities = nil
+	return global.GVA_DB.Create(&group).Error
+}
+
+// DeleteUserGroup 删除用户组 同时清理成员与角色关联
+func (userGroupService *UserGroupService) DeleteUserGroup(id uint) error {
+	userIds, err := userGroupService.getGroupUserIds(global.GVA_DB, id)
+	if err != nil {
+		return err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&system.SysUserGroup{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&[]system.SysUserGroupUser{}, "sys_user_group_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&[]system.SysUserGroupAuthority{}, "sys_user_group_id = ?", id).Error
+	})
+	if err == nil {
+		utils.ClearUserGroupAuthorityCache(userIds...)
+	}
+	return err
+}
+
+// UpdateUserGroup 更新用户组基本信息
+func (userGroupService *UserGroupService) UpdateUserGroup(group system.SysUserGroup) error {
+	var exist system.SysUserGroup
+	if !errors.Is(global.GVA_DB.Where("name = ? AND id <> ?", group.Name, group.ID).First(&exist).Error, gorm.ErrRecordNotFound) {
+		return errors.New("存在相同名称的用户组")
+	}
+	return global.GVA_DB.Model(&system.SysUserGroup{}).Where("id = ?", group.ID).Updates(map[string]interface{}{
+		"name":        group.Name,
+		"description": group.Description,
+	}).Error
+}
+
+// GetUserGroup 根据ID获取用户组 包含成员与角色
+func (userGroupService *UserGroupService) GetUserGroup(id uint) (group system.SysUserGroup, err error) {
+	err = global.GVA_DB.Preload("Users").Preload("Authorities").Where("id = ?", id).First(&group).Error
+	return
+}
+
+// GetUserGroupList 分页获取用户组
+func (userGroupService *UserGroupService) GetUserGroupList(info systemReq.SysUserGroupSearch) (list []system.SysUserGroup, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysUserGroup{})
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	err = db.Preload("Authorities").Find(&list).Error
+	return list, total, err
+}
+
+// SetUserGroupUsers 设置用户组成员 操作者需有权管理用户组拥有的全部角色
+func (userGroupService *UserGroupService) SetUserGroupUsers(adminAuthorityID, id uint, userIds []uint) error {
+	oldUserIds, err := userGroupService.getGroupUserIds(global.GVA_DB, id)
+	if err != nil {
+		return err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&system.SysUserGroup{}).Error; err != nil {
+			return errors.New("用户组不存在")
+		}
+		var authorityIds []uint
+		if err := tx.Model(&system.SysUserGroupAuthority{}).Where("sys_user_group_id = ?", id).Pluck("sys_authority_authority_id", &authorityIds).Error; err != nil {
+			return err
+		}
+		for _, v := range authorityIds {
+			if err := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, v); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&[]system.SysUserGroupUser{}, "sys_user_group_id = ?", id).Error; err != nil {
+			return err
+		}
+		if len(userIds) == 0 {
+			return nil
+		}
+		var members []system.SysUserGroupUser
+		for _, v := range userIds {
+			members = append(members, system.SysUserGroupUser{SysUserGroupId: id, SysUserId: v})
+		}
+		return tx.Create(&members).Error
+	})
+	if err == nil {
+		utils.ClearUserGroupAuthorityCache(append(oldUserIds, userIds...)...)
+	}
+	return err
+}
+
+// SetUserGroupAuthorities 设置用户组角色 只能分配操作者有权管理的角色
+func (userGroupService *UserGroupService) SetUserGroupAuthorities(adminAuthorityID, id uint, authorityIds []uint) error {
+	userIds, err := userGroupService.getGroupUserIds(global.GVA_DB, id)
+	if err != nil {
+		return err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&system.SysUserGroup{}).Error; err != nil {
+			return errors.New("用户组不存在")
+		}
+		if err := tx.Delete(&[]system.SysUserGroupAuthority{}, "sys_user_group_id = ?", id).Error; err != nil {
+			return err
+		}
+		if len(authorityIds) == 0 {
+			return nil
+		}
+		var groupAuthorities []system.SysUserGroupAuthority
+		for _, v := range authorityIds {
+			if err := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, v); err != nil {
+				return err
+			}
+			groupAuthorities = append(groupAuthorities, system.SysUserGroupAuthority{SysUserGroupId: id, SysAuthorityAuthorityId: v})
+		}
+		return tx.Create(&groupAuthorities).Error
+	})
+	if err == nil {
+		utils.ClearUserGroupAuthorityCache(userIds...)
+	}
+	return err
+}
+
+// GetUserGroupAuthorities 获取用户通过用户组获得的角色
+func (userGroupService *UserGroupService) GetUserGroupAuthorities(userID uint) (authorities []system.SysAuthority, err error) {
+	ids := utils.GetUserGroupAuthorityIds(userID)
+	if len(ids) == 0 {
+		return
+	}
+	err = global.GVA_DB.Where("authority_id in ?", ids).Find(&authorities).Error
+	return
+}
+
+// MergeGroupAuthorities 将用户组授予的角色并入用户的角色列表 供角色切换使用
+func (userGroupService *UserGroupService) MergeGroupAuthorities(user *system.SysUser) error {
+	groupAuthorities, err := userGroupService.GetUserGroupAuthorities(user.ID)
+	if err != nil {
+		return err
+	}
+	owned := make(map[uint]bool, len(user.Authorities))
+	for i := range user.Authorities {
+		owned[user.Authorities[i].AuthorityId] = true
+	}
+	for i := range groupAuthorities {
+		if !owned[groupAuthorities[i].AuthorityId] {
+			owned[groupAuthorities[i].AuthorityId] = true
+			user.Authorities = append(user.Authorities, groupAuthorities[i])
+		}
+	}
+	return nil
+}
+
+// HasAuthority 判断用户是否直接或通过用户组拥有某角色
+func (userGroupService *UserGroupService) HasAuthority(userID, authorityID uint) bool {
+	err := global.GVA_DB.Where("sys_user_id = ? AND sys_authority_authority_id = ?", userID, authorityID).First(&system.SysUserAuthority{}).Error
+	if err == nil {
+		return true
+	}
+	for _, v := range utils.GetUserGroupAuthorityIds(userID) {
+		if v == authorityID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserAuthoritySources 获取用户每个角色的来源 直接分配或所属用户组
+func (userGroupService *UserGroupService) GetUserAuthoritySources(userID uint) (sources []systemRes.SysUserAuthoritySource, err error) {
+	var user system.SysUser
+	err = global.GVA_DB.Preload("Authorities").Preload("Groups.Authorities").Where("id = ?", userID).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[uint]int)
+	for _, authority := range user.Authorities {
+		index[authority.AuthorityId] = len(sources)
+		sources = append(sources, systemRes.SysUserAuthoritySource{Authority: authority, Direct: true})
+	}
+	for _, group := range user.Groups {
+		authorities := group.Authorities
+		group.Authorities = nil
+		for _, authority := range authorities {
+			i, ok := index[authority.AuthorityId]
+			if !ok {
+				i = len(sources)
+				index[authority.AuthorityId] = i
+				sources = append(sources, systemRes.SysUserAuthoritySource{Authority: authority})
+			}
+			sources[i].Groups = append(sources[i].Groups, group)
+		}
+	}
+	return sources, nil
+}
+
+func (userGroupService *UserGroupService) getGroupUserIds(db *gorm.DB, id uint) (userIds []uint, err error) {
+	err = db.Model(&system.SysUserGroupUser{}).Where("sys_user_group_id = ?", id).Pluck("sys_user_id", &userIds).Error
+	return
+}
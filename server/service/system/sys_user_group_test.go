@@ -0,0 +1,51 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/glebarez/sqlite"
+	"github.com/songzhibin97/gkit/cache/local_cache"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSetUserGroupUsersChecksAuthority(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/user_group.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&system.SysAuthority{}, &system.SysUser{}, &system.SysUserGroup{}); err != nil {
+		t.Fatal(err)
+	}
+	global.GVA_DB = db
+	global.GVA_LOG = zap.NewNop()
+	global.BlackCache = local_cache.NewCache()
+	global.GVA_CONFIG.System.UseStrictAuth = true
+	t.Cleanup(func() { global.GVA_CONFIG.System.UseStrictAuth = false })
+
+	root, child := uint(0), uint(888)
+	assert.NoError(t, db.Create(&[]system.SysAuthority{
+		{AuthorityId: 888, AuthorityName: "管理员", ParentId: &root},
+		{AuthorityId: 8881, AuthorityName: "子角色", ParentId: &child},
+		{AuthorityId: 9528, AuthorityName: "普通用户", ParentId: &root},
+	}).Error)
+	admins := system.SysUserGroup{Name: "admins"}
+	assert.NoError(t, db.Create(&admins).Error)
+	assert.NoError(t, UserGroupServiceApp.SetUserGroupAuthorities(888, admins.ID, []uint{888}))
+
+	// 普通用户不能把自己加入拥有管理员角色的用户组
+	assert.Error(t, UserGroupServiceApp.SetUserGroupUsers(9528, admins.ID, []uint{2}))
+	var count int64
+	db.Model(&system.SysUserGroupUser{}).Where("sys_user_group_id = ?", admins.ID).Count(&count)
+	assert.Zero(t, count)
+
+	// 子角色同样不能管理上级角色
+	assert.Error(t, UserGroupServiceApp.SetUserGroupUsers(8881, admins.ID, []uint{2}))
+	assert.NoError(t, UserGroupServiceApp.SetUserGroupUsers(888, admins.ID, []uint{2}))
+	db.Model(&system.SysUserGroupUser{}).Where("sys_user_group_id = ?", admins.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,231 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 匿名化后替换操作记录中个人数据的占位内容
+const anonymizedPlaceholder = "[anonymized]"
+
+// GetDeletedUserList 分页获取已删除的用户
+func (userService *UserService) GetDeletedUserList(info systemReq.GetUserList) (list []system.SysUser, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Unscoped().Model(&system.SysUser{}).Where("deleted_at IS NOT NULL")
+	if info.NickName != "" {
+		db = db.Where("nick_name LIKE ?", "%"+info.NickName+"%")
+	}
+	if info.Phone != "" {
+		db = db.Where("phone LIKE ?", "%"+info.Phone+"%")
+	}
+	if info.Username != "" {
+		db = db.Where("username LIKE ?", "%"+info.Username+"%")
+	}
+	if info.Email != "" {
+		db = db.Where("email LIKE ?", "%"+info.Email+"%")
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	err = db.Order("deleted_at desc").Find(&list).Error
+	return list, total, err
+}
+
+// RestoreUser 恢复已删除的用户 并恢复其主角色
+func (userService *UserService) RestoreUser(id uint) error {
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var user system.SysUser
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error; err != nil {
+			return errors.New("已删除的用户不存在")
+		}
+		if user.Anonymized {
+			return errors.New("已匿名化的用户无法恢复")
+		}
+		if !errors.Is(tx.Where("username = ?", user.Username).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+			return errors.New("用户名已被其他用户使用")
+		}
+		if err := tx.Unscoped().Model(&system.SysUser{}).Where("id = ?", id).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if errors.Is(tx.Where("authority_id = ?", user.AuthorityId).First(&system.SysAuthority{}).Error, gorm.ErrRecordNotFound) {
+			// 主角色已被删除 恢复后由管理员重新分配角色
+			return nil
+		}
+		return createUserAuthorities(tx, id, []uint{user.AuthorityId})
+	})
+}
+
+// PurgeUser 彻底清除已删除的用户 删除时间需超过配置的保留天数
+func (userService *UserService) PurgeUser(id uint) error {
+	graceDays := global.GVA_CONFIG.Account.PurgeGraceDays
+	var user system.SysUser
+	if err := global.GVA_DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error; err != nil {
+		return errors.New("已删除的用户不存在")
+	}
+	if user.DeletedAt.Time.After(purgeDeadline()) {
+		return fmt.Errorf("用户删除未满 %d 天,暂不能彻底清除", graceDays)
+	}
+	return purgeUser(global.GVA_DB, id)
+}
+
+// PurgeExpiredUsers 彻底清除所有超过保留天数的已删除用户 供定时任务调用 保留天数不大于0时不自动清除
+func (userService *UserService) PurgeExpiredUsers() (count int, err error) {
+	if global.GVA_CONFIG.Account.PurgeGraceDays <= 0 {
+		return 0, nil
+	}
+	var ids []uint
+	err = global.GVA_DB.Unscoped().Model(&system.SysUser{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", purgeDeadline()).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err = purgeUser(global.GVA_DB, id); err != nil {
+			global.GVA_LOG.Error("彻底清除用户失败!", zap.Uint("id", id), zap.Error(err))
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// AnonymizeUser 匿名化用户 清除用户与操作记录中的个人数据 保留用户ID与UUID作为假名标识
+// 哈希链上的操作记录以及 file、http 存储中的记录无法清除 通过返回的结果告知调用方
+func (userService *UserService) AnonymizeUser(id uint) (result systemRes.UserAnonymizeResult, err error) {
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var user system.SysUser
+		if err := tx.Unscoped().Where("id = ?", id).First(&user).Error; err != nil {
+			return errors.New("用户不存在")
+		}
+		if user.Anonymized {
+			return errors.New("用户已匿名化")
+		}
+		err := tx.Unscoped().Model(&system.SysUser{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"username":       fmt.Sprintf("anonymized_%d", id),
+			"nick_name":      "已注销用户",
+			"header_img":     "",
+			"password":       utils.BcryptHash(randomToken()),
+			"phone":          "",
+			"email":          "",
+			"email_verified": false,
+			"phone_verified": false,
+			"enable":         2,
+			"origin_setting": nil,
+			"anonymized":     true,
+		}).Error
+		if err != nil {
+			return err
+		}
+		// 操作记录保留路径、方法、状态等审计字段 只清除可能包含个人数据的内容
+		// 只追加模式下哈希链上的记录不能修改 只清除链外的记录
+		scrubbed := tx.Model(&system.SysOperationRecord{}).Where("user_id = ? AND (hash IS NULL OR hash = '')", id).Updates(map[string]interface{}{
+			"ip":    anonymizedPlaceholder,
+			"agent": anonymizedPlaceholder,
+			"body":  anonymizedPlaceholder,
+			"resp":  anonymizedPlaceholder,
+		})
+		if scrubbed.Error != nil {
+			return scrubbed.Error
+		}
+		result.ScrubbedRecords = scrubbed.RowsAffected
+		err = tx.Model(&system.SysOperationRecord{}).Where("user_id = ? AND hash <> ''", id).Count(&result.RetainedRecords).Error
+		if err != nil {
+			return err
+		}
+		if err = tx.Model(&system.SysUserRegistration{}).Where("user_id = ?", id).Update("remark", "").Error; err != nil {
+			return err
+		}
+		if err = tx.Unscoped().Delete(&[]system.SysUserToken{}, "user_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err = tx.Delete(&[]system.SysUserAuthority{}, "sys_user_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&[]system.SysUserGroupUser{}, "sys_user_id = ?", id).Error
+	})
+	if err != nil {
+		return result, err
+	}
+	utils.ClearUserGroupAuthorityCache(id)
+
+	sinks, _, _ := currentRecordSinks()
+	for _, sink := range sinks {
+		if sink.Name() == OperationRecordSinkDB {
+			continue
+		}
+		scrubber, ok := sink.(OperationRecordScrubber)
+		if !ok {
+			result.UnscrubbedSinks = append(result.UnscrubbedSinks, sink.Name())
+			continue
+		}
+		count, e := scrubber.ScrubUser(id)
+		if e != nil {
+			global.GVA_LOG.Error("清除操作记录中的个人数据失败!", zap.String("sink", sink.Name()), zap.Error(e))
+			result.UnscrubbedSinks = append(result.UnscrubbedSinks, sink.Name())
+			continue
+		}
+		result.ScrubbedRecords += count
+	}
+	return result, nil
+}
+
+// ExportUserData 导出系统中保存的某个用户的全部个人数据 已删除的用户同样可以导出
+func (userService *UserService) ExportUserData(id uint) (data systemRes.UserPersonalData, err error) {
+	err = global.GVA_DB.Unscoped().Preload("Authorities").Preload("Groups").Where("id = ?", id).First(&data.User).Error
+	if err != nil {
+		return data, errors.New("用户不存在")
+	}
+	if err = global.GVA_DB.Where("user_id = ?", id).Order("id").Find(&data.Registrations).Error; err != nil {
+		return
+	}
+	if err = global.GVA_DB.Unscoped().Where("user_id = ?", id).Order("id").Find(&data.Tokens).Error; err != nil {
+		return
+	}
+	if err = global.GVA_DB.Where("user_id = ?", id).Order("id").Find(&data.OperationRecords).Error; err != nil {
+		return
+	}
+	data.ExportedAt = time.Now()
+	return data, nil
+}
+
+func purgeDeadline() time.Time {
+	return time.Now().AddDate(0, 0, -global.GVA_CONFIG.Account.PurgeGraceDays)
+}
+
+// purgeUser 物理删除用户及其关联数据 操作记录保留以维持审计完整性
+func purgeUser(db *gorm.DB, id uint) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&system.SysUser{}, "id = ? AND deleted_at IS NOT NULL", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&[]system.SysUserAuthority{}, "sys_user_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&[]system.SysUserGroupUser{}, "sys_user_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&[]system.SysUserToken{}, "user_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&[]system.SysUserRegistration{}, "user_id = ?", id).Error
+	})
+	if err == nil {
+		utils.ClearUserGroupAuthorityCache(id)
+	}
+	return err
+}
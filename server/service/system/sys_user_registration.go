@@ -0,0 +1,170 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CheckRegisterLimit 自助注册频率限制
+func (userService *UserService) CheckRegisterLimit(ip string) error {
+	return checkAccountLimit("GVA_AccountRegister_"+ip, global.GVA_CONFIG.Account.RegisterLimitCount, global.GVA_CONFIG.Account.RegisterLimitTime)
+}
+
+// SelfRegister 自助注册 创建待审核用户并通知审核人
+func (userService *UserService) SelfRegister(req systemReq.SelfRegister) (registration system.SysUserRegistration, err error) {
+	if !global.GVA_CONFIG.Account.OpenRegister {
+		return registration, errors.New("未开放注册")
+	}
+	if err = utils.VerifyPassword(req.Password); err != nil {
+		return registration, err
+	}
+	if req.Phone != "" && !utils.IsPhone(req.Phone) {
+		return registration, errors.New("手机号格式错误")
+	}
+	if req.Email != "" && !utils.IsEmail(req.Email) {
+		return registration, errors.New("邮箱格式错误")
+	}
+	authorityId := global.GVA_CONFIG.Account.RegisterAuthorityId
+	if err = global.GVA_DB.Where("authority_id = ?", authorityId).First(&system.SysAuthority{}).Error; err != nil {
+		global.GVA_LOG.Error("自助注册默认角色不存在!", zap.Uint("authorityId", authorityId))
+		return registration, errors.New("注册配置错误,请联系管理员")
+	}
+	if req.NickName == "" {
+		req.NickName = req.Username
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if !errors.Is(tx.Where("username = ?", req.Username).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+			return errors.New("用户名已注册")
+		}
+		user := system.SysUser{
+			UUID:        uuid.New(),
+			Username:    req.Username,
+			NickName:    req.NickName,
+			Password:    utils.BcryptHash(req.Password),
+			AuthorityId: authorityId,
+			Phone:       req.Phone,
+			Email:       req.Email,
+			Enable:      system.UserEnablePending,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := createUserAuthorities(tx, user.ID, []uint{authorityId}); err != nil {
+			return err
+		}
+		registration = system.SysUserRegistration{UserID: user.ID, Remark: req.Remark, Status: system.RegistrationPending}
+		if err := tx.Create(&registration).Error; err != nil {
+			return err
+		}
+		registration.User = user
+		return nil
+	})
+	if err == nil {
+		go notifyRegistrationApprovers(registration)
+	}
+	return registration, err
+}
+
+// GetRegistrationList 分页获取注册申请
+func (userService *UserService) GetRegistrationList(info systemReq.RegistrationSearch) (list []system.SysUserRegistration, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysUserRegistration{})
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if info.Username != "" {
+		db = db.Where("user_id IN (?)", global.GVA_DB.Model(&system.SysUser{}).Select("id").Where("username LIKE ?", "%"+info.Username+"%"))
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	err = db.Preload("User").Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// ApproveRegistration 审核通过 启用用户
+func (userService *UserService) ApproveRegistration(reviewerID uint, req systemReq.ReviewRegistration) error {
+	return userService.reviewRegistration(reviewerID, req, system.RegistrationApproved, system.UserEnableNormal)
+}
+
+// RejectRegistration 拒绝注册 必须填写原因
+func (userService *UserService) RejectRegistration(reviewerID uint, req systemReq.ReviewRegistration) error {
+	if strings.TrimSpace(req.Reason) == "" {
+		return errors.New("请填写拒绝原因")
+	}
+	return userService.reviewRegistration(reviewerID, req, system.RegistrationRejected, system.UserEnableRejected)
+}
+
+func (userService *UserService) reviewRegistration(reviewerID uint, req systemReq.ReviewRegistration, status string, enable int) error {
+	var registration system.SysUserRegistration
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("User").Where("id = ?", req.ID).First(&registration).Error; err != nil {
+			return errors.New("注册申请不存在")
+		}
+		if registration.Status != system.RegistrationPending {
+			return errors.New("该申请已审核")
+		}
+		now := time.Now()
+		result := tx.Model(&system.SysUserRegistration{}).
+			Where("id = ? AND status = ?", registration.ID, system.RegistrationPending).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"reason":      req.Reason,
+				"reviewer_id": reviewerID,
+				"reviewed_at": &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("该申请已审核")
+		}
+		registration.Status, registration.Reason = status, req.Reason
+		return tx.Model(&system.SysUser{}).Where("id = ?", registration.UserID).Update("enable", enable).Error
+	})
+	if err == nil && registration.User.Email != "" {
+		go notifyRegistrationResult(registration)
+	}
+	return err
+}
+
+func notifyRegistrationApprovers(registration system.SysUserRegistration) {
+	to := global.GVA_CONFIG.Account.RegisterApprovers
+	if to == "" {
+		to = global.GVA_CONFIG.Email.To
+	}
+	if to == "" {
+		return
+	}
+	body := fmt.Sprintf("用户 %s(%s) 提交了注册申请,请登录系统审核。<br/>申请说明: %s",
+		registration.User.Username, registration.User.NickName, registration.Remark)
+	if err := emailUtils.Email(to, "新的注册申请待审核", body); err != nil {
+		global.GVA_LOG.Error("发送注册审核通知失败!", zap.Error(err))
+	}
+}
+
+func notifyRegistrationResult(registration system.SysUserRegistration) {
+	subject, body := "注册申请已通过", fmt.Sprintf("您好 %s,您的注册申请已通过审核,现在可以登录系统。", registration.User.NickName)
+	if registration.Status == system.RegistrationRejected {
+		subject, body = "注册申请未通过", fmt.Sprintf("您好 %s,您的注册申请未通过审核。原因: %s", registration.User.NickName, registration.Reason)
+	}
+	if err := emailUtils.Email(registration.User.Email, subject, body); err != nil {
+		global.GVA_LOG.Error("发送注册审核结果失败!", zap.Error(err))
+	}
+}
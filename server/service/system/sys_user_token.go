@@ -0,0 +1,231 @@
+package system
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/sms"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserTokenInvalid = errors.New("链接或验证码无效或已过期")
+	ErrSendTooFrequent  = errors.New("发送过于频繁,请稍后再试")
+)
+
+// CheckSendLimit 发送频率限制 同一个key在窗口内超过次数后拒绝
+func (userService *UserService) CheckSendLimit(key string) error {
+	return checkAccountLimit("GVA_AccountSend_"+key, global.GVA_CONFIG.Account.SendLimitCount, global.GVA_CONFIG.Account.SendLimitTime)
+}
+
+// checkAccountLimit 基于 BlackCache 的固定窗口计数 limit 或 window 不大于0时不限制
+func checkAccountLimit(key string, limit int, window int) error {
+	if limit <= 0 || window <= 0 {
+		return nil
+	}
+	v, ok := global.BlackCache.Get(key)
+	if !ok {
+		global.BlackCache.Set(key, 1, time.Duration(window)*time.Second)
+		return nil
+	}
+	if count, _ := v.(int); count >= limit {
+		return ErrSendTooFrequent
+	}
+	return global.BlackCache.Increment(key, 1)
+}
+
+// ForgotPassword 找回密码 无论用户是否存在都不返回差异 仅向已验证的邮箱发送一次性重置链接
+func (userService *UserService) ForgotPassword(username string) {
+	var user system.SysUser
+	if err := global.GVA_DB.Where("username = ?", username).First(&user).Error; err != nil {
+		return
+	}
+	if user.Enable != 1 || user.Email == "" || !user.EmailVerified {
+		return
+	}
+	expire := time.Duration(global.GVA_CONFIG.Account.ResetTokenExpire) * time.Minute
+	token, err := userService.issueUserToken(user.ID, system.UserTokenResetPassword, user.Email, randomToken(), expire)
+	if err != nil {
+		global.GVA_LOG.Error("生成找回密码令牌失败!", zap.Error(err))
+		return
+	}
+	link := global.GVA_CONFIG.Account.ResetURL
+	if strings.Contains(link, "?") {
+		link += "&token=" + url.QueryEscape(token)
+	} else {
+		link += "?token=" + url.QueryEscape(token)
+	}
+	body := fmt.Sprintf("您好 %s,<br/>请在 %d 分钟内点击以下链接重置密码,如非本人操作请忽略本邮件:<br/><a href=\"%s\">%s</a>",
+		user.NickName, global.GVA_CONFIG.Account.ResetTokenExpire, link, link)
+	if err = emailUtils.Email(user.Email, "重置密码", body); err != nil {
+		global.GVA_LOG.Error("发送找回密码邮件失败!", zap.Error(err))
+	}
+}
+
+// ResetPasswordByToken 使用找回密码令牌设置新密码 令牌只能使用一次
+func (userService *UserService) ResetPasswordByToken(token string, newPassword string) error {
+	if err := utils.VerifyPassword(newPassword); err != nil {
+		return err
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		record, err := consumeUserToken(tx, system.UserTokenResetPassword, hashToken(token))
+		if err != nil {
+			return err
+		}
+		if err = tx.Model(&system.SysUser{}).Where("id = ?", record.UserID).Update("password", utils.BcryptHash(newPassword)).Error; err != nil {
+			return err
+		}
+		// 作废该用户其余未使用的找回密码令牌
+		return tx.Model(&system.SysUserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", record.UserID, system.UserTokenResetPassword).
+			Update("used_at", time.Now()).Error
+	})
+}
+
+// SendEmailVerification 向用户当前邮箱发送验证码
+func (userService *UserService) SendEmailVerification(userID uint) error {
+	var user system.SysUser
+	if err := global.GVA_DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return errors.New("请先设置邮箱")
+	}
+	if user.EmailVerified {
+		return errors.New("邮箱已验证")
+	}
+	code := randomCode()
+	expire := global.GVA_CONFIG.Account.VerifyCodeExpire
+	if _, err := userService.issueUserToken(user.ID, system.UserTokenVerifyEmail, user.Email, code, time.Duration(expire)*time.Minute); err != nil {
+		return err
+	}
+	body := fmt.Sprintf("您的邮箱验证码为 <b>%s</b>,%d 分钟内有效。", code, expire)
+	return emailUtils.Email(user.Email, "邮箱验证", body)
+}
+
+// VerifyEmail 校验邮箱验证码 验证的邮箱必须与用户当前邮箱一致
+func (userService *UserService) VerifyEmail(userID uint, code string) error {
+	return userService.verifyContact(userID, system.UserTokenVerifyEmail, code)
+}
+
+// SendPhoneVerification 向用户当前手机号发送验证码
+func (userService *UserService) SendPhoneVerification(userID uint) error {
+	var user system.SysUser
+	if err := global.GVA_DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	if user.Phone == "" {
+		return errors.New("请先设置手机号")
+	}
+	if user.PhoneVerified {
+		return errors.New("手机号已验证")
+	}
+	code := randomCode()
+	expire := global.GVA_CONFIG.Account.VerifyCodeExpire
+	if _, err := userService.issueUserToken(user.ID, system.UserTokenVerifyPhone, user.Phone, code, time.Duration(expire)*time.Minute); err != nil {
+		return err
+	}
+	return sms.NewSms().Send(user.Phone, fmt.Sprintf("您的手机验证码为 %s,%d 分钟内有效。", code, expire))
+}
+
+// VerifyPhone 校验手机验证码 验证的手机号必须与用户当前手机号一致
+func (userService *UserService) VerifyPhone(userID uint, code string) error {
+	return userService.verifyContact(userID, system.UserTokenVerifyPhone, code)
+}
+
+func (userService *UserService) verifyContact(userID uint, purpose string, code string) error {
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var user system.SysUser
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		record, err := consumeUserToken(tx, purpose, hashToken(userTokenCodeKey(userID, code)))
+		if err != nil {
+			return err
+		}
+		column, current := "email_verified", user.Email
+		if purpose == system.UserTokenVerifyPhone {
+			column, current = "phone_verified", user.Phone
+		}
+		if record.UserID != userID || record.Target != current {
+			return ErrUserTokenInvalid
+		}
+		return tx.Model(&system.SysUser{}).Where("id = ?", userID).Update(column, true).Error
+	})
+}
+
+// issueUserToken 生成一次性令牌 同一用途下旧的未使用令牌会被作废 返回明文供发送
+func (userService *UserService) issueUserToken(userID uint, purpose string, target string, secret string, expire time.Duration) (string, error) {
+	key := secret
+	if purpose != system.UserTokenResetPassword {
+		// 验证码位数较短 与用户ID一起哈希以避免不同用户间碰撞
+		key = userTokenCodeKey(userID, secret)
+	}
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&system.SysUserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&system.SysUserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hashToken(key),
+			Target:    target,
+			ExpiresAt: time.Now().Add(expire),
+		}).Error
+	})
+	return secret, err
+}
+
+// consumeUserToken 查找并标记令牌为已使用 通过条件更新保证并发下只能使用一次
+func consumeUserToken(tx *gorm.DB, purpose string, tokenHash string) (record system.SysUserToken, err error) {
+	err = tx.Where("token_hash = ? AND purpose = ? AND used_at IS NULL", tokenHash, purpose).Order("id desc").First(&record).Error
+	if err != nil {
+		return record, ErrUserTokenInvalid
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return record, ErrUserTokenInvalid
+	}
+	result := tx.Model(&system.SysUserToken{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return record, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return record, ErrUserTokenInvalid
+	}
+	return record, nil
+}
+
+func userTokenCodeKey(userID uint, code string) string {
+	return fmt.Sprintf("%d:%s", userID, code)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func randomCode() string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
+	return fmt.Sprintf("%06d", n.Int64())
+}
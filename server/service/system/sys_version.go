@@ -2,14 +2,22 @@ package system
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type SysVersionService struct{}
 
+var SysVersionServiceApp = new(SysVersionService)
+
 // CreateSysVersion 创建版本管理记录
 // Author [yourname](https://github.com/yourname)
 func (sysVersionService *SysVersionService) CreateSysVersion(ctx context.Context, sysVersion *system.SysVersion) (err error) {
@@ -201,9 +209,10 @@ func (sysVersionService *SysVersionService) ImportApis(apis []system.SysApi) err
 	})
 }
 
-// ImportDictionaries 导入字典数据
+// ImportDictionaries 导入字典数据 提交后清除导入的字典类型的缓存 不存在的类型同样会被缓存
 func (sysVersionService *SysVersionService) ImportDictionaries(dictionaries []system.SysDictionary) error {
-	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+	var types []string
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		for _, dict := range dictionaries {
 			// 检查字典是否已存在
 			var existingDict system.SysDictionary
@@ -224,7 +233,56 @@ func (sysVersionService *SysVersionService) ImportDictionaries(dictionaries []sy
 			if err := tx.Create(&newDict).Error; err != nil {
 				return err
 			}
+			types = append(types, newDict.Type)
 		}
 		return nil
 	})
+	if err == nil {
+		dictCache.invalidate(types...)
+	}
+	return err
+}
+
+// ImportVersion 导入版本数据并创建导入记录 progress 不为空时在每一步完成后上报进度
+func (sysVersionService *SysVersionService) ImportVersion(ctx context.Context, importData systemReq.ImportVersionRequest, progress func(percent int, message string)) error {
+	if progress == nil {
+		progress = func(int, string) {}
+	}
+	// 导入菜单数据
+	if len(importData.ExportMenu) > 0 {
+		if err := sysVersionService.ImportMenus(ctx, importData.ExportMenu); err != nil {
+			return fmt.Errorf("导入菜单失败: %w", err)
+		}
+	}
+	progress(30, "菜单导入完成")
+
+	// 导入API数据
+	if len(importData.ExportApi) > 0 {
+		if err := sysVersionService.ImportApis(importData.ExportApi); err != nil {
+			return fmt.Errorf("导入API失败: %w", err)
+		}
+	}
+	progress(60, "API导入完成")
+
+	// 导入字典数据
+	if len(importData.ExportDictionary) > 0 {
+		if err := sysVersionService.ImportDictionaries(importData.ExportDictionary); err != nil {
+			return fmt.Errorf("导入字典失败: %w", err)
+		}
+	}
+	progress(90, "字典导入完成")
+
+	// 创建导入记录
+	jsonData, _ := json.Marshal(importData)
+	version := system.SysVersion{
+		VersionName: utils.Pointer(importData.VersionInfo.Name),
+		VersionCode: utils.Pointer(fmt.Sprintf("%s_imported_%s", importData.VersionInfo.Code, time.Now().Format("20060102150405"))),
+		Description: utils.Pointer(fmt.Sprintf("导入版本: %s", importData.VersionInfo.Description)),
+		VersionData: utils.Pointer(string(jsonData)),
+	}
+	if err := sysVersionService.CreateSysVersion(ctx, &version); err != nil {
+		// 这里不返回错误，因为数据已经导入成功
+		global.GVA_LOG.Error("保存导入记录失败!", zap.Error(err))
+	}
+	return nil
 }
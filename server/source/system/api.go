@@ -141,6 +141,7 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 
 		{ApiGroup: "系统字典详情", Method: "GET", Path: "/sysDictionaryDetail/getDictionaryTreeList", Description: "获取字典数列表"},
 		{ApiGroup: "系统字典详情", Method: "GET", Path: "/sysDictionaryDetail/getDictionaryTreeListByType", Description: "根据分类获取字典数列表"},
+		{ApiGroup: "系统字典详情", Method: "GET", Path: "/sysDictionaryDetail/getDictionaryTreeListByTypes", Description: "批量根据分类获取字典数列表"},
 		{ApiGroup: "系统字典详情", Method: "GET", Path: "/sysDictionaryDetail/getDictionaryDetailsByParent", Description: "根据父级ID获取字典详情"},
 		{ApiGroup: "系统字典详情", Method: "GET", Path: "/sysDictionaryDetail/getDictionaryPath", Description: "获取字典详情的完整路径"},
 
@@ -202,6 +203,88 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 		{ApiGroup: "版本控制", Method: "POST", Path: "/sysVersion/importVersion", Description: "同步版本"},
 		{ApiGroup: "版本控制", Method: "DELETE", Path: "/sysVersion/deleteSysVersion", Description: "删除版本"},
 		{ApiGroup: "版本控制", Method: "DELETE", Path: "/sysVersion/deleteSysVersionByIds", Description: "批量删除版本"},
+
+		{ApiGroup: "用户组", Method: "POST", Path: "/userGroup/createUserGroup", Description: "创建用户组"},
+		{ApiGroup: "用户组", Method: "DELETE", Path: "/userGroup/deleteUserGroup", Description: "删除用户组"},
+		{ApiGroup: "用户组", Method: "PUT", Path: "/userGroup/updateUserGroup", Description: "更新用户组"},
+		{ApiGroup: "用户组", Method: "GET", Path: "/userGroup/findUserGroup", Description: "获取用户组详情"},
+		{ApiGroup: "用户组", Method: "GET", Path: "/userGroup/getUserGroupList", Description: "获取用户组列表"},
+		{ApiGroup: "用户组", Method: "POST", Path: "/userGroup/setUserGroupUsers", Description: "设置用户组成员"},
+		{ApiGroup: "用户组", Method: "POST", Path: "/userGroup/setUserGroupAuthorities", Description: "设置用户组角色"},
+		{ApiGroup: "用户组", Method: "GET", Path: "/userGroup/getUserAuthoritySources", Description: "获取用户角色来源"},
+
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/importUsers", Description: "批量导入用户"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/exportUsers", Description: "导出用户"},
+		{ApiGroup: "系统用户", Method: "GET", Path: "/user/downloadImportReport", Description: "下载用户导入错误报告"},
+
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/sendEmailVerification", Description: "发送邮箱验证码"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/verifyEmail", Description: "验证邮箱"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/sendPhoneVerification", Description: "发送手机验证码"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/verifyPhone", Description: "验证手机号"},
+
+		{ApiGroup: "系统用户", Method: "GET", Path: "/user/getRegistrationList", Description: "分页获取注册申请"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/approveRegistration", Description: "通过注册申请"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/rejectRegistration", Description: "拒绝注册申请"},
+
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/getDeletedUserList", Description: "分页获取已删除的用户"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/restoreUser", Description: "恢复已删除的用户"},
+		{ApiGroup: "系统用户", Method: "DELETE", Path: "/user/purgeUser", Description: "彻底清除已删除的用户"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/anonymizeUser", Description: "匿名化用户个人数据"},
+		{ApiGroup: "系统用户", Method: "GET", Path: "/user/exportUserData", Description: "导出用户个人数据"},
+
+		{ApiGroup: "操作记录", Method: "GET", Path: "/sysOperationRecord/verifyOperationRecordChain", Description: "校验操作记录哈希链"},
+		{ApiGroup: "操作记录", Method: "POST", Path: "/sysOperationRecord/createOperationRecordCheckpoint", Description: "生成操作记录检查点"},
+		{ApiGroup: "操作记录", Method: "POST", Path: "/sysOperationRecord/sealOperationRecordArchive", Description: "封存归档操作记录"},
+
+		{ApiGroup: "操作记录", Method: "GET", Path: "/sysOperationRecord/getOperationRecordTimeline", Description: "操作记录请求趋势"},
+		{ApiGroup: "操作记录", Method: "GET", Path: "/sysOperationRecord/getOperationRecordEndpointStats", Description: "操作记录接口统计"},
+		{ApiGroup: "操作记录", Method: "GET", Path: "/sysOperationRecord/getOperationRecordTopUsers", Description: "操作记录活跃用户"},
+		{ApiGroup: "操作记录", Method: "GET", Path: "/sysOperationRecord/getOperationRecordStatusStats", Description: "操作记录状态码分布"},
+
+		{ApiGroup: "变更历史", Method: "GET", Path: "/changeHistory/getChangeHistory", Description: "获取记录变更历史"},
+		{ApiGroup: "变更历史", Method: "POST", Path: "/changeHistory/revertChange", Description: "恢复记录到历史版本"},
+
+		{ApiGroup: "定时任务", Method: "POST", Path: "/sysJob/createSysJob", Description: "新建定时任务"},
+		{ApiGroup: "定时任务", Method: "PUT", Path: "/sysJob/updateSysJob", Description: "更新定时任务"},
+		{ApiGroup: "定时任务", Method: "DELETE", Path: "/sysJob/deleteSysJob", Description: "删除定时任务"},
+		{ApiGroup: "定时任务", Method: "PUT", Path: "/sysJob/setSysJobEnabled", Description: "启用或停用定时任务"},
+		{ApiGroup: "定时任务", Method: "POST", Path: "/sysJob/triggerSysJob", Description: "立即执行一次定时任务"},
+		{ApiGroup: "定时任务", Method: "GET", Path: "/sysJob/findSysJob", Description: "根据ID获取定时任务"},
+		{ApiGroup: "定时任务", Method: "GET", Path: "/sysJob/getSysJobList", Description: "获取定时任务列表"},
+		{ApiGroup: "定时任务", Method: "GET", Path: "/sysJob/getSysJobRunList", Description: "获取定时任务执行记录"},
+		{ApiGroup: "定时任务", Method: "GET", Path: "/sysJob/getSysJobHandlers", Description: "获取已注册的执行方法"},
+
+		{ApiGroup: "后台任务", Method: "GET", Path: "/sysAsyncTask/getSysAsyncTaskList", Description: "获取当前用户的后台任务列表"},
+		{ApiGroup: "后台任务", Method: "GET", Path: "/sysAsyncTask/findSysAsyncTask", Description: "获取后台任务状态与进度"},
+		{ApiGroup: "后台任务", Method: "GET", Path: "/sysAsyncTask/streamSysAsyncTask", Description: "以SSE推送后台任务进度"},
+		{ApiGroup: "后台任务", Method: "POST", Path: "/sysAsyncTask/cancelSysAsyncTask", Description: "取消后台任务"},
+		{ApiGroup: "后台任务", Method: "GET", Path: "/sysAsyncTask/downloadSysAsyncTaskArtifact", Description: "下载后台任务生成的文件"},
+
+		{ApiGroup: "导出模板", Method: "POST", Path: "/sysExportTemplate/exportExcelAsync", Description: "创建后台导出任务"},
+		{ApiGroup: "导出模板", Method: "POST", Path: "/sysExportTemplate/importExcelAsync", Description: "创建后台导入任务"},
+
+		{ApiGroup: "版本控制", Method: "POST", Path: "/sysVersion/importVersionAsync", Description: "创建后台导入版本任务"},
+
+		{ApiGroup: "代码生成器", Method: "POST", Path: "/autoCode/createTempAsync", Description: "创建后台代码生成任务"},
+		{ApiGroup: "代码生成器", Method: "POST", Path: "/autoCode/installPluginAsync", Description: "创建后台安装插件任务"},
+
+		{ApiGroup: "导出模板", Method: "GET", Path: "/sysExportTemplate/downloadImportErrors", Description: "下载导入的错误工作簿"},
+
+		{ApiGroup: "导出订阅", Method: "POST", Path: "/sysExportSubscription/createSysExportSubscription", Description: "新建导出订阅"},
+		{ApiGroup: "导出订阅", Method: "PUT", Path: "/sysExportSubscription/updateSysExportSubscription", Description: "更新导出订阅"},
+		{ApiGroup: "导出订阅", Method: "DELETE", Path: "/sysExportSubscription/deleteSysExportSubscription", Description: "删除导出订阅"},
+		{ApiGroup: "导出订阅", Method: "PUT", Path: "/sysExportSubscription/setSysExportSubscriptionEnabled", Description: "启用或停用导出订阅"},
+		{ApiGroup: "导出订阅", Method: "POST", Path: "/sysExportSubscription/triggerSysExportSubscription", Description: "立即导出发送一次"},
+		{ApiGroup: "导出订阅", Method: "GET", Path: "/sysExportSubscription/findSysExportSubscription", Description: "根据ID获取导出订阅"},
+		{ApiGroup: "导出订阅", Method: "GET", Path: "/sysExportSubscription/getSysExportSubscriptionList", Description: "获取导出订阅列表"},
+		{ApiGroup: "导出订阅", Method: "GET", Path: "/sysExportSubscription/getSysExportSubscriptionRunList", Description: "获取导出订阅执行记录"},
+
+		{ApiGroup: "组合导出", Method: "POST", Path: "/sysExportWorkbook/createSysExportWorkbook", Description: "新建组合导出"},
+		{ApiGroup: "组合导出", Method: "PUT", Path: "/sysExportWorkbook/updateSysExportWorkbook", Description: "更新组合导出"},
+		{ApiGroup: "组合导出", Method: "DELETE", Path: "/sysExportWorkbook/deleteSysExportWorkbook", Description: "删除组合导出"},
+		{ApiGroup: "组合导出", Method: "GET", Path: "/sysExportWorkbook/findSysExportWorkbook", Description: "根据ID获取组合导出"},
+		{ApiGroup: "组合导出", Method: "GET", Path: "/sysExportWorkbook/getSysExportWorkbookList", Description: "获取组合导出列表"},
+		{ApiGroup: "组合导出", Method: "GET", Path: "/sysExportWorkbook/exportSysExportWorkbook", Description: "导出工作簿"},
 	}
 	if err := db.Create(&entities).Error; err != nil {
 		return ctx, errors.Wrap(err, sysModel.SysApi{}.TableName()+"表数据初始化失败!")
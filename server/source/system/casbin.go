@@ -141,6 +141,7 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "888", V1: "/sysDictionaryDetail/deleteSysDictionaryDetail", V2: "DELETE"},
 		{Ptype: "p", V0: "888", V1: "/sysDictionaryDetail/getDictionaryTreeList", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/sysDictionaryDetail/getDictionaryTreeListByType", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysDictionaryDetail/getDictionaryTreeListByTypes", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/sysDictionaryDetail/getDictionaryDetailsByParent", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/sysDictionaryDetail/getDictionaryPath", V2: "GET"},
 
@@ -204,6 +205,88 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "888", V1: "/sysVersion/deleteSysVersion", V2: "DELETE"},
 		{Ptype: "p", V0: "888", V1: "/sysVersion/deleteSysVersionByIds", V2: "DELETE"},
 
+		{Ptype: "p", V0: "888", V1: "/userGroup/createUserGroup", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/userGroup/deleteUserGroup", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/userGroup/updateUserGroup", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/userGroup/findUserGroup", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/userGroup/getUserGroupList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/userGroup/setUserGroupUsers", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/userGroup/setUserGroupAuthorities", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/userGroup/getUserAuthoritySources", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/user/importUsers", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/exportUsers", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/downloadImportReport", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/user/sendEmailVerification", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/verifyEmail", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/sendPhoneVerification", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/verifyPhone", V2: "POST"},
+
+		{Ptype: "p", V0: "888", V1: "/user/getRegistrationList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/user/approveRegistration", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/rejectRegistration", V2: "POST"},
+
+		{Ptype: "p", V0: "888", V1: "/user/getDeletedUserList", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/restoreUser", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/purgeUser", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/user/anonymizeUser", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/exportUserData", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/sysOperationRecord/verifyOperationRecordChain", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysOperationRecord/createOperationRecordCheckpoint", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysOperationRecord/sealOperationRecordArchive", V2: "POST"},
+
+		{Ptype: "p", V0: "888", V1: "/sysOperationRecord/getOperationRecordTimeline", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysOperationRecord/getOperationRecordEndpointStats", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysOperationRecord/getOperationRecordTopUsers", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysOperationRecord/getOperationRecordStatusStats", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/changeHistory/getChangeHistory", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/changeHistory/revertChange", V2: "POST"},
+
+		{Ptype: "p", V0: "888", V1: "/sysJob/createSysJob", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/updateSysJob", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/deleteSysJob", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/setSysJobEnabled", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/triggerSysJob", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/findSysJob", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/getSysJobList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/getSysJobRunList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysJob/getSysJobHandlers", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/sysAsyncTask/getSysAsyncTaskList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysAsyncTask/findSysAsyncTask", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysAsyncTask/streamSysAsyncTask", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysAsyncTask/cancelSysAsyncTask", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysAsyncTask/downloadSysAsyncTaskArtifact", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/sysExportTemplate/exportExcelAsync", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysExportTemplate/importExcelAsync", V2: "POST"},
+
+		{Ptype: "p", V0: "888", V1: "/sysVersion/importVersionAsync", V2: "POST"},
+
+		{Ptype: "p", V0: "888", V1: "/autoCode/createTempAsync", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/autoCode/installPluginAsync", V2: "POST"},
+
+		{Ptype: "p", V0: "888", V1: "/sysExportTemplate/downloadImportErrors", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/createSysExportSubscription", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/updateSysExportSubscription", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/deleteSysExportSubscription", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/setSysExportSubscriptionEnabled", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/triggerSysExportSubscription", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/findSysExportSubscription", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/getSysExportSubscriptionList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysExportSubscription/getSysExportSubscriptionRunList", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/sysExportWorkbook/createSysExportWorkbook", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/sysExportWorkbook/updateSysExportWorkbook", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/sysExportWorkbook/deleteSysExportWorkbook", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/sysExportWorkbook/findSysExportWorkbook", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysExportWorkbook/getSysExportWorkbookList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/sysExportWorkbook/exportSysExportWorkbook", V2: "GET"},
+
 		{Ptype: "p", V0: "8881", V1: "/user/admin_register", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/api/createApi", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/api/getApiList", V2: "POST"},
@@ -1,51 +1,385 @@
 package task
 
 import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/flipped-aurora/gin-vue-admin/server/model/common"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/upload"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// RetentionParamKey 系统参数中覆盖清理策略的键 值为与配置文件格式相同的JSON数组
+const RetentionParamKey = "retention.policies"
+
+// defaultRetentionPolicies 未配置任何策略时使用
+var defaultRetentionPolicies = []config.RetentionPolicy{
+	{TableName: "sys_operation_records", CompareField: "created_at", Interval: "2160h"},
+	{TableName: "jwt_blacklists", CompareField: "created_at", Interval: "168h"},
+}
+
+// retentionTables 系统参数中的策略只能清理这些日志与历史表 以及配置文件中已配置策略的表
+var retentionTables = []string{
+	"sys_operation_records",
+	"sys_operation_record_hourly",
+	"sys_operation_record_user_hourly",
+	"jwt_blacklists",
+	"sys_job_runs",
+	"sys_async_tasks",
+	"sys_change_histories",
+}
+
+var retentionIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 //@author: [songzhibin97](https://github.com/songzhibin97)
 //@function: ClearTable
-//@description: 清理数据库表数据
-//@param: db(数据库对象) *gorm.DB, tableName(表名) string, compareField(比较字段) string, interval(间隔) string
-//@return: error
+//@description: 按清理策略分批删除过期数据 开启归档的策略在删除前先写入压缩文件 db 的 context 取消时停止
+//@param: db(数据库对象) *gorm.DB
+//@return: string, error
 
-func ClearTable(db *gorm.DB) error {
-	var ClearTableDetail []common.ClearDB
+func ClearTable(db *gorm.DB) (string, error) {
+	if db == nil {
+		return "", errors.New("db Cannot be empty")
+	}
+	policies, err := RetentionPolicies(db)
+	if err != nil {
+		return "", err
+	}
+	var output []string
+	var errs []error
+	for _, policy := range policies {
+		if policy.Disabled {
+			continue
+		}
+		// 只追加模式下操作记录只能通过封存归档清理
+		if policy.TableName == "sys_operation_records" && global.GVA_CONFIG.OperationRecord.AppendOnly {
+			continue
+		}
+		result, err := applyRetention(db, policy)
+		if result != "" {
+			output = append(output, policy.TableName+": "+result)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", policy.TableName, err))
+		}
+	}
+	return strings.Join(output, "\n"), errors.Join(errs...)
+}
 
-	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
-		TableName:    "sys_operation_records",
-		CompareField: "created_at",
-		Interval:     "2160h",
-	})
+// RetentionPolicies 获取清理策略 系统参数中的策略覆盖配置文件中同表名的策略
+func RetentionPolicies(db *gorm.DB) ([]config.RetentionPolicy, error) {
+	policies := slices.Clone(global.GVA_CONFIG.Retention.Policies)
+	if len(policies) == 0 {
+		policies = slices.Clone(defaultRetentionPolicies)
+	}
+	var param system.SysParams
+	if err := db.Where(system.SysParams{Key: RetentionParamKey}).Limit(1).Find(&param).Error; err != nil {
+		return nil, err
+	}
+	if param.Value == "" {
+		return policies, nil
+	}
+	overrides, err := ParseRetentionOverrides(param.Value)
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		i := slices.IndexFunc(policies, func(p config.RetentionPolicy) bool { return p.TableName == override.TableName })
+		if i >= 0 {
+			policies[i] = override
+		} else {
+			policies = append(policies, override)
+		}
+	}
+	return policies, nil
+}
 
-	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
-		TableName:    "jwt_blacklists",
-		CompareField: "created_at",
-		Interval:     "168h",
-	})
+// ParseRetentionOverrides 解析系统参数 retention.policies 的值 只允许清理 retentionTables 与配置文件中的表
+func ParseRetentionOverrides(value string) ([]config.RetentionPolicy, error) {
+	var overrides []config.RetentionPolicy
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return nil, fmt.Errorf("系统参数 %s 格式错误: %w", RetentionParamKey, err)
+	}
+	for _, override := range overrides {
+		configured := slices.ContainsFunc(global.GVA_CONFIG.Retention.Policies, func(p config.RetentionPolicy) bool {
+			return p.TableName == override.TableName
+		})
+		if !configured && !slices.Contains(retentionTables, override.TableName) {
+			return nil, fmt.Errorf("系统参数 %s 中的表 %q 不允许清理", RetentionParamKey, override.TableName)
+		}
+	}
+	return overrides, nil
+}
 
-	if db == nil {
-		return errors.New("db Cannot be empty")
+// applyRetention 执行单表的清理策略 开启归档时只删除已写入归档的记录
+func applyRetention(db *gorm.DB, policy config.RetentionPolicy) (string, error) {
+	if policy.PrimaryKey == "" {
+		policy.PrimaryKey = "id"
+	}
+	for _, name := range []string{policy.TableName, policy.CompareField, policy.PrimaryKey} {
+		if !retentionIdentifier.MatchString(name) {
+			return "", fmt.Errorf("无效的表名或字段名 %q", name)
+		}
 	}
+	duration, err := time.ParseDuration(policy.Interval)
+	if err != nil {
+		return "", err
+	}
+	if duration <= 0 {
+		return "", errors.New("parse duration <= 0")
+	}
+	cutoff := time.Now().Add(-duration)
 
-	for _, detail := range ClearTableDetail {
-		duration, err := time.ParseDuration(detail.Interval)
+	var result string
+	var last interface{}
+	if policy.Archive {
+		location, count, lastKey, err := archiveRetention(db, policy, cutoff)
 		if err != nil {
-			return err
+			return "", err
+		}
+		if count == 0 {
+			return "", nil
+		}
+		result = fmt.Sprintf("归档 %d 条 %s ", count, location)
+		last = lastKey
+	}
+	deleted, err := deleteRetention(db, policy, cutoff, last)
+	if deleted > 0 || result != "" {
+		result += fmt.Sprintf("删除 %d 条", deleted)
+	}
+	return result, err
+}
+
+func retentionBatch() (batch int, pause time.Duration) {
+	conf := global.GVA_CONFIG.Retention
+	batch = conf.BatchSize
+	if batch <= 0 {
+		batch = 1000
+	}
+	return batch, time.Duration(conf.Pause) * time.Millisecond
+}
+
+// retentionPause 两批之间等待 context 取消时返回错误
+func retentionPause(db *gorm.DB, pause time.Duration) error {
+	ctx := db.Statement.Context
+	if pause <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(pause):
+		return nil
+	}
+}
+
+// deleteRetention 按主键分批删除过期记录 last 不为空时只删除主键不大于 last 的记录
+func deleteRetention(db *gorm.DB, policy config.RetentionPolicy, cutoff time.Time, last interface{}) (deleted int64, err error) {
+	batch, pause := retentionBatch()
+	pk := clause.Column{Name: policy.PrimaryKey}
+	for {
+		query := db.Table(policy.TableName).Where(clause.Lt{Column: clause.Column{Name: policy.CompareField}, Value: cutoff})
+		if last != nil {
+			query = query.Where(clause.Lte{Column: pk, Value: last})
+		}
+		var ids []interface{}
+		if err = query.Order(clause.OrderByColumn{Column: pk}).Limit(batch).Pluck(policy.PrimaryKey, &ids).Error; err != nil {
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+		result := db.Exec("DELETE FROM ? WHERE ? IN ?", clause.Table{Name: policy.TableName}, pk, ids)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+		if len(ids) < batch {
+			return
+		}
+		if err = retentionPause(db, pause); err != nil {
+			return
+		}
+	}
+}
+
+// archiveRetention 将过期记录按主键顺序写入 gzip 压缩的 jsonl 或 csv 文件 返回文件位置、条数与最后一条的主键
+func archiveRetention(db *gorm.DB, policy config.RetentionPolicy, cutoff time.Time) (location string, count int, last interface{}, err error) {
+	conf := global.GVA_CONFIG.Retention.Archive
+	format := conf.Format
+	if format == "" {
+		format = "jsonl"
+	}
+	if format != "jsonl" && format != "csv" {
+		return "", 0, nil, fmt.Errorf("不支持的归档格式 %s", format)
+	}
+	dir := conf.Dir
+	if dir == "" {
+		dir = "./log/retention"
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+	location = filepath.Join(dir, fmt.Sprintf("%s-%s.%s.gz", policy.TableName, time.Now().Format("20060102150405"), format))
+	file, err := os.OpenFile(location, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	gz := gzip.NewWriter(file)
+	writer := newArchiveWriter(format, gz)
+	count, last, err = writeRetentionRows(db, policy, cutoff, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || count == 0 {
+		os.Remove(location)
+		return "", 0, nil, err
+	}
+	if conf.Storage == "oss" {
+		url, _, err := upload.UploadLocalFile(upload.NewOss(), location)
+		if err != nil {
+			// 上传失败时保留本地文件 不删除记录
+			return "", 0, nil, fmt.Errorf("上传归档文件 %s 失败: %w", location, err)
 		}
-		if duration < 0 {
-			return errors.New("parse duration < 0")
+		os.Remove(location)
+		location = url
+	}
+	return location, count, last, nil
+}
+
+func writeRetentionRows(db *gorm.DB, policy config.RetentionPolicy, cutoff time.Time, writer archiveWriter) (count int, last interface{}, err error) {
+	batch, _ := retentionBatch()
+	pk := clause.Column{Name: policy.PrimaryKey}
+	for {
+		query := db.Table(policy.TableName).Where(clause.Lt{Column: clause.Column{Name: policy.CompareField}, Value: cutoff})
+		if last != nil {
+			query = query.Where(clause.Gt{Column: pk, Value: last})
 		}
-		err = db.Debug().Exec(fmt.Sprintf("DELETE FROM %s WHERE %s < ?", detail.TableName, detail.CompareField), time.Now().Add(-duration)).Error
+		n, lastKey, err := writeRetentionBatch(query.Order(clause.OrderByColumn{Column: pk}).Limit(batch), policy.PrimaryKey, writer)
 		if err != nil {
-			return err
+			return count, last, err
+		}
+		count += n
+		if n == 0 {
+			return count, last, nil
+		}
+		last = lastKey
+		if n < batch {
+			return count, last, nil
+		}
+	}
+}
+
+func writeRetentionBatch(query *gorm.DB, primaryKey string, writer archiveWriter) (count int, last interface{}, err error) {
+	rows, err := query.Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+	pkIndex := slices.Index(columns, primaryKey)
+	if pkIndex < 0 {
+		return 0, nil, fmt.Errorf("主键字段 %s 不存在", primaryKey)
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
 		}
+		if err = writer.Write(columns, values); err != nil {
+			return
+		}
+		last = values[pkIndex]
+		count++
+	}
+	return count, last, rows.Err()
+}
+
+// archiveWriter 按格式写入归档记录
+type archiveWriter interface {
+	Write(columns []string, values []interface{}) error
+	Flush() error
+}
+
+func newArchiveWriter(format string, gz *gzip.Writer) archiveWriter {
+	if format == "csv" {
+		return &csvArchiveWriter{writer: csv.NewWriter(gz)}
+	}
+	return jsonlArchiveWriter{encoder: json.NewEncoder(gz)}
+}
+
+type jsonlArchiveWriter struct {
+	encoder *json.Encoder
+}
+
+func (w jsonlArchiveWriter) Write(columns []string, values []interface{}) error {
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		row[column] = values[i]
 	}
+	return w.encoder.Encode(row)
+}
+
+func (w jsonlArchiveWriter) Flush() error {
 	return nil
 }
+
+type csvArchiveWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func (w *csvArchiveWriter) Write(columns []string, values []interface{}) error {
+	if !w.header {
+		if err := w.writer.Write(columns); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case time.Time:
+			record[i] = v.Format(time.RFC3339Nano)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvArchiveWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
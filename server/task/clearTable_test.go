@@ -0,0 +1,109 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParseRetentionOverrides(t *testing.T) {
+	conf := global.GVA_CONFIG.Retention
+	defer func() { global.GVA_CONFIG.Retention = conf }()
+	global.GVA_CONFIG.Retention.Policies = []config.RetentionPolicy{{TableName: "app_logs", CompareField: "created_at", Interval: "720h"}}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "whitelisted", value: `[{"table-name":"sys_job_runs","compare-field":"created_at","interval":"720h"}]`},
+		{name: "configured", value: `[{"table-name":"app_logs","compare-field":"created_at","interval":"24h","archive":true}]`},
+		{name: "disable", value: `[{"table-name":"jwt_blacklists","disabled":true}]`},
+		{name: "business table", value: `[{"table-name":"sys_users","compare-field":"created_at","interval":"1h"}]`, wantErr: true},
+		{name: "injection", value: `[{"table-name":"sys_job_runs; DROP TABLE sys_users","compare-field":"created_at","interval":"1h"}]`, wantErr: true},
+		{name: "invalid json", value: `{"table-name":"sys_job_runs"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRetentionOverrides(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func newRetentionDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/retention.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&system.SysParams{}); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	for _, table := range []string{"sys_operation_records", "jwt_blacklists"} {
+		if err = db.Exec("CREATE TABLE " + table + " (id integer primary key, created_at datetime)").Error; err != nil {
+			t.Fatal(err)
+		}
+		if err = db.Exec("INSERT INTO "+table+" (created_at) VALUES (?), (?)", old, time.Now()).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db.WithContext(context.Background())
+}
+
+func TestClearTableSkipsAppendOnlyOperationRecords(t *testing.T) {
+	conf, appendOnly := global.GVA_CONFIG.Retention, global.GVA_CONFIG.OperationRecord.AppendOnly
+	defer func() {
+		global.GVA_CONFIG.Retention, global.GVA_CONFIG.OperationRecord.AppendOnly = conf, appendOnly
+	}()
+	global.GVA_CONFIG.Retention = config.Retention{}
+
+	count := func(db *gorm.DB, table string) (n int64) {
+		assert.NoError(t, db.Table(table).Count(&n).Error)
+		return n
+	}
+
+	// 只追加模式下操作记录只能封存归档 不按清理策略删除
+	db := newRetentionDB(t)
+	global.GVA_CONFIG.OperationRecord.AppendOnly = true
+	_, err := ClearTable(db)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count(db, "sys_operation_records"))
+	assert.EqualValues(t, 1, count(db, "jwt_blacklists"))
+
+	db = newRetentionDB(t)
+	global.GVA_CONFIG.OperationRecord.AppendOnly = false
+	_, err = ClearTable(db)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count(db, "sys_operation_records"))
+	assert.EqualValues(t, 1, count(db, "jwt_blacklists"))
+
+	// 系统参数中不允许清理的表 整体报错 不执行任何策略
+	db = newRetentionDB(t)
+	assert.NoError(t, db.Create(&system.SysParams{Name: "清理策略", Key: RetentionParamKey, Value: `[{"table-name":"sys_users","compare-field":"created_at","interval":"1h"}]`}).Error)
+	_, err = ClearTable(db)
+	assert.Error(t, err)
+	assert.EqualValues(t, 2, count(db, "jwt_blacklists"))
+
+	// 配置文件中的表名同样按标识符校验
+	db = newRetentionDB(t)
+	global.GVA_CONFIG.Retention.Policies = []config.RetentionPolicy{
+		{TableName: "jwt_blacklists; DROP TABLE sys_params", CompareField: "created_at", Interval: "1h"},
+	}
+	_, err = ClearTable(db)
+	assert.Error(t, err)
+	assert.True(t, db.Migrator().HasTable(&system.SysParams{}))
+}
@@ -0,0 +1,53 @@
+package task
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Handler 定时任务的执行方法 params 为任务配置的参数 返回的 output 保存到执行记录中
+type Handler func(ctx context.Context, params string) (output string, err error)
+
+// Definition 定时任务的执行方法定义
+type Definition struct {
+	Name        string  `json:"name"`        // 唯一标识 任务通过该名称引用执行方法
+	Description string  `json:"description"` // 说明
+	Spec        string  `json:"spec"`        // 默认执行周期 不为空时启动时自动创建同名任务
+	Params      string  `json:"params"`      // 默认参数
+	Handler     Handler `json:"-"`
+	// OnFailure 定时执行重试后仍失败时调用 为空时按 job.notify-email 通知
+	OnFailure func(params string, err error) `json:"-"`
+}
+
+var (
+	definitions   = make(map[string]Definition)
+	definitionsMu sync.RWMutex
+)
+
+// Register 注册执行方法 插件可在初始化时注册 同名时覆盖
+func Register(def Definition) {
+	definitionsMu.Lock()
+	defer definitionsMu.Unlock()
+	definitions[def.Name] = def
+}
+
+// Get 获取执行方法
+func Get(name string) (Definition, bool) {
+	definitionsMu.RLock()
+	defer definitionsMu.RUnlock()
+	def, ok := definitions[name]
+	return def, ok
+}
+
+// Definitions 获取所有已注册的执行方法 按名称排序
+func Definitions() []Definition {
+	definitionsMu.RLock()
+	defer definitionsMu.RUnlock()
+	list := make([]Definition, 0, len(definitions))
+	for _, def := range definitions {
+		list = append(list, def)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
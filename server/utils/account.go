@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"unicode"
+)
+
+var (
+	phoneRegexp = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	emailRegexp = regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(\.[A-Za-z0-9\-]+)+$`)
+)
+
+// IsPhone 校验是否为大陆手机号
+func IsPhone(phone string) bool {
+	return phoneRegexp.MatchString(phone)
+}
+
+// IsEmail 校验邮箱格式
+func IsEmail(email string) bool {
+	return emailRegexp.MatchString(email)
+}
+
+// VerifyPassword 密码策略 长度6-32位 同时包含字母和数字 不允许空白字符
+func VerifyPassword(password string) error {
+	if len(password) < 6 || len(password) > 32 {
+		return errors.New("密码长度需为6-32位")
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsSpace(r):
+			return errors.New("密码不能包含空白字符")
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsLetter(r):
+			hasLetter = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("密码需同时包含字母和数字")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestVerifyPassword(t *testing.T) {
+	cases := map[string]bool{
+		"abc123":       true,
+		"123456":       false,
+		"abcdef":       false,
+		"ab1":          false,
+		"abc 123":      false,
+		"Passw0rd!@#$": true,
+	}
+	for password, ok := range cases {
+		if err := VerifyPassword(password); (err == nil) != ok {
+			t.Errorf("VerifyPassword(%q) = %v, want ok=%v", password, err, ok)
+		}
+	}
+}
+
+func TestIsPhoneAndEmail(t *testing.T) {
+	if !IsPhone("13800138000") || IsPhone("1380013800") || IsPhone("23800138000") {
+		t.Error("手机号校验失败")
+	}
+	if !IsEmail("gva@example.com") || IsEmail("gva@example") || IsEmail("gva.example.com") {
+		t.Error("邮箱校验失败")
+	}
+}
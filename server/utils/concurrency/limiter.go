@@ -0,0 +1,184 @@
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBusy      = errors.New("concurrency: limit reached")
+	ErrUserLimit = errors.New("concurrency: user limit reached")
+)
+
+// Options 并发限制 Limit 为同时执行的请求数 为 0 时不限制
+// PerUser 为同一用户同时执行及排队的请求数 为 0 时不限制 与 Limit 相互独立
+// Queue 为排队的最大数量 MaxWait 为排队的最长时间
+type Options struct {
+	Limit   int
+	PerUser int
+	Queue   int
+	MaxWait time.Duration
+}
+
+// Stats 当前的使用情况
+type Stats struct {
+	Name     string `json:"name"`
+	Limit    int    `json:"limit"`
+	PerUser  int    `json:"perUser"`
+	Queue    int    `json:"queue"`
+	InFlight int    `json:"inFlight"`
+	Waiting  int    `json:"waiting"`
+	Rejected uint64 `json:"rejected"`
+}
+
+type waiter struct {
+	ready chan struct{}
+}
+
+// Limiter 限制同时执行的请求数 超出时按先后顺序排队
+// 每次获取时传入配置 配置重新加载后立即生效
+type Limiter struct {
+	name     string
+	mu       sync.Mutex
+	opts     Options
+	inFlight int
+	users    map[string]int
+	waiters  list.List
+	rejected uint64
+}
+
+func NewLimiter(name string) *Limiter {
+	return &Limiter{name: name, users: make(map[string]int)}
+}
+
+// Acquire 获取执行名额 成功时返回的 release 必须在请求结束后调用
+func (l *Limiter) Acquire(ctx context.Context, user string, opts Options) (release func(), err error) {
+	if opts.Limit <= 0 && opts.PerUser <= 0 {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	l.opts = opts
+	if opts.PerUser > 0 && l.users[user] >= opts.PerUser {
+		l.rejected++
+		l.mu.Unlock()
+		return nil, ErrUserLimit
+	}
+	release = l.releaser(user)
+	if opts.Limit <= 0 || (l.inFlight < opts.Limit && l.waiters.Len() == 0) {
+		l.inFlight++
+		l.users[user]++
+		l.mu.Unlock()
+		return release, nil
+	}
+	if l.waiters.Len() >= opts.Queue || opts.MaxWait <= 0 {
+		l.rejected++
+		l.mu.Unlock()
+		return nil, ErrBusy
+	}
+	w := &waiter{ready: make(chan struct{})}
+	elem := l.waiters.PushBack(w)
+	l.users[user]++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(opts.MaxWait)
+	defer timer.Stop()
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-timer.C:
+		err = ErrBusy
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	l.mu.Lock()
+	select {
+	case <-w.ready:
+		// 超时的同时已获得名额 归还名额
+		l.mu.Unlock()
+		release()
+		return nil, err
+	default:
+	}
+	l.waiters.Remove(elem)
+	l.decUser(user)
+	l.rejected++
+	l.mu.Unlock()
+	return nil, err
+}
+
+func (l *Limiter) releaser(user string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.inFlight--
+			l.decUser(user)
+			// 按当前配置唤醒排队的请求 不再限制时全部唤醒
+			for (l.opts.Limit <= 0 || l.inFlight < l.opts.Limit) && l.waiters.Len() > 0 {
+				w := l.waiters.Remove(l.waiters.Front()).(*waiter)
+				l.inFlight++
+				close(w.ready)
+			}
+		})
+	}
+}
+
+func (l *Limiter) decUser(user string) {
+	if l.users[user] <= 1 {
+		delete(l.users, user)
+		return
+	}
+	l.users[user]--
+}
+
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Stats{
+		Name:     l.name,
+		Limit:    l.opts.Limit,
+		PerUser:  l.opts.PerUser,
+		Queue:    l.opts.Queue,
+		InFlight: l.inFlight,
+		Waiting:  l.waiters.Len(),
+		Rejected: l.rejected,
+	}
+}
+
+var (
+	limiters   = make(map[string]*Limiter)
+	limitersMu sync.Mutex
+)
+
+// Get 获取指定名称的限制器 不存在时创建
+func Get(name string) *Limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[name]
+	if !ok {
+		l = NewLimiter(name)
+		limiters[name] = l
+	}
+	return l
+}
+
+// AllStats 所有已使用过的限制器的使用情况 按名称排序
+func AllStats() []Stats {
+	limitersMu.Lock()
+	all := make([]*Limiter, 0, len(limiters))
+	for _, l := range limiters {
+		all = append(all, l)
+	}
+	limitersMu.Unlock()
+	stats := make([]Stats, 0, len(all))
+	for _, l := range all {
+		stats = append(stats, l.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
@@ -0,0 +1,89 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterQueue(t *testing.T) {
+	l := NewLimiter("test")
+	opts := Options{Limit: 1, Queue: 1, MaxWait: time.Second}
+	release, err := l.Acquire(context.Background(), "a", opts)
+	assert.NoError(t, err)
+
+	acquired := make(chan func())
+	go func() {
+		r, err := l.Acquire(context.Background(), "b", opts)
+		assert.NoError(t, err)
+		acquired <- r
+	}()
+	assert.Eventually(t, func() bool { return l.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+
+	// 队列已满
+	_, err = l.Acquire(context.Background(), "c", opts)
+	assert.ErrorIs(t, err, ErrBusy)
+
+	release()
+	release() // 重复调用无效
+	next := <-acquired
+	stats := l.Stats()
+	assert.Equal(t, 1, stats.InFlight)
+	assert.Equal(t, 0, stats.Waiting)
+	assert.Equal(t, uint64(1), stats.Rejected)
+	next()
+	assert.Equal(t, 0, l.Stats().InFlight)
+}
+
+func TestLimiterWaitTimeout(t *testing.T) {
+	l := NewLimiter("test")
+	opts := Options{Limit: 1, Queue: 5, MaxWait: 20 * time.Millisecond}
+	release, _ := l.Acquire(context.Background(), "a", opts)
+	defer release()
+	_, err := l.Acquire(context.Background(), "b", opts)
+	assert.ErrorIs(t, err, ErrBusy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.Acquire(ctx, "b", opts)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, l.Stats().Waiting)
+}
+
+func TestLimiterPerUser(t *testing.T) {
+	l := NewLimiter("test")
+	opts := Options{Limit: 5, PerUser: 1}
+	release, err := l.Acquire(context.Background(), "a", opts)
+	assert.NoError(t, err)
+	_, err = l.Acquire(context.Background(), "a", opts)
+	assert.ErrorIs(t, err, ErrUserLimit)
+	_, err = l.Acquire(context.Background(), "b", opts)
+	assert.NoError(t, err)
+	release()
+	_, err = l.Acquire(context.Background(), "a", opts)
+	assert.NoError(t, err)
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	release, err := NewLimiter("test").Acquire(context.Background(), "a", Options{})
+	assert.NoError(t, err)
+	release()
+}
+
+func TestLimiterPerUserWithoutLimit(t *testing.T) {
+	l := NewLimiter("test")
+	opts := Options{PerUser: 1}
+	release, err := l.Acquire(context.Background(), "a", opts)
+	assert.NoError(t, err)
+	_, err = l.Acquire(context.Background(), "a", opts)
+	assert.ErrorIs(t, err, ErrUserLimit)
+	other, err := l.Acquire(context.Background(), "b", opts)
+	assert.NoError(t, err)
+	release()
+	other()
+	assert.Equal(t, 0, l.Stats().InFlight)
+	_, err = l.Acquire(context.Background(), "a", opts)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckETag 设置响应的 ETag 请求头 If-None-Match 与之匹配时响应 304 并返回 true 调用方不再输出响应体
+// Cache-Control 为 no-cache 浏览器每次都会携带 ETag 重新验证
+func CheckETag(c *gin.Context, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if !etagMatch(c.GetHeader("If-None-Match"), etag) {
+		return false
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// etagMatch If-None-Match 为逗号分隔的 ETag 列表或 * 按弱比较忽略 W/ 前缀
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
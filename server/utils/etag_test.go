@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEtagMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		etag   string
+		want   bool
+	}{
+		{name: "empty", header: "", etag: `"abc"`, want: false},
+		{name: "equal", header: `"abc"`, etag: `"abc"`, want: true},
+		{name: "different", header: `"abd"`, etag: `"abc"`, want: false},
+		{name: "any", header: "*", etag: `"abc"`, want: true},
+		{name: "list", header: `"x", "abc" ,"y"`, etag: `"abc"`, want: true},
+		{name: "weak header", header: `W/"abc"`, etag: `"abc"`, want: true},
+		{name: "weak etag", header: `"abc"`, etag: `W/"abc"`, want: true},
+		{name: "unquoted", header: "abc", etag: `"abc"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatch(tt.header, tt.etag); got != tt.want {
+				t.Errorf("etagMatch(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		want        bool
+		wantStatus  int
+		wantHeader  string
+	}{
+		{name: "no etag", ifNoneMatch: `"abc"`, etag: "", want: false, wantStatus: http.StatusOK, wantHeader: ""},
+		{name: "first request", ifNoneMatch: "", etag: `"abc"`, want: false, wantStatus: http.StatusOK, wantHeader: `"abc"`},
+		{name: "changed", ifNoneMatch: `"old"`, etag: `"abc"`, want: false, wantStatus: http.StatusOK, wantHeader: `"abc"`},
+		{name: "not modified", ifNoneMatch: `"abc"`, etag: `"abc"`, want: true, wantStatus: http.StatusNotModified, wantHeader: `"abc"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.ifNoneMatch != "" {
+				c.Request.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if got := CheckETag(c, tt.etag); got != tt.want {
+				t.Errorf("CheckETag() = %v, want %v", got, tt.want)
+			}
+			if !tt.want {
+				c.Status(http.StatusOK)
+			}
+			c.Writer.WriteHeaderNow()
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantHeader {
+				t.Errorf("ETag = %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+var (
+	defaultNodeID     string
+	defaultNodeIDOnce sync.Once
+)
+
+// NodeID 当前节点的标识 优先使用配置 job.node-id 未配置时为 主机名-进程号
+func NodeID() string {
+	if global.GVA_CONFIG.Job.NodeID != "" {
+		return global.GVA_CONFIG.Job.NodeID
+	}
+	defaultNodeIDOnce.Do(func() {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "unknown"
+		}
+		defaultNodeID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	})
+	return defaultNodeID
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter 进程内限流器 未开启 redis 或 redis 不可用时使用 多实例部署时各实例单独计数
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	algorithm string
+	bucket    tokenBucket
+	sliding   slidingWindow
+	fixed     fixedWindow
+	expireAt  time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{entries: make(map[string]*memoryEntry), now: time.Now}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string, rule Rule) (Result, error) {
+	if !rule.valid() {
+		return Result{Allowed: true}, nil
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	entry, ok := l.entries[key]
+	if !ok || entry.algorithm != rule.Algorithm {
+		entry = &memoryEntry{algorithm: rule.Algorithm}
+		l.entries[key] = entry
+	}
+	ms := now.UnixMilli()
+	var result Result
+	switch rule.Algorithm {
+	case AlgorithmTokenBucket:
+		result = entry.bucket.take(ms, rule)
+		entry.expireAt = now.Add(result.Reset)
+	case AlgorithmSlidingWindow:
+		result = entry.sliding.take(ms, rule)
+		entry.expireAt = now.Add(2 * rule.Window)
+	default:
+		result = entry.fixed.take(ms, rule)
+		entry.expireAt = now.Add(result.Reset)
+	}
+	return result, nil
+}
+
+// sweep 每分钟清理一次已过期的计数
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.entries {
+		if !entry.expireAt.After(now) {
+			delete(l.entries, key)
+		}
+	}
+}
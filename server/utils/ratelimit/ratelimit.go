@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "token-bucket"
+	AlgorithmSlidingWindow = "sliding-window"
+	AlgorithmFixedWindow   = "fixed-window"
+)
+
+// Rule 限流规则
+// 令牌桶: 每个 Window 补充 Limit 个令牌 桶容量为 Burst 未设置时等于 Limit
+// 滑动窗口: 以前一个固定窗口的计数按剩余比例加权 估算最近一个 Window 内的请求数
+// 固定窗口: 每个 Window 内最多 Limit 次
+type Rule struct {
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+	Burst     int
+}
+
+// Result 限流结果 Reset 为额度完全恢复所需的时间 RetryAfter 为被拒绝时距下次可以请求的时间
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter 限流器 key 相同的请求共享额度
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// valid 次数或窗口未配置的规则不限流
+func (r Rule) valid() bool {
+	return r.Limit > 0 && r.Window >= time.Millisecond
+}
+
+func (r Rule) capacity() int {
+	if r.Algorithm == AlgorithmTokenBucket && r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// tokenBucket 令牌桶状态 last 为上次补充令牌的时间(毫秒)
+type tokenBucket struct {
+	tokens float64
+	last   int64
+}
+
+func (b *tokenBucket) take(now int64, rule Rule) Result {
+	window := rule.Window.Milliseconds()
+	capacity := float64(rule.capacity())
+	rate := float64(rule.Limit) / float64(window)
+	if b.last == 0 {
+		b.tokens, b.last = capacity, now
+	}
+	if now > b.last {
+		b.tokens = math.Min(capacity, b.tokens+float64(now-b.last)*rate)
+		b.last = now
+	}
+	result := Result{Limit: rule.capacity()}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = msDuration(math.Ceil((1 - b.tokens) / rate))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = msDuration(math.Ceil((capacity - b.tokens) / rate))
+	return result
+}
+
+// slidingWindow 滑动窗口状态 window 为当前固定窗口的序号
+type slidingWindow struct {
+	window   int64
+	current  int
+	previous int
+}
+
+func (w *slidingWindow) take(now int64, rule Rule) Result {
+	size := rule.Window.Milliseconds()
+	index := now / size
+	if w.window != index {
+		if w.window == index-1 {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+		w.window, w.current = index, 0
+	}
+	elapsed := now - index*size
+	count := float64(w.previous)*float64(size-elapsed)/float64(size) + float64(w.current)
+	result := Result{Limit: rule.Limit, Reset: msDuration(float64(size - elapsed))}
+	if count+1 <= float64(rule.Limit) {
+		w.current++
+		result.Allowed = true
+		result.Remaining = int(float64(rule.Limit) - count - 1)
+		return result
+	}
+	result.RetryAfter = msDuration(float64(slidingRetry(w.previous, w.current, rule.Limit, size, elapsed)))
+	return result
+}
+
+// slidingRetry 计算前一个窗口的权重下降到足以再放行一次所需的时间 当前窗口已满时等到下一个窗口
+func slidingRetry(previous, current, limit int, size, elapsed int64) int64 {
+	if current+1 > limit || previous == 0 {
+		return size - elapsed
+	}
+	wait := int64(math.Ceil(float64(size)-float64(limit-current-1)*float64(size)/float64(previous))) - elapsed
+	if wait < 1 {
+		wait = 1
+	}
+	return wait
+}
+
+// fixedWindow 固定窗口状态 start 为窗口开始时间(毫秒)
+type fixedWindow struct {
+	start int64
+	count int
+}
+
+func (w *fixedWindow) take(now int64, rule Rule) Result {
+	size := rule.Window.Milliseconds()
+	if w.start == 0 || now-w.start >= size {
+		w.start, w.count = now, 0
+	}
+	result := Result{Limit: rule.Limit, Reset: msDuration(float64(w.start + size - now))}
+	if w.count < rule.Limit {
+		w.count++
+		result.Allowed = true
+		result.Remaining = rule.Limit - w.count
+		return result
+	}
+	result.RetryAfter = result.Reset
+	return result
+}
+
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
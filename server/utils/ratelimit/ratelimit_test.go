@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.UnixMilli(1_700_000_000_000)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestTokenBucket(t *testing.T) {
+	l, now := newTestLimiter()
+	rule := Rule{Algorithm: AlgorithmTokenBucket, Limit: 10, Window: 10 * time.Second, Burst: 3}
+	for i := 2; i >= 0; i-- {
+		r, _ := l.Allow(context.Background(), "k", rule)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, i, r.Remaining)
+	}
+	r, _ := l.Allow(context.Background(), "k", rule)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, time.Second, r.RetryAfter)
+	assert.Equal(t, 3*time.Second, r.Reset)
+
+	// 每秒补充一个令牌
+	*now = now.Add(time.Second)
+	r, _ = l.Allow(context.Background(), "k", rule)
+	assert.True(t, r.Allowed)
+	r, _ = l.Allow(context.Background(), "k", rule)
+	assert.False(t, r.Allowed)
+
+	// 令牌不超过桶容量
+	*now = now.Add(time.Minute)
+	r, _ = l.Allow(context.Background(), "k", rule)
+	assert.Equal(t, 2, r.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l, now := newTestLimiter()
+	rule := Rule{Algorithm: AlgorithmSlidingWindow, Limit: 4, Window: 10 * time.Second}
+	// 对齐到窗口开始
+	*now = time.UnixMilli(now.UnixMilli() / 10000 * 10000)
+	for i := 0; i < 4; i++ {
+		r, _ := l.Allow(context.Background(), "k", rule)
+		assert.True(t, r.Allowed)
+	}
+	r, _ := l.Allow(context.Background(), "k", rule)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 10*time.Second, r.RetryAfter)
+
+	// 下一个窗口过去一半 前一个窗口按一半计数
+	*now = now.Add(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		r, _ = l.Allow(context.Background(), "k", rule)
+		assert.True(t, r.Allowed)
+	}
+	r, _ = l.Allow(context.Background(), "k", rule)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 2500*time.Millisecond, r.RetryAfter)
+
+	// 间隔超过两个窗口后重新计数
+	*now = now.Add(30 * time.Second)
+	r, _ = l.Allow(context.Background(), "k", rule)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 3, r.Remaining)
+}
+
+func TestFixedWindow(t *testing.T) {
+	l, now := newTestLimiter()
+	rule := Rule{Algorithm: AlgorithmFixedWindow, Limit: 2, Window: time.Minute}
+	r, _ := l.Allow(context.Background(), "k", rule)
+	assert.Equal(t, 1, r.Remaining)
+	r, _ = l.Allow(context.Background(), "other", rule)
+	assert.True(t, r.Allowed)
+	l.Allow(context.Background(), "k", rule)
+
+	*now = now.Add(20 * time.Second)
+	r, _ = l.Allow(context.Background(), "k", rule)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 40*time.Second, r.RetryAfter)
+
+	*now = now.Add(40 * time.Second)
+	r, _ = l.Allow(context.Background(), "k", rule)
+	assert.True(t, r.Allowed)
+}
+
+func TestInvalidRule(t *testing.T) {
+	l, _ := newTestLimiter()
+	r, err := l.Allow(context.Background(), "k", Rule{Algorithm: AlgorithmFixedWindow})
+	assert.NoError(t, err)
+	assert.True(t, r.Allowed)
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 脚本中的算法与 tokenBucket、slidingWindow、fixedWindow 保持一致 时间统一取 redis 服务端时间
+// 每个脚本只访问一个 key 兼容 redis 集群
+// 返回 {是否放行, 剩余次数, 重试等待毫秒, 完全恢复毫秒}
+
+var tokenBucketScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(data[1])
+local last = tonumber(data[2])
+if tokens == nil or last == nil then
+	tokens = capacity
+	last = now
+end
+if now > last then
+	tokens = math.min(capacity, tokens + (now - last) * rate)
+	last = now
+end
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'last', last)
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local size = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local index = math.floor(now / size)
+local data = redis.call('HMGET', KEYS[1], 'window', 'current', 'previous')
+local window = tonumber(data[1]) or index
+local current = tonumber(data[2]) or 0
+local previous = tonumber(data[3]) or 0
+if window ~= index then
+	if window == index - 1 then
+		previous = current
+	else
+		previous = 0
+	end
+	current = 0
+end
+local elapsed = now - index * size
+local count = previous * (size - elapsed) / size + current
+local allowed = 0
+local remaining = 0
+local retry = 0
+if count + 1 <= limit then
+	current = current + 1
+	allowed = 1
+	remaining = math.floor(limit - count - 1)
+elseif current + 1 > limit or previous == 0 then
+	retry = size - elapsed
+else
+	retry = math.max(math.ceil(size - (limit - current - 1) * size / previous) - elapsed, 1)
+end
+redis.call('HMSET', KEYS[1], 'window', index, 'current', current, 'previous', previous)
+redis.call('PEXPIRE', KEYS[1], size * 2)
+return {allowed, remaining, retry, size - elapsed}
+`)
+
+var fixedWindowScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+local limit = tonumber(ARGV[2])
+if count > limit then
+	return {0, 0, ttl, ttl}
+end
+return {1, limit - count, 0, ttl}
+`)
+
+// RedisLimiter 基于 redis lua 脚本的限流器 判断与计数在同一个脚本中原子执行 多实例共享额度
+type RedisLimiter struct {
+	client redis.UniversalClient
+}
+
+func NewRedisLimiter(client redis.UniversalClient) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if !rule.valid() {
+		return Result{Allowed: true}, nil
+	}
+	window := rule.Window.Milliseconds()
+	var cmd *redis.Cmd
+	switch rule.Algorithm {
+	case AlgorithmTokenBucket:
+		rate := float64(rule.Limit) / float64(window)
+		cmd = tokenBucketScript.Run(ctx, l.client, []string{key}, strconv.FormatFloat(rate, 'f', -1, 64), rule.capacity())
+	case AlgorithmSlidingWindow:
+		cmd = slidingWindowScript.Run(ctx, l.client, []string{key}, window, rule.Limit)
+	default:
+		cmd = fixedWindowScript.Run(ctx, l.client, []string{key}, window, rule.Limit)
+	}
+	values, err := cmd.Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      rule.capacity(),
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainHash(t *testing.T) {
+	first := ChainHash("", []byte(`{"path":"/user/setUserInfo"}`))
+	second := ChainHash(first, []byte(`{"path":"/user/deleteUser"}`))
+	assert.Len(t, first, 64)
+	assert.Equal(t, second, ChainHash(first, []byte(`{"path":"/user/deleteUser"}`)))
+	assert.NotEqual(t, second, ChainHash("", []byte(`{"path":"/user/deleteUser"}`)))
+	assert.NotEqual(t, first, ChainHash("", []byte(`{"path":"/user/setUserInfo "}`)))
+}
+
+func TestSign(t *testing.T) {
+	sig := Sign("secret", []byte("1|abc"))
+	assert.True(t, VerifySign("secret", []byte("1|abc"), sig))
+	assert.False(t, VerifySign("other", []byte("1|abc"), sig))
+	assert.False(t, VerifySign("secret", []byte("2|abc"), sig))
+	assert.False(t, VerifySign("secret", []byte("1|abc"), "not-hex"))
+}
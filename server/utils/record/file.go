@@ -0,0 +1,142 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileWriter 按天及文件大小切割的 JSON Lines 文件
+// 文件名格式 prefix-2006-01-02.jsonl 超出大小后依次为 prefix-2006-01-02.1.jsonl ...
+type FileWriter struct {
+	dir      string
+	prefix   string
+	maxSize  int64 // 单个文件最大字节数 不大于0时不按大小切割
+	keepDays int   // 文件保留天数 不大于0时不清理
+	mutex    sync.Mutex
+	file     *os.File
+	day      string
+	index    int
+	size     int64
+	cleaned  string
+}
+
+func NewFileWriter(dir string, prefix string, maxSizeMB int, keepDays int) *FileWriter {
+	return &FileWriter{
+		dir:      dir,
+		prefix:   prefix,
+		maxSize:  int64(maxSizeMB) * 1024 * 1024,
+		keepDays: keepDays,
+	}
+}
+
+// WriteLines 每个元素序列化为一行 JSON 写入当前文件
+func (w *FileWriter) WriteLines(items ...interface{}) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := w.rotate(time.Now()); err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(w.file)
+	for _, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		b = append(b, '\n')
+		n, err := buf.Write(b)
+		w.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// Close 关闭当前文件
+func (w *FileWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *FileWriter) rotate(now time.Time) error {
+	day := now.Format(time.DateOnly)
+	if w.file != nil && day == w.day && (w.maxSize <= 0 || w.size < w.maxSize) {
+		return nil
+	}
+	if w.file != nil {
+		_ = w.file.Close()
+		w.file = nil
+	}
+	if err := os.MkdirAll(w.dir, os.ModePerm); err != nil {
+		return err
+	}
+	if day != w.day {
+		w.day, w.index = day, 0
+	}
+	for {
+		name := filepath.Join(w.dir, w.fileName(day, w.index))
+		info, err := os.Stat(name)
+		if err == nil && w.maxSize > 0 && info.Size() >= w.maxSize {
+			w.index++
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		w.file = file
+		w.size = 0
+		if info != nil {
+			w.size = info.Size()
+		}
+		break
+	}
+	if w.keepDays > 0 && w.cleaned != day {
+		w.cleaned = day
+		w.removeExpired(now)
+	}
+	return nil
+}
+
+func (w *FileWriter) fileName(day string, index int) string {
+	if index == 0 {
+		return fmt.Sprintf("%s-%s.jsonl", w.prefix, day)
+	}
+	return fmt.Sprintf("%s-%s.%d.jsonl", w.prefix, day, index)
+}
+
+func (w *FileWriter) removeExpired(now time.Time) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+	deadline := now.AddDate(0, 0, -w.keepDays).Format(time.DateOnly)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, w.prefix+"-") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day := strings.TrimPrefix(name, w.prefix+"-")
+		if len(day) < len(time.DateOnly) {
+			continue
+		}
+		if day[:len(time.DateOnly)] < deadline {
+			_ = os.Remove(filepath.Join(w.dir, name))
+		}
+	}
+}
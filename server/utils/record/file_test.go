@@ -0,0 +1,49 @@
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileWriterRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	w := NewFileWriter(dir, "op", 0, 0)
+	w.maxSize = 64
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, w.WriteLines(map[string]interface{}{"id": i, "path": "/user/setUserInfo"}))
+	}
+	assert.NoError(t, w.Close())
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Greater(t, len(entries), 1)
+	lines := 0
+	for _, entry := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		assert.NoError(t, err)
+		lines += strings.Count(string(b), "\n")
+	}
+	assert.Equal(t, 10, lines)
+}
+
+func TestFileWriterRemoveExpired(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "op-"+time.Now().AddDate(0, 0, -10).Format(time.DateOnly)+".jsonl")
+	other := filepath.Join(dir, "other.log")
+	assert.NoError(t, os.WriteFile(old, []byte("{}\n"), 0644))
+	assert.NoError(t, os.WriteFile(other, []byte("x"), 0644))
+
+	w := NewFileWriter(dir, "op", 0, 7)
+	assert.NoError(t, w.WriteLines(map[string]int{"id": 1}))
+	assert.NoError(t, w.Close())
+
+	_, err := os.Stat(old)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(other)
+	assert.NoError(t, err)
+}
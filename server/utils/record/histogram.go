@@ -0,0 +1,50 @@
+package record
+
+import "time"
+
+// LatencyBounds 延迟直方图各桶的上界 直方图比上界多一个桶 记录超出最大上界的数量
+var LatencyBounds = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyPercentile 由直方图估算分位数 q 取值 0-1
+// counts 为各桶不累计的数量 桶内按线性插值 超出最大上界的桶以 max 作为上界 结果不超过 max
+func LatencyPercentile(counts []int64, max time.Duration, q float64) time.Duration {
+	var total int64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := q * float64(total)
+	var cum int64
+	var lower time.Duration
+	for i, c := range counts {
+		upper := max
+		if i < len(LatencyBounds) {
+			upper = LatencyBounds[i]
+		}
+		if upper < lower {
+			upper = lower
+		}
+		if c > 0 && float64(cum+c) >= rank {
+			d := lower + time.Duration((rank-float64(cum))/float64(c)*float64(upper-lower))
+			if max > 0 && d > max {
+				d = max
+			}
+			return d
+		}
+		cum += c
+		lower = upper
+	}
+	return max
+}
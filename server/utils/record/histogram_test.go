@@ -0,0 +1,24 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyPercentile(t *testing.T) {
+	counts := make([]int64, len(LatencyBounds)+1)
+	assert.Equal(t, time.Duration(0), LatencyPercentile(counts, 0, 0.5))
+
+	// 100 个请求全部落在 (10ms, 50ms]
+	counts[1] = 100
+	assert.Equal(t, 30*time.Millisecond, LatencyPercentile(counts, 50*time.Millisecond, 0.5))
+	assert.Equal(t, 48*time.Millisecond, LatencyPercentile(counts, 50*time.Millisecond, 0.95))
+	// 不超过实际的最大延迟
+	assert.Equal(t, 40*time.Millisecond, LatencyPercentile(counts, 40*time.Millisecond, 0.99))
+
+	// 超出最大上界的桶以最大延迟作为上界
+	counts[1], counts[len(LatencyBounds)] = 90, 10
+	assert.Equal(t, 15*time.Second, LatencyPercentile(counts, 20*time.Second, 0.95))
+}
@@ -0,0 +1,131 @@
+package record
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+const DefaultMask = "******"
+
+// Redactor 脱敏规则
+// keys 按字段名匹配 不区分大小写 字段名包含规则即命中 如 password 可命中 newPassword
+// paths 按 JSON 路径匹配 以 . 分隔 * 匹配任意字段 数组不占用路径层级 如 data.user.phone
+type Redactor struct {
+	keys  []string
+	paths [][]string
+	mask  string
+}
+
+func NewRedactor(keys []string, paths []string, mask string) *Redactor {
+	r := &Redactor{mask: mask}
+	if r.mask == "" {
+		r.mask = DefaultMask
+	}
+	for _, k := range keys {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			r.keys = append(r.keys, k)
+		}
+	}
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			r.paths = append(r.paths, strings.Split(p, "."))
+		}
+	}
+	return r
+}
+
+// Redact 对 JSON 或 query/form 格式的内容脱敏 无法识别的内容原样返回
+func (r *Redactor) Redact(s string) string {
+	if s == "" || (len(r.keys) == 0 && len(r.paths) == 0) {
+		return s
+	}
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var v interface{}
+		// 保留数字原样 避免大整数精度丢失
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return s
+		}
+		v, changed := r.redactValue(v, nil)
+		if !changed {
+			return s
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return s
+		}
+		return string(b)
+	}
+	if strings.Contains(s, "=") {
+		values, err := url.ParseQuery(s)
+		if err != nil {
+			return s
+		}
+		changed := false
+		for k := range values {
+			if r.match(k, []string{k}) {
+				values[k] = []string{r.mask}
+				changed = true
+			}
+		}
+		if changed {
+			return values.Encode()
+		}
+	}
+	return s
+}
+
+func (r *Redactor) redactValue(v interface{}, path []string) (interface{}, bool) {
+	changed := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			childPath := append(path[:len(path):len(path)], k)
+			if r.match(k, childPath) {
+				val[k] = r.mask
+				changed = true
+				continue
+			}
+			if nv, ok := r.redactValue(child, childPath); ok {
+				val[k] = nv
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, child := range val {
+			if nv, ok := r.redactValue(child, path); ok {
+				val[i] = nv
+				changed = true
+			}
+		}
+	}
+	return v, changed
+}
+
+func (r *Redactor) match(key string, path []string) bool {
+	lower := strings.ToLower(key)
+	for _, k := range r.keys {
+		if strings.Contains(lower, k) {
+			return true
+		}
+	}
+	for _, p := range r.paths {
+		if len(p) != len(path) {
+			continue
+		}
+		matched := true
+		for i := range p {
+			if p[i] != "*" && p[i] != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactorKeys(t *testing.T) {
+	r := NewRedactor([]string{"password", "token"}, nil, "")
+	out := r.Redact(`{"username":"admin","password":"123456","newPassword":"abcdef"}`)
+	assert.JSONEq(t, `{"username":"admin","password":"******","newPassword":"******"}`, out)
+
+	out = r.Redact(`{"code":0,"data":{"user":{"userName":"admin"},"token":"eyJ"},"msg":"ok"}`)
+	assert.JSONEq(t, `{"code":0,"data":{"user":{"userName":"admin"},"token":"******"},"msg":"ok"}`, out)
+
+	out = r.Redact(`{"id":9007199254740993,"token":"t"}`)
+	assert.Equal(t, `{"id":9007199254740993,"token":"******"}`, out)
+
+	out = r.Redact(`[{"password":"1"},{"name":"a"}]`)
+	assert.JSONEq(t, `[{"password":"******"},{"name":"a"}]`, out)
+}
+
+func TestRedactorPaths(t *testing.T) {
+	r := NewRedactor(nil, []string{"data.*.phone", "secret"}, "***")
+	out := r.Redact(`{"phone":"1","secret":"s","data":{"list":[{"phone":"13800000000","name":"a"}]}}`)
+	assert.JSONEq(t, `{"phone":"1","secret":"***","data":{"list":[{"phone":"***","name":"a"}]}}`, out)
+}
+
+func TestRedactorQueryAndPlain(t *testing.T) {
+	r := NewRedactor([]string{"token"}, nil, "")
+	assert.Equal(t, "id=1&token=%2A%2A%2A%2A%2A%2A", r.Redact("id=1&token=abc"))
+	assert.Equal(t, "[文件]", r.Redact("[文件]"))
+	assert.Equal(t, `{"id":1}`, r.Redact(`{"id":1}`))
+	assert.Equal(t, `{broken`, r.Redact(`{broken`))
+}
@@ -0,0 +1,171 @@
+package record
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	OverflowDrop  = "drop"  // 队列满时丢弃并计数
+	OverflowBlock = "block" // 队列满时阻塞直到入队
+)
+
+type Options struct {
+	BufferSize    int                    // 缓冲队列长度
+	BatchSize     int                    // 每批写入的最大条数
+	FlushInterval time.Duration          // 未满一批时的最长写入间隔
+	Overflow      string                 // 队列满时的策略
+	OnError       func(err error, n int) // 批量写入失败时回调 n 为本批条数
+}
+
+// Stats 写入器运行指标
+type Stats struct {
+	Queued   int    `json:"queued"`   // 当前排队条数
+	Capacity int    `json:"capacity"` // 队列容量
+	Written  uint64 `json:"written"`  // 已成功写入条数
+	Dropped  uint64 `json:"dropped"`  // 因队列已满或已关闭而丢弃的条数
+	Failed   uint64 `json:"failed"`   // 写入失败的条数
+}
+
+// Writer 有界队列加批量写入 达到批大小或间隔时调用 flush
+type Writer[T any] struct {
+	opts    Options
+	ch      chan T
+	flush   func([]T) error
+	done    chan struct{}
+	quit    chan struct{} // 关闭时唤醒阻塞在入队上的 Write
+	mu      sync.RWMutex
+	closed  bool
+	pending sync.WaitGroup // 正在入队的 Write 全部返回后才能关闭 ch
+	written atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// NewWriter 创建并启动写入器 flush 在单个goroutine中串行调用 且不能持有传入的切片
+func NewWriter[T any](opts Options, flush func([]T) error) *Writer[T] {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.Overflow != OverflowBlock {
+		opts.Overflow = OverflowDrop
+	}
+	w := &Writer[T]{
+		opts:  opts,
+		ch:    make(chan T, opts.BufferSize),
+		flush: flush,
+		done:  make(chan struct{}),
+		quit:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write 入队一条数据 返回 false 表示已被丢弃 阻塞模式下写入器关闭时放弃入队
+func (w *Writer[T]) Write(item T) bool {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		w.dropped.Add(1)
+		return false
+	}
+	w.pending.Add(1)
+	w.mu.RUnlock()
+	defer w.pending.Done()
+
+	if w.opts.Overflow == OverflowBlock {
+		select {
+		case w.ch <- item:
+			return true
+		case <-w.quit:
+			w.dropped.Add(1)
+			return false
+		}
+	}
+	select {
+	case w.ch <- item:
+		return true
+	default:
+		w.dropped.Add(1)
+		return false
+	}
+}
+
+// Close 停止接收新数据并写入队列中剩余的数据 ctx 结束时不再等待 剩余数据继续在后台写入
+func (w *Writer[T]) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.quit)
+		go func() {
+			w.pending.Wait()
+			close(w.ch)
+		}()
+	}
+	w.mu.Unlock()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats 获取运行指标
+func (w *Writer[T]) Stats() Stats {
+	return Stats{
+		Queued:   len(w.ch),
+		Capacity: cap(w.ch),
+		Written:  w.written.Load(),
+		Dropped:  w.dropped.Load(),
+		Failed:   w.failed.Load(),
+	}
+}
+
+func (w *Writer[T]) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]T, 0, w.opts.BatchSize)
+	for {
+		select {
+		case item, ok := <-w.ch:
+			if !ok {
+				w.write(batch)
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) >= w.opts.BatchSize {
+				w.write(batch)
+				batch = make([]T, 0, w.opts.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.write(batch)
+				batch = make([]T, 0, w.opts.BatchSize)
+			}
+		}
+	}
+}
+
+func (w *Writer[T]) write(batch []T) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := w.flush(batch); err != nil {
+		w.failed.Add(uint64(len(batch)))
+		if w.opts.OnError != nil {
+			w.opts.OnError(err, len(batch))
+		}
+		return
+	}
+	w.written.Add(uint64(len(batch)))
+}
@@ -0,0 +1,132 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type collector struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (c *collector) flush(items []int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batches = append(c.batches, append([]int(nil), items...))
+	return nil
+}
+
+func (c *collector) count() (batches int, items int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range c.batches {
+		items += len(b)
+	}
+	return len(c.batches), items
+}
+
+func TestWriterFlushBySize(t *testing.T) {
+	c := &collector{}
+	w := NewWriter(Options{BufferSize: 16, BatchSize: 4, FlushInterval: time.Hour}, c.flush)
+	for i := 0; i < 8; i++ {
+		assert.True(t, w.Write(i))
+	}
+	assert.Eventually(t, func() bool {
+		_, items := c.count()
+		return items == 8
+	}, time.Second, 5*time.Millisecond)
+	batches, _ := c.count()
+	assert.Equal(t, 2, batches)
+	assert.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, uint64(8), w.Stats().Written)
+}
+
+func TestWriterFlushByInterval(t *testing.T) {
+	c := &collector{}
+	w := NewWriter(Options{BufferSize: 16, BatchSize: 100, FlushInterval: 10 * time.Millisecond}, c.flush)
+	w.Write(1)
+	assert.Eventually(t, func() bool {
+		_, items := c.count()
+		return items == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, w.Close(context.Background()))
+}
+
+func TestWriterCloseFlushesRemaining(t *testing.T) {
+	c := &collector{}
+	w := NewWriter(Options{BufferSize: 16, BatchSize: 100, FlushInterval: time.Hour}, c.flush)
+	for i := 0; i < 5; i++ {
+		w.Write(i)
+	}
+	assert.NoError(t, w.Close(context.Background()))
+	_, items := c.count()
+	assert.Equal(t, 5, items)
+	assert.False(t, w.Write(6))
+	assert.Equal(t, uint64(1), w.Stats().Dropped)
+}
+
+func TestWriterDropWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	w := NewWriter(Options{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour}, func([]int) error {
+		<-release
+		return nil
+	})
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		if w.Write(i) {
+			accepted++
+		}
+	}
+	assert.Less(t, accepted, 10)
+	assert.Equal(t, uint64(10-accepted), w.Stats().Dropped)
+	close(release)
+	assert.NoError(t, w.Close(context.Background()))
+}
+
+func TestWriterFailedBatch(t *testing.T) {
+	var failed int
+	w := NewWriter(Options{BatchSize: 2, FlushInterval: time.Hour, OnError: func(err error, n int) {
+		failed += n
+	}}, func([]int) error {
+		return errors.New("db down")
+	})
+	w.Write(1)
+	w.Write(2)
+	w.Write(3)
+	assert.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, 3, failed)
+	assert.Equal(t, uint64(3), w.Stats().Failed)
+}
+
+func TestWriterCloseWhileBlocked(t *testing.T) {
+	release := make(chan struct{})
+	w := NewWriter(Options{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour, Overflow: OverflowBlock}, func([]int) error {
+		<-release
+		return nil
+	})
+	// 第一条被取出后阻塞在 flush 第二条占满队列 第三条阻塞在入队
+	assert.True(t, w.Write(1))
+	assert.True(t, w.Write(2))
+	blocked := make(chan bool)
+	go func() { blocked <- w.Write(3) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Close(ctx), context.DeadlineExceeded)
+	select {
+	case ok := <-blocked:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("Write 在关闭后仍然阻塞")
+	}
+	close(release)
+	assert.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, uint64(2), w.Stats().Written)
+	assert.Equal(t, uint64(1), w.Stats().Dropped)
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "context"
+
+type requestInfoKey struct{}
+
+// RequestInfo 请求ID与操作人 由中间件写入请求的 context 供 gorm 回调等拿不到 gin.Context 的地方使用
+type RequestInfo struct {
+	RequestID string
+	UserID    uint
+	Username  string
+}
+
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// GetRequestInfo 获取请求信息 不是来自请求的 context 时返回零值
+func GetRequestInfo(ctx context.Context) RequestInfo {
+	if ctx == nil {
+		return RequestInfo{}
+	}
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/concurrency"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/record"
 	"runtime"
 	"time"
 
@@ -18,10 +20,12 @@ const (
 )
 
 type Server struct {
-	Os   Os   `json:"os"`
-	Cpu  Cpu  `json:"cpu"`
-	Ram  Ram  `json:"ram"`
-	Disk []Disk `json:"disk"`
+	Os              Os                  `json:"os"`
+	Cpu             Cpu                 `json:"cpu"`
+	Ram             Ram                 `json:"ram"`
+	Disk            []Disk              `json:"disk"`
+	OperationRecord *record.Stats       `json:"operationRecord,omitempty"`
+	Concurrency     []concurrency.Stats `json:"concurrency"`
 }
 
 type Os struct {
@@ -0,0 +1,56 @@
+package sms
+
+import (
+	"sync"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"go.uber.org/zap"
+)
+
+// Message 本地模拟发送的短信
+type Message struct {
+	Phone   string
+	Content string
+}
+
+// Local 本地模拟短信 不会真正发送 仅记录日志并保存最近的消息 供开发和测试使用
+type Local struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+var LocalSms = new(Local)
+
+const localMaxMessages = 100
+
+func (l *Local) Send(phone string, content string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, Message{Phone: phone, Content: content})
+	if len(l.messages) > localMaxMessages {
+		l.messages = l.messages[len(l.messages)-localMaxMessages:]
+	}
+	if global.GVA_LOG != nil {
+		global.GVA_LOG.Info("本地模拟短信发送", zap.String("phone", phone), zap.String("content", content))
+	}
+	return nil
+}
+
+// Last 返回发送给某手机号的最后一条短信
+func (l *Local) Last(phone string) (Message, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.messages) - 1; i >= 0; i-- {
+		if l.messages[i].Phone == phone {
+			return l.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// Reset 清空已记录的短信
+func (l *Local) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = nil
+}
@@ -0,0 +1,27 @@
+package sms
+
+import "testing"
+
+func TestLocalSms(t *testing.T) {
+	l := new(Local)
+	if _, ok := l.Last("13800138000"); ok {
+		t.Fatal("空记录不应返回短信")
+	}
+	for i := 0; i < localMaxMessages+10; i++ {
+		if err := l.Send("13900139000", "filler"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = l.Send("13800138000", "验证码 123456")
+	m, ok := l.Last("13800138000")
+	if !ok || m.Content != "验证码 123456" {
+		t.Fatalf("未找到最后一条短信: %+v", m)
+	}
+	if len(l.messages) != localMaxMessages {
+		t.Fatalf("记录数应被限制为 %d, 实际 %d", localMaxMessages, len(l.messages))
+	}
+	l.Reset()
+	if _, ok := l.Last("13800138000"); ok {
+		t.Fatal("Reset 后不应返回短信")
+	}
+}
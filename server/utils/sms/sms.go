@@ -0,0 +1,20 @@
+package sms
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// Sms 短信发送接口 接入新的服务商只需实现该接口并在 NewSms 中注册
+type Sms interface {
+	Send(phone string, content string) error
+}
+
+// NewSms 短信的实例化方法
+func NewSms() Sms {
+	switch global.GVA_CONFIG.Account.SmsProvider {
+	case "local":
+		return LocalSms
+	default:
+		return LocalSms
+	}
+}
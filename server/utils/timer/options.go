@@ -0,0 +1,126 @@
+package timer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	OverlapAllow = ""      // 允许重叠执行
+	OverlapSkip  = "skip"  // 上一次仍在执行时跳过本次
+	OverlapDelay = "delay" // 上一次仍在执行时等待其完成后再执行
+)
+
+// TaskOptions 任务执行选项
+type TaskOptions struct {
+	Timeout   time.Duration                    // 单次执行的超时时间 到期后取消 ctx 为 0 时不限制
+	Retry     int                              // 失败后的重试次数
+	Backoff   time.Duration                    // 第一次重试前的等待时间 之后每次翻倍 为 0 时为 1s
+	Overlap   string                           // 上一次仍在执行时的策略 skip|delay 为空时允许重叠
+	OnFailure func(taskName string, err error) // 重试后仍失败或 panic 时调用
+	Logger    *zap.Logger                      // 为空时使用 zap.L()
+	// Wrap 不为空时包裹每次触发的执行 run 执行任务并按 Retry 重试 返回重试后的结果
+	// 用于在多次重试之间持有分布式锁等 传给 run 的 ctx 取消时不再重试
+	Wrap func(run func(ctx context.Context) error) error
+}
+
+type attemptKey struct{}
+
+// Attempt 当前是第几次尝试 从1开始 不是由 TaskOptions 执行时为0
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+func (o TaskOptions) logger() *zap.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return zap.L()
+}
+
+// job 按选项包装任务 panic 与超时都作为失败处理
+func (o TaskOptions) job(taskName string, fun func(ctx context.Context) error) cron.Job {
+	logger := o.logger()
+	run := cron.FuncJob(func() {
+		retry := func(ctx context.Context) error {
+			return o.retry(ctx, logger, taskName, fun)
+		}
+		var err error
+		if o.Wrap != nil {
+			err = o.Wrap(retry)
+		} else {
+			err = retry(context.Background())
+		}
+		if err == nil {
+			return
+		}
+		logger.Error("timer task failed after retries", zap.String("task", taskName), zap.Int("retry", o.Retry), zap.Error(err))
+		if o.OnFailure != nil {
+			o.OnFailure(taskName, err)
+		}
+	})
+	var wrappers []cron.JobWrapper
+	switch o.Overlap {
+	case OverlapSkip:
+		wrappers = append(wrappers, cron.SkipIfStillRunning(cronLogger{logger: logger}))
+	case OverlapDelay:
+		wrappers = append(wrappers, cron.DelayIfStillRunning(cronLogger{logger: logger}))
+	}
+	return cron.NewChain(wrappers...).Then(run)
+}
+
+// retry 执行任务 失败后按 Backoff 翻倍的间隔重试 ctx 取消时不再重试
+func (o TaskOptions) retry(ctx context.Context, logger *zap.Logger, taskName string, fun func(ctx context.Context) error) (err error) {
+	backoff := o.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 1; attempt <= o.Retry+1; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = o.runOnce(context.WithValue(ctx, attemptKey{}, attempt), logger, taskName, fun); err == nil {
+			return nil
+		}
+		logger.Warn("timer task failed", zap.String("task", taskName), zap.Int("attempt", attempt), zap.Error(err))
+	}
+	return err
+}
+
+func (o TaskOptions) runOnce(ctx context.Context, logger *zap.Logger, taskName string, fun func(ctx context.Context) error) (err error) {
+	cancel := context.CancelFunc(func() {})
+	if o.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+	}
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("timer task panic", zap.String("task", taskName), zap.Any("panic", r), zap.Stack("stack"))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fun(ctx)
+}
+
+// cronLogger 将 cron 的日志输出到 zap
+type cronLogger struct {
+	logger *zap.Logger
+}
+
+func (l cronLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Sugar().Infow(msg, keysAndValues...)
+}
+
+func (l cronLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.logger.Sugar().Errorw(msg, append(keysAndValues, "error", err)...)
+}
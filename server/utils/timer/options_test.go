@@ -0,0 +1,128 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskOptionsRetry(t *testing.T) {
+	var calls int32
+	var failed error
+	opts := TaskOptions{Retry: 2, Backoff: time.Millisecond, OnFailure: func(taskName string, err error) { failed = err }}
+	opts.job("retry", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("boom")
+	}).Run()
+	assert.Equal(t, int32(3), calls)
+	assert.EqualError(t, failed, "boom")
+
+	// 重试成功后不调用失败回调
+	calls, failed = 0, nil
+	opts.job("retry", func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			return errors.New("boom")
+		}
+		return nil
+	}).Run()
+	assert.Equal(t, int32(2), calls)
+	assert.Nil(t, failed)
+}
+
+func TestTaskOptionsPanicAndTimeout(t *testing.T) {
+	var failed error
+	opts := TaskOptions{Timeout: 10 * time.Millisecond, OnFailure: func(taskName string, err error) { failed = err }}
+	opts.job("panic", func(ctx context.Context) error {
+		panic("oops")
+	}).Run()
+	assert.EqualError(t, failed, "panic: oops")
+
+	opts.job("timeout", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}).Run()
+	assert.ErrorIs(t, failed, context.DeadlineExceeded)
+}
+
+func TestTaskOptionsOverlapSkip(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	job := TaskOptions{Overlap: OverlapSkip}.job("skip", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		job.Run()
+	}()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+	// 上一次仍在执行 跳过
+	job.Run()
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestTaskOptionsWrap(t *testing.T) {
+	var wraps, calls int32
+	var attempts []int
+	var failed error
+	opts := TaskOptions{
+		Retry:     2,
+		Backoff:   time.Millisecond,
+		OnFailure: func(taskName string, err error) { failed = err },
+		// 全部重试只包裹一次
+		Wrap: func(run func(ctx context.Context) error) error {
+			atomic.AddInt32(&wraps, 1)
+			return run(context.Background())
+		},
+	}
+	opts.job("wrap", func(ctx context.Context) error {
+		attempts = append(attempts, Attempt(ctx))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	}).Run()
+	assert.Equal(t, int32(1), wraps)
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+	assert.Nil(t, failed)
+
+	// Wrap 跳过本次执行
+	calls = 0
+	opts.Wrap = func(run func(ctx context.Context) error) error { return nil }
+	opts.job("skip", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}).Run()
+	assert.Zero(t, calls)
+
+	// 传给 run 的 ctx 取消后不再重试
+	calls = 0
+	opts.Backoff = time.Hour
+	opts.Wrap = func(run func(ctx context.Context) error) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			for atomic.LoadInt32(&calls) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+		}()
+		return run(ctx)
+	}
+	opts.job("cancel", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("boom")
+	}).Run()
+	assert.Equal(t, int32(1), calls)
+	assert.EqualError(t, failed, "boom")
+	assert.Zero(t, Attempt(context.Background()))
+}
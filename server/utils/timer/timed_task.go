@@ -1,8 +1,11 @@
 package timer
 
 import (
-	"github.com/robfig/cron/v3"
+	"context"
 	"sync"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
 )
 
 type Timer interface {
@@ -16,6 +19,8 @@ type Timer interface {
 	AddTaskByFunc(cronName string, spec string, task func(), taskName string, option ...cron.Option) (cron.EntryID, error)
 	// 通过接口的方法添加任务 要实现一个带有 Run方法的接口触发
 	AddTaskByJob(cronName string, spec string, job interface{ Run() }, taskName string, option ...cron.Option) (cron.EntryID, error)
+	// 通过函数的方法添加任务 并设置超时、重试、重叠策略和失败回调
+	AddTaskByFuncWithOptions(cronName string, spec string, fun func(ctx context.Context) error, taskName string, opts TaskOptions, option ...cron.Option) (cron.EntryID, error)
 	// 获取对应taskName的cron 可能会为空
 	FindCron(cronName string) (*taskManager, bool)
 	// 指定cron开始执行
@@ -58,7 +63,7 @@ func (t *timer) AddTaskByFunc(cronName string, spec string, fun func(), taskName
 	if _, ok := t.cronList[cronName]; !ok {
 		tasks := make(map[cron.EntryID]*task)
 		t.cronList[cronName] = &taskManager{
-			corn:  cron.New(option...),
+			corn:  newCron(option...),
 			tasks: tasks,
 		}
 	}
@@ -72,6 +77,27 @@ func (t *timer) AddTaskByFunc(cronName string, spec string, fun func(), taskName
 	return id, err
 }
 
+// AddTaskByFuncWithOptions 通过函数的方法添加任务 按 opts 设置超时、重试、重叠策略和失败回调
+func (t *timer) AddTaskByFuncWithOptions(cronName string, spec string, fun func(ctx context.Context) error, taskName string, opts TaskOptions, option ...cron.Option) (cron.EntryID, error) {
+	t.Lock()
+	defer t.Unlock()
+	if _, ok := t.cronList[cronName]; !ok {
+		tasks := make(map[cron.EntryID]*task)
+		t.cronList[cronName] = &taskManager{
+			corn:  newCron(option...),
+			tasks: tasks,
+		}
+	}
+	id, err := t.cronList[cronName].corn.AddJob(spec, opts.job(taskName, fun))
+	t.cronList[cronName].corn.Start()
+	t.cronList[cronName].tasks[id] = &task{
+		EntryID:  id,
+		Spec:     spec,
+		TaskName: taskName,
+	}
+	return id, err
+}
+
 // AddTaskByFuncWithSecond 通过函数的方法使用WithSeconds添加任务
 func (t *timer) AddTaskByFuncWithSecond(cronName string, spec string, fun func(), taskName string, option ...cron.Option) (cron.EntryID, error) {
 	t.Lock()
@@ -80,7 +106,7 @@ func (t *timer) AddTaskByFuncWithSecond(cronName string, spec string, fun func()
 	if _, ok := t.cronList[cronName]; !ok {
 		tasks := make(map[cron.EntryID]*task)
 		t.cronList[cronName] = &taskManager{
-			corn:  cron.New(option...),
+			corn:  newCron(option...),
 			tasks: tasks,
 		}
 	}
@@ -101,7 +127,7 @@ func (t *timer) AddTaskByJob(cronName string, spec string, job interface{ Run()
 	if _, ok := t.cronList[cronName]; !ok {
 		tasks := make(map[cron.EntryID]*task)
 		t.cronList[cronName] = &taskManager{
-			corn:  cron.New(option...),
+			corn:  newCron(option...),
 			tasks: tasks,
 		}
 	}
@@ -123,7 +149,7 @@ func (t *timer) AddTaskByJobWithSeconds(cronName string, spec string, job interf
 	if _, ok := t.cronList[cronName]; !ok {
 		tasks := make(map[cron.EntryID]*task)
 		t.cronList[cronName] = &taskManager{
-			corn:  cron.New(option...),
+			corn:  newCron(option...),
 			tasks: tasks,
 		}
 	}
@@ -224,6 +250,12 @@ func (t *timer) Close() {
 	}
 }
 
+// newCron 默认捕获任务中的 panic 并记录日志 避免导致进程退出 option 中的 WithChain 会覆盖默认设置
+func newCron(option ...cron.Option) *cron.Cron {
+	option = append([]cron.Option{cron.WithChain(cron.Recover(cronLogger{logger: zap.L()}))}, option...)
+	return cron.New(option...)
+}
+
 func NewTimerTask() Timer {
 	return &timer{cronList: make(map[string]*taskManager)}
 }
@@ -0,0 +1,43 @@
+package upload
+
+import (
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+)
+
+// UploadLocalFile 通过 OSS 上传服务端生成的本地文件
+// OSS 的上传方法只接收表单文件 这里将文件封装为 multipart 表单再解析 大文件由 ReadForm 暂存到临时目录
+func UploadLocalFile(oss OSS, path string) (url string, key string, err error) {
+	return UploadLocalFileAs(oss, path, filepath.Base(path))
+}
+
+// UploadLocalFileAs 以 name 作为文件名上传本地文件 name 可以带目录 最终的存储路径由各 OSS 按文件名生成
+func UploadLocalFileAs(oss OSS, path string, name string) (url string, key string, err error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		part, err := writer.CreateFormFile("file", filepath.Base(path))
+		if err == nil {
+			var file *os.File
+			if file, err = os.Open(path); err == nil {
+				_, err = io.Copy(part, file)
+				file.Close()
+			}
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(32 << 20)
+	pr.Close()
+	if err != nil {
+		return "", "", err
+	}
+	defer form.RemoveAll()
+	file := form.File["file"][0]
+	file.Filename = name // 表单中的文件名不能带目录
+	return oss.UploadFile(file)
+}
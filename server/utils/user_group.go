@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"go.uber.org/zap"
+)
+
+const userGroupAuthorityCacheExpire = 5 * time.Minute
+
+func userGroupAuthorityCacheKey(userID uint) string {
+	return "GVA_UserGroupAuthority_" + strconv.Itoa(int(userID))
+}
+
+// GetUserGroupAuthorityIds 获取用户通过用户组获得的角色ID 结果缓存在 BlackCache 中
+func GetUserGroupAuthorityIds(userID uint) []uint {
+	if userID == 0 || global.GVA_DB == nil {
+		return nil
+	}
+	key := userGroupAuthorityCacheKey(userID)
+	if v, ok := global.BlackCache.Get(key); ok {
+		if ids, ok := v.([]uint); ok {
+			return ids
+		}
+	}
+	var ids []uint
+	err := global.GVA_DB.Model(&system.SysUserGroupAuthority{}).
+		Distinct("sys_user_group_authorities.sys_authority_authority_id").
+		Joins("JOIN sys_user_group_users ON sys_user_group_users.sys_user_group_id = sys_user_group_authorities.sys_user_group_id").
+		Joins("JOIN sys_user_groups ON sys_user_groups.id = sys_user_group_authorities.sys_user_group_id AND sys_user_groups.deleted_at IS NULL").
+		Where("sys_user_group_users.sys_user_id = ?", userID).
+		Pluck("sys_user_group_authorities.sys_authority_authority_id", &ids).Error
+	if err != nil {
+		global.GVA_LOG.Error("获取用户组角色失败!", zap.Error(err))
+		return nil
+	}
+	global.BlackCache.Set(key, ids, userGroupAuthorityCacheExpire)
+	return ids
+}
+
+// ClearUserGroupAuthorityCache 用户组成员或角色变更后清除对应用户的缓存
+func ClearUserGroupAuthorityCache(userIDs ...uint) {
+	for _, id := range userIDs {
+		global.BlackCache.Delete(userGroupAuthorityCacheKey(id))
+	}
+}
@@ -16,4 +16,9 @@ var (
 	OldAuthorityVerify     = Rules{"OldAuthorityId": {NotEmpty()}}
 	ChangePasswordVerify   = Rules{"Password": {NotEmpty()}, "NewPassword": {NotEmpty()}}
 	SetUserAuthorityVerify = Rules{"AuthorityId": {NotEmpty()}}
+	UserGroupVerify        = Rules{"Name": {NotEmpty()}}
+	ForgotPasswordVerify   = Rules{"Username": {NotEmpty()}}
+	ResetByTokenVerify     = Rules{"Token": {NotEmpty()}, "NewPassword": {NotEmpty()}}
+	VerifyCodeVerify       = Rules{"Code": {NotEmpty()}}
+	SelfRegisterVerify     = Rules{"Username": {NotEmpty()}, "Password": {NotEmpty()}, "Captcha": {NotEmpty()}, "CaptchaId": {NotEmpty()}}
 )
@@ -111,6 +111,22 @@ export const getDictionaryTreeListByType = (params) => {
   })
 }
 
+// @Tags SysDictionaryDetail
+// @Summary 批量获取层级字典详情树形结构（根据字典类型）
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param types query string true "字典类型 逗号分隔"
+// @Success 200 {string} string "{"success":true,"data":{"lists":{}},"msg":"获取成功"}"
+// @Router /sysDictionaryDetail/getDictionaryTreeListByTypes [get]
+export const getDictionaryTreeListByTypes = (params) => {
+  return service({
+    url: '/sysDictionaryDetail/getDictionaryTreeListByTypes',
+    method: 'get',
+    params
+  })
+}
+
 // @Tags SysDictionaryDetail
 // @Summary 根据父级ID获取字典详情
 // @Security ApiKeyAuth